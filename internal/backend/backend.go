@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/tinkerbell/hegel/internal/backend/file"
 	"github.com/tinkerbell/hegel/internal/backend/flatfile"
 	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/backend/mock"
 	"github.com/tinkerbell/hegel/internal/frontend/ec2"
 	"github.com/tinkerbell/hegel/internal/frontend/hack"
+	"github.com/tinkerbell/hegel/internal/frontend/instances"
+	"github.com/tinkerbell/hegel/internal/frontend/watch"
 	"github.com/tinkerbell/hegel/internal/healthcheck"
 )
 
@@ -23,6 +27,8 @@ var ErrMultipleBackends = errors.New("only one backend option can be specified")
 type Client interface {
 	ec2.Client
 	hack.Client
+	instances.Client
+	watch.Client
 	healthcheck.Client
 }
 
@@ -38,19 +44,20 @@ func New(ctx context.Context, opts Options) (Client, error) {
 	case opts.Flatfile != nil:
 		return flatfile.FromYAMLFile(opts.Flatfile.Path)
 
+	case opts.File != nil:
+		return file.NewBackend(ctx, opts.File.Path)
+
+	case opts.Mock != nil:
+		return mock.NewBackend(opts.Mock.IPs)
+
 	case opts.Kubernetes != nil:
-		kubeclient, err := kubernetes.NewBackend(ctx, kubernetes.Config{
-			Kubeconfig:       opts.Kubernetes.Kubeconfig,
-			APIServerAddress: opts.Kubernetes.APIServerAddress,
-			Namespace:        opts.Kubernetes.Namespace,
-		})
+		kubeclient, err := kubernetes.NewBackend(ctx, *opts.Kubernetes)
 		if err != nil {
 			return nil, fmt.Errorf("kubernetes client: %v", err)
 		}
-		if ok := kubeclient.WaitForCacheSync(ctx); !ok {
-			return nil, errors.New("failed to sync kubernetes cache")
-		}
 
+		// The cache syncs in the background. Readiness is reported through the healthcheck
+		// Client so we needn't block.
 		return kubeclient, nil
 
 	default:
@@ -62,7 +69,9 @@ func New(ctx context.Context, opts Options) (Client, error) {
 // specified at a time.
 type Options struct {
 	Flatfile   *Flatfile
+	File       *File
 	Kubernetes *kubernetes.Config
+	Mock       *Mock
 }
 
 func (o Options) validate() error {
@@ -72,10 +81,18 @@ func (o Options) validate() error {
 		count++
 	}
 
+	if o.File != nil {
+		count++
+	}
+
 	if o.Kubernetes != nil {
 		count++
 	}
 
+	if o.Mock != nil {
+		count++
+	}
+
 	if count > 1 {
 		return ErrMultipleBackends
 	}
@@ -88,3 +105,16 @@ type Flatfile struct {
 	// Path is a path to a YAML file containing a list of flatfile instances.
 	Path string
 }
+
+// File is the configuration for a file backend.
+type File struct {
+	// Path is a path to a JSON or YAML file containing a map of IP addresses to Hardware specs.
+	Path string
+}
+
+// Mock is the configuration for a mock backend.
+type Mock struct {
+	// IPs are the IP addresses instances are served for. When empty, instances are served for
+	// every IP address.
+	IPs []string
+}
@@ -23,6 +23,22 @@ func TestNew(t *testing.T) {
 			},
 			Error: ErrMultipleBackends,
 		},
+		{
+			Name: "FileAndFlatfile",
+			Options: Options{
+				Flatfile: &Flatfile{},
+				File:     &File{},
+			},
+			Error: ErrMultipleBackends,
+		},
+		{
+			Name: "MockAndFile",
+			Options: Options{
+				Mock: &Mock{},
+				File: &File{},
+			},
+			Error: ErrMultipleBackends,
+		},
 		{
 			Name:    "MissingBackend",
 			Options: Options{},
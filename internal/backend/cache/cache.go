@@ -0,0 +1,164 @@
+/*
+Package cache contains a backend decorator that caches instance lookups in memory. It protects
+backends from repeated lookups, such as scan traffic from unknown IPs, by caching both found
+instances and ec2.ErrInstanceNotFound results.
+*/
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// DefaultSize is the default maximum number of entries cached per lookup kind.
+const DefaultSize = 4096
+
+// Lookup kinds reported to an Observer. Each kind is cached separately.
+const (
+	LookupIP  = "ip"
+	LookupMAC = "mac"
+)
+
+// Eviction reasons reported to an Observer.
+const (
+	// EvictedCapacity indicates the least recently used entry was evicted for a new entry.
+	EvictedCapacity = "capacity"
+
+	// EvictedExpired indicates an entry was evicted when it was found to have expired.
+	EvictedExpired = "expired"
+)
+
+// Observer observes the activity of a Backend's cache, such as to instrument it. kind is one of
+// LookupIP or LookupMAC. Observers are called while the cache is locked so must not block.
+type Observer interface {
+	// Hit is called when a lookup is served from the cache.
+	Hit(kind string)
+
+	// Miss is called when a lookup is passed to the decorated client.
+	Miss(kind string)
+
+	// Evicted is called when an entry is evicted for reason, one of EvictedCapacity or
+	// EvictedExpired.
+	Evicted(kind, reason string)
+
+	// Entries is called with the number of cached entries when it may have changed.
+	Entries(kind string, n int)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) Hit(string)             {}
+func (noopObserver) Miss(string)            {}
+func (noopObserver) Evicted(string, string) {}
+func (noopObserver) Entries(string, int)    {}
+
+// Config configures a Backend.
+type Config struct {
+	// TTL is how long found instances are cached. When 0, found instances aren't cached.
+	TTL time.Duration
+
+	// NegativeTTL is how long ec2.ErrInstanceNotFound results are cached. When 0, not found
+	// results aren't cached.
+	NegativeTTL time.Duration
+
+	// Size is the maximum number of entries cached per lookup kind. When the cache is full the
+	// least recently used entry is evicted. Defaults to DefaultSize.
+	Size int
+
+	// Observer is optional. When specified it's notified of cache activity.
+	Observer Observer
+}
+
+// Backend decorates a backend.Client caching EC2 instance lookups by IP and MAC address. All
+// other calls are passed through to the decorated client.
+//
+// Cached instances may be stale for up to the configured TTL.
+type Backend struct {
+	backend.Client
+
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+	observer    Observer
+
+	byIP  *lru[ec2.Instance]
+	byMAC *lru[ec2.Instance]
+}
+
+// New creates a new Backend that decorates client.
+func New(client backend.Client, cfg Config) *Backend {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultSize
+	}
+
+	if cfg.Observer == nil {
+		cfg.Observer = noopObserver{}
+	}
+
+	evicted := func(kind string) func(reason string) {
+		return func(reason string) {
+			cfg.Observer.Evicted(kind, reason)
+		}
+	}
+
+	return &Backend{
+		Client:      client,
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		now:         time.Now,
+		observer:    cfg.Observer,
+		byIP:        newLRU[ec2.Instance](cfg.Size, evicted(LookupIP)),
+		byMAC:       newLRU[ec2.Instance](cfg.Size, evicted(LookupMAC)),
+	}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	return b.lookup(ctx, LookupIP, b.byIP, ipaddr.Normalize(ip), b.Client.GetEC2Instance)
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	return b.lookup(ctx, LookupMAC, b.byMAC, mac, b.Client.GetEC2InstanceByMAC)
+}
+
+// lookup retrieves the instance for key from cache. If it isn't cached, it's retrieved using
+// fetch and cached according to the result. Errors other than ec2.ErrInstanceNotFound are never
+// cached.
+func (b *Backend) lookup(
+	ctx context.Context,
+	kind string,
+	cache *lru[ec2.Instance],
+	key string,
+	fetch func(context.Context, string) (ec2.Instance, error),
+) (ec2.Instance, error) {
+	now := b.now()
+
+	if e, ok := cache.get(key, now); ok {
+		b.observer.Hit(kind)
+		return e.value, e.err
+	}
+	b.observer.Miss(kind)
+
+	// The miss may have evicted an expired entry so entries are reported even if the result
+	// isn't cached.
+	defer func() {
+		b.observer.Entries(kind, cache.len())
+	}()
+
+	instance, err := fetch(ctx, key)
+
+	switch {
+	case err == nil && b.ttl > 0:
+		cache.add(key, instance, nil, now.Add(b.ttl))
+	case errors.Is(err, ec2.ErrInstanceNotFound) && b.negativeTTL > 0:
+		cache.add(key, ec2.Instance{}, err, now.Add(b.negativeTTL))
+	}
+
+	return instance, err
+}
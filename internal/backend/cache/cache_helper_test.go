@@ -0,0 +1,10 @@
+package cache
+
+import "time"
+
+// NewTestBackend is the same as New but lets tests control the clock used to expire entries.
+func NewTestBackend(client *MockClient, cfg Config, now func() time.Time) *Backend {
+	b := New(client, cfg)
+	b.now = now
+	return b
+}
@@ -0,0 +1,184 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/cache"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Config        Config
+		Instance      ec2.Instance
+		Error         error
+		ExpectedCalls int
+	}{
+		{
+			Name:          "Found",
+			Config:        Config{TTL: time.Minute},
+			Instance:      ec2.Instance{Userdata: "userdata"},
+			ExpectedCalls: 1,
+		},
+		{
+			Name:          "FoundWithoutTTL",
+			Config:        Config{NegativeTTL: time.Minute},
+			Instance:      ec2.Instance{Userdata: "userdata"},
+			ExpectedCalls: 2,
+		},
+		{
+			Name:          "NotFound",
+			Config:        Config{NegativeTTL: time.Minute},
+			Error:         ec2.ErrInstanceNotFound,
+			ExpectedCalls: 1,
+		},
+		{
+			Name:          "NotFoundWithoutNegativeTTL",
+			Config:        Config{TTL: time.Minute},
+			Error:         ec2.ErrInstanceNotFound,
+			ExpectedCalls: 2,
+		},
+		{
+			Name:          "GenericError",
+			Config:        Config{TTL: time.Minute, NegativeTTL: time.Minute},
+			Error:         errors.New("generic error"),
+			ExpectedCalls: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(tc.Instance, tc.Error).
+				Times(tc.ExpectedCalls)
+
+			backend := New(client, tc.Config)
+
+			for i := 0; i < 2; i++ {
+				instance, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+				if !errors.Is(err, tc.Error) {
+					t.Fatalf("Expected: %v; Received: %v", tc.Error, err)
+				}
+
+				if diff := cmp.Diff(tc.Instance, instance); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceNormalizesIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{Userdata: "userdata"}, nil)
+
+	backend := New(client, Config{TTL: time.Minute})
+
+	// Equivalent forms of an IP share a cache entry.
+	for _, ip := range []string{"10.10.10.10", "::ffff:10.10.10.10"} {
+		instance, err := backend.GetEC2Instance(context.Background(), ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if instance.Userdata != "userdata" {
+			t.Fatalf("Expected userdata: userdata; Received: %v", instance.Userdata)
+		}
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{Userdata: "userdata"}, nil).
+		Times(1)
+
+	backend := New(client, Config{TTL: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		instance, err := backend.GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if instance.Userdata != "userdata" {
+			t.Fatalf("Expected userdata: userdata; Received: %v", instance.Userdata)
+		}
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	gomock.InOrder(
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), "10.10.10.10").
+			Return(ec2.Instance{}, ec2.ErrInstanceNotFound),
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), "10.10.10.10").
+			Return(ec2.Instance{Userdata: "userdata"}, nil),
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), "10.10.10.10").
+			Return(ec2.Instance{Userdata: "updated"}, nil),
+	)
+
+	now := time.Now()
+	backend := NewTestBackend(client, Config{TTL: time.Minute, NegativeTTL: time.Second}, func() time.Time {
+		return now
+	})
+
+	expect := func(userdata string, expectErr error) {
+		t.Helper()
+		instance, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+		if !errors.Is(err, expectErr) {
+			t.Fatalf("Expected: %v; Received: %v", expectErr, err)
+		}
+		if instance.Userdata != userdata {
+			t.Fatalf("Expected userdata: %v; Received: %v", userdata, instance.Userdata)
+		}
+	}
+
+	expect("", ec2.ErrInstanceNotFound)
+	expect("", ec2.ErrInstanceNotFound)
+
+	// The negative entry expires independently of the positive TTL.
+	now = now.Add(time.Second)
+	expect("userdata", nil)
+
+	now = now.Add(time.Minute)
+	expect("updated", nil)
+}
+
+func TestEviction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound).
+		Times(2)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.11").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound).
+		Times(1)
+
+	backend := New(client, Config{NegativeTTL: time.Minute, Size: 1})
+
+	for _, ip := range []string{"10.10.10.10", "10.10.10.11", "10.10.10.11", "10.10.10.10"} {
+		if _, err := backend.GetEC2Instance(context.Background(), ip); !errors.Is(err, ec2.ErrInstanceNotFound) {
+			t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+		}
+	}
+}
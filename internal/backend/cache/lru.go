@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a fixed size least recently used cache whose entries expire.
+type lru[V any] struct {
+	mu      sync.Mutex
+	size    int
+	items   map[string]*list.Element
+	order   *list.List // Front is most recently used.
+	evicted func(reason string)
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	err     error
+	expires time.Time
+}
+
+// newLRU creates an lru of size entries calling evicted with the reason for every eviction.
+func newLRU[V any](size int, evicted func(reason string)) *lru[V] {
+	return &lru[V]{
+		size:    size,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+		evicted: evicted,
+	}
+}
+
+// get retrieves the entry cached for key. If key isn't cached or its entry expired before now,
+// it returns false.
+func (l *lru[V]) get(key string, now time.Time) (entry[V], bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return entry[V]{}, false
+	}
+
+	e := elem.Value.(*entry[V])
+	if !now.Before(e.expires) {
+		l.order.Remove(elem)
+		delete(l.items, key)
+		l.evicted(EvictedExpired)
+		return entry[V]{}, false
+	}
+
+	l.order.MoveToFront(elem)
+	return *e, true
+}
+
+// add caches value and err for key until expires. If the cache is full, the least recently used
+// entry is evicted.
+func (l *lru[V]) add(key string, value V, err error, expires time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		elem.Value = &entry[V]{key: key, value: value, err: err, expires: expires}
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	if l.order.Len() >= l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*entry[V]).key)
+		l.evicted(EvictedCapacity)
+	}
+
+	l.items[key] = l.order.PushFront(&entry[V]{key: key, value: value, err: err, expires: expires})
+}
+
+// len returns the number of cached entries, including those that have expired but haven't been
+// evicted.
+func (l *lru[V]) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
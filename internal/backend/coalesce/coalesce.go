@@ -0,0 +1,73 @@
+/*
+Package coalesce contains a backend decorator that coalesces concurrent identical instance
+lookups. During boot storms many requests for the same IP arrive at once; coalescing them means the
+decorated backend performs a single lookup whose result, including errors, is shared by all callers
+waiting on it. Results aren't retained once the lookup completes.
+*/
+package coalesce
+
+import (
+	"context"
+	"time"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"golang.org/x/sync/singleflight"
+)
+
+// Backend decorates a backend.Client coalescing concurrent EC2 instance lookups by IP and MAC
+// address. All other calls are passed through to the decorated client.
+type Backend struct {
+	backend.Client
+
+	// timeout bounds shared lookups. When 0, they aren't bounded.
+	timeout time.Duration
+
+	byIP  singleflight.Group
+	byMAC singleflight.Group
+}
+
+// New creates a new Backend that decorates client. Shared lookups are cancelled after timeout,
+// which should match the deadline callers are given. When 0, shared lookups aren't bounded.
+func New(client backend.Client, timeout time.Duration) *Backend {
+	return &Backend{Client: client, timeout: timeout}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	return b.lookup(ctx, &b.byIP, ipaddr.Normalize(ip), b.Client.GetEC2Instance)
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	return b.lookup(ctx, &b.byMAC, mac, b.Client.GetEC2InstanceByMAC)
+}
+
+// lookup retrieves the instance for key using fetch, sharing the call with concurrent lookups of
+// the same key. The shared call isn't cancelled when a caller's ctx is done so other callers still
+// receive its result; the caller whose ctx is done returns immediately with the ctx error. The
+// shared call is instead bounded by b.timeout so a hung backend doesn't hold up later callers.
+func (b *Backend) lookup(
+	ctx context.Context,
+	group *singleflight.Group,
+	key string,
+	fetch func(context.Context, string) (ec2.Instance, error),
+) (ec2.Instance, error) {
+	result := group.DoChan(key, func() (interface{}, error) {
+		fetchCtx := context.WithoutCancel(ctx)
+		if b.timeout > 0 {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(fetchCtx, b.timeout)
+			defer cancel()
+		}
+		return fetch(fetchCtx, key)
+	})
+
+	select {
+	case r := <-result:
+		return r.Val.(ec2.Instance), r.Err
+	case <-ctx.Done():
+		return ec2.Instance{}, ctx.Err()
+	}
+}
@@ -0,0 +1,185 @@
+package coalesce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/tinkerbell/hegel/internal/backend/coalesce"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2InstanceCoalesces(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Instance ec2.Instance
+		Error    error
+	}{
+		{
+			Name:     "Found",
+			Instance: ec2.Instance{Userdata: "userdata"},
+		},
+		{
+			Name:  "NotFound",
+			Error: ec2.ErrInstanceNotFound,
+		},
+		{
+			Name:  "GenericError",
+			Error: errors.New("generic error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			const callers = 50
+
+			release := make(chan struct{})
+
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				DoAndReturn(func(context.Context, string) (ec2.Instance, error) {
+					<-release
+					return tc.Instance, tc.Error
+				}).
+				Times(1)
+
+			backend := New(client, 0)
+
+			var started, done sync.WaitGroup
+			started.Add(callers)
+			done.Add(callers)
+
+			type result struct {
+				instance ec2.Instance
+				err      error
+			}
+			results := make(chan result, callers)
+
+			for i := 0; i < callers; i++ {
+				go func() {
+					defer done.Done()
+					started.Done()
+					instance, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+					results <- result{instance, err}
+				}()
+			}
+
+			// Give the callers a chance to join the in-flight lookup before it completes.
+			started.Wait()
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			done.Wait()
+			close(results)
+
+			for r := range results {
+				if !errors.Is(r.err, tc.Error) {
+					t.Fatalf("Expected error: %v; Received: %v", tc.Error, r.err)
+				}
+				if r.instance.Userdata != tc.Instance.Userdata {
+					t.Fatalf("Expected userdata: %v; Received: %v", tc.Instance.Userdata, r.instance.Userdata)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceDoesNotRetainResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	gomock.InOrder(
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), "10.10.10.10").
+			Return(ec2.Instance{}, ec2.ErrInstanceNotFound),
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), "10.10.10.10").
+			Return(ec2.Instance{Userdata: "userdata"}, nil),
+	)
+
+	backend := New(client, 0)
+
+	if _, err := backend.GetEC2Instance(context.Background(), "10.10.10.10"); !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+
+	// Sequential lookups aren't coalesced so the second lookup observes the new instance.
+	instance, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Userdata != "userdata" {
+		t.Fatalf("Expected userdata: userdata; Received: %v", instance.Userdata)
+	}
+}
+
+func TestGetEC2InstanceCallerCancelled(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetchErr := make(chan error, 1)
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		DoAndReturn(func(ctx context.Context, _ string) (ec2.Instance, error) {
+			close(started)
+			<-release
+			// The shared lookup must not be cancelled by the caller that started it.
+			fetchErr <- ctx.Err()
+			return ec2.Instance{}, nil
+		})
+
+	backend := New(client, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := backend.GetEC2Instance(ctx, "10.10.10.10")
+		cancelled <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected: %v; Received: %v", context.Canceled, err)
+	}
+
+	close(release)
+	if err := <-fetchErr; err != nil {
+		t.Fatalf("Expected shared lookup to continue; Received: %v", err)
+	}
+}
+
+func TestGetEC2InstanceSharedLookupTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		DoAndReturn(func(ctx context.Context, _ string) (ec2.Instance, error) {
+			// Block as a hung backend would until the shared lookup is cancelled.
+			<-ctx.Done()
+			return ec2.Instance{}, ctx.Err()
+		})
+
+	backend := New(client, 50*time.Millisecond)
+
+	// Callers without a deadline of their own still don't wait on a hung backend indefinitely.
+	errs := make(chan error, 1)
+	go func() {
+		_, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected: %v; Received: %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected shared lookup to be cancelled")
+	}
+}
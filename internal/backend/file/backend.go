@@ -0,0 +1,290 @@
+/*
+Package file contains a backend that serves Tinkerbell Hardware specs from a JSON or YAML file. It
+is intended for standalone deployments, such as labs and CI, where a Kubernetes cluster isn't
+available.
+
+The file is a map of IP addresses to Hardware specs:
+
+	10.10.10.10:
+	  metadata:
+	    instance:
+	      hostname: foo
+	  userData: "#cloud-config"
+*/
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/hack"
+	"github.com/tinkerbell/hegel/internal/frontend/watch"
+	"github.com/tinkerbell/hegel/internal/reload"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// Backend is a file-based backend that serves Hardware keyed by IP address. The file is reloaded
+// whenever it changes.
+type Backend struct {
+	path   string
+	closer <-chan struct{}
+
+	mu       sync.RWMutex
+	hardware map[string]tinkv1.Hardware
+	macs     map[string]string // MAC to IP address.
+	err      error
+
+	// subscriptions maps subscriptions to the IP address they're subscribed to.
+	subscriptions map[*watch.Subscription]string
+}
+
+// NewBackend creates a new Backend instance that serves the Hardware in the file at path. It
+// launches a goroutine to reload the file when it changes until ctx is cancelled. If the file
+// cannot be loaded NewBackend returns an error.
+func NewBackend(ctx context.Context, path string) (*Backend, error) {
+	if path == "" {
+		return nil, errors.New("file: path cannot be empty")
+	}
+
+	b := &Backend{
+		path:          filepath.Clean(path),
+		closer:        ctx.Done(),
+		subscriptions: make(map[*watch.Subscription]string),
+	}
+
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	err := reload.WatchFiles(ctx, []string{b.path}, func() { _ = b.load() }, func(err error) {
+		b.mu.Lock()
+		b.err = err
+		b.mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(_ context.Context, ip string) (ec2.Instance, error) {
+	hw, ok := b.retrieveByIP(ip)
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	return kubernetes.ToEC2Instance(hw), nil
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(_ context.Context, mac string) (ec2.Instance, error) {
+	b.mu.RLock()
+	ip, ok := b.macs[mac]
+	b.mu.RUnlock()
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	hw, ok := b.retrieveByIP(ip)
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	return kubernetes.ToEC2Instance(hw), nil
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(_ context.Context, id string) (ec2.Instance, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, hw := range b.hardware {
+		if hw.Spec.Metadata != nil && hw.Spec.Metadata.Instance != nil && hw.Spec.Metadata.Instance.ID == id {
+			return kubernetes.ToEC2Instance(hw), nil
+		}
+	}
+
+	return ec2.Instance{}, ec2.ErrInstanceNotFound
+}
+
+// GetHackInstance satisfies hack.Client.
+func (b *Backend) GetHackInstance(_ context.Context, ip string) (hack.Instance, error) {
+	hw, ok := b.retrieveByIP(ip)
+	if !ok {
+		return hack.Instance{}, errors.New("no hardware found")
+	}
+
+	return kubernetes.ToHackInstance(hw)
+}
+
+// Subscribe satisfies watch.Client. The current instance is delivered immediately and subsequent
+// instances are delivered when a reload changes the Hardware.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ip = ipaddr.Normalize(ip)
+	hw, ok := b.hardware[ip]
+	if !ok {
+		return nil, ec2.ErrInstanceNotFound
+	}
+
+	sub := watch.NewSubscription()
+	sub.Publish(kubernetes.ToEC2Instance(hw))
+	b.subscriptions[sub] = ip
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-b.closer:
+		}
+
+		b.mu.Lock()
+		delete(b.subscriptions, sub)
+		b.mu.Unlock()
+
+		sub.Close()
+	}()
+
+	return sub.Events(), nil
+}
+
+// IsHealthy returns true until the context used to create the Backend is cancelled or the most
+// recent attempt to reload the file failed.
+func (b *Backend) IsHealthy(context.Context) bool {
+	select {
+	case <-b.closer:
+		return false
+	default:
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.err == nil
+}
+
+// IsReady satisfies healthcheck.Client. The file is loaded on construction so the Backend is
+// always ready.
+func (b *Backend) IsReady(context.Context) bool {
+	return true
+}
+
+func (b *Backend) retrieveByIP(ip string) (tinkv1.Hardware, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	hw, ok := b.hardware[ipaddr.Normalize(ip)]
+	return hw, ok
+}
+
+// Reload re-reads the file. If it can't be read or parsed the previously loaded Hardware continues
+// to be served. It satisfies reload.Reloader.
+func (b *Backend) Reload() error {
+	return b.load()
+}
+
+// load reads the file and replaces the served Hardware. If the file cannot be read or parsed the
+// previously loaded Hardware continues to be served.
+func (b *Backend) load() error {
+	hardware, err := readFile(b.path)
+
+	var macs map[string]string
+	if err == nil {
+		macs, err = indexMACs(hardware)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.err = err
+	if err == nil {
+		b.notify(hardware)
+		b.hardware = hardware
+		b.macs = macs
+	}
+
+	return err
+}
+
+// notify publishes Hardware that differs between the currently served Hardware and hardware to
+// subscriptions. Subscriptions whose Hardware was removed are closed. The caller must hold b.mu.
+func (b *Backend) notify(hardware map[string]tinkv1.Hardware) {
+	for sub, ip := range b.subscriptions {
+		hw, ok := hardware[ip]
+		if !ok {
+			delete(b.subscriptions, sub)
+			sub.Close()
+			continue
+		}
+
+		if !reflect.DeepEqual(b.hardware[ip], hw) {
+			sub.Publish(kubernetes.ToEC2Instance(hw))
+		}
+	}
+}
+
+// readFile reads a JSON or YAML file containing a map of IP addresses to Hardware specs. The
+// returned Hardware is keyed by IP addresses normalized with ipaddr.Normalize.
+func readFile(path string) (map[string]tinkv1.Hardware, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs map[string]tinkv1.HardwareSpec
+	if err := yaml.Unmarshal(raw, &specs); err != nil {
+		return nil, fmt.Errorf("parse %v: %v", path, err)
+	}
+
+	hardware := make(map[string]tinkv1.Hardware, len(specs))
+	for ip, spec := range specs {
+		if _, err := netip.ParseAddr(ip); err != nil {
+			return nil, fmt.Errorf("parse %v: invalid ip: %v", path, ip)
+		}
+
+		normalized := ipaddr.Normalize(ip)
+		if _, ok := hardware[normalized]; ok {
+			return nil, fmt.Errorf("parse %v: ip %v specified multiple times", path, normalized)
+		}
+		hardware[normalized] = tinkv1.Hardware{Spec: spec}
+	}
+
+	return hardware, nil
+}
+
+// indexMACs builds a map of normalized MAC addresses to the IP address of the Hardware they
+// belong to. A MAC address may only belong to a single Hardware.
+func indexMACs(hardware map[string]tinkv1.Hardware) (map[string]string, error) {
+	macs := make(map[string]string)
+	for ip, hw := range hardware {
+		for _, iface := range hw.Spec.Interfaces {
+			if iface.DHCP == nil || iface.DHCP.MAC == "" {
+				continue
+			}
+
+			mac, err := net.ParseMAC(iface.DHCP.MAC)
+			if err != nil {
+				return nil, fmt.Errorf("hardware %v: invalid mac: %v", ip, iface.DHCP.MAC)
+			}
+
+			if other, ok := macs[mac.String()]; ok && other != ip {
+				return nil, fmt.Errorf("mac %v found on multiple hardware: %v, %v", mac, other, ip)
+			}
+			macs[mac.String()] = ip
+		}
+	}
+
+	return macs, nil
+}
@@ -0,0 +1,362 @@
+package file_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/file"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestNewBackend(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Path        string
+		ExpectError bool
+	}{
+		{
+			Name: "ValidYAML",
+			Path: "testdata/TestGetEC2Instance.yml",
+		},
+		{
+			Name: "ValidJSON",
+			Path: "testdata/TestGetEC2Instance.json",
+		},
+		{
+			Name:        "MalformedYAML",
+			Path:        "testdata/TestNewBackend_Malformed.yml",
+			ExpectError: true,
+		},
+		{
+			Name:        "InvalidIP",
+			Path:        "testdata/TestNewBackend_InvalidIP.yml",
+			ExpectError: true,
+		},
+		{
+			Name:        "DuplicateIP",
+			Path:        "testdata/TestNewBackend_DuplicateIP.yml",
+			ExpectError: true,
+		},
+		{
+			Name:        "DuplicateMAC",
+			Path:        "testdata/TestNewBackend_DuplicateMAC.yml",
+			ExpectError: true,
+		},
+		{
+			Name:        "MissingFile",
+			Path:        "testdata/TestNewBackend_Missing.yml",
+			ExpectError: true,
+		},
+		{
+			Name:        "EmptyPath",
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			_, err := NewBackend(ctx, tc.Path)
+			if tc.ExpectError {
+				if err == nil {
+					t.Fatal("Expected error but received nil")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Expected nil error; Received: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEC2Instance(t *testing.T) {
+	expect := ec2.Instance{
+		Userdata: "userdata",
+		Metadata: ec2.Metadata{
+			InstanceID:    "instanceid",
+			InstanceType:  "plan",
+			Hostname:      "hostname",
+			LocalHostname: "hostname",
+			Plan:          "plan",
+			Facility:      "facility",
+			Tags:          []string{"foo", "bar"},
+			PublicIPv4:    "10.10.10.10",
+			PublicIPv4s:   []string{"10.10.10.10"},
+			MAC:           "00:00:00:00:00:01",
+			Placement:     ec2.Placement{AvailabilityZone: "facility"},
+			Network: ec2.Network{
+				Interfaces: []ec2.NetworkInterface{{MAC: "00:00:00:00:00:01"}},
+			},
+		},
+	}
+
+	cases := []struct {
+		Name             string
+		Path             string
+		LookupIP         string
+		ExpectedInstance *ec2.Instance
+		ExpectedError    error
+	}{
+		{
+			Name:             "YAMLIPFound",
+			Path:             "testdata/TestGetEC2Instance.yml",
+			LookupIP:         "10.10.10.10",
+			ExpectedInstance: &expect,
+		},
+		{
+			Name:             "JSONIPFound",
+			Path:             "testdata/TestGetEC2Instance.json",
+			LookupIP:         "10.10.10.10",
+			ExpectedInstance: &expect,
+		},
+		{
+			Name:          "IPNotFound",
+			Path:          "testdata/TestGetEC2Instance.yml",
+			LookupIP:      "9.9.9.9",
+			ExpectedError: ec2.ErrInstanceNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			backend, err := NewBackend(ctx, tc.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			instance, err := backend.GetEC2Instance(ctx, tc.LookupIP)
+
+			switch {
+			case tc.ExpectedError != nil:
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Expected: %v;\nReceived: %v", tc.ExpectedError, err)
+				}
+
+			case tc.ExpectedInstance != nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !cmp.Equal(&instance, tc.ExpectedInstance) {
+					t.Errorf(cmp.Diff(instance, tc.ExpectedInstance))
+				}
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceNormalizesIPs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hardware.yml")
+	hardware := `"::ffff:10.10.10.10":
+  userData: ipv4
+"2001:0DB8:0000:0000:0000:0000:0000:0001":
+  userData: ipv6
+"fe80::1%eth0":
+  userData: link-local
+`
+	writeFile(t, path, hardware)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := NewBackend(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"10.10.10.10":        "ipv4",
+		"::ffff:10.10.10.10": "ipv4",
+		"2001:db8::1":        "ipv6",
+		"fe80::1":            "link-local",
+		"fe80::1%eth1":       "link-local",
+	}
+
+	for ip, userdata := range cases {
+		t.Run(ip, func(t *testing.T) {
+			instance, err := backend.GetEC2Instance(ctx, ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Userdata != userdata {
+				t.Fatalf("Expected userdata: %v; Received: %v", userdata, instance.Userdata)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := NewBackend(ctx, "testdata/TestGetEC2Instance.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := backend.GetEC2InstanceByMAC(ctx, "00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Metadata.InstanceID != "instanceid" {
+		t.Fatalf("Expected instance ID: instanceid; Received: %v", instance.Metadata.InstanceID)
+	}
+
+	_, err = backend.GetEC2InstanceByMAC(ctx, "00:00:00:00:00:02")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v;\nReceived: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestGetEC2InstanceByID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := NewBackend(ctx, "testdata/TestGetEC2Instance.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := backend.GetEC2InstanceByID(ctx, "instanceid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Metadata.Hostname != "hostname" {
+		t.Fatalf("Expected hostname: hostname; Received: %v", instance.Metadata.Hostname)
+	}
+
+	_, err = backend.GetEC2InstanceByID(ctx, "missing")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v;\nReceived: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hardware.yml")
+	writeFile(t, path, "10.10.10.10:\n  userData: before\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := NewBackend(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A malformed file should mark the backend unhealthy but continue serving the last good data.
+	writeFile(t, path, "10.10.10.10:\n userData: broken\n metadata")
+	eventually(t, func() bool { return !backend.IsHealthy(ctx) })
+
+	instance, err := backend.GetEC2Instance(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Userdata != "before" {
+		t.Fatalf("Expected userdata: before; Received: %v", instance.Userdata)
+	}
+
+	writeFile(t, path, "10.10.10.11:\n  userData: after\n")
+	eventually(t, func() bool { return backend.IsHealthy(ctx) })
+
+	if _, err := backend.GetEC2Instance(ctx, "10.10.10.10"); !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v;\nReceived: %v", ec2.ErrInstanceNotFound, err)
+	}
+
+	instance, err = backend.GetEC2Instance(ctx, "10.10.10.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Userdata != "after" {
+		t.Fatalf("Expected userdata: after; Received: %v", instance.Userdata)
+	}
+}
+
+// writeFile atomically writes content to path so the backend never observes a partial write.
+func TestSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hardware.yml")
+	writeFile(t, path, "10.10.10.10:\n  userData: before\n10.10.10.11:\n  userData: other\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := NewBackend(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.Subscribe(ctx, "10.10.10.12"); !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v;\nReceived: %v", ec2.ErrInstanceNotFound, err)
+	}
+
+	events, err := backend.Subscribe(ctx, "::ffff:10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectUserdata(t, events, "before")
+
+	// Changes to other Hardware shouldn't be published.
+	writeFile(t, path, "10.10.10.10:\n  userData: before\n10.10.10.11:\n  userData: changed\n")
+	writeFile(t, path, "10.10.10.10:\n  userData: after\n10.10.10.11:\n  userData: changed\n")
+	expectUserdata(t, events, "after")
+
+	// Removing the Hardware closes the subscription.
+	writeFile(t, path, "10.10.10.11:\n  userData: changed\n")
+	select {
+	case instance, ok := <-events:
+		if ok {
+			t.Fatalf("Expected events channel to be closed; Received: %+v", instance)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for events channel to close")
+	}
+}
+
+func expectUserdata(t *testing.T, events <-chan ec2.Instance, userdata string) {
+	t.Helper()
+	select {
+	case instance, ok := <-events:
+		if !ok {
+			t.Fatal("Events channel closed unexpectedly")
+		}
+		if instance.Userdata != userdata {
+			t.Fatalf("Expected userdata: %v; Received: %v", userdata, instance.Userdata)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for instance")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
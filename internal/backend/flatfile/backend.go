@@ -3,12 +3,14 @@ package flatfile
 import (
 	"context"
 
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
 	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/watch"
 )
 
 // Backend is a file-based implementation of a backend. It's primary use-case is testing.
 type Backend struct {
-	// Map of IPv4 addresses to instances.
+	// Map of IPv4 addresses, normalized with ipaddr.Normalize, to instances.
 	instances map[string]Instance
 }
 
@@ -19,7 +21,7 @@ func NewBackend(instances []Instance) *Backend {
 
 // RetrieveEC2InstanceByIP satisfies ec2.Client.
 func (b *Backend) GetEC2Instance(_ context.Context, ip string) (ec2.Instance, error) {
-	hw, ok := b.instances[ip]
+	hw, ok := b.instances[ipaddr.Normalize(ip)]
 	if !ok {
 		return ec2.Instance{}, ec2.ErrInstanceNotFound
 	}
@@ -27,22 +29,67 @@ func (b *Backend) GetEC2Instance(_ context.Context, ip string) (ec2.Instance, er
 	return toEC2Instance(hw), nil
 }
 
+// GetEC2InstanceByMAC satisfies ec2.Client. Flatfile instances don't define MAC addresses so
+// it always returns ec2.ErrInstanceNotFound.
+func (b *Backend) GetEC2InstanceByMAC(context.Context, string) (ec2.Instance, error) {
+	return ec2.Instance{}, ec2.ErrInstanceNotFound
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(_ context.Context, id string) (ec2.Instance, error) {
+	for _, hw := range b.instances {
+		if hw.Metadata.ID == id {
+			return toEC2Instance(hw), nil
+		}
+	}
+
+	return ec2.Instance{}, ec2.ErrInstanceNotFound
+}
+
+// Subscribe satisfies watch.Client. Flatfile instances never change so only the current instance
+// is delivered.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	hw, ok := b.instances[ipaddr.Normalize(ip)]
+	if !ok {
+		return nil, ec2.ErrInstanceNotFound
+	}
+
+	sub := watch.NewSubscription()
+	sub.Publish(toEC2Instance(hw))
+
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+
+	return sub.Events(), nil
+}
+
 // IsHealthy satisfies healthcheck.Client.
 func (b *Backend) IsHealthy(context.Context) bool {
 	return true
 }
 
+// IsReady satisfies healthcheck.Client.
+func (b *Backend) IsReady(context.Context) bool {
+	return true
+}
+
 func toEC2Instance(i Instance) ec2.Instance {
 	return ec2.Instance{
-		Userdata: i.Userdata,
+		Userdata:   i.Userdata,
+		Vendordata: i.Vendordata,
 		Metadata: ec2.Metadata{
 			InstanceID:    i.Metadata.ID,
+			InstanceType:  i.Metadata.Plan,
+			Lifecycle:     i.Metadata.Lifecycle,
 			Hostname:      i.Metadata.Hostname,
 			LocalHostname: i.Metadata.LocalHostname,
 			IQN:           i.Metadata.IQN,
 			Plan:          i.Metadata.Plan,
 			Facility:      i.Metadata.Facility,
 			Tags:          i.Metadata.Tags,
+			PublicKeys:    i.Metadata.PublicKeys,
 			OperatingSystem: ec2.OperatingSystem{
 				Slug:     i.Metadata.OS.Slug,
 				Distro:   i.Metadata.OS.Distro,
@@ -52,24 +99,34 @@ func toEC2Instance(i Instance) ec2.Instance {
 					State: i.Metadata.OS.LicenseActivationState,
 				},
 			},
-			PublicIPv4: i.Metadata.IPv4.Public,
-			PublicIPv6: i.Metadata.IPv6.Public,
-			LocalIPv4:  i.Metadata.IPv4.Local,
+			PublicIPv4:  i.Metadata.IPv4.Public,
+			PublicIPv6:  i.Metadata.IPv6.Public,
+			LocalIPv4:   i.Metadata.IPv4.Local,
+			PublicIPv4s: nonEmpty(i.Metadata.IPv4.Public),
+			PublicIPv6s: nonEmpty(i.Metadata.IPv6.Public),
+			LocalIPv4s:  nonEmpty(i.Metadata.IPv4.Local),
+			Placement: ec2.Placement{
+				AvailabilityZone: i.Metadata.Facility,
+			},
+			Custom: i.Metadata.Custom,
 		},
 	}
 }
 
 // Instance is a representation of a machine instance.
 type Instance struct {
-	Userdata string `yaml:"userdata"`
-	Metadata struct {
+	Userdata   string `yaml:"userdata"`
+	Vendordata string `yaml:"vendordata"`
+	Metadata   struct {
 		ID            string   `yaml:"id"`
 		Hostname      string   `yaml:"hostname"`
 		LocalHostname string   `yaml:"localHostname"`
 		IQN           string   `yaml:"iqn"`
 		Plan          string   `yaml:"plan"`
+		Lifecycle     string   `yaml:"lifecycle"`
 		Facility      string   `yaml:"facility"`
 		Tags          []string `yaml:"tags"`
+		PublicKeys    []string `yaml:"publicKeys"`
 		IPv4          struct {
 			Local  string `yaml:"local"`
 			Public string `yaml:"public"`
@@ -84,13 +141,22 @@ type Instance struct {
 			ImageTag               string `yaml:"imageTag"`
 			LicenseActivationState string `yaml:"licenseActivationState"`
 		} `yaml:"os"`
+		Custom map[string]string `yaml:"custom"`
 	} `yaml:"metadata"`
 }
 
 func toIPInstanceMap(instances []Instance) map[string]Instance {
 	m := make(map[string]Instance, len(instances))
 	for _, i := range instances {
-		m[i.Metadata.IPv4.Public] = i
+		m[ipaddr.Normalize(i.Metadata.IPv4.Public)] = i
 	}
 	return m
 }
+
+// nonEmpty lists ip, if any. Instances have at most one address of each kind.
+func nonEmpty(ip string) []string {
+	if ip == "" {
+		return nil
+	}
+	return []string{ip}
+}
@@ -26,15 +26,19 @@ func TestGetEC2Instance(t *testing.T) {
 			Name:     "IPFound",
 			LookupIP: "10.10.10.10",
 			ExpectedInstance: &ec2.Instance{
-				Userdata: "test",
+				Userdata:   "test",
+				Vendordata: "vendor",
 				Metadata: ec2.Metadata{
 					InstanceID:    "instanceid",
+					InstanceType:  "plan",
+					Lifecycle:     "spot",
 					Hostname:      "hostname",
 					LocalHostname: "localhostname",
 					IQN:           "iqn",
 					Plan:          "plan",
 					Facility:      "facility",
 					Tags:          []string{"foo", "bar"},
+					PublicKeys:    []string{"ssh-rsa key"},
 					OperatingSystem: ec2.OperatingSystem{
 						Slug:     "slug",
 						Distro:   "distro",
@@ -44,9 +48,16 @@ func TestGetEC2Instance(t *testing.T) {
 							State: "licenseactivationstate",
 						},
 					},
-					PublicIPv4: "10.10.10.10",
-					PublicIPv6: "2001:db8:0:1:1:1:1:1",
-					LocalIPv4:  "10.10.10.11",
+					PublicIPv4:  "10.10.10.10",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					LocalIPv4:   "10.10.10.11",
+					PublicIPv4s: []string{"10.10.10.10"},
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1"},
+					LocalIPv4s:  []string{"10.10.10.11"},
+					Placement: ec2.Placement{
+						AvailabilityZone: "facility",
+					},
+					Custom: map[string]string{"rack": "r12", "network/vlan": "100"},
 				},
 			},
 		},
@@ -79,3 +90,23 @@ func TestGetEC2Instance(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEC2InstanceByID(t *testing.T) {
+	backend, err := FromYAMLFile("testdata/TestGetEC2Instance.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := backend.GetEC2InstanceByID(context.Background(), "instanceid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Metadata.PublicIPv4 != "10.10.10.10" {
+		t.Fatalf("Expected public IPv4: 10.10.10.10; Received: %v", instance.Metadata.PublicIPv4)
+	}
+
+	_, err = backend.GetEC2InstanceByID(context.Background(), "missing")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
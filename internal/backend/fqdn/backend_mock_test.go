@@ -0,0 +1,140 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/backend/backend.go
+
+// Package fqdn is a generated GoMock package.
+package fqdn
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	ec2 "github.com/tinkerbell/hegel/internal/frontend/ec2"
+	hack "github.com/tinkerbell/hegel/internal/frontend/hack"
+)
+
+// MockClient is a mock of Client interface.
+type MockClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockClientMockRecorder
+}
+
+// MockClientMockRecorder is the mock recorder for MockClient.
+type MockClientMockRecorder struct {
+	mock *MockClient
+}
+
+// NewMockClient creates a new mock instance.
+func NewMockClient(ctrl *gomock.Controller) *MockClient {
+	mock := &MockClient{ctrl: ctrl}
+	mock.recorder = &MockClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClient) EXPECT() *MockClientMockRecorder {
+	return m.recorder
+}
+
+// GetEC2Instance mocks base method.
+func (m *MockClient) GetEC2Instance(arg0 context.Context, ip string) (ec2.Instance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEC2Instance", arg0, ip)
+	ret0, _ := ret[0].(ec2.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEC2Instance indicates an expected call of GetEC2Instance.
+func (mr *MockClientMockRecorder) GetEC2Instance(arg0, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEC2Instance", reflect.TypeOf((*MockClient)(nil).GetEC2Instance), arg0, ip)
+}
+
+// GetEC2InstanceByID mocks base method.
+func (m *MockClient) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEC2InstanceByID", ctx, id)
+	ret0, _ := ret[0].(ec2.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEC2InstanceByID indicates an expected call of GetEC2InstanceByID.
+func (mr *MockClientMockRecorder) GetEC2InstanceByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEC2InstanceByID", reflect.TypeOf((*MockClient)(nil).GetEC2InstanceByID), ctx, id)
+}
+
+// GetEC2InstanceByMAC mocks base method.
+func (m *MockClient) GetEC2InstanceByMAC(arg0 context.Context, mac string) (ec2.Instance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEC2InstanceByMAC", arg0, mac)
+	ret0, _ := ret[0].(ec2.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEC2InstanceByMAC indicates an expected call of GetEC2InstanceByMAC.
+func (mr *MockClientMockRecorder) GetEC2InstanceByMAC(arg0, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEC2InstanceByMAC", reflect.TypeOf((*MockClient)(nil).GetEC2InstanceByMAC), arg0, mac)
+}
+
+// GetHackInstance mocks base method.
+func (m *MockClient) GetHackInstance(ctx context.Context, ip string) (hack.Instance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHackInstance", ctx, ip)
+	ret0, _ := ret[0].(hack.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHackInstance indicates an expected call of GetHackInstance.
+func (mr *MockClientMockRecorder) GetHackInstance(ctx, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHackInstance", reflect.TypeOf((*MockClient)(nil).GetHackInstance), ctx, ip)
+}
+
+// IsHealthy mocks base method.
+func (m *MockClient) IsHealthy(arg0 context.Context) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsHealthy", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsHealthy indicates an expected call of IsHealthy.
+func (mr *MockClientMockRecorder) IsHealthy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsHealthy", reflect.TypeOf((*MockClient)(nil).IsHealthy), arg0)
+}
+
+// IsReady mocks base method.
+func (m *MockClient) IsReady(arg0 context.Context) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsReady", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsReady indicates an expected call of IsReady.
+func (mr *MockClientMockRecorder) IsReady(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReady", reflect.TypeOf((*MockClient)(nil).IsReady), arg0)
+}
+
+// Subscribe mocks base method.
+func (m *MockClient) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", ctx, ip)
+	ret0, _ := ret[0].(<-chan ec2.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockClientMockRecorder) Subscribe(ctx, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockClient)(nil).Subscribe), ctx, ip)
+}
@@ -0,0 +1,121 @@
+/*
+Package fqdn contains a backend decorator that qualifies instance hostnames with a domain suffix.
+cloud-init uses /meta-data/local-hostname to set the instance's FQDN, such as for phone-home and
+/etc/hosts, so Hardware storing short hostnames would otherwise boot without a domain.
+*/
+package fqdn
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// label matches a DNS label.
+var label = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// ParseSuffix normalizes suffix, such as "example.com" or ".example.com.", to a domain without
+// leading or trailing dots. It returns an error if suffix isn't a domain.
+func ParseSuffix(suffix string) (string, error) {
+	domain := strings.Trim(suffix, ".")
+	if domain == "" {
+		return "", fmt.Errorf("%q is not a domain such as example.com", suffix)
+	}
+
+	for _, l := range strings.Split(domain, ".") {
+		if !label.MatchString(l) {
+			return "", fmt.Errorf("%q is not a domain such as example.com", suffix)
+		}
+	}
+
+	return domain, nil
+}
+
+// Backend decorates a backend.Client qualifying short hostnames, those without a dot, with a
+// domain so /meta-data/hostname and /meta-data/local-hostname are served as FQDNs. Hostnames that
+// are already qualified and empty hostnames are left as they are.
+type Backend struct {
+	backend.Client
+
+	domain string
+}
+
+// New creates a new Backend that decorates client qualifying hostnames with domain. domain should
+// be normalized with ParseSuffix. When domain is empty, hostnames are served as they are.
+func New(client backend.Client, domain string) *Backend {
+	return &Backend{Client: client, domain: domain}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance)
+	return instance, nil
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByMAC(ctx, mac)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance)
+	return instance, nil
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByID(ctx, id)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance)
+	return instance, nil
+}
+
+// Subscribe satisfies watch.Client.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	updates, err := b.Client.Subscribe(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(chan ec2.Instance)
+	go func() {
+		defer close(applied)
+
+		for instance := range updates {
+			b.apply(&instance)
+
+			select {
+			case applied <- instance:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return applied, nil
+}
+
+func (b *Backend) apply(instance *ec2.Instance) {
+	instance.Metadata.Hostname = b.qualify(instance.Metadata.Hostname)
+	instance.Metadata.LocalHostname = b.qualify(instance.Metadata.LocalHostname)
+}
+
+func (b *Backend) qualify(hostname string) string {
+	if b.domain == "" || hostname == "" || strings.Contains(hostname, ".") {
+		return hostname
+	}
+	return hostname + "." + b.domain
+}
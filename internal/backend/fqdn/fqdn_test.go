@@ -0,0 +1,158 @@
+package fqdn_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/fqdn"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Domain   string
+		Instance ec2.Metadata
+		Expect   ec2.Metadata
+	}{
+		{
+			Name:     "Short",
+			Domain:   "example.com",
+			Instance: ec2.Metadata{Hostname: "node-1", LocalHostname: "node-1"},
+			Expect:   ec2.Metadata{Hostname: "node-1.example.com", LocalHostname: "node-1.example.com"},
+		},
+		{
+			Name:     "AlreadyQualified",
+			Domain:   "example.com",
+			Instance: ec2.Metadata{Hostname: "node-1.other.com", LocalHostname: "node-1.example.com"},
+			Expect:   ec2.Metadata{Hostname: "node-1.other.com", LocalHostname: "node-1.example.com"},
+		},
+		{
+			Name:     "NoHostname",
+			Domain:   "example.com",
+			Instance: ec2.Metadata{InstanceID: "i-1234"},
+			Expect:   ec2.Metadata{InstanceID: "i-1234"},
+		},
+		{
+			Name:     "EmptyDomain",
+			Instance: ec2.Metadata{Hostname: "node-1", LocalHostname: "node-1"},
+			Expect:   ec2.Metadata{Hostname: "node-1", LocalHostname: "node-1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(ec2.Instance{Metadata: tc.Instance}, nil)
+
+			instance, err := New(client, tc.Domain).GetEC2Instance(context.Background(), "10.10.10.10")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.Expect, instance.Metadata); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{Metadata: ec2.Metadata{Hostname: "node-1", LocalHostname: "node-1"}}, nil)
+
+	instance, err := New(client, "example.com").GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instance.Metadata.LocalHostname != "node-1.example.com" {
+		t.Fatalf("Expected local hostname: node-1.example.com; Received: %v", instance.Metadata.LocalHostname)
+	}
+}
+
+func TestGetEC2InstanceError(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	_, err := New(client, "example.com").GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	updates := make(chan ec2.Instance, 2)
+	updates <- ec2.Instance{Metadata: ec2.Metadata{Hostname: "node-1", LocalHostname: "node-1"}}
+	updates <- ec2.Instance{Metadata: ec2.Metadata{Hostname: "node-2", LocalHostname: "node-2"}}
+	close(updates)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		Subscribe(gomock.Any(), "10.10.10.10").
+		Return((<-chan ec2.Instance)(updates), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	qualified, err := New(client, "example.com").Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []ec2.Metadata
+	for instance := range qualified {
+		received = append(received, instance.Metadata)
+	}
+
+	expect := []ec2.Metadata{
+		{Hostname: "node-1.example.com", LocalHostname: "node-1.example.com"},
+		{Hostname: "node-2.example.com", LocalHostname: "node-2.example.com"},
+	}
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestParseSuffix(t *testing.T) {
+	cases := []struct {
+		Suffix string
+		Expect string
+	}{
+		{Suffix: "example.com", Expect: "example.com"},
+		{Suffix: ".example.com.", Expect: "example.com"},
+		{Suffix: "lab", Expect: "lab"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Suffix, func(t *testing.T) {
+			domain, err := ParseSuffix(tc.Suffix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if domain != tc.Expect {
+				t.Fatalf("Expected: %v; Received: %v", tc.Expect, domain)
+			}
+		})
+	}
+}
+
+func TestParseSuffixInvalid(t *testing.T) {
+	for _, suffix := range []string{"", ".", "example..com", "exa_mple.com", "-example.com"} {
+		t.Run(suffix, func(t *testing.T) {
+			if _, err := ParseSuffix(suffix); err == nil {
+				t.Fatalf("Expected error for %q", suffix)
+			}
+		})
+	}
+}
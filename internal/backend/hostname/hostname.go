@@ -0,0 +1,162 @@
+/*
+Package hostname contains a backend decorator that derives a hostname for instances that don't
+have one. cloud-init expects /meta-data/hostname and /meta-data/local-hostname to resolve so
+Hardware without a hostname would otherwise boot with an arbitrary name.
+
+Hostnames are derived from a template such as "host-{id}" or "ip-{ip}".
+*/
+package hostname
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// Template placeholders.
+const (
+	// PlaceholderID is replaced with the instance ID.
+	PlaceholderID = "{id}"
+
+	// PlaceholderIP is replaced with the instance IP address with separators replaced by dashes,
+	// such as 10-10-10-10, so it's a valid hostname label.
+	PlaceholderIP = "{ip}"
+)
+
+var placeholder = regexp.MustCompile(`\{[^}]*\}`)
+
+// Template derives hostnames. Create one with NewTemplate.
+type Template struct {
+	raw string
+}
+
+// NewTemplate parses raw into a Template. raw must contain at least one placeholder so derived
+// hostnames are unique.
+func NewTemplate(raw string) (Template, error) {
+	placeholders := placeholder.FindAllString(raw, -1)
+	if len(placeholders) == 0 {
+		return Template{}, fmt.Errorf("template %q must contain %v or %v", raw, PlaceholderID, PlaceholderIP)
+	}
+
+	for _, p := range placeholders {
+		if p != PlaceholderID && p != PlaceholderIP {
+			return Template{}, fmt.Errorf("template %q: unknown placeholder %v", raw, p)
+		}
+	}
+
+	return Template{raw: raw}, nil
+}
+
+// Execute derives a hostname for the instance with id and ip. If a placeholder used by t has no
+// value it returns false.
+func (t Template) Execute(id, ip string) (string, bool) {
+	if strings.Contains(t.raw, PlaceholderID) && id == "" {
+		return "", false
+	}
+
+	if strings.Contains(t.raw, PlaceholderIP) && ip == "" {
+		return "", false
+	}
+
+	label := strings.NewReplacer(".", "-", ":", "-").Replace(ip)
+	return strings.NewReplacer(PlaceholderID, id, PlaceholderIP, label).Replace(t.raw), true
+}
+
+// Backend decorates a backend.Client deriving hostnames for instances that don't have one. When
+// an instance has no local hostname its hostname is used. All other calls are passed through to
+// the decorated client.
+type Backend struct {
+	backend.Client
+
+	template Template
+}
+
+// New creates a new Backend that decorates client deriving hostnames with template.
+func New(client backend.Client, template Template) *Backend {
+	return &Backend{Client: client, template: template}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance, ip)
+	return instance, nil
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByMAC(ctx, mac)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance, "")
+	return instance, nil
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByID(ctx, id)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance, "")
+	return instance, nil
+}
+
+// Subscribe satisfies watch.Client. Hostnames are derived using ip as they are for lookups by IP.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	updates, err := b.Client.Subscribe(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(chan ec2.Instance)
+	go func() {
+		defer close(applied)
+
+		for instance := range updates {
+			b.apply(&instance, ip)
+
+			select {
+			case applied <- instance:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return applied, nil
+}
+
+// apply derives the hostnames of instance that are empty. ip is the address the instance was
+// looked up by; when empty the instance's own address is used.
+func (b *Backend) apply(instance *ec2.Instance, ip string) {
+	metadata := &instance.Metadata
+
+	if metadata.Hostname == "" {
+		if ip == "" {
+			ip = metadata.LocalIPv4
+		}
+		if ip == "" {
+			ip = metadata.PublicIPv4
+		}
+
+		if hostname, ok := b.template.Execute(metadata.InstanceID, ip); ok {
+			metadata.Hostname = hostname
+		}
+	}
+
+	if metadata.LocalHostname == "" {
+		metadata.LocalHostname = metadata.Hostname
+	}
+}
@@ -0,0 +1,183 @@
+package hostname_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/hostname"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Template string
+		IP       string
+		Instance ec2.Metadata
+		Expect   ec2.Metadata
+	}{
+		{
+			Name:     "ExplicitHostname",
+			Template: "host-{id}",
+			IP:       "10.10.10.10",
+			Instance: ec2.Metadata{InstanceID: "i-1234", Hostname: "explicit", LocalHostname: "explicit.local"},
+			Expect:   ec2.Metadata{InstanceID: "i-1234", Hostname: "explicit", LocalHostname: "explicit.local"},
+		},
+		{
+			Name:     "ExplicitHostnameWithoutLocalHostname",
+			Template: "host-{id}",
+			IP:       "10.10.10.10",
+			Instance: ec2.Metadata{InstanceID: "i-1234", Hostname: "explicit"},
+			Expect:   ec2.Metadata{InstanceID: "i-1234", Hostname: "explicit", LocalHostname: "explicit"},
+		},
+		{
+			Name:     "DerivedFromID",
+			Template: "host-{id}",
+			IP:       "10.10.10.10",
+			Instance: ec2.Metadata{InstanceID: "i-1234"},
+			Expect:   ec2.Metadata{InstanceID: "i-1234", Hostname: "host-i-1234", LocalHostname: "host-i-1234"},
+		},
+		{
+			Name:     "DerivedFromIP",
+			Template: "ip-{ip}",
+			IP:       "10.10.10.10",
+			Instance: ec2.Metadata{},
+			Expect:   ec2.Metadata{Hostname: "ip-10-10-10-10", LocalHostname: "ip-10-10-10-10"},
+		},
+		{
+			Name:     "DerivedFromIPv6",
+			Template: "ip-{ip}",
+			IP:       "2001:db8::1",
+			Instance: ec2.Metadata{},
+			Expect:   ec2.Metadata{Hostname: "ip-2001-db8--1", LocalHostname: "ip-2001-db8--1"},
+		},
+		{
+			Name:     "DerivedFromIDAndIP",
+			Template: "{id}.{ip}",
+			IP:       "10.10.10.10",
+			Instance: ec2.Metadata{InstanceID: "i-1234"},
+			Expect:   ec2.Metadata{InstanceID: "i-1234", Hostname: "i-1234.10-10-10-10", LocalHostname: "i-1234.10-10-10-10"},
+		},
+		{
+			// Deriving a hostname from an empty ID would give every such instance the same name.
+			Name:     "MissingID",
+			Template: "host-{id}",
+			IP:       "10.10.10.10",
+			Instance: ec2.Metadata{},
+			Expect:   ec2.Metadata{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			template, err := NewTemplate(tc.Template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), tc.IP).
+				Return(ec2.Instance{Metadata: tc.Instance}, nil)
+
+			instance, err := New(client, template).GetEC2Instance(context.Background(), tc.IP)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.Expect, instance.Metadata); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	template, err := NewTemplate("ip-{ip}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lookups by MAC don't know the requester's IP so the instance's own address is used.
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{Metadata: ec2.Metadata{LocalIPv4: "10.10.10.11"}}, nil)
+
+	instance, err := New(client, template).GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instance.Metadata.Hostname != "ip-10-10-10-11" {
+		t.Fatalf("Expected hostname: ip-10-10-10-11; Received: %v", instance.Metadata.Hostname)
+	}
+}
+
+func TestGetEC2InstanceError(t *testing.T) {
+	template, err := NewTemplate("host-{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	_, err = New(client, template).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	template, err := NewTemplate("ip-{ip}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(chan ec2.Instance, 2)
+	updates <- ec2.Instance{}
+	updates <- ec2.Instance{Metadata: ec2.Metadata{Hostname: "configured"}}
+	close(updates)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		Subscribe(gomock.Any(), "10.10.10.10").
+		Return((<-chan ec2.Instance)(updates), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	derived, err := New(client, template).Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []ec2.Metadata
+	for instance := range derived {
+		received = append(received, instance.Metadata)
+	}
+
+	expect := []ec2.Metadata{
+		{Hostname: "ip-10-10-10-10", LocalHostname: "ip-10-10-10-10"},
+		{Hostname: "configured", LocalHostname: "configured"},
+	}
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestNewTemplateInvalid(t *testing.T) {
+	for _, raw := range []string{"", "static-hostname", "host-{name}", "{id}-{mac}"} {
+		t.Run(raw, func(t *testing.T) {
+			if _, err := NewTemplate(raw); err == nil {
+				t.Fatalf("Expected error for %q", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Package ipaddr normalizes IP addresses so backends and caches key instances consistently.
+package ipaddr
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// Normalize returns the canonical form of ip so equivalent textual forms, such as compressed and
+// expanded IPv6 addresses, match. IPv4-mapped IPv6 addresses are normalized to IPv4 and zones are
+// removed. If ip isn't a valid IP address it's returned unchanged.
+func Normalize(ip string) string {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().WithZone("").String()
+}
@@ -0,0 +1,27 @@
+package ipaddr_test
+
+import (
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/backend/ipaddr"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"10.10.10.10": "10.10.10.10",
+		"2001:db8::1": "2001:db8::1",
+		"2001:0DB8:0000:0000:0000:0000:0000:0001": "2001:db8::1",
+		"::ffff:10.10.10.10":                      "10.10.10.10",
+		"fe80::1%eth0":                            "fe80::1",
+		" 10.10.10.10 ":                           "10.10.10.10",
+		"not-an-ip":                               "not-an-ip",
+	}
+
+	for ip, expected := range cases {
+		t.Run(ip, func(t *testing.T) {
+			if normalized := Normalize(ip); normalized != expected {
+				t.Fatalf("Expected: %v; Received: %v", expected, normalized)
+			}
+		})
+	}
+}
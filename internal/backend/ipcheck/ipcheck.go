@@ -0,0 +1,88 @@
+/*
+Package ipcheck contains a backend decorator that detects instances without IP addresses. Hardware
+with instance metadata but no IPs resolves to an instance without local-ipv4, public-ipv4 or
+public-ipv6 and clients such as cloud-init can misconfigure networking without reporting an
+error.
+
+By default such instances are logged and served. In strict mode they're not served.
+*/
+package ipcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// ErrNoIPs indicates an instance has no IP addresses in its metadata.
+var ErrNoIPs = errors.New("instance has no ip addresses")
+
+// Backend decorates a backend.Client checking instances have IP addresses. All other calls are
+// passed through to the decorated client.
+type Backend struct {
+	backend.Client
+
+	logger logr.Logger
+	strict bool
+}
+
+// Option configures a Backend.
+type Option func(*Backend)
+
+// WithStrict configures the Backend to return ErrNoIPs for instances without IP addresses rather
+// than serving them.
+func WithStrict() Option {
+	return func(b *Backend) {
+		b.strict = true
+	}
+}
+
+// New creates a new Backend that decorates client logging instances without IP addresses to
+// logger.
+func New(client backend.Client, logger logr.Logger, opts ...Option) *Backend {
+	b := &Backend{Client: client, logger: logger}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	return b.check(b.Client.GetEC2Instance(ctx, ip))
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	return b.check(b.Client.GetEC2InstanceByMAC(ctx, mac))
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	return b.check(b.Client.GetEC2InstanceByID(ctx, id))
+}
+
+// check logs instance if it has no IP addresses. In strict mode it returns ErrNoIPs instead of
+// the instance.
+func (b *Backend) check(instance ec2.Instance, err error) (ec2.Instance, error) {
+	if err != nil || hasIPs(instance) {
+		return instance, err
+	}
+
+	if b.strict {
+		return ec2.Instance{}, fmt.Errorf("%w: %v", ErrNoIPs, instance.Metadata.InstanceID)
+	}
+
+	b.logger.Info("Instance has no IP addresses; clients may not configure networking",
+		"instance", instance.Metadata.InstanceID)
+	return instance, nil
+}
+
+func hasIPs(instance ec2.Instance) bool {
+	m := instance.Metadata
+	return m.LocalIPv4 != "" || m.PublicIPv4 != "" || m.PublicIPv6 != ""
+}
@@ -0,0 +1,106 @@
+package ipcheck_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/ipcheck"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2Instance(t *testing.T) {
+	withIPs := ec2.Instance{Metadata: ec2.Metadata{InstanceID: "i-1234", LocalIPv4: "10.10.10.10"}}
+	withoutIPs := ec2.Instance{Metadata: ec2.Metadata{InstanceID: "i-1234"}}
+
+	cases := []struct {
+		Name           string
+		Instance       ec2.Instance
+		Options        []Option
+		ExpectInstance ec2.Instance
+		ExpectErr      error
+		ExpectLogged   bool
+	}{
+		{
+			Name:           "Lenient",
+			Instance:       withIPs,
+			ExpectInstance: withIPs,
+		},
+		{
+			Name:           "LenientWithoutIPs",
+			Instance:       withoutIPs,
+			ExpectInstance: withoutIPs,
+			ExpectLogged:   true,
+		},
+		{
+			Name:           "LenientPublicIPv6Only",
+			Instance:       ec2.Instance{Metadata: ec2.Metadata{PublicIPv6: "2001:db8::1"}},
+			ExpectInstance: ec2.Instance{Metadata: ec2.Metadata{PublicIPv6: "2001:db8::1"}},
+		},
+		{
+			Name:           "Strict",
+			Instance:       withIPs,
+			Options:        []Option{WithStrict()},
+			ExpectInstance: withIPs,
+		},
+		{
+			Name:      "StrictWithoutIPs",
+			Instance:  withoutIPs,
+			Options:   []Option{WithStrict()},
+			ExpectErr: ErrNoIPs,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(tc.Instance, nil)
+
+			var logged bool
+			logger := funcr.New(func(string, string) { logged = true }, funcr.Options{})
+
+			instance, err := New(client, logger, tc.Options...).GetEC2Instance(context.Background(), "10.10.10.10")
+			if !errors.Is(err, tc.ExpectErr) {
+				t.Fatalf("Expected: %v; Received: %v", tc.ExpectErr, err)
+			}
+
+			if diff := cmp.Diff(tc.ExpectInstance, instance); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if logged != tc.ExpectLogged {
+				t.Fatalf("Expected logged: %v; Received: %v", tc.ExpectLogged, logged)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceBackendError(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	_, err := New(client, logr.Discard(), WithStrict()).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestGetEC2InstanceByMACStrict(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{}, nil)
+
+	_, err := New(client, logr.Discard(), WithStrict()).GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if !errors.Is(err, ErrNoIPs) {
+		t.Fatalf("Expected: %v; Received: %v", ErrNoIPs, err)
+	}
+}
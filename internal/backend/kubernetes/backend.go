@@ -2,13 +2,23 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-
+	"net"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
 	"github.com/tinkerbell/hegel/internal/frontend/ec2"
 	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
@@ -18,6 +28,27 @@ import (
 
 var errNotFound = errors.New("no hardware found")
 
+// ErrMultipleHardware indicates a lookup matched more than one Hardware and the duplicate policy
+// couldn't select one.
+var ErrMultipleHardware = errors.New("multiple hardware found")
+
+// ErrNotSynced indicates the Backend's cache didn't complete its initial sync.
+var ErrNotSynced = errors.New("cache not synced")
+
+// LifecycleAnnotation is the Hardware annotation specifying the instance lifecycle, such as
+// ec2.LifecycleSpot, served at /meta-data/instance-life-cycle.
+const LifecycleAnnotation = "hegel.tinkerbell.org/instance-life-cycle"
+
+// CustomMetadataAnnotation is the Hardware annotation specifying custom metadata served under
+// /meta-data/x. Its value is a JSON object of string values such as {"network/vlan": "100"}.
+// Invalid values are ignored.
+const CustomMetadataAnnotation = "hegel.tinkerbell.org/custom-metadata"
+
+// PrimaryMACAnnotation is the Hardware annotation specifying the MAC address of the primary
+// interface served at /meta-data/mac. When it's missing or doesn't match one of the Hardware's
+// interfaces the first interface is primary.
+const PrimaryMACAnnotation = "hegel.tinkerbell.org/primary-mac"
+
 // Build the scheme as a package variable so we don't need to perform error checks.
 var scheme = kubescheme.Scheme
 
@@ -28,7 +59,24 @@ func init() {
 // Backend is a hardware Backend backed by a Backend cluster that contains hardware resources.
 type Backend struct {
 	client listerClient
+	events eventSource
 	closer <-chan struct{}
+	synced atomic.Bool
+
+	// config is the configuration the Backend was created with.
+	config Config
+
+	onDuplicate DuplicatePolicy
+	logger      logr.Logger
+
+	// secrets resolves Secrets referenced by Hardware. When nil, Hardware referencing Secrets
+	// can't be converted.
+	secrets *secretCache
+
+	// maxRetries is how many times List calls failing with retryable errors are retried.
+	// retryDelay is the delay before the first retry; when 0, initialRetryDelay is used.
+	maxRetries int
+	retryDelay time.Duration
 
 	// WaitForCacheSync waits for the initial sync to be completed. Returns false if the cache
 	// fails to sync.
@@ -51,9 +99,7 @@ func NewBackend(ctx context.Context, cfg Config) (*Backend, error) {
 
 	conf := func(opts *cluster.Options) {
 		opts.Scheme = scheme
-		if cfg.Namespace != "" {
-			opts.Cache.DefaultNamespaces = map[string]cache.Config{cfg.Namespace: {}}
-		}
+		opts.Cache.DefaultNamespaces = cacheNamespaces(cfg.Namespaces)
 	}
 
 	clstr, err := cluster.New(cfg.ClientConfig, conf)
@@ -71,6 +117,31 @@ func NewBackend(ctx context.Context, cfg Config) (*Backend, error) {
 		return nil, fmt.Errorf("register index: %v", err)
 	}
 
+	err = clstr.GetFieldIndexer().IndexField(
+		ctx,
+		&tinkv1.Hardware{},
+		hardwareMACAddrIndex,
+		hardwareMACIndexFunc,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("register index: %v", err)
+	}
+
+	err = clstr.GetFieldIndexer().IndexField(
+		ctx,
+		&tinkv1.Hardware{},
+		hardwareInstanceIDIndex,
+		hardwareInstanceIDIndexFunc,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("register index: %v", err)
+	}
+
+	informer, err := clstr.GetCache().GetInformer(ctx, &tinkv1.Hardware{})
+	if err != nil {
+		return nil, fmt.Errorf("get hardware informer: %v", err)
+	}
+
 	// TODO(chrisdoherty4) Stop panicing on error. This will likely require exposing Start in
 	// some capacity and allowing the caller to handle the error.
 	go func() {
@@ -79,31 +150,124 @@ func NewBackend(ctx context.Context, cfg Config) (*Backend, error) {
 		}
 	}()
 
+	b := newBackend(cfg, clstr.GetClient())
+	b.closer = ctx.Done()
+	b.events = informer
+	b.secrets = newSecretCache(clstr.GetAPIReader(), secretCacheTTL)
+	b.WaitForCacheSync = clstr.GetCache().WaitForCacheSync
+
+	// Track the initial sync so readiness can be reported without blocking.
+	go func() {
+		if b.WaitForCacheSync(ctx) {
+			b.synced.Store(true)
+		}
+	}()
+
+	return b, nil
+}
+
+// newBackend creates a Backend listing Hardware with client and configured by cfg.
+func newBackend(cfg Config, client listerClient) *Backend {
 	return &Backend{
-		closer:           ctx.Done(),
-		client:           clstr.GetClient(),
-		WaitForCacheSync: clstr.GetCache().WaitForCacheSync,
-	}, nil
+		client:      client,
+		config:      cfg,
+		onDuplicate: cfg.OnDuplicate,
+		logger:      cfg.Logger,
+		maxRetries:  cfg.MaxRetries,
+	}
+}
+
+// Config returns the configuration the Backend was created with. Its ClientConfig is the
+// configuration used to connect to the API Server.
+func (b *Backend) Config() Config {
+	return b.config
+}
+
+// WaitForSync blocks until the initial cache sync has completed. If it doesn't complete within
+// timeout it returns an error wrapping ErrNotSynced. If ctx is cancelled first it returns the
+// context error.
+func (b *Backend) WaitForSync(ctx context.Context, timeout time.Duration) error {
+	syncCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if b.WaitForCacheSync(syncCtx) {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf(
+		"%w within %v: check the Kubernetes API Server is reachable and Hegel is authorized to list Hardware",
+		ErrNotSynced,
+		timeout,
+	)
+}
+
+// cacheNamespaces builds the cache configuration that restricts the cache to namespaces. When
+// namespaces is empty it returns nil so all namespaces are cached.
+func cacheNamespaces(namespaces []string) map[string]cache.Config {
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	config := make(map[string]cache.Config, len(namespaces))
+	for _, ns := range namespaces {
+		config[ns] = cache.Config{}
+	}
+	return config
+}
+
+// inCluster reports whether Hegel is running in a Kubernetes cluster with in-cluster configuration
+// available.
+var inCluster = func() bool {
+	_, err := rest.InClusterConfig()
+	return err == nil
 }
 
 func loadConfig(cfg Config) (Config, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	loadingRules.ExplicitPath = cfg.Kubeconfig
+	// Decode the kubeconfig data regardless of whether it's used so misconfiguration is surfaced.
+	var kubeconfigData []byte
+	if cfg.KubeconfigData != "" {
+		data, err := base64.StdEncoding.DecodeString(cfg.KubeconfigData)
+		if err != nil {
+			return Config{}, fmt.Errorf("decode kubeconfig data: %w", err)
+		}
+		kubeconfigData = data
+	}
 
 	overrides := &clientcmd.ConfigOverrides{
 		ClusterInfo: clientcmdapi.Cluster{
 			Server: cfg.APIServerAddress,
 		},
-		Context: clientcmdapi.Context{
-			Namespace: cfg.Namespace,
-		},
 	}
 
-	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	// The client's default namespace is only meaningful when a single namespace is targeted.
+	if len(cfg.Namespaces) == 1 {
+		overrides.Context.Namespace = cfg.Namespaces[0]
+	}
+
+	var loader clientcmd.ClientConfig
+	if kubeconfigData != nil && cfg.Kubeconfig == "" && !inCluster() {
+		kubeconfig, err := clientcmd.Load(kubeconfigData)
+		if err != nil {
+			return Config{}, fmt.Errorf("load kubeconfig data: %w", err)
+		}
+		loader = clientcmd.NewNonInteractiveClientConfig(*kubeconfig, kubeconfig.CurrentContext, overrides, nil)
+	} else {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = cfg.Kubeconfig
+		loader = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	}
+
 	config, err := loader.ClientConfig()
 	if err != nil {
 		return Config{}, err
 	}
+	config.QPS = cfg.QPS
+	config.Burst = cfg.Burst
+	config.UserAgent = cfg.UserAgent
 	cfg.ClientConfig = config
 
 	return cfg, nil
@@ -119,9 +283,15 @@ func (b *Backend) IsHealthy(context.Context) bool {
 	}
 }
 
+// IsReady returns true once the initial cache sync has completed and until the context used to
+// create the Backend is cancelled.
+func (b *Backend) IsReady(ctx context.Context) bool {
+	return b.IsHealthy(ctx) && b.synced.Load()
+}
+
 // GetEC2InstanceByIP satisfies ec2.Client.
 func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
-	hw, err := b.retrieveByIP(ctx, ip)
+	hw, err := b.retrieveByIP(ctx, dataModelEC2, ip)
 	if err != nil {
 		if errors.Is(err, errNotFound) {
 			return ec2.Instance{}, ec2.ErrInstanceNotFound
@@ -130,13 +300,81 @@ func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance,
 		return ec2.Instance{}, err
 	}
 
-	return toEC2Instance(hw), nil
+	return b.toEC2Instance(ctx, hw)
 }
 
-func (b *Backend) retrieveByIP(ctx context.Context, ip string) (tinkv1.Hardware, error) {
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	hw, err := b.retrieveByMAC(ctx, dataModelEC2, mac)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return ec2.Instance{}, ec2.ErrInstanceNotFound
+		}
+
+		return ec2.Instance{}, err
+	}
+
+	return b.toEC2Instance(ctx, hw)
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	hw, err := b.retrieveByInstanceID(ctx, dataModelEC2, id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return ec2.Instance{}, ec2.ErrInstanceNotFound
+		}
+
+		return ec2.Instance{}, err
+	}
+
+	return b.toEC2Instance(ctx, hw)
+}
+
+// ListHardwareByIP satisfies lookup.HardwareLister.
+func (b *Backend) ListHardwareByIP(ctx context.Context, ip string) ([]string, error) {
+	var hw tinkv1.HardwareList
+	if err := b.list(ctx, &hw, crclient.MatchingFields{hardwareIPAddrIndex: ipaddr.Normalize(ip)}); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(hw.Items))
+	for _, item := range hw.Items {
+		names = append(names, hardwareName(item))
+	}
+	return names, nil
+}
+
+// retrieveByIP retrieves the Hardware associated with ip for conversion to dataModel. ip is
+// normalized so any textual form of the address matches.
+func (b *Backend) retrieveByIP(ctx context.Context, dataModel, ip string) (tinkv1.Hardware, error) {
+	ctx, span := startLookupSpan(ctx, dataModel, attrClientIP.String(ip))
+	hw, err := b.retrieve(ctx, hardwareIPAddrIndex, ipaddr.Normalize(ip))
+	endLookupSpan(span, err)
+	return hw, err
+}
+
+// retrieveByMAC retrieves the Hardware associated with mac for conversion to dataModel.
+func (b *Backend) retrieveByMAC(ctx context.Context, dataModel, mac string) (tinkv1.Hardware, error) {
+	ctx, span := startLookupSpan(ctx, dataModel, attrClientMAC.String(mac))
+	hw, err := b.retrieve(ctx, hardwareMACAddrIndex, mac)
+	endLookupSpan(span, err)
+	return hw, err
+}
+
+// retrieveByInstanceID retrieves the Hardware whose instance ID is id for conversion to dataModel.
+func (b *Backend) retrieveByInstanceID(ctx context.Context, dataModel, id string) (tinkv1.Hardware, error) {
+	ctx, span := startLookupSpan(ctx, dataModel, attrInstanceID.String(id))
+	hw, err := b.retrieve(ctx, hardwareInstanceIDIndex, id)
+	endLookupSpan(span, err)
+	return hw, err
+}
+
+// retrieve retrieves the single Hardware whose index matches value.
+func (b *Backend) retrieve(ctx context.Context, index, value string) (tinkv1.Hardware, error) {
 	var hw tinkv1.HardwareList
-	err := b.client.List(ctx, &hw, crclient.MatchingFields{
-		hardwareIPAddrIndex: ip,
+	err := b.list(ctx, &hw, crclient.MatchingFields{
+		index: value,
 	})
 	if err != nil {
 		return tinkv1.Hardware{}, err
@@ -146,11 +384,31 @@ func (b *Backend) retrieveByIP(ctx context.Context, ip string) (tinkv1.Hardware,
 		return tinkv1.Hardware{}, errNotFound
 	}
 
-	if len(hw.Items) > 1 {
-		return tinkv1.Hardware{}, fmt.Errorf("multiple hardware found")
+	if len(hw.Items) == 1 {
+		return hw.Items[0], nil
+	}
+
+	// Hardware is cached across all configured namespaces so the same value may be found in
+	// more than one of them.
+	names := make([]string, 0, len(hw.Items))
+	for _, item := range hw.Items {
+		names = append(names, hardwareName(item))
+	}
+
+	selected, ok := selectHardware(hw.Items, b.onDuplicate)
+	if !ok {
+		return tinkv1.Hardware{}, fmt.Errorf("%w for %v: %v", ErrMultipleHardware, value, strings.Join(names, ", "))
 	}
 
-	return hw.Items[0], nil
+	b.logger.Info(
+		"Multiple hardware found, selecting one using the duplicate policy",
+		"value", value,
+		"hardware", names,
+		"policy", b.onDuplicate,
+		"selected", hardwareName(selected),
+	)
+
+	return selected, nil
 }
 
 // listerClient lists Kubernetes resources using a sigs.k8s.io/controller-runtime Backend.
@@ -158,15 +416,42 @@ type listerClient interface {
 	List(ctx context.Context, list crclient.ObjectList, opts ...crclient.ListOption) error
 }
 
+// secretReader reads Kubernetes resources using a sigs.k8s.io/controller-runtime client. It's
+// satisfied by the uncached API reader so Secrets aren't watched.
+type secretReader interface {
+	Get(ctx context.Context, key crclient.ObjectKey, obj crclient.Object, opts ...crclient.GetOption) error
+}
+
+// ToEC2Instance converts a Tinkerbell Hardware resource to an ec2.Instance.
+//
 //nolint:cyclop // This function is just mapping data with a bunch of nil checks, it's not complex.
-func toEC2Instance(hw tinkv1.Hardware) ec2.Instance {
+func ToEC2Instance(hw tinkv1.Hardware) ec2.Instance {
 	var i ec2.Instance
 
+	if hw.Spec.UserData != nil {
+		i.Userdata = *hw.Spec.UserData
+	}
+
+	if hw.Spec.VendorData != nil {
+		i.Vendordata = *hw.Spec.VendorData
+	}
+
+	i.Metadata.BlockDeviceMapping = toBlockDeviceMapping(hw)
+	i.Metadata.Network = toNetwork(hw)
+	i.Metadata.MAC = toPrimaryMAC(hw, i.Metadata.Network)
+	i.Metadata.Lifecycle = hw.Annotations[LifecycleAnnotation]
+	i.Metadata.Custom = toCustomMetadata(hw)
+
+	if hw.Spec.Metadata == nil {
+		return i
+	}
+
 	if hw.Spec.Metadata.Instance != nil {
 		i.Metadata.InstanceID = hw.Spec.Metadata.Instance.ID
 		i.Metadata.Hostname = hw.Spec.Metadata.Instance.Hostname
 		i.Metadata.LocalHostname = hw.Spec.Metadata.Instance.Hostname
 		i.Metadata.Tags = hw.Spec.Metadata.Instance.Tags
+		i.Metadata.PublicKeys = hw.Spec.Metadata.Instance.SSHKeys
 
 		if hw.Spec.Metadata.Instance.OperatingSystem != nil {
 			i.Metadata.OperatingSystem.Slug = hw.Spec.Metadata.Instance.OperatingSystem.Slug
@@ -175,40 +460,163 @@ func toEC2Instance(hw tinkv1.Hardware) ec2.Instance {
 			i.Metadata.OperatingSystem.ImageTag = hw.Spec.Metadata.Instance.OperatingSystem.ImageTag
 		}
 
-		// Iterate over all IPs and set the first one for IPv4 and IPv6 as the values in the
-		// instance metadata.
+		// Iterate over all IPs and list them by kind. The first one of each kind is also set as
+		// the singular value in the instance metadata.
 		for _, ip := range hw.Spec.Metadata.Instance.Ips {
+			switch {
 			// Public IPv4
-			if ip.Family == 4 && ip.Public && i.Metadata.PublicIPv4 == "" {
-				i.Metadata.PublicIPv4 = ip.Address
-			}
+			case ip.Family == 4 && ip.Public:
+				i.Metadata.PublicIPv4s = append(i.Metadata.PublicIPv4s, ip.Address)
 
 			// Private IPv4
-			if ip.Family == 4 && !ip.Public && i.Metadata.LocalIPv4 == "" {
-				i.Metadata.LocalIPv4 = ip.Address
-			}
+			case ip.Family == 4 && !ip.Public:
+				i.Metadata.LocalIPv4s = append(i.Metadata.LocalIPv4s, ip.Address)
 
 			// Public IPv6
-			if ip.Family == 6 && i.Metadata.PublicIPv6 == "" {
-				i.Metadata.PublicIPv6 = ip.Address
+			case ip.Family == 6:
+				i.Metadata.PublicIPv6s = append(i.Metadata.PublicIPv6s, ip.Address)
 			}
 		}
+		i.Metadata.PublicIPv4 = first(i.Metadata.PublicIPv4s)
+		i.Metadata.LocalIPv4 = first(i.Metadata.LocalIPv4s)
+		i.Metadata.PublicIPv6 = first(i.Metadata.PublicIPv6s)
+	}
+
+	if hw.Spec.Metadata.Custom != nil {
+		// Marshaling the typed custom metadata can't fail.
+		i.Metadata.HardwareCustom, _ = json.Marshal(hw.Spec.Metadata.Custom)
 	}
 
 	if hw.Spec.Metadata.Facility != nil {
 		i.Metadata.Plan = hw.Spec.Metadata.Facility.PlanSlug
+		i.Metadata.InstanceType = hw.Spec.Metadata.Facility.PlanSlug
 		i.Metadata.Facility = hw.Spec.Metadata.Facility.FacilityCode
+		i.Metadata.Placement.AvailabilityZone = hw.Spec.Metadata.Facility.FacilityCode
 	}
 
-	if hw.Spec.UserData != nil {
-		i.Userdata = *hw.Spec.UserData
+	return i
+}
+
+// toBlockDeviceMapping builds the block device mapping for hw from its disks and storage
+// metadata. The root device is the device mounted at / if specified, else the first disk. All
+// other disks are ephemeral.
+func toBlockDeviceMapping(hw tinkv1.Hardware) ec2.BlockDeviceMapping {
+	var disks []string
+	addDisk := func(device string) {
+		if device != "" && !slices.Contains(disks, device) {
+			disks = append(disks, device)
+		}
 	}
 
-	// TODO(chrisdoherty4) Support public keys. The frontend doesn't handle public keys correctly
-	// as it expects a single string and just outputs that key. Until we can support multiple keys
-	// its not worth adding it to the metadata.
-	//
-	// https://github.com/tinkerbell/hegel/issues/165
+	for _, disk := range hw.Spec.Disks {
+		addDisk(disk.Device)
+	}
 
-	return i
+	var storage *tinkv1.MetadataInstanceStorage
+	if hw.Spec.Metadata != nil && hw.Spec.Metadata.Instance != nil {
+		storage = hw.Spec.Metadata.Instance.Storage
+	}
+
+	var mapping ec2.BlockDeviceMapping
+	if storage != nil {
+		for _, disk := range storage.Disks {
+			if disk != nil {
+				addDisk(disk.Device)
+			}
+		}
+
+		for _, fs := range storage.Filesystems {
+			if fs != nil && fs.Mount != nil && fs.Mount.Point == "/" {
+				mapping.Root = fs.Mount.Device
+				break
+			}
+		}
+	}
+
+	if mapping.Root == "" && len(disks) > 0 {
+		mapping.Root = disks[0]
+	}
+
+	for _, disk := range disks {
+		// The root device may be a partition of a disk such as /dev/sda3 on /dev/sda.
+		if !strings.HasPrefix(mapping.Root, disk) {
+			mapping.Ephemeral = append(mapping.Ephemeral, disk)
+		}
+	}
+
+	return mapping
+}
+
+// first returns the first of ips or an empty string if there are none.
+func first(ips []string) string {
+	if len(ips) == 0 {
+		return ""
+	}
+	return ips[0]
+}
+
+// toCustomMetadata parses the CustomMetadataAnnotation of hw. If hw has no annotation or it isn't
+// a JSON object of string values it returns nil.
+func toCustomMetadata(hw tinkv1.Hardware) map[string]string {
+	raw, ok := hw.Annotations[CustomMetadataAnnotation]
+	if !ok {
+		return nil
+	}
+
+	var custom map[string]string
+	if err := json.Unmarshal([]byte(raw), &custom); err != nil {
+		return nil
+	}
+	return custom
+}
+
+// toPrimaryMAC selects the MAC address of the primary interface in network according to the
+// PrimaryMACAnnotation of hw. If network has no interfaces it returns an empty string.
+func toPrimaryMAC(hw tinkv1.Hardware, network ec2.Network) string {
+	if len(network.Interfaces) == 0 {
+		return ""
+	}
+
+	if primary, err := net.ParseMAC(hw.Annotations[PrimaryMACAnnotation]); err == nil {
+		for _, iface := range network.Interfaces {
+			if iface.MAC == primary.String() {
+				return iface.MAC
+			}
+		}
+	}
+
+	return network.Interfaces[0].MAC
+}
+
+// toNetwork builds the network metadata for hw from its DHCP configured interfaces. Interfaces
+// without a valid MAC are omitted.
+func toNetwork(hw tinkv1.Hardware) ec2.Network {
+	var network ec2.Network
+	for _, iface := range hw.Spec.Interfaces {
+		if iface.DHCP == nil {
+			continue
+		}
+
+		mac, err := net.ParseMAC(iface.DHCP.MAC)
+		if err != nil {
+			continue
+		}
+
+		netIface := ec2.NetworkInterface{MAC: mac.String()}
+		if ip := iface.DHCP.IP; ip != nil {
+			if addr := net.ParseIP(ip.Address).To4(); addr != nil {
+				netIface.LocalIPv4s = []string{addr.String()}
+
+				if mask := net.ParseIP(ip.Netmask).To4(); mask != nil {
+					subnet := net.IPNet{IP: addr.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+					netIface.SubnetIPv4CIDRBlock = subnet.String()
+				}
+			}
+			netIface.Gateway = ip.Gateway
+		}
+
+		network.Interfaces = append(network.Interfaces, netIface)
+	}
+
+	return network
 }
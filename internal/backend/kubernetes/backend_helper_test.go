@@ -1,5 +1,12 @@
 package kubernetes
 
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
 // NewTestBackend isn't representative of how Backends are constructed but is useful
 // when wanting to validate the business logic around data retrieval and conversion.
 func NewTestBackend(c listerClient, closer <-chan struct{}) *Backend {
@@ -8,3 +15,63 @@ func NewTestBackend(c listerClient, closer <-chan struct{}) *Backend {
 		closer: closer,
 	}
 }
+
+// NewTestBackendWithEvents is the same as NewTestBackend but configures the Backend with an
+// event source for subscriptions.
+func NewTestBackendWithEvents(c listerClient, events eventSource, closer <-chan struct{}) *Backend {
+	return &Backend{
+		client: c,
+		events: events,
+		closer: closer,
+	}
+}
+
+// NewTestBackendWithDuplicatePolicy is the same as NewTestBackend but configures the Backend to
+// resolve lookups matching more than one Hardware using policy.
+func NewTestBackendWithDuplicatePolicy(c listerClient, policy DuplicatePolicy, logger logr.Logger) *Backend {
+	return &Backend{
+		client:      c,
+		onDuplicate: policy,
+		logger:      logger,
+	}
+}
+
+// NewTestBackendWithConfig is the same as NewTestBackend but configures the Backend with cfg as
+// NewBackend does. Retries are delayed by a millisecond.
+func NewTestBackendWithConfig(c listerClient, cfg Config) *Backend {
+	b := newBackend(cfg, c)
+	b.retryDelay = time.Millisecond
+	return b
+}
+
+// IsRetryable exposes isRetryable for testing.
+var IsRetryable = isRetryable
+
+// CacheNamespaces exposes cacheNamespaces for testing.
+var CacheNamespaces = cacheNamespaces
+
+// LoadConfig exposes loadConfig for testing.
+var LoadConfig = loadConfig
+
+// SetInCluster overrides in-cluster detection for the duration of t.
+func SetInCluster(t *testing.T, v bool) {
+	t.Helper()
+	original := inCluster
+	inCluster = func() bool { return v }
+	t.Cleanup(func() { inCluster = original })
+}
+
+// HardwareIPIndex is the field index Hardware are listed by when looked up by IP.
+const HardwareIPIndex = hardwareIPAddrIndex
+
+// HardwareIPIndexFunc exposes hardwareIPIndexFunc for testing.
+var HardwareIPIndexFunc = hardwareIPIndexFunc
+
+// NewTestBackendWithSecrets is the same as NewTestBackend but configures the Backend to read
+// Secrets referenced by Hardware from reader caching them for ttl.
+func NewTestBackendWithSecrets(c listerClient, reader secretReader, ttl time.Duration) *Backend {
+	return &Backend{
+		client:  c,
+		secrets: newSecretCache(reader, ttl),
+	}
+}
@@ -53,3 +53,45 @@ func (mr *MocklisterClientMockRecorder) List(ctx, list interface{}, opts ...inte
 	varargs := append([]interface{}{ctx, list}, opts...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MocklisterClient)(nil).List), varargs...)
 }
+
+// MocksecretReader is a mock of secretReader interface.
+type MocksecretReader struct {
+	ctrl     *gomock.Controller
+	recorder *MocksecretReaderMockRecorder
+}
+
+// MocksecretReaderMockRecorder is the mock recorder for MocksecretReader.
+type MocksecretReaderMockRecorder struct {
+	mock *MocksecretReader
+}
+
+// NewMocksecretReader creates a new mock instance.
+func NewMocksecretReader(ctrl *gomock.Controller) *MocksecretReader {
+	mock := &MocksecretReader{ctrl: ctrl}
+	mock.recorder = &MocksecretReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksecretReader) EXPECT() *MocksecretReaderMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MocksecretReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, key, obj}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Get", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Get indicates an expected call of Get.
+func (mr *MocksecretReaderMockRecorder) Get(ctx, key, obj interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key, obj}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MocksecretReader)(nil).Get), varargs...)
+}
@@ -4,14 +4,23 @@ package kubernetes_test
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
 	. "github.com/tinkerbell/hegel/internal/backend/kubernetes"
 	"github.com/tinkerbell/hegel/internal/frontend/ec2"
 	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -35,6 +44,7 @@ func TestGetEC2Instance(t *testing.T) {
 							ID:       "instance-id",
 							Hostname: "instance-hostname",
 							Tags:     []string{"tag"},
+							SSHKeys:  []string{"ssh-rsa key"},
 							OperatingSystem: &tinkv1.MetadataInstanceOperatingSystem{
 								Slug:     "slug",
 								Distro:   "distro",
@@ -57,10 +67,14 @@ func TestGetEC2Instance(t *testing.T) {
 					InstanceID:    "instance-id",
 					Hostname:      "instance-hostname",
 					LocalHostname: "instance-hostname",
+					InstanceType:  "plan-slug",
 					Plan:          "plan-slug",
 					Facility:      "facility-code",
+					Placement:     ec2.Placement{AvailabilityZone: "facility-code"},
 					Tags:          []string{"tag"},
+					PublicKeys:    []string{"ssh-rsa key"},
 					PublicIPv4:    "10.10.10.10",
+					PublicIPv4s:   []string{"10.10.10.10"},
 					OperatingSystem: ec2.OperatingSystem{
 						Slug:     "slug",
 						Distro:   "distro",
@@ -103,6 +117,7 @@ func TestGetEC2Instance(t *testing.T) {
 					LocalHostname: "instance-hostname",
 					Tags:          []string{"tag"},
 					PublicIPv4:    "10.10.10.10",
+					PublicIPv4s:   []string{"10.10.10.10"},
 					OperatingSystem: ec2.OperatingSystem{
 						Slug:     "slug",
 						Distro:   "distro",
@@ -127,8 +142,261 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					Plan:     "plan-slug",
-					Facility: "facility-code",
+					InstanceType: "plan-slug",
+					Plan:         "plan-slug",
+					Facility:     "facility-code",
+					Placement:    ec2.Placement{AvailabilityZone: "facility-code"},
+				},
+			},
+		},
+		{
+			Name: "NilMetadata",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					UserData: ptr("userdata"),
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Userdata: "userdata",
+			},
+		},
+		{
+			Name: "Vendordata",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					UserData:   ptr("userdata"),
+					VendorData: ptr("vendordata"),
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Userdata:   "userdata",
+				Vendordata: "vendordata",
+			},
+		},
+		{
+			Name: "CustomMetadata",
+			Hardware: tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						CustomMetadataAnnotation: `{"rack": "r12", "network/vlan": "100"}`,
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					Custom: map[string]string{"rack": "r12", "network/vlan": "100"},
+				},
+			},
+		},
+		{
+			Name: "InvalidCustomMetadata",
+			Hardware: tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{CustomMetadataAnnotation: `{"rack": 12}`},
+				},
+			},
+			ExpectedInstance: ec2.Instance{},
+		},
+		{
+			Name: "HardwareCustomMetadata",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Metadata: &tinkv1.HardwareMetadata{
+						Custom: &tinkv1.MetadataCustom{
+							PreinstalledOperatingSystemVersion: &tinkv1.MetadataInstanceOperatingSystem{
+								Slug:    "ubuntu_22_04",
+								Version: "22.04",
+							},
+							PrivateSubnets: []string{"10.0.0.0/8"},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					HardwareCustom: []byte(
+						`{"preinstalled_operating_system_version":{"slug":"ubuntu_22_04","version":"22.04"},` +
+							`"private_subnets":["10.0.0.0/8"]}`,
+					),
+				},
+			},
+		},
+		{
+			Name: "Lifecycle",
+			Hardware: tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{LifecycleAnnotation: ec2.LifecycleSpot},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					Lifecycle: ec2.LifecycleSpot,
+				},
+			},
+		},
+		{
+			Name: "BlockDevicesFromRootMount",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Disks: []tinkv1.Disk{{Device: "/dev/sda"}, {Device: "/dev/sdb"}},
+					Metadata: &tinkv1.HardwareMetadata{
+						Instance: &tinkv1.MetadataInstance{
+							Storage: &tinkv1.MetadataInstanceStorage{
+								Disks: []*tinkv1.MetadataInstanceStorageDisk{
+									{Device: "/dev/sda"},
+									{Device: "/dev/sdc"},
+								},
+								Filesystems: []*tinkv1.MetadataInstanceStorageFilesystem{
+									{Mount: &tinkv1.MetadataInstanceStorageMount{Device: "/dev/sda1", Point: "/boot"}},
+									{Mount: &tinkv1.MetadataInstanceStorageMount{Device: "/dev/sda3", Point: "/"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					BlockDeviceMapping: ec2.BlockDeviceMapping{
+						Root:      "/dev/sda3",
+						Ephemeral: []string{"/dev/sdb", "/dev/sdc"},
+					},
+				},
+			},
+		},
+		{
+			Name: "BlockDevicesWithoutRootMount",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Disks: []tinkv1.Disk{{Device: "/dev/nvme0n1"}, {Device: "/dev/nvme1n1"}},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					BlockDeviceMapping: ec2.BlockDeviceMapping{
+						Root:      "/dev/nvme0n1",
+						Ephemeral: []string{"/dev/nvme1n1"},
+					},
+				},
+			},
+		},
+		{
+			Name: "DualNetworkInterfaces",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Interfaces: []tinkv1.Interface{
+						{
+							DHCP: &tinkv1.DHCP{
+								MAC: "00:00:00:00:00:01",
+								IP: &tinkv1.IP{
+									Address: "10.10.10.10",
+									Netmask: "255.255.255.0",
+									Gateway: "10.10.10.1",
+									Family:  4,
+								},
+							},
+						},
+						{
+							DHCP: &tinkv1.DHCP{
+								MAC: "00:00:00:00:00:02",
+								IP: &tinkv1.IP{
+									Address: "172.16.5.10",
+									Netmask: "255.255.0.0",
+									Family:  4,
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					MAC: "00:00:00:00:00:01",
+					Network: ec2.Network{
+						Interfaces: []ec2.NetworkInterface{
+							{
+								MAC:                 "00:00:00:00:00:01",
+								LocalIPv4s:          []string{"10.10.10.10"},
+								SubnetIPv4CIDRBlock: "10.10.10.0/24",
+								Gateway:             "10.10.10.1",
+							},
+							{
+								MAC:                 "00:00:00:00:00:02",
+								LocalIPv4s:          []string{"172.16.5.10"},
+								SubnetIPv4CIDRBlock: "172.16.0.0/16",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "NetworkInterfaceWithoutIP",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Interfaces: []tinkv1.Interface{
+						{DHCP: &tinkv1.DHCP{MAC: "00:00:00:00:00:01"}},
+						{Netboot: &tinkv1.Netboot{}},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					MAC: "00:00:00:00:00:01",
+					Network: ec2.Network{
+						Interfaces: []ec2.NetworkInterface{{MAC: "00:00:00:00:00:01"}},
+					},
+				},
+			},
+		},
+		{
+			Name: "DesignatedPrimaryInterface",
+			Hardware: tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PrimaryMACAnnotation: "00:00:00:00:00:02"},
+				},
+				Spec: tinkv1.HardwareSpec{
+					Interfaces: []tinkv1.Interface{
+						{DHCP: &tinkv1.DHCP{MAC: "00:00:00:00:00:01"}},
+						{DHCP: &tinkv1.DHCP{MAC: "00:00:00:00:00:02"}},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					MAC: "00:00:00:00:00:02",
+					Network: ec2.Network{
+						Interfaces: []ec2.NetworkInterface{
+							{MAC: "00:00:00:00:00:01"},
+							{MAC: "00:00:00:00:00:02"},
+						},
+					},
+				},
+			},
+		},
+		{
+			// A designated MAC that isn't one of the Hardware's interfaces is ignored.
+			Name: "UnknownPrimaryInterface",
+			Hardware: tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PrimaryMACAnnotation: "00:00:00:00:00:03"},
+				},
+				Spec: tinkv1.HardwareSpec{
+					Interfaces: []tinkv1.Interface{
+						{DHCP: &tinkv1.DHCP{MAC: "00:00:00:00:00:01"}},
+						{DHCP: &tinkv1.DHCP{MAC: "00:00:00:00:00:02"}},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					MAC: "00:00:00:00:00:01",
+					Network: ec2.Network{
+						Interfaces: []ec2.NetworkInterface{
+							{MAC: "00:00:00:00:00:01"},
+							{MAC: "00:00:00:00:00:02"},
+						},
+					},
 				},
 			},
 		},
@@ -162,10 +430,13 @@ func TestGetEC2Instance(t *testing.T) {
 					InstanceID:    "instance-id",
 					Hostname:      "instance-hostname",
 					LocalHostname: "instance-hostname",
+					InstanceType:  "plan-slug",
 					Plan:          "plan-slug",
 					Facility:      "facility-code",
+					Placement:     ec2.Placement{AvailabilityZone: "facility-code"},
 					Tags:          []string{"tag"},
 					PublicIPv4:    "10.10.10.10",
+					PublicIPv4s:   []string{"10.10.10.10"},
 				},
 			},
 		},
@@ -193,7 +464,8 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					PublicIPv4: "10.10.10.10",
+					PublicIPv4:  "10.10.10.10",
+					PublicIPv4s: []string{"10.10.10.10", "172.15.0.1"},
 				},
 			},
 		},
@@ -217,7 +489,8 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					LocalIPv4: "10.10.10.11",
+					LocalIPv4:  "10.10.10.11",
+					LocalIPv4s: []string{"10.10.10.11"},
 				},
 			},
 		},
@@ -247,7 +520,8 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					LocalIPv4: "10.10.10.10",
+					LocalIPv4:  "10.10.10.10",
+					LocalIPv4s: []string{"10.10.10.10", "172.15.0.1"},
 				},
 			},
 		},
@@ -276,8 +550,10 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					PublicIPv4: "10.10.10.10",
-					LocalIPv4:  "172.15.0.1",
+					PublicIPv4:  "10.10.10.10",
+					LocalIPv4:   "172.15.0.1",
+					PublicIPv4s: []string{"10.10.10.10"},
+					LocalIPv4s:  []string{"172.15.0.1"},
 				},
 			},
 		},
@@ -306,8 +582,10 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					PublicIPv4: "172.15.0.1",
-					LocalIPv4:  "10.10.10.10",
+					PublicIPv4:  "172.15.0.1",
+					LocalIPv4:   "10.10.10.10",
+					PublicIPv4s: []string{"172.15.0.1"},
+					LocalIPv4s:  []string{"10.10.10.10"},
 				},
 			},
 		},
@@ -330,7 +608,8 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					PublicIPv6: "2001:db8:0:1:1:1:1:1",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1"},
 				},
 			},
 		},
@@ -358,82 +637,810 @@ func TestGetEC2Instance(t *testing.T) {
 			},
 			ExpectedInstance: ec2.Instance{
 				Metadata: ec2.Metadata{
-					PublicIPv6: "2001:db8:0:1:1:1:1:1",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1", "1001:ca5:0:1:1:1:1:1"},
 				},
 			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.Name, func(t *testing.T) {
-			ctrl := gomock.NewController(t)
-			lister := NewMocklisterClient(ctrl)
-			lister.EXPECT().
-				List(gomock.Any(), gomock.Any(), gomock.Any()).
-				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
-					l.Items = append(l.Items, tc.Hardware)
-					return nil
-				})
-
-			client := NewTestBackend(lister, nil)
-
-			instance, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			if !cmp.Equal(instance, tc.ExpectedInstance) {
-				t.Fatal(cmp.Diff(instance, tc.ExpectedInstance))
-			}
-		})
-	}
-}
-
-func TestGetEC2InstanceWithClientError(t *testing.T) {
-	expect := errors.New("foo-bar")
-	ctrl := gomock.NewController(t)
-	lister := NewMocklisterClient(ctrl)
-	lister.EXPECT().
-		List(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(expect)
-
-	client := NewTestBackend(lister, nil)
-
-	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
-	if !errors.Is(err, expect) {
-		t.Fatal(err)
-	}
-}
-
-func TestGetEC2InstanceWithGt1Result(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	lister := NewMocklisterClient(ctrl)
-	lister.EXPECT().
-		List(gomock.Any(), gomock.Any(), gomock.Any()).
-		DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
-			l.Items = make([]tinkv1.Hardware, 2)
-			return nil
-		})
-
-	client := NewTestBackend(lister, nil)
-
-	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
-	if err == nil {
-		t.Fatal("Expected error for > 2 results")
-	}
-}
-
-func TestGetEC2InstanceWithNoResults(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	lister := NewMocklisterClient(ctrl)
-	lister.EXPECT().
-		List(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(nil)
-
-	client := NewTestBackend(lister, nil)
-
-	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
-	if !errors.Is(err, ec2.ErrInstanceNotFound) {
-		t.Fatalf("Expected: ec2.ErrInstanceNotFound; Received: %v", err)
-	}
+		{
+			Name: "TwoPublicIPv4sAndIPv6",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Metadata: &tinkv1.HardwareMetadata{
+						Instance: &tinkv1.MetadataInstance{
+							Ips: []*tinkv1.MetadataInstanceIP{
+								{
+									Address: "203.0.113.10",
+									Family:  4,
+									Public:  true,
+								},
+								{
+									Address: "2001:db8:0:1:1:1:1:1",
+									Family:  6,
+									Public:  true,
+								},
+								{
+									Address: "203.0.113.11",
+									Family:  4,
+									Public:  true,
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					PublicIPv4:  "203.0.113.10",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					PublicIPv4s: []string{"203.0.113.10", "203.0.113.11"},
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1"},
+				},
+			},
+		},
+		{
+			Name: "PrivateIPv4ThenIPv6ThenPublicIPv4",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Metadata: &tinkv1.HardwareMetadata{
+						Instance: &tinkv1.MetadataInstance{
+							Ips: []*tinkv1.MetadataInstanceIP{
+								{
+									Address: "172.15.0.1",
+									Family:  4,
+									Public:  false,
+								},
+								{
+									Address: "2001:db8:0:1:1:1:1:1",
+									Family:  6,
+									Public:  true,
+								},
+								{
+									Address: "10.10.10.10",
+									Family:  4,
+									Public:  true,
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					PublicIPv4:  "10.10.10.10",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					LocalIPv4:   "172.15.0.1",
+					PublicIPv4s: []string{"10.10.10.10"},
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1"},
+					LocalIPv4s:  []string{"172.15.0.1"},
+				},
+			},
+		},
+		{
+			Name: "IPv6ThenPublicIPv4ThenPrivateIPv4",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Metadata: &tinkv1.HardwareMetadata{
+						Instance: &tinkv1.MetadataInstance{
+							Ips: []*tinkv1.MetadataInstanceIP{
+								{
+									Address: "2001:db8:0:1:1:1:1:1",
+									Family:  6,
+									Public:  true,
+								},
+								{
+									Address: "10.10.10.10",
+									Family:  4,
+									Public:  true,
+								},
+								{
+									Address: "172.15.0.1",
+									Family:  4,
+									Public:  false,
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					PublicIPv4:  "10.10.10.10",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					LocalIPv4:   "172.15.0.1",
+					PublicIPv4s: []string{"10.10.10.10"},
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1"},
+					LocalIPv4s:  []string{"172.15.0.1"},
+				},
+			},
+		},
+		{
+			Name: "InterleavedPublicAndPrivateIPs",
+			Hardware: tinkv1.Hardware{
+				Spec: tinkv1.HardwareSpec{
+					Metadata: &tinkv1.HardwareMetadata{
+						Instance: &tinkv1.MetadataInstance{
+							Ips: []*tinkv1.MetadataInstanceIP{
+								{
+									Address: "172.15.0.1",
+									Family:  4,
+									Public:  false,
+								},
+								{
+									Address: "10.10.10.10",
+									Family:  4,
+									Public:  true,
+								},
+								{
+									Address: "172.15.0.2",
+									Family:  4,
+									Public:  false,
+								},
+								{
+									Address: "10.10.10.11",
+									Family:  4,
+									Public:  true,
+								},
+								{
+									Address: "2001:db8:0:1:1:1:1:1",
+									Family:  6,
+									Public:  true,
+								},
+								{
+									Address: "1001:ca5:0:1:1:1:1:1",
+									Family:  6,
+									Public:  true,
+								},
+							},
+						},
+					},
+				},
+			},
+			ExpectedInstance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					PublicIPv4:  "10.10.10.10",
+					PublicIPv6:  "2001:db8:0:1:1:1:1:1",
+					LocalIPv4:   "172.15.0.1",
+					PublicIPv4s: []string{"10.10.10.10", "10.10.10.11"},
+					PublicIPv6s: []string{"2001:db8:0:1:1:1:1:1", "1001:ca5:0:1:1:1:1:1"},
+					LocalIPv4s:  []string{"172.15.0.1", "172.15.0.2"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, tc.Hardware)
+					return nil
+				})
+
+			client := NewTestBackend(lister, nil)
+
+			instance, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(instance, tc.ExpectedInstance) {
+				t.Fatal(cmp.Diff(instance, tc.ExpectedInstance))
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceWithClientError(t *testing.T) {
+	expect := errors.New("foo-bar")
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(expect)
+
+	client := NewTestBackend(lister, nil)
+
+	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, expect) {
+		t.Fatal(err)
+	}
+}
+
+func TestGetEC2InstanceWithGt1Result(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+			l.Items = make([]tinkv1.Hardware, 2)
+			return nil
+		})
+
+	client := NewTestBackend(lister, nil)
+
+	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+	if err == nil {
+		t.Fatal("Expected error for > 2 results")
+	}
+}
+
+func TestGetEC2InstanceWithNoResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	client := NewTestBackend(lister, nil)
+
+	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: ec2.ErrInstanceNotFound; Received: %v", err)
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Hardware      []tinkv1.Hardware
+		ExpectedError error
+	}{
+		{
+			Name: "Found",
+			Hardware: []tinkv1.Hardware{
+				{
+					Spec: tinkv1.HardwareSpec{
+						Metadata: &tinkv1.HardwareMetadata{
+							Instance: &tinkv1.MetadataInstance{
+								Hostname: "hostname",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:          "NotFound",
+			ExpectedError: ec2.ErrInstanceNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), crclient.MatchingFields{
+					".Spec.Interfaces.DHCP.MAC": "00:00:00:00:00:01",
+				}).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, tc.Hardware...)
+					return nil
+				})
+
+			client := NewTestBackend(lister, nil)
+
+			instance, err := client.GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Expected: %v; Received: %v", tc.ExpectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Metadata.Hostname != "hostname" {
+				t.Fatalf("Expected hostname: hostname; Received: %v", instance.Metadata.Hostname)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceByID(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Hardware      []tinkv1.Hardware
+		ExpectedError error
+	}{
+		{
+			Name: "Found",
+			Hardware: []tinkv1.Hardware{
+				{
+					Spec: tinkv1.HardwareSpec{
+						Metadata: &tinkv1.HardwareMetadata{
+							Instance: &tinkv1.MetadataInstance{
+								ID:       "instance-id",
+								Hostname: "hostname",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:          "NotFound",
+			ExpectedError: ec2.ErrInstanceNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), crclient.MatchingFields{
+					".Spec.Metadata.Instance.ID": "instance-id",
+				}).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, tc.Hardware...)
+					return nil
+				})
+
+			client := NewTestBackend(lister, nil)
+
+			instance, err := client.GetEC2InstanceByID(context.Background(), "instance-id")
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Expected: %v; Received: %v", tc.ExpectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Metadata.Hostname != "hostname" {
+				t.Fatalf("Expected hostname: hostname; Received: %v", instance.Metadata.Hostname)
+			}
+		})
+	}
+}
+
+func TestCacheNamespaces(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Namespaces []string
+		Expected   map[string]cache.Config
+	}{
+		{
+			Name: "AllNamespaces",
+		},
+		{
+			Name:       "SingleNamespace",
+			Namespaces: []string{"tenant-a"},
+			Expected:   map[string]cache.Config{"tenant-a": {}},
+		},
+		{
+			Name:       "MultipleNamespaces",
+			Namespaces: []string{"tenant-a", "tenant-b"},
+			Expected:   map[string]cache.Config{"tenant-a": {}, "tenant-b": {}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.Expected, CacheNamespaces(tc.Namespaces)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceAcrossNamespaces(t *testing.T) {
+	hardware := func(namespace string) tinkv1.Hardware {
+		return tinkv1.Hardware{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hardware",
+				Namespace: namespace,
+			},
+		}
+	}
+
+	cases := []struct {
+		Name          string
+		Hardware      []tinkv1.Hardware
+		ExpectedError string
+	}{
+		{
+			Name:     "UniqueIP",
+			Hardware: []tinkv1.Hardware{hardware("tenant-b")},
+		},
+		{
+			Name:          "DuplicateIP",
+			Hardware:      []tinkv1.Hardware{hardware("tenant-a"), hardware("tenant-b")},
+			ExpectedError: "multiple hardware found for 10.10.10.10: tenant-a/hardware, tenant-b/hardware",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, tc.Hardware...)
+					return nil
+				})
+
+			client := NewTestBackend(lister, nil)
+
+			_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+			switch {
+			case tc.ExpectedError == "" && err != nil:
+				t.Fatal(err)
+			case tc.ExpectedError != "" && (err == nil || err.Error() != tc.ExpectedError):
+				t.Fatalf("Expected: %v; Received: %v", tc.ExpectedError, err)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceDuplicatePolicy(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	hardware := func(namespace, name string, age time.Duration) tinkv1.Hardware {
+		return tinkv1.Hardware{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			},
+			Spec: tinkv1.HardwareSpec{
+				Metadata: &tinkv1.HardwareMetadata{
+					Instance: &tinkv1.MetadataInstance{ID: namespace + "/" + name},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		Name       string
+		Policy     DuplicatePolicy
+		Hardware   []tinkv1.Hardware
+		ExpectedID string
+		ExpectErr  bool
+	}{
+		{
+			Name:      "Error",
+			Policy:    DuplicateError,
+			Hardware:  []tinkv1.Hardware{hardware("tenant-b", "hw", time.Hour), hardware("tenant-a", "hw", 0)},
+			ExpectErr: true,
+		},
+		{
+			Name:       "First",
+			Policy:     DuplicateFirst,
+			Hardware:   []tinkv1.Hardware{hardware("tenant-b", "hw", time.Hour), hardware("tenant-a", "hw", 0)},
+			ExpectedID: "tenant-a/hw",
+		},
+		{
+			Name:       "FirstByName",
+			Policy:     DuplicateFirst,
+			Hardware:   []tinkv1.Hardware{hardware("tenant-a", "hw-2", 0), hardware("tenant-a", "hw-1", 0)},
+			ExpectedID: "tenant-a/hw-1",
+		},
+		{
+			Name:       "Newest",
+			Policy:     DuplicateNewest,
+			Hardware:   []tinkv1.Hardware{hardware("tenant-a", "hw", time.Hour), hardware("tenant-b", "hw", 0)},
+			ExpectedID: "tenant-b/hw",
+		},
+		{
+			Name:       "NewestTieBrokenByName",
+			Policy:     DuplicateNewest,
+			Hardware:   []tinkv1.Hardware{hardware("tenant-b", "hw", 0), hardware("tenant-a", "hw", 0)},
+			ExpectedID: "tenant-a/hw",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, tc.Hardware...)
+					return nil
+				})
+
+			var logged []string
+			logger := funcr.New(func(_, args string) {
+				logged = append(logged, args)
+			}, funcr.Options{})
+
+			client := NewTestBackendWithDuplicatePolicy(lister, tc.Policy, logger)
+
+			instance, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatal("Expected error, received nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Metadata.InstanceID != tc.ExpectedID {
+				t.Fatalf("Expected instance: %v; Received: %v", tc.ExpectedID, instance.Metadata.InstanceID)
+			}
+
+			if len(logged) != 1 {
+				t.Fatalf("Expected 1 warning to be logged; Received: %v", logged)
+			}
+		})
+	}
+}
+
+func TestListHardwareByIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), crclient.MatchingFields{".Spec.Interfaces.DHCP.IP": "10.10.10.10"}).
+		DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+			l.Items = append(l.Items,
+				tinkv1.Hardware{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "hw"}},
+				tinkv1.Hardware{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-b", Name: "hw"}},
+			)
+			return nil
+		})
+
+	client := NewTestBackend(lister, nil)
+
+	names, err := client.ListHardwareByIP(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{"tenant-a/hw", "tenant-b/hw"}
+	if diff := cmp.Diff(expect, names); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	cases := []struct {
+		Policy    string
+		Expected  DuplicatePolicy
+		ExpectErr bool
+	}{
+		{Policy: "", Expected: DuplicateError},
+		{Policy: "error", Expected: DuplicateError},
+		{Policy: "first", Expected: DuplicateFirst},
+		{Policy: "newest", Expected: DuplicateNewest},
+		{Policy: "oldest", ExpectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Policy, func(t *testing.T) {
+			policy, err := ParseDuplicatePolicy(tc.Policy)
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatal("Expected error, received nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if policy != tc.Expected {
+				t.Fatalf("Expected: %v; Received: %v", tc.Expected, policy)
+			}
+		})
+	}
+}
+
+func TestIsReadyBeforeCacheSync(t *testing.T) {
+	client := NewTestBackend(nil, nil)
+
+	if client.IsReady(context.Background()) {
+		t.Fatal("Expected backend to not be ready before the cache has synced")
+	}
+}
+
+func TestWaitForSync(t *testing.T) {
+	client := NewTestBackend(nil, nil)
+	client.WaitForCacheSync = func(context.Context) bool { return true }
+
+	if err := client.WaitForSync(context.Background(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForSyncTimeout(t *testing.T) {
+	client := NewTestBackend(nil, nil)
+	client.WaitForCacheSync = func(ctx context.Context) bool {
+		<-ctx.Done()
+		return false
+	}
+
+	err := client.WaitForSync(context.Background(), 10*time.Millisecond)
+	if !errors.Is(err, ErrNotSynced) {
+		t.Fatalf("Expected: %v; Received: %v", ErrNotSynced, err)
+	}
+}
+
+func TestWaitForSyncCancelled(t *testing.T) {
+	client := NewTestBackend(nil, nil)
+	client.WaitForCacheSync = func(ctx context.Context) bool {
+		<-ctx.Done()
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.WaitForSync(ctx, time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected: %v; Received: %v", context.Canceled, err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestLoadConfig(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	contents := `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: context
+  context:
+    cluster: cluster
+    user: user
+current-context: context
+users:
+- name: user
+  user:
+    token: token
+`
+	if err := os.WriteFile(kubeconfig, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(Config{
+		Kubeconfig: kubeconfig,
+		QPS:        50,
+		Burst:      100,
+		UserAgent:  "hegel/v0.12.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.ClientConfig.Host != "https://127.0.0.1:6443" {
+		t.Fatalf("Expected host: https://127.0.0.1:6443; Received: %v", cfg.ClientConfig.Host)
+	}
+
+	if cfg.ClientConfig.QPS != 50 {
+		t.Fatalf("Expected QPS: 50; Received: %v", cfg.ClientConfig.QPS)
+	}
+
+	if cfg.ClientConfig.Burst != 100 {
+		t.Fatalf("Expected burst: 100; Received: %v", cfg.ClientConfig.Burst)
+	}
+
+	if cfg.ClientConfig.UserAgent != "hegel/v0.12.0" {
+		t.Fatalf("Expected user agent: hegel/v0.12.0; Received: %v", cfg.ClientConfig.UserAgent)
+	}
+}
+
+func TestLoadConfigSources(t *testing.T) {
+	dir := t.TempDir()
+	writeKubeconfig := func(name, server string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(kubeconfigFor(server)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := writeKubeconfig("path", "https://path:6443")
+	ambient := writeKubeconfig("ambient", "https://ambient:6443")
+	data := base64.StdEncoding.EncodeToString([]byte(kubeconfigFor("https://data:6443")))
+
+	cases := []struct {
+		Name         string
+		Kubeconfig   string
+		InCluster    bool
+		ExpectedHost string
+	}{
+		{
+			Name:         "Path",
+			Kubeconfig:   path,
+			ExpectedHost: "https://path:6443",
+		},
+		{
+			Name:         "Data",
+			ExpectedHost: "https://data:6443",
+		},
+		{
+			// In-cluster config can't be loaded outside a cluster so the default loading rules,
+			// which consider in-cluster config, are observed via KUBECONFIG instead.
+			Name:         "InCluster",
+			InCluster:    true,
+			ExpectedHost: "https://ambient:6443",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			SetInCluster(t, tc.InCluster)
+			t.Setenv("KUBECONFIG", ambient)
+
+			cfg, err := LoadConfig(Config{Kubeconfig: tc.Kubeconfig, KubeconfigData: data})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.ClientConfig.Host != tc.ExpectedHost {
+				t.Fatalf("Expected host: %v; Received: %v", tc.ExpectedHost, cfg.ClientConfig.Host)
+			}
+		})
+	}
+}
+
+func TestLoadConfigInvalidData(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Data          string
+		ExpectedError string
+	}{
+		{
+			Name:          "InvalidBase64",
+			Data:          "not base64!",
+			ExpectedError: "decode kubeconfig data",
+		},
+		{
+			Name:          "InvalidKubeconfig",
+			Data:          base64.StdEncoding.EncodeToString([]byte("clusters: {")),
+			ExpectedError: "load kubeconfig data",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			SetInCluster(t, false)
+
+			_, err := LoadConfig(Config{KubeconfigData: tc.Data})
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+				t.Fatalf("Expected error containing %q; Received: %v", tc.ExpectedError, err)
+			}
+		})
+	}
+}
+
+// kubeconfigFor returns a kubeconfig targeting server.
+func kubeconfigFor(server string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: %v
+contexts:
+- name: context
+  context:
+    cluster: cluster
+    user: user
+current-context: context
+users:
+- name: user
+  user:
+    token: token
+`, server)
 }
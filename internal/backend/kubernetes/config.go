@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"github.com/go-logr/logr"
 	"k8s.io/client-go/rest"
 )
 
@@ -10,14 +11,43 @@ type Config struct {
 	// config. Optional.
 	Kubeconfig string
 
+	// KubeconfigData is a base64 encoded kubeconfig. It's used only when neither in-cluster config
+	// nor Kubeconfig is available. Optional.
+	KubeconfigData string
+
 	// APIServerAddress is the address of the kubernetes cluster (https://hostname:port). Optional.
 	APIServerAddress string
 
-	// Namespace restricts the scope of the backend such that Hardware objects are retrieved from
-	// this namespace only. Optional.
-	Namespace string
+	// Namespaces restricts the scope of the backend such that Hardware objects are retrieved from
+	// these namespaces only. When empty, Hardware objects are retrieved from all namespaces.
+	// Optional.
+	Namespaces []string
+
+	// OnDuplicate determines how lookups that match more than one Hardware are resolved. When
+	// empty, such lookups fail. Optional.
+	OnDuplicate DuplicatePolicy
+
+	// QPS is the maximum sustained queries per second to the API server. When 0, the client-go
+	// default is used. Optional.
+	QPS float32
+
+	// Burst is the maximum burst of queries to the API server above QPS. When 0, the client-go
+	// default is used. Optional.
+	Burst int
+
+	// UserAgent identifies requests to the API server, such as in audit logs. When empty, the
+	// client-go default is used. Optional.
+	UserAgent string
+
+	// MaxRetries is how many times lookups failing with transient errors, such as throttling or
+	// timeouts, are retried with exponential backoff. When 0, lookups aren't retried. Optional.
+	MaxRetries int
+
+	// Logger is used to warn when a lookup matches more than one Hardware. Optional.
+	Logger logr.Logger
 
 	// ClientConfig is a Kubernetes client config. If specified, it will be used instead of
-	// constructing a client using the other configuration in this object. Optional.
+	// constructing a client using the other configuration in this object, including QPS, Burst
+	// and UserAgent. Optional.
 	ClientConfig *rest.Config
 }
@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+)
+
+// DuplicatePolicy determines how a lookup that matches more than one Hardware is resolved.
+type DuplicatePolicy string
+
+const (
+	// DuplicateError fails the lookup.
+	DuplicateError DuplicatePolicy = "error"
+
+	// DuplicateFirst selects the Hardware that sorts first by namespace and name.
+	DuplicateFirst DuplicatePolicy = "first"
+
+	// DuplicateNewest selects the most recently created Hardware. Hardware created at the same
+	// time are resolved as with DuplicateFirst.
+	DuplicateNewest DuplicatePolicy = "newest"
+)
+
+// duplicatePolicies lists all valid DuplicatePolicy values.
+var duplicatePolicies = []DuplicatePolicy{DuplicateError, DuplicateFirst, DuplicateNewest}
+
+// ParseDuplicatePolicy parses policy into a DuplicatePolicy. An empty policy is DuplicateError.
+func ParseDuplicatePolicy(policy string) (DuplicatePolicy, error) {
+	if policy == "" {
+		return DuplicateError, nil
+	}
+
+	if p := DuplicatePolicy(policy); slices.Contains(duplicatePolicies, p) {
+		return p, nil
+	}
+
+	valid := make([]string, len(duplicatePolicies))
+	for i, p := range duplicatePolicies {
+		valid[i] = string(p)
+	}
+	return "", fmt.Errorf("unknown duplicate policy %q: valid values are %v", policy, strings.Join(valid, ", "))
+}
+
+// selectHardware selects a single Hardware from hw, which contains more than one item, according
+// to policy. If policy is DuplicateError, it returns false.
+func selectHardware(hw []tinkv1.Hardware, policy DuplicatePolicy) (tinkv1.Hardware, bool) {
+	byName := func(a, b tinkv1.Hardware) int {
+		return strings.Compare(hardwareName(a), hardwareName(b))
+	}
+
+	switch policy {
+	case DuplicateFirst:
+		return slices.MinFunc(hw, byName), true
+
+	case DuplicateNewest:
+		return slices.MinFunc(hw, func(a, b tinkv1.Hardware) int {
+			switch {
+			case a.CreationTimestamp.Equal(&b.CreationTimestamp):
+				return byName(a, b)
+			case b.CreationTimestamp.Before(&a.CreationTimestamp):
+				return -1
+			default:
+				return 1
+			}
+		}), true
+	}
+
+	return tinkv1.Hardware{}, false
+}
+
+func hardwareName(hw tinkv1.Hardware) string {
+	return hw.Namespace + "/" + hw.Name
+}
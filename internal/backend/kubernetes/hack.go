@@ -9,19 +9,19 @@ import (
 )
 
 func (b *Backend) GetHackInstance(ctx context.Context, ip string) (hack.Instance, error) {
-	hw, err := b.retrieveByIP(ctx, ip)
+	hw, err := b.retrieveByIP(ctx, dataModelHack, ip)
 	if err != nil {
 		return hack.Instance{}, err
 	}
 
-	return toHackInstance(hw)
+	return ToHackInstance(hw)
 }
 
-// toHackInstance converts a Tinkerbell Hardware resource to a hack.Instance by marshalling and
+// ToHackInstance converts a Tinkerbell Hardware resource to a hack.Instance by marshalling and
 // unmarshalling. This works because the Hardware resource has historical roots that align with
 // the hack.Instance struct that is derived from the rootio action. See the hack frontend for more
 // details.
-func toHackInstance(hw tinkv1.Hardware) (hack.Instance, error) {
+func ToHackInstance(hw tinkv1.Hardware) (hack.Instance, error) {
 	marshalled, err := json.Marshal(hw.Spec)
 	if err != nil {
 		return hack.Instance{}, err
@@ -1,6 +1,9 @@
 package kubernetes
 
 import (
+	"net"
+
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
 	"github.com/tinkerbell/tink/api/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -9,7 +12,8 @@ import (
 // the controller-runtimes MatchingFields selector.
 const hardwareIPAddrIndex = ".Spec.Interfaces.DHCP.IP"
 
-// hardwareIPIndexFunc satisfies the controller runtimes index.
+// hardwareIPIndexFunc satisfies the controller runtimes index. IP addresses are normalized with
+// ipaddr.Normalize.
 func hardwareIPIndexFunc(obj client.Object) []string {
 	hw, ok := obj.(*v1alpha1.Hardware)
 	if !ok {
@@ -18,7 +22,46 @@ func hardwareIPIndexFunc(obj client.Object) []string {
 	resp := []string{}
 	for _, iface := range hw.Spec.Interfaces {
 		if iface.DHCP != nil && iface.DHCP.IP != nil && iface.DHCP.IP.Address != "" {
-			resp = append(resp, iface.DHCP.IP.Address)
+			resp = append(resp, ipaddr.Normalize(iface.DHCP.IP.Address))
+		}
+	}
+	return resp
+}
+
+// hardwareInstanceIDIndex is the index used to retrieve hardware by instance ID. It is used with
+// the controller-runtimes MatchingFields selector.
+const hardwareInstanceIDIndex = ".Spec.Metadata.Instance.ID"
+
+// hardwareInstanceIDIndexFunc satisfies the controller runtimes index.
+func hardwareInstanceIDIndexFunc(obj client.Object) []string {
+	hw, ok := obj.(*v1alpha1.Hardware)
+	if !ok {
+		return nil
+	}
+	if hw.Spec.Metadata == nil || hw.Spec.Metadata.Instance == nil || hw.Spec.Metadata.Instance.ID == "" {
+		return nil
+	}
+	return []string{hw.Spec.Metadata.Instance.ID}
+}
+
+// hardwareMACAddrIndex is the index used to retrieve hardware by MAC address. It is used with
+// the controller-runtimes MatchingFields selector.
+const hardwareMACAddrIndex = ".Spec.Interfaces.DHCP.MAC"
+
+// hardwareMACIndexFunc satisfies the controller runtimes index. MAC addresses are normalized to
+// lower case colon separated form.
+func hardwareMACIndexFunc(obj client.Object) []string {
+	hw, ok := obj.(*v1alpha1.Hardware)
+	if !ok {
+		return nil
+	}
+	resp := []string{}
+	for _, iface := range hw.Spec.Interfaces {
+		if iface.DHCP == nil || iface.DHCP.MAC == "" {
+			continue
+		}
+		if mac, err := net.ParseMAC(iface.DHCP.MAC); err == nil {
+			resp = append(resp, mac.String())
 		}
 	}
 	return resp
@@ -0,0 +1,84 @@
+//go:build !integration
+
+package kubernetes_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// indexLister lists the Hardware whose IP index contains the IP selected by the list options, as
+// the controller-runtime cache does.
+type indexLister struct {
+	hardware []tinkv1.Hardware
+}
+
+func (l indexLister) List(_ context.Context, list crclient.ObjectList, opts ...crclient.ListOption) error {
+	var options crclient.ListOptions
+	options.ApplyOptions(opts)
+
+	ip, _ := options.FieldSelector.RequiresExactMatch(HardwareIPIndex)
+
+	hwList := list.(*tinkv1.HardwareList)
+	for _, hw := range l.hardware {
+		if slices.Contains(HardwareIPIndexFunc(&hw), ip) {
+			hwList.Items = append(hwList.Items, hw)
+		}
+	}
+	return nil
+}
+
+func TestGetEC2InstanceEquivalentIPs(t *testing.T) {
+	hardware := func(name, ip string) tinkv1.Hardware {
+		return tinkv1.Hardware{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: tinkv1.HardwareSpec{
+				Interfaces: []tinkv1.Interface{{
+					DHCP: &tinkv1.DHCP{IP: &tinkv1.IP{Address: ip}},
+				}},
+				Metadata: &tinkv1.HardwareMetadata{
+					Instance: &tinkv1.MetadataInstance{ID: name},
+				},
+			},
+		}
+	}
+
+	client := NewTestBackend(indexLister{hardware: []tinkv1.Hardware{
+		hardware("compressed", "2001:db8::1"),
+		hardware("expanded", "2001:0DB8:0000:0000:0000:0000:0000:0002"),
+		hardware("ipv4", "10.10.10.10"),
+	}}, nil)
+
+	cases := []struct {
+		Name     string
+		IP       string
+		Expected string
+	}{
+		{Name: "Compressed", IP: "2001:db8::1", Expected: "compressed"},
+		{Name: "ExpandedMatchesCompressed", IP: "2001:0db8:0000:0000:0000:0000:0000:0001", Expected: "compressed"},
+		{Name: "UpperCaseMatchesCompressed", IP: "2001:DB8::1", Expected: "compressed"},
+		{Name: "CompressedMatchesExpanded", IP: "2001:db8::2", Expected: "expanded"},
+		{Name: "PartiallyCompressedMatchesExpanded", IP: "2001:db8:0:0::2", Expected: "expanded"},
+		{Name: "IPv4", IP: "10.10.10.10", Expected: "ipv4"},
+		{Name: "IPv4MappedMatchesIPv4", IP: "::ffff:10.10.10.10", Expected: "ipv4"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			instance, err := client.GetEC2Instance(context.Background(), tc.IP)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Metadata.InstanceID != tc.Expected {
+				t.Fatalf("Expected: %v; Received: %v", tc.Expected, instance.Metadata.InstanceID)
+			}
+		})
+	}
+}
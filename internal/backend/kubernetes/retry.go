@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Delays between retries of failed List calls. The delay doubles with each retry up to
+// maxRetryDelay.
+const (
+	initialRetryDelay = 100 * time.Millisecond
+	maxRetryDelay     = 2 * time.Second
+)
+
+// list lists resources as b.client.List does retrying retryable errors, up to b.maxRetries times,
+// with exponential backoff. If ctx is done while waiting to retry, the last error is returned.
+func (b *Backend) list(ctx context.Context, list crclient.ObjectList, opts ...crclient.ListOption) error {
+	delay := b.retryDelay
+	if delay == 0 {
+		delay = initialRetryDelay
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := b.client.List(ctx, list, opts...)
+		if err == nil || attempt >= b.maxRetries || !isRetryable(err) {
+			return err
+		}
+
+		b.logger.V(1).Info("Retrying failed list", "attempt", attempt+1, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// isRetryable reports whether err is transient such that retrying the call may succeed. Errors
+// the API server responds with are retryable when they indicate throttling, a timeout or a
+// server side failure.
+func isRetryable(err error) bool {
+	// Cancellation and deadlines apply to the caller's context so retrying won't help.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		code := status.Status().Code
+		return apierrors.IsTooManyRequests(err) ||
+			apierrors.IsTimeout(err) ||
+			apierrors.IsServerTimeout(err) ||
+			code >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
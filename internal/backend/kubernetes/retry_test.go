@@ -0,0 +1,163 @@
+//go:build !integration
+
+package kubernetes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// flakyLister fails List calls with errs in order before succeeding with a single Hardware.
+type flakyLister struct {
+	errs  []error
+	calls int
+}
+
+func (l *flakyLister) List(_ context.Context, list crclient.ObjectList, _ ...crclient.ListOption) error {
+	l.calls++
+	if l.calls <= len(l.errs) {
+		return l.errs[l.calls-1]
+	}
+
+	list.(*tinkv1.HardwareList).Items = []tinkv1.Hardware{{
+		Spec: tinkv1.HardwareSpec{
+			Metadata: &tinkv1.HardwareMetadata{
+				Instance: &tinkv1.MetadataInstance{ID: "instance-id"},
+			},
+		},
+	}}
+	return nil
+}
+
+func TestGetEC2InstanceRetries(t *testing.T) {
+	throttled := apierrors.NewTooManyRequests("throttled", 1)
+	unavailable := apierrors.NewServiceUnavailable("unavailable")
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "hardware"}, "hardware")
+
+	cases := []struct {
+		Name          string
+		Errors        []error
+		MaxRetries    int
+		ExpectedCalls int
+		ExpectedError error
+	}{
+		{
+			Name:          "SucceedsAfterTransientErrors",
+			Errors:        []error{throttled, unavailable},
+			MaxRetries:    3,
+			ExpectedCalls: 3,
+		},
+		{
+			Name:          "RetriesExhausted",
+			Errors:        []error{throttled, throttled, throttled},
+			MaxRetries:    2,
+			ExpectedCalls: 3,
+			ExpectedError: throttled,
+		},
+		{
+			Name:          "RetriesDisabled",
+			Errors:        []error{throttled},
+			ExpectedCalls: 1,
+			ExpectedError: throttled,
+		},
+		{
+			Name:          "NotFoundNotRetried",
+			Errors:        []error{notFound},
+			MaxRetries:    3,
+			ExpectedCalls: 1,
+			ExpectedError: notFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			lister := &flakyLister{errs: tc.Errors}
+			client := NewTestBackendWithConfig(lister, Config{MaxRetries: tc.MaxRetries})
+
+			instance, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+
+			if lister.calls != tc.ExpectedCalls {
+				t.Fatalf("Expected List calls: %v; Received: %v", tc.ExpectedCalls, lister.calls)
+			}
+
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Expected error: %v; Received: %v", tc.ExpectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Metadata.InstanceID != "instance-id" {
+				t.Fatalf("Expected instance-id; Received: %v", instance.Metadata.InstanceID)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceNoHardwareNotRetried(t *testing.T) {
+	lister := &emptyLister{}
+	client := NewTestBackendWithConfig(lister, Config{MaxRetries: 3})
+
+	_, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: ec2.ErrInstanceNotFound; Received: %v", err)
+	}
+
+	if lister.calls != 1 {
+		t.Fatalf("Expected List calls: 1; Received: %v", lister.calls)
+	}
+}
+
+// emptyLister lists no resources.
+type emptyLister struct {
+	calls int
+}
+
+func (l *emptyLister) List(context.Context, crclient.ObjectList, ...crclient.ListOption) error {
+	l.calls++
+	return nil
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Error     error
+		Retryable bool
+	}{
+		{Name: "TooManyRequests", Error: apierrors.NewTooManyRequests("throttled", 1), Retryable: true},
+		{Name: "ServerTimeout", Error: apierrors.NewServerTimeout(schema.GroupResource{}, "list", 1), Retryable: true},
+		{Name: "Timeout", Error: apierrors.NewTimeoutError("timeout", 1), Retryable: true},
+		{Name: "InternalError", Error: apierrors.NewInternalError(errors.New("boom")), Retryable: true},
+		{Name: "ServiceUnavailable", Error: apierrors.NewServiceUnavailable("unavailable"), Retryable: true},
+		{Name: "ConnectionReset", Error: fmt.Errorf("read: %w", syscall.ECONNRESET), Retryable: true},
+		{Name: "UnexpectedEOF", Error: io.ErrUnexpectedEOF, Retryable: true},
+		{Name: "NotFound", Error: apierrors.NewNotFound(schema.GroupResource{}, "hardware")},
+		{Name: "Forbidden", Error: apierrors.NewForbidden(schema.GroupResource{}, "hardware", errors.New("denied"))},
+		{Name: "ContextCanceled", Error: context.Canceled},
+		{Name: "ContextDeadlineExceeded", Error: context.DeadlineExceeded},
+		{Name: "Generic", Error: errors.New("generic")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if retryable := IsRetryable(tc.Error); retryable != tc.Retryable {
+				t.Fatalf("Expected retryable: %v; Received: %v", tc.Retryable, retryable)
+			}
+		})
+	}
+}
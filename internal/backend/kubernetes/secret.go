@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UserdataSecretAnnotation is the Hardware annotation referencing a Secret key whose value is
+// served as userdata in place of the Hardware's userdata. Its value has the form name/key and
+// references a Secret in the Hardware's namespace. Hegel must be authorized to get the Secret.
+const UserdataSecretAnnotation = "hegel.tinkerbell.org/userdata-secret"
+
+// secretCacheTTL is how long Secrets are cached to limit calls to the API server.
+const secretCacheTTL = 30 * time.Second
+
+// errSecretsUnsupported indicates a Hardware references a Secret but the Backend can't read them.
+var errSecretsUnsupported = errors.New("secrets are not supported")
+
+// secretCache reads Secrets from the API server caching them for a short TTL. Secrets aren't
+// watched so they aren't all cached in memory as Hardware are.
+type secretCache struct {
+	reader secretReader
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[crclient.ObjectKey]cachedSecret
+}
+
+type cachedSecret struct {
+	data    map[string][]byte
+	expires time.Time
+}
+
+func newSecretCache(reader secretReader, ttl time.Duration) *secretCache {
+	return &secretCache{
+		reader:  reader,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[crclient.ObjectKey]cachedSecret{},
+	}
+}
+
+// value retrieves the value of the Secret key referenced by ref of the form name/key from
+// namespace. The form namespace/name/key is accepted if it names namespace. Secrets are read with
+// Hegel's permissions so references to other namespaces are rejected, else anyone permitted to
+// write Hardware could read Secrets from any namespace through Hegel.
+func (c *secretCache) value(ctx context.Context, namespace, ref string) (string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) == 3 {
+		if parts[0] != namespace {
+			return "", fmt.Errorf("invalid secret reference %q: secret must be in namespace %v", ref, namespace)
+		}
+		parts = parts[1:]
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid secret reference %q: expected name/key", ref)
+	}
+	key, field := crclient.ObjectKey{Namespace: namespace, Name: parts[0]}, parts[1]
+
+	data, err := c.get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %v has no key %v", key, field)
+	}
+	return string(value), nil
+}
+
+// get retrieves the data of the Secret identified by key. Failed reads aren't cached.
+func (c *secretCache) get(ctx context.Context, key crclient.ObjectKey) (map[string][]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expires) {
+		return entry.data, nil
+	}
+
+	var secret corev1.Secret
+	if err := c.reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("get secret %v: %w", key, err)
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cachedSecret{data: secret.Data, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return secret.Data, nil
+}
+
+// toEC2Instance converts hw to an ec2.Instance resolving its userdata from the Secret referenced
+// by the UserdataSecretAnnotation of hw, if any.
+func (b *Backend) toEC2Instance(ctx context.Context, hw tinkv1.Hardware) (ec2.Instance, error) {
+	instance := ToEC2Instance(hw)
+
+	ref, ok := hw.Annotations[UserdataSecretAnnotation]
+	if !ok {
+		return instance, nil
+	}
+
+	if b.secrets == nil {
+		return ec2.Instance{}, fmt.Errorf("resolve userdata for %v: %w", hardwareName(hw), errSecretsUnsupported)
+	}
+
+	userdata, err := b.secrets.value(ctx, hw.Namespace, ref)
+	if err != nil {
+		return ec2.Instance{}, fmt.Errorf("resolve userdata for %v: %w", hardwareName(hw), err)
+	}
+	instance.Userdata = userdata
+
+	return instance, nil
+}
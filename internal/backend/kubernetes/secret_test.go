@@ -0,0 +1,202 @@
+//go:build !integration
+
+package kubernetes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetEC2InstanceUserdataSecret(t *testing.T) {
+	userdata := "#cloud-config"
+	secretKey := crclient.ObjectKey{Namespace: "tink-system", Name: "userdata"}
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "userdata")
+
+	cases := []struct {
+		Name             string
+		Annotations      map[string]string
+		Secret           *corev1.Secret
+		SecretErr        error
+		ExpectedUserdata string
+		ExpectError      bool
+	}{
+		{
+			Name:             "Inline",
+			ExpectedUserdata: userdata,
+		},
+		{
+			Name:        "SecretResolves",
+			Annotations: map[string]string{UserdataSecretAnnotation: "userdata/user-data"},
+			Secret: &corev1.Secret{
+				Data: map[string][]byte{"user-data": []byte("#cloud-config\npassword: secret")},
+			},
+			ExpectedUserdata: "#cloud-config\npassword: secret",
+		},
+		{
+			Name:        "SecretMissing",
+			Annotations: map[string]string{UserdataSecretAnnotation: "userdata/user-data"},
+			SecretErr:   notFound,
+			ExpectError: true,
+		},
+		{
+			Name:        "SecretKeyMissing",
+			Annotations: map[string]string{UserdataSecretAnnotation: "userdata/user-data"},
+			Secret: &corev1.Secret{
+				Data: map[string][]byte{"vendor-data": []byte("vendordata")},
+			},
+			ExpectError: true,
+		},
+		{
+			Name:        "SecretInNamespaceResolves",
+			Annotations: map[string]string{UserdataSecretAnnotation: "tink-system/userdata/user-data"},
+			Secret: &corev1.Secret{
+				Data: map[string][]byte{"user-data": []byte("#cloud-config\npassword: secret")},
+			},
+			ExpectedUserdata: "#cloud-config\npassword: secret",
+		},
+		{
+			// Secrets are read with Hegel's permissions so Hardware can't reference Secrets in
+			// other namespaces.
+			Name:        "SecretInOtherNamespace",
+			Annotations: map[string]string{UserdataSecretAnnotation: "kube-system/userdata/user-data"},
+			ExpectError: true,
+		},
+		{
+			Name:        "InvalidReference",
+			Annotations: map[string]string{UserdataSecretAnnotation: "user-data"},
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			hw := tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tink-system", Annotations: tc.Annotations},
+				Spec:       tinkv1.HardwareSpec{UserData: &userdata},
+			}
+
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, hw)
+					return nil
+				})
+
+			reader := NewMocksecretReader(ctrl)
+			if tc.Secret != nil || tc.SecretErr != nil {
+				reader.EXPECT().
+					Get(gomock.Any(), secretKey, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ crclient.ObjectKey, obj crclient.Object, _ ...crclient.GetOption) error {
+						if tc.SecretErr != nil {
+							return tc.SecretErr
+						}
+						*obj.(*corev1.Secret) = *tc.Secret
+						return nil
+					})
+			}
+
+			client := NewTestBackendWithSecrets(lister, reader, time.Minute)
+
+			instance, err := client.GetEC2Instance(context.Background(), "10.10.10.10")
+			if tc.ExpectError {
+				if err == nil {
+					t.Fatalf("Expected error; Received instance: %+v", instance)
+				}
+				if tc.SecretErr != nil && !errors.Is(err, tc.SecretErr) {
+					t.Fatalf("Expected: %v; Received: %v", tc.SecretErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Userdata != tc.ExpectedUserdata {
+				t.Fatalf("Expected userdata: %q; Received: %q", tc.ExpectedUserdata, instance.Userdata)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceUserdataSecretCached(t *testing.T) {
+	cases := []struct {
+		Name          string
+		TTL           time.Duration
+		ExpectedReads int
+	}{
+		{Name: "WithinTTL", TTL: time.Minute, ExpectedReads: 1},
+		{Name: "Expired", ExpectedReads: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			hw := tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "tink-system",
+					Annotations: map[string]string{UserdataSecretAnnotation: "userdata/user-data"},
+				},
+			}
+
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, hw)
+					return nil
+				}).
+				Times(2)
+
+			reader := NewMocksecretReader(ctrl)
+			reader.EXPECT().
+				Get(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ crclient.ObjectKey, obj crclient.Object, _ ...crclient.GetOption) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{"user-data": []byte("#cloud-config")}
+					return nil
+				}).
+				Times(tc.ExpectedReads)
+
+			client := NewTestBackendWithSecrets(lister, reader, tc.TTL)
+
+			for i := 0; i < 2; i++ {
+				if _, err := client.GetEC2Instance(context.Background(), "10.10.10.10"); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceUserdataSecretUnsupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+			l.Items = append(l.Items, tinkv1.Hardware{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "tink-system",
+					Annotations: map[string]string{UserdataSecretAnnotation: "userdata/user-data"},
+				},
+			})
+			return nil
+		})
+
+	if _, err := NewTestBackend(lister, nil).GetEC2Instance(context.Background(), "10.10.10.10"); err == nil {
+		t.Fatal("Expected error for a Backend that can't read Secrets")
+	}
+}
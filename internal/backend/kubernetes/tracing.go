@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies spans created by the Backend.
+const tracerName = "github.com/tinkerbell/hegel/internal/backend/kubernetes"
+
+// Span attribute keys.
+const (
+	attrClientIP   = attribute.Key("hegel.client_ip")
+	attrClientMAC  = attribute.Key("hegel.client_mac")
+	attrInstanceID = attribute.Key("hegel.instance_id")
+	attrDataModel  = attribute.Key("hegel.data_model")
+	attrResult     = attribute.Key("hegel.result")
+)
+
+// Data models a lookup is converted to.
+const (
+	dataModelEC2  = "ec2"
+	dataModelHack = "hack"
+)
+
+// Lookup results.
+const (
+	resultFound     = "found"
+	resultNotFound  = "not_found"
+	resultDuplicate = "duplicate"
+	resultError     = "error"
+)
+
+// startLookupSpan starts a span for a Hardware lookup that will be converted to dataModel. The
+// span is created with the global tracer provider so it's a child of any span in ctx.
+func startLookupSpan(
+	ctx context.Context,
+	dataModel string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(
+		ctx,
+		"kubernetes.retrieve",
+		trace.WithAttributes(append(attrs, attrDataModel.String(dataModel))...),
+	)
+}
+
+// endLookupSpan records the lookup result derived from err and ends span. Not finding Hardware is
+// an expected outcome so it isn't recorded as an error.
+func endLookupSpan(span trace.Span, err error) {
+	result := resultFound
+	switch {
+	case err == nil:
+	case errors.Is(err, errNotFound):
+		result = resultNotFound
+	case errors.Is(err, ErrMultipleHardware):
+		result = resultDuplicate
+	default:
+		result = resultError
+	}
+
+	span.SetAttributes(attrResult.String(result))
+
+	if err != nil && result != resultNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
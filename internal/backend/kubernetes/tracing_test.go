@@ -0,0 +1,163 @@
+//go:build !integration
+
+package kubernetes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestLookupSpans(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Hardware   []tinkv1.Hardware
+		ListError  error
+		Lookup     func(context.Context, *Backend) error
+		Attributes map[attribute.Key]string
+		Error      bool
+	}{
+		{
+			Name:     "EC2Found",
+			Hardware: []tinkv1.Hardware{newHardware("1", "10.10.10.10", "userdata")},
+			Lookup: func(ctx context.Context, b *Backend) error {
+				_, err := b.GetEC2Instance(ctx, "10.10.10.10")
+				return err
+			},
+			Attributes: map[attribute.Key]string{
+				"hegel.client_ip":  "10.10.10.10",
+				"hegel.data_model": "ec2",
+				"hegel.result":     "found",
+			},
+		},
+		{
+			Name: "HackNotFound",
+			Lookup: func(ctx context.Context, b *Backend) error {
+				_, err := b.GetHackInstance(ctx, "10.10.10.10")
+				return err
+			},
+			Attributes: map[attribute.Key]string{
+				"hegel.client_ip":  "10.10.10.10",
+				"hegel.data_model": "hack",
+				"hegel.result":     "not_found",
+			},
+		},
+		{
+			Name: "Duplicate",
+			Hardware: []tinkv1.Hardware{
+				newNamedHardware("a", "10.10.10.10"),
+				newNamedHardware("b", "10.10.10.10"),
+			},
+			Lookup: func(ctx context.Context, b *Backend) error {
+				_, err := b.GetEC2Instance(ctx, "10.10.10.10")
+				return err
+			},
+			Attributes: map[attribute.Key]string{
+				"hegel.client_ip":  "10.10.10.10",
+				"hegel.data_model": "ec2",
+				"hegel.result":     "duplicate",
+			},
+			Error: true,
+		},
+		{
+			Name:      "ByMACClientError",
+			ListError: errors.New("client error"),
+			Lookup: func(ctx context.Context, b *Backend) error {
+				_, err := b.GetEC2InstanceByMAC(ctx, "00:00:00:00:00:01")
+				return err
+			},
+			Attributes: map[attribute.Key]string{
+				"hegel.client_mac": "00:00:00:00:00:01",
+				"hegel.data_model": "ec2",
+				"hegel.result":     "error",
+			},
+			Error: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder := useSpanRecorder(t)
+
+			ctrl := gomock.NewController(t)
+			lister := NewMocklisterClient(ctrl)
+			lister.EXPECT().
+				List(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+					l.Items = append(l.Items, tc.Hardware...)
+					return tc.ListError
+				})
+
+			client := NewTestBackend(lister, nil)
+
+			// Lookups should be children of the caller's span, such as the HTTP request span.
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+			_ = tc.Lookup(ctx, client)
+			parent.End()
+
+			var spans []sdktrace.ReadOnlySpan
+			for _, span := range recorder.Ended() {
+				if span.Name() != "request" {
+					spans = append(spans, span)
+				}
+			}
+
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 backend span; Received: %v", len(spans))
+			}
+			span := spans[0]
+
+			if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+				t.Fatal("Expected backend span to be a child of the request span")
+			}
+
+			attrs := map[attribute.Key]string{}
+			for _, attr := range span.Attributes() {
+				attrs[attr.Key] = attr.Value.AsString()
+			}
+			for key, expect := range tc.Attributes {
+				if attrs[key] != expect {
+					t.Fatalf("Expected %v: %v; Received: %v", key, expect, attrs[key])
+				}
+			}
+
+			if tc.Error {
+				if span.Status().Code != codes.Error || len(span.Events()) == 0 {
+					t.Fatalf("Expected span to record an error; Received status: %v", span.Status())
+				}
+			} else if span.Status().Code == codes.Error {
+				t.Fatalf("Expected span without error; Received status: %v", span.Status())
+			}
+		})
+	}
+}
+
+// useSpanRecorder configures the global tracer provider to record spans in memory for the
+// duration of the test.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func newNamedHardware(name, ip string) tinkv1.Hardware {
+	hw := newHardware("1", ip, "")
+	hw.ObjectMeta = metav1.ObjectMeta{Namespace: "default", Name: name}
+	return hw
+}
@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/watch"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// eventSource notifies handlers of Hardware changes. It is satisfied by controller-runtime's
+// cache.Informer.
+type eventSource interface {
+	AddEventHandler(toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error)
+	RemoveEventHandler(toolscache.ResourceEventHandlerRegistration) error
+}
+
+// Subscribe satisfies watch.Client. The current instance is delivered when the Hardware informer
+// replays its cache to the subscription. Periodic resyncs that don't change the Hardware are not
+// delivered.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	if b.events == nil {
+		return nil, errors.New("subscriptions are not supported")
+	}
+
+	if _, err := b.retrieveByIP(ctx, dataModelEC2, ip); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, ec2.ErrInstanceNotFound
+		}
+		return nil, err
+	}
+
+	sub := watch.NewSubscription()
+	registration, err := b.events.AddEventHandler(b.subscriptionHandler(ctx, ip, sub))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-b.closer:
+		}
+		_ = b.events.RemoveEventHandler(registration)
+		sub.Close()
+	}()
+
+	return sub.Events(), nil
+}
+
+// subscriptionHandler creates an event handler that publishes Hardware associated with ip to sub.
+// If the Hardware is deleted, is no longer associated with ip or can't be converted, sub is
+// closed.
+func (b *Backend) subscriptionHandler(ctx context.Context, ip string, sub *watch.Subscription) toolscache.ResourceEventHandler {
+	publish := func(hw tinkv1.Hardware) {
+		instance, err := b.toEC2Instance(ctx, hw)
+		if err != nil {
+			sub.Close()
+			return
+		}
+		sub.Publish(instance)
+	}
+
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if hw, ok := obj.(*tinkv1.Hardware); ok && hasIP(hw, ip) {
+				publish(*hw)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, ok := oldObj.(*tinkv1.Hardware)
+			if !ok {
+				return
+			}
+			hw, ok := newObj.(*tinkv1.Hardware)
+			if !ok {
+				return
+			}
+
+			switch {
+			case hasIP(hw, ip):
+				// Resyncs redeliver the cached object without changes.
+				if old.ResourceVersion != hw.ResourceVersion {
+					publish(*hw)
+				}
+			case hasIP(old, ip):
+				sub.Close()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if hw, ok := obj.(*tinkv1.Hardware); ok && hasIP(hw, ip) {
+				sub.Close()
+			}
+		},
+	}
+}
+
+func hasIP(hw *tinkv1.Hardware, ip string) bool {
+	return slices.Contains(hardwareIPIndexFunc(hw), ipaddr.Normalize(ip))
+}
@@ -0,0 +1,216 @@
+//go:build !integration
+
+package kubernetes_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	tinkv1 "github.com/tinkerbell/tink/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), crclient.MatchingFields{
+			".Spec.Interfaces.DHCP.IP": "10.10.10.10",
+		}).
+		DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+			l.Items = append(l.Items, newHardware("1", "10.10.10.10", "initial"))
+			return nil
+		})
+
+	informer := &fakeInformer{}
+	client := NewTestBackendWithEvents(lister, informer, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initial := newHardware("1", "10.10.10.10", "initial")
+	updated := newHardware("2", "10.10.10.10", "updated")
+	other := newHardware("1", "10.10.10.11", "other")
+
+	// Simulate the informer replaying its cache to the new handler.
+	informer.Add(&initial)
+	informer.Add(&other)
+	expectUserdata(t, events, "initial")
+
+	// Resyncs redeliver unchanged objects and shouldn't be published.
+	informer.Update(&initial, &initial)
+	informer.Update(&other, &other)
+	informer.Update(&initial, &updated)
+	expectUserdata(t, events, "updated")
+
+	informer.Delete(&updated)
+	expectClosed(t, events)
+}
+
+func TestSubscribeClosesOnCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().
+		List(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, l *tinkv1.HardwareList, _ ...crclient.ListOption) error {
+			l.Items = append(l.Items, newHardware("1", "10.10.10.10", "initial"))
+			return nil
+		})
+
+	informer := &fakeInformer{}
+	client := NewTestBackendWithEvents(lister, informer, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := client.Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	expectClosed(t, events)
+
+	if informer.Handlers() != 0 {
+		t.Fatalf("Expected handler to be removed; Received %v handlers", informer.Handlers())
+	}
+}
+
+func TestSubscribeNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	lister := NewMocklisterClient(ctrl)
+	lister.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	client := NewTestBackendWithEvents(lister, &fakeInformer{}, nil)
+
+	_, err := client.Subscribe(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func newHardware(resourceVersion, ip, userdata string) tinkv1.Hardware {
+	return tinkv1.Hardware{
+		ObjectMeta: metav1.ObjectMeta{
+			ResourceVersion: resourceVersion,
+		},
+		Spec: tinkv1.HardwareSpec{
+			UserData: ptr(userdata),
+			Interfaces: []tinkv1.Interface{
+				{
+					DHCP: &tinkv1.DHCP{
+						IP: &tinkv1.IP{Address: ip},
+					},
+				},
+			},
+		},
+	}
+}
+
+func expectUserdata(t *testing.T, events <-chan ec2.Instance, userdata string) {
+	t.Helper()
+
+	select {
+	case instance, ok := <-events:
+		if !ok {
+			t.Fatal("Events channel closed unexpectedly")
+		}
+		if instance.Userdata != userdata {
+			t.Fatalf("Expected userdata: %v; Received: %v", userdata, instance.Userdata)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for instance")
+	}
+}
+
+func expectClosed(t *testing.T, events <-chan ec2.Instance) {
+	t.Helper()
+
+	select {
+	case instance, ok := <-events:
+		if ok {
+			t.Fatalf("Expected events channel to be closed; Received: %+v", instance)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for events channel to close")
+	}
+}
+
+// fakeInformer is an informer that delivers events to its handlers on demand.
+type fakeInformer struct {
+	mu       sync.Mutex
+	handlers map[*fakeRegistration]toolscache.ResourceEventHandler
+}
+
+type fakeRegistration struct {
+	synced bool
+}
+
+func (r *fakeRegistration) HasSynced() bool { return r.synced }
+
+func (f *fakeInformer) AddEventHandler(
+	handler toolscache.ResourceEventHandler,
+) (toolscache.ResourceEventHandlerRegistration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.handlers == nil {
+		f.handlers = make(map[*fakeRegistration]toolscache.ResourceEventHandler)
+	}
+
+	registration := &fakeRegistration{synced: true}
+	f.handlers[registration] = handler
+	return registration, nil
+}
+
+func (f *fakeInformer) RemoveEventHandler(registration toolscache.ResourceEventHandlerRegistration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r, ok := registration.(*fakeRegistration); ok {
+		delete(f.handlers, r)
+	}
+	return nil
+}
+
+func (f *fakeInformer) Handlers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.handlers)
+}
+
+func (f *fakeInformer) Add(obj interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, h := range f.handlers {
+		h.OnAdd(obj, false)
+	}
+}
+
+func (f *fakeInformer) Update(oldObj, newObj interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, h := range f.handlers {
+		h.OnUpdate(oldObj, newObj)
+	}
+}
+
+func (f *fakeInformer) Delete(obj interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, h := range f.handlers {
+		h.OnDelete(obj)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Package mock contains a backend that serves deterministic instances generated from the requester's
+IP address. It requires no external data so it's suited to demos and tests.
+
+Each instance's ID, hostname and MAC address are derived from its IP address. For example, the
+instance for 10.10.10.10 has ID i-0a0a0a0a, hostname mock-10-10-10-10 and MAC 02:00:0a:0a:0a:0a.
+*/
+package mock
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/hack"
+	"github.com/tinkerbell/hegel/internal/frontend/watch"
+)
+
+// PublicKey is the public key served for every instance.
+const PublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHegelMockKeyHegelMockKeyHegelMockKey hegel@mock"
+
+// Backend serves generated instances for IP addresses.
+type Backend struct {
+	// ips is the set of IP addresses served. When nil, every IP address is served.
+	ips map[netip.Addr]struct{}
+}
+
+// NewBackend creates a Backend that serves instances for ips. When ips is empty, an instance is
+// served for every IP address. If any of ips isn't a valid IP address it returns an error.
+func NewBackend(ips []string) (*Backend, error) {
+	if len(ips) == 0 {
+		return &Backend{}, nil
+	}
+
+	b := &Backend{ips: make(map[netip.Addr]struct{}, len(ips))}
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, fmt.Errorf("mock: %v", err)
+		}
+		b.ips[addr.Unmap()] = struct{}{}
+	}
+
+	return b, nil
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(_ context.Context, ip string) (ec2.Instance, error) {
+	addr, ok := b.lookup(ip)
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	return toEC2Instance(addr), nil
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client. Only IPv4 instances can be found by MAC address.
+func (b *Backend) GetEC2InstanceByMAC(_ context.Context, mac string) (ec2.Instance, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 || hw[0] != 0x02 || hw[1] != 0x00 {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	addr, ok := b.lookup(netip.AddrFrom4([4]byte(hw[2:])).String())
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	return toEC2Instance(addr), nil
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(_ context.Context, id string) (ec2.Instance, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(id, "i-"))
+	if err != nil || !strings.HasPrefix(id, "i-") {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	decoded, ok := netip.AddrFromSlice(raw)
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	addr, ok := b.lookup(decoded.String())
+	if !ok {
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	return toEC2Instance(addr), nil
+}
+
+// GetHackInstance satisfies hack.Client. Mock instances have no storage configuration.
+func (b *Backend) GetHackInstance(_ context.Context, ip string) (hack.Instance, error) {
+	if _, ok := b.lookup(ip); !ok {
+		return hack.Instance{}, ec2.ErrInstanceNotFound
+	}
+
+	return hack.Instance{}, nil
+}
+
+// Subscribe satisfies watch.Client. Mock instances never change so only the current instance is
+// delivered.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	addr, ok := b.lookup(ip)
+	if !ok {
+		return nil, ec2.ErrInstanceNotFound
+	}
+
+	sub := watch.NewSubscription()
+	sub.Publish(toEC2Instance(addr))
+
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+
+	return sub.Events(), nil
+}
+
+// IsHealthy satisfies healthcheck.Client.
+func (b *Backend) IsHealthy(context.Context) bool {
+	return true
+}
+
+// IsReady satisfies healthcheck.Client.
+func (b *Backend) IsReady(context.Context) bool {
+	return true
+}
+
+// lookup parses ip and reports whether it's served.
+func (b *Backend) lookup(ip string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+
+	if b.ips == nil {
+		return addr, true
+	}
+
+	_, ok := b.ips[addr]
+	return addr, ok
+}
+
+func toEC2Instance(addr netip.Addr) ec2.Instance {
+	hostname := "mock-" + strings.NewReplacer(".", "-", ":", "-").Replace(addr.StringExpanded())
+
+	instance := ec2.Instance{
+		Userdata: fmt.Sprintf("#cloud-config\nhostname: %v\n", hostname),
+		Metadata: ec2.Metadata{
+			InstanceID:    "i-" + hex.EncodeToString(addr.AsSlice()),
+			InstanceType:  "mock",
+			Hostname:      hostname,
+			LocalHostname: hostname,
+			Tags:          []string{"hegel=mock"},
+			PublicKeys:    []string{PublicKey},
+			OperatingSystem: ec2.OperatingSystem{
+				Slug:    "ubuntu_22_04",
+				Distro:  "ubuntu",
+				Version: "22.04",
+			},
+			Placement: ec2.Placement{
+				AvailabilityZone: "mock-1a",
+			},
+		},
+	}
+
+	if addr.Is4() {
+		ip := addr.As4()
+		instance.Metadata.LocalIPv4 = addr.String()
+		instance.Metadata.Network.Interfaces = []ec2.NetworkInterface{
+			{
+				MAC:        net.HardwareAddr{0x02, 0x00, ip[0], ip[1], ip[2], ip[3]}.String(),
+				LocalIPv4s: []string{addr.String()},
+			},
+		}
+	} else {
+		instance.Metadata.PublicIPv6 = addr.String()
+	}
+
+	return instance
+}
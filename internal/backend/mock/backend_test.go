@@ -0,0 +1,182 @@
+package mock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/mock"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2Instance(t *testing.T) {
+	backend, err := NewBackend(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := ec2.Instance{
+		Userdata: "#cloud-config\nhostname: mock-10-10-10-10\n",
+		Metadata: ec2.Metadata{
+			InstanceID:    "i-0a0a0a0a",
+			InstanceType:  "mock",
+			Hostname:      "mock-10-10-10-10",
+			LocalHostname: "mock-10-10-10-10",
+			Tags:          []string{"hegel=mock"},
+			PublicKeys:    []string{PublicKey},
+			LocalIPv4:     "10.10.10.10",
+			OperatingSystem: ec2.OperatingSystem{
+				Slug:    "ubuntu_22_04",
+				Distro:  "ubuntu",
+				Version: "22.04",
+			},
+			Placement: ec2.Placement{
+				AvailabilityZone: "mock-1a",
+			},
+			Network: ec2.Network{
+				Interfaces: []ec2.NetworkInterface{
+					{
+						MAC:        "02:00:0a:0a:0a:0a",
+						LocalIPv4s: []string{"10.10.10.10"},
+					},
+				},
+			},
+		},
+	}
+
+	if !cmp.Equal(expect, instance) {
+		t.Fatal(cmp.Diff(expect, instance))
+	}
+
+	// The same IP always produces the same instance.
+	again, err := backend.GetEC2Instance(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(instance, again) {
+		t.Fatal(cmp.Diff(instance, again))
+	}
+}
+
+func TestLookups(t *testing.T) {
+	cases := []struct {
+		Name       string
+		IPs        []string
+		Lookup     func(context.Context, *Backend) (ec2.Instance, error)
+		InstanceID string
+		NotFound   bool
+	}{
+		{
+			Name: "MappedIP",
+			IPs:  []string{"10.10.10.10", "10.10.10.11"},
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2Instance(ctx, "10.10.10.11")
+			},
+			InstanceID: "i-0a0a0a0b",
+		},
+		{
+			Name: "UnmappedIP",
+			IPs:  []string{"10.10.10.10"},
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2Instance(ctx, "10.10.10.11")
+			},
+			NotFound: true,
+		},
+		{
+			Name: "InvalidIP",
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2Instance(ctx, "invalid")
+			},
+			NotFound: true,
+		},
+		{
+			Name: "IPv6",
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2Instance(ctx, "2001:db8::1")
+			},
+			InstanceID: "i-20010db8000000000000000000000001",
+		},
+		{
+			Name: "MAC",
+			IPs:  []string{"10.10.10.10"},
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2InstanceByMAC(ctx, "02:00:0a:0a:0a:0a")
+			},
+			InstanceID: "i-0a0a0a0a",
+		},
+		{
+			Name: "UnmappedMAC",
+			IPs:  []string{"10.10.10.10"},
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2InstanceByMAC(ctx, "02:00:0a:0a:0a:0b")
+			},
+			NotFound: true,
+		},
+		{
+			Name: "ForeignMAC",
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2InstanceByMAC(ctx, "3c:ec:ef:4c:4f:54")
+			},
+			NotFound: true,
+		},
+		{
+			Name: "ID",
+			IPs:  []string{"10.10.10.10"},
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2InstanceByID(ctx, "i-0a0a0a0a")
+			},
+			InstanceID: "i-0a0a0a0a",
+		},
+		{
+			Name: "UnmappedID",
+			IPs:  []string{"10.10.10.10"},
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2InstanceByID(ctx, "i-0a0a0a0b")
+			},
+			NotFound: true,
+		},
+		{
+			Name: "InvalidID",
+			Lookup: func(ctx context.Context, b *Backend) (ec2.Instance, error) {
+				return b.GetEC2InstanceByID(ctx, "instance-id")
+			},
+			NotFound: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			backend, err := NewBackend(tc.IPs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			instance, err := tc.Lookup(context.Background(), backend)
+			if tc.NotFound {
+				if !errors.Is(err, ec2.ErrInstanceNotFound) {
+					t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instance.Metadata.InstanceID != tc.InstanceID {
+				t.Fatalf("Expected instance ID: %v; Received: %v", tc.InstanceID, instance.Metadata.InstanceID)
+			}
+		})
+	}
+}
+
+func TestNewBackendInvalidIP(t *testing.T) {
+	if _, err := NewBackend([]string{"10.10.10.10", "invalid"}); err == nil {
+		t.Fatal("Expected error, received nil")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Package overlay contains a backend decorator that fills in fields missing from instances using
+defaults configured for the CIDR block containing the instance's IP address. It lets operators
+supply common userdata or tags without repeating them in every Hardware.
+
+Overlays are read from a JSON or YAML file containing a list of overlays:
+
+	# Defaults for rack A.
+	- cidr: 10.10.0.0/16
+	  user-data: "#cloud-config"
+	  tags:
+	  - rack=a
+*/
+package overlay
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"sigs.k8s.io/yaml"
+)
+
+// Overlay defines the defaults for instances whose IP address is in CIDR. A default is only used
+// when the instance's own field is empty.
+type Overlay struct {
+	CIDR       string   `json:"cidr"`
+	Userdata   string   `json:"user-data"`
+	Vendordata string   `json:"vendor-data"`
+	Tags       []string `json:"tags"`
+	PublicKeys []string `json:"public-keys"`
+}
+
+// Load reads the overlays in the JSON or YAML file at path.
+func Load(path string) ([]Overlay, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlays []Overlay
+	if err := yaml.Unmarshal(raw, &overlays); err != nil {
+		return nil, fmt.Errorf("parse %v: %v", path, err)
+	}
+
+	for i, o := range overlays {
+		if _, err := netip.ParsePrefix(o.CIDR); err != nil {
+			return nil, fmt.Errorf("overlay %d: invalid cidr: %v", i, err)
+		}
+	}
+
+	return overlays, nil
+}
+
+// Backend decorates a backend.Client applying overlays to instances looked up by IP address. All
+// other calls, including lookups by MAC address where the client's IP isn't known, are passed
+// through to the decorated client.
+type Backend struct {
+	backend.Client
+
+	overlays []overlay
+}
+
+type overlay struct {
+	Overlay
+	prefix netip.Prefix
+}
+
+// New creates a new Backend that decorates client. When more than one overlay contains an IP
+// address each field is taken from the most specific CIDR that defines it. Overlays with an
+// invalid CIDR are ignored; use Load to validate them.
+func New(client backend.Client, overlays []Overlay) *Backend {
+	var parsed []overlay
+	for _, o := range overlays {
+		if prefix, err := netip.ParsePrefix(o.CIDR); err == nil {
+			parsed = append(parsed, overlay{Overlay: o, prefix: prefix.Masked()})
+		}
+	}
+
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].prefix.Bits() > parsed[j].prefix.Bits()
+	})
+
+	return &Backend{Client: client, overlays: parsed}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance, ip)
+	return instance, nil
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByMAC(ctx, mac)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance, "")
+	return instance, nil
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByID(ctx, id)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(&instance, "")
+	return instance, nil
+}
+
+// Subscribe satisfies watch.Client. Overlays are applied using ip as they are for lookups by IP.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	updates, err := b.Client.Subscribe(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(chan ec2.Instance)
+	go func() {
+		defer close(applied)
+
+		for instance := range updates {
+			b.apply(&instance, ip)
+
+			select {
+			case applied <- instance:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return applied, nil
+}
+
+// apply applies the overlays containing ip to instance. ip is the address the instance was
+// looked up by; when empty the instance's own address is used.
+func (b *Backend) apply(instance *ec2.Instance, ip string) {
+	if ip == "" {
+		ip = instance.Metadata.LocalIPv4
+	}
+	if ip == "" {
+		ip = instance.Metadata.PublicIPv4
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return
+	}
+	addr = addr.Unmap()
+
+	for _, o := range b.overlays {
+		if o.prefix.Contains(addr) {
+			o.apply(instance)
+		}
+	}
+}
+
+// apply fills in the fields of instance that are empty.
+func (o Overlay) apply(instance *ec2.Instance) {
+	if instance.Userdata == "" {
+		instance.Userdata = o.Userdata
+	}
+
+	if instance.Vendordata == "" {
+		instance.Vendordata = o.Vendordata
+	}
+
+	if len(instance.Metadata.Tags) == 0 {
+		instance.Metadata.Tags = slices.Clone(o.Tags)
+	}
+
+	if len(instance.Metadata.PublicKeys) == 0 {
+		instance.Metadata.PublicKeys = slices.Clone(o.PublicKeys)
+	}
+}
@@ -0,0 +1,237 @@
+package overlay_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/overlay"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+const overlays = `
+- cidr: 10.10.0.0/16
+  user-data: "#cloud-config rack"
+  vendor-data: vendordata
+  tags: [rack=a]
+  public-keys: [rack-key]
+- cidr: 10.10.10.0/24
+  user-data: "#cloud-config row"
+- cidr: 192.168.0.0/16
+  tags: [other]
+`
+
+func TestGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name     string
+		IP       string
+		Instance ec2.Instance
+		Expect   ec2.Instance
+	}{
+		{
+			Name: "NoMatchingCIDR",
+			IP:   "172.16.0.1",
+			Instance: ec2.Instance{
+				Metadata: ec2.Metadata{InstanceID: "id"},
+			},
+			Expect: ec2.Instance{
+				Metadata: ec2.Metadata{InstanceID: "id"},
+			},
+		},
+		{
+			Name: "EmptyFieldsFilled",
+			IP:   "10.10.20.1",
+			Instance: ec2.Instance{
+				Metadata: ec2.Metadata{InstanceID: "id"},
+			},
+			Expect: ec2.Instance{
+				Userdata:   "#cloud-config rack",
+				Vendordata: "vendordata",
+				Metadata: ec2.Metadata{
+					InstanceID: "id",
+					Tags:       []string{"rack=a"},
+					PublicKeys: []string{"rack-key"},
+				},
+			},
+		},
+		{
+			Name: "HardwareValuesWin",
+			IP:   "10.10.20.1",
+			Instance: ec2.Instance{
+				Userdata: "#cloud-config hardware",
+				Metadata: ec2.Metadata{
+					Tags: []string{"hardware"},
+				},
+			},
+			Expect: ec2.Instance{
+				Userdata:   "#cloud-config hardware",
+				Vendordata: "vendordata",
+				Metadata: ec2.Metadata{
+					Tags:       []string{"hardware"},
+					PublicKeys: []string{"rack-key"},
+				},
+			},
+		},
+		{
+			Name: "MostSpecificCIDRWins",
+			IP:   "10.10.10.10",
+			Expect: ec2.Instance{
+				Userdata:   "#cloud-config row",
+				Vendordata: "vendordata",
+				Metadata: ec2.Metadata{
+					Tags:       []string{"rack=a"},
+					PublicKeys: []string{"rack-key"},
+				},
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "overlays.yml")
+	if err := os.WriteFile(path, []byte(overlays), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), tc.IP).
+				Return(tc.Instance, nil)
+
+			instance, err := New(client, loaded).GetEC2Instance(context.Background(), tc.IP)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(tc.Expect, instance) {
+				t.Fatal(cmp.Diff(tc.Expect, instance))
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceNotFound(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	overlays := []Overlay{{CIDR: "10.10.0.0/16", Userdata: "#cloud-config"}}
+
+	// Overlays only fill in instances that exist.
+	_, err := New(client, overlays).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestGetEC2InstanceByMACAndID(t *testing.T) {
+	// Lookups by MAC and ID don't know the requester's IP so the instance's own address is used.
+	instance := ec2.Instance{Metadata: ec2.Metadata{LocalIPv4: "10.10.10.10"}}
+	expect := ec2.Instance{Userdata: "#cloud-config", Metadata: ec2.Metadata{LocalIPv4: "10.10.10.10"}}
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").Return(instance, nil)
+	client.EXPECT().GetEC2InstanceByID(gomock.Any(), "id").Return(instance, nil)
+
+	backend := New(client, []Overlay{{CIDR: "10.10.0.0/16", Userdata: "#cloud-config"}})
+
+	byMAC, err := backend.GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, byMAC); diff != "" {
+		t.Fatal(diff)
+	}
+
+	byID, err := backend.GetEC2InstanceByID(context.Background(), "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, byID); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	updates := make(chan ec2.Instance, 2)
+	updates <- ec2.Instance{}
+	updates <- ec2.Instance{Userdata: "#cloud-config hardware"}
+	close(updates)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		Subscribe(gomock.Any(), "10.10.10.10").
+		Return((<-chan ec2.Instance)(updates), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	overlays := []Overlay{{CIDR: "10.10.0.0/16", Userdata: "#cloud-config", Tags: []string{"rack=a"}}}
+
+	applied, err := New(client, overlays).Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []ec2.Instance
+	for instance := range applied {
+		received = append(received, instance)
+	}
+
+	expect := []ec2.Instance{
+		{Userdata: "#cloud-config", Metadata: ec2.Metadata{Tags: []string{"rack=a"}}},
+		{Userdata: "#cloud-config hardware", Metadata: ec2.Metadata{Tags: []string{"rack=a"}}},
+	}
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Overlays string
+		Error    string
+	}{
+		{
+			Name:     "InvalidCIDR",
+			Overlays: "- cidr: 10.10.10.10\n",
+			Error:    "overlay 0: invalid cidr",
+		},
+		{
+			Name:     "MissingCIDR",
+			Overlays: "- user-data: \"#cloud-config\"\n",
+			Error:    "overlay 0: invalid cidr",
+		},
+		{
+			Name:     "Malformed",
+			Overlays: "cidr: 10.10.0.0/16\n",
+			Error:    "parse",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "overlays.yml")
+			if err := os.WriteFile(path, []byte(tc.Overlays), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("Expected error containing: %v; Received: %v", tc.Error, err)
+			}
+		})
+	}
+}
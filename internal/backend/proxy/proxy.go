@@ -0,0 +1,117 @@
+/*
+Package proxy contains a backend decorator that falls back to an upstream metadata service for
+instances the decorated backend doesn't have. It lets operators migrate instances to Hegel
+incrementally while the upstream continues serving the rest.
+
+The upstream must serve the EC2 instance document at /2009-04-04/meta-data.json, as Hegel does,
+and trust the X-Forwarded-For header from Hegel so it resolves the instance of the original
+requester.
+*/
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// DefaultTimeout is the default duration an upstream request may take.
+const DefaultTimeout = 10 * time.Second
+
+// documentPath is the path of the instance document relative to the upstream URL.
+const documentPath = "2009-04-04/meta-data.json"
+
+// Upstream retrieves instances from an upstream metadata service.
+type Upstream struct {
+	document *url.URL
+	client   *http.Client
+}
+
+// NewUpstream creates an Upstream for the metadata service at rawURL, such as
+// http://metadata.example.com. rawURL may include a path prefix the service is served under.
+// Requests taking longer than timeout fail; when 0, DefaultTimeout is used.
+func NewUpstream(rawURL string, timeout time.Duration) (*Upstream, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream url: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream url %q: must be an absolute http or https url", rawURL)
+	}
+
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Upstream{
+		document: u.JoinPath(documentPath),
+		client: &http.Client{
+			Timeout: timeout,
+			// Propagate the trace of the request being served so the upstream continues it.
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}, nil
+}
+
+// GetEC2Instance retrieves the instance associated with ip from the upstream. If the upstream
+// has no instance for ip it returns ec2.ErrInstanceNotFound.
+func (u *Upstream) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.document.String(), nil)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+	req.Header.Set("X-Forwarded-For", ip)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return ec2.Instance{}, fmt.Errorf("upstream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ec2.Instance{}, ec2.ErrInstanceNotFound
+	default:
+		return ec2.Instance{}, fmt.Errorf("upstream: unexpected status: %v", resp.Status)
+	}
+
+	var instance ec2.Instance
+	if err := json.NewDecoder(resp.Body).Decode(&instance); err != nil {
+		return ec2.Instance{}, fmt.Errorf("upstream: decode instance: %w", err)
+	}
+
+	return instance, nil
+}
+
+// Backend decorates a backend.Client retrieving instances looked up by IP address from an
+// Upstream when the decorated client doesn't have them. All other calls are passed through to the
+// decorated client.
+type Backend struct {
+	backend.Client
+
+	upstream *Upstream
+}
+
+// New creates a new Backend that decorates client falling back to upstream.
+func New(client backend.Client, upstream *Upstream) *Backend {
+	return &Backend{Client: client, upstream: upstream}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if errors.Is(err, ec2.ErrInstanceNotFound) {
+		return b.upstream.GetEC2Instance(ctx, ip)
+	}
+	return instance, err
+}
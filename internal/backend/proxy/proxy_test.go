@@ -0,0 +1,273 @@
+package proxy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/proxy"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const document = `{
+	"user-data": "#cloud-config",
+	"meta-data": {
+		"instance-id": "upstream-id",
+		"hostname": "upstream-hostname",
+		"public-keys": ["ssh-ed25519 key"]
+	}
+}`
+
+var upstreamInstance = ec2.Instance{
+	Userdata: "#cloud-config",
+	Metadata: ec2.Metadata{
+		InstanceID: "upstream-id",
+		Hostname:   "upstream-hostname",
+		PublicKeys: []string{"ssh-ed25519 key"},
+	},
+}
+
+// newUpstream starts an upstream serving document for 10.10.10.10 and a 500 for 10.10.10.99
+// under prefix. It returns the upstream URL and a count of requests received.
+func newUpstream(t *testing.T, prefix string) (string, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/2009-04-04/meta-data.json", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		switch r.Header.Get("X-Forwarded-For") {
+		case "10.10.10.10":
+			fmt.Fprint(w, document)
+		case "10.10.10.99":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server.URL + prefix, &requests
+}
+
+func TestUpstreamGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Prefix        string
+		IP            string
+		Expect        ec2.Instance
+		ExpectedError error
+		Error         bool
+	}{
+		{
+			Name:   "Found",
+			IP:     "10.10.10.10",
+			Expect: upstreamInstance,
+		},
+		{
+			Name:   "FoundWithPrefix",
+			Prefix: "/hegel",
+			IP:     "10.10.10.10",
+			Expect: upstreamInstance,
+		},
+		{
+			Name:          "NotFound",
+			IP:            "10.10.10.11",
+			ExpectedError: ec2.ErrInstanceNotFound,
+		},
+		{
+			Name:  "UpstreamError",
+			IP:    "10.10.10.99",
+			Error: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			url, _ := newUpstream(t, tc.Prefix)
+
+			upstream, err := NewUpstream(url, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			instance, err := upstream.GetEC2Instance(context.Background(), tc.IP)
+
+			switch {
+			case tc.ExpectedError != nil:
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Expected error: %v; Received: %v", tc.ExpectedError, err)
+				}
+			case tc.Error:
+				if err == nil || errors.Is(err, ec2.ErrInstanceNotFound) {
+					t.Fatalf("Expected an upstream error; Received: %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.Expect, instance); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamPropagatesTrace(t *testing.T) {
+	// The global propagator is used so the upstream receives the trace of the request being served.
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		fmt.Fprint(w, document)
+	}))
+	t.Cleanup(server.Close)
+
+	upstream, err := NewUpstream(server.URL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := upstream.GetEC2Instance(ctx, "10.10.10.10"); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if traceparent != expect {
+		t.Fatalf("Expected traceparent: %v; Received: %v", expect, traceparent)
+	}
+}
+
+func TestNewUpstreamInvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"", "metadata.example.com", "ftp://metadata.example.com", "http://%zz"} {
+		t.Run(rawURL, func(t *testing.T) {
+			if _, err := NewUpstream(rawURL, 0); err == nil {
+				t.Fatalf("Expected error for %q", rawURL)
+			}
+		})
+	}
+}
+
+func TestBackendGetEC2Instance(t *testing.T) {
+	primaryErr := errors.New("primary unavailable")
+
+	cases := []struct {
+		Name             string
+		IP               string
+		Primary          ec2.Instance
+		PrimaryErr       error
+		Expect           ec2.Instance
+		ExpectedError    error
+		ExpectedRequests int64
+	}{
+		{
+			Name:    "PrimaryFound",
+			IP:      "10.10.10.10",
+			Primary: ec2.Instance{Metadata: ec2.Metadata{InstanceID: "primary-id"}},
+			Expect:  ec2.Instance{Metadata: ec2.Metadata{InstanceID: "primary-id"}},
+		},
+		{
+			Name:             "FallbackFound",
+			IP:               "10.10.10.10",
+			PrimaryErr:       ec2.ErrInstanceNotFound,
+			Expect:           upstreamInstance,
+			ExpectedRequests: 1,
+		},
+		{
+			Name:             "FallbackNotFound",
+			IP:               "10.10.10.11",
+			PrimaryErr:       ec2.ErrInstanceNotFound,
+			ExpectedError:    ec2.ErrInstanceNotFound,
+			ExpectedRequests: 1,
+		},
+		{
+			// Only lookups the primary can't satisfy are proxied; its failures are surfaced.
+			Name:          "PrimaryError",
+			IP:            "10.10.10.10",
+			PrimaryErr:    primaryErr,
+			ExpectedError: primaryErr,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			url, requests := newUpstream(t, "")
+
+			upstream, err := NewUpstream(url, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), tc.IP).
+				Return(tc.Primary, tc.PrimaryErr)
+
+			instance, err := New(client, upstream).GetEC2Instance(context.Background(), tc.IP)
+
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Expected error: %v; Received: %v", tc.ExpectedError, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.Expect, instance); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+
+			if received := requests.Load(); received != tc.ExpectedRequests {
+				t.Fatalf("Expected upstream requests: %v; Received: %v", tc.ExpectedRequests, received)
+			}
+		})
+	}
+}
+
+func TestBackendPassesThroughByMAC(t *testing.T) {
+	url, requests := newUpstream(t, "")
+
+	upstream, err := NewUpstream(url, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	_, err = New(client, upstream).GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected error: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+
+	if requests.Load() != 0 {
+		t.Fatal("Expected lookups by MAC not to be proxied")
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Package rdns contains a backend decorator that resolves hostnames for instances that don't have one
+with a reverse DNS lookup of the requesting IP. It suits environments where DNS is the source of
+truth for hostnames and Hardware isn't kept in sync with it.
+
+Lookups are bounded by a timeout so a slow resolver doesn't hold up metadata requests, and their
+results, including addresses with no name, are cached.
+*/
+package rdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// DefaultTimeout is the default duration a reverse lookup may take.
+const DefaultTimeout = time.Second
+
+// DefaultTTL is the default duration lookup results are cached for.
+const DefaultTTL = 5 * time.Minute
+
+// maxEntries bounds the number of cached results so scan traffic can't grow the cache unbounded.
+const maxEntries = 4096
+
+// Resolver performs reverse DNS lookups. It's satisfied by *net.Resolver.
+type Resolver interface {
+	// LookupAddr returns the names of addr.
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// Config configures a Backend.
+type Config struct {
+	// Resolver performs lookups. When nil, net.DefaultResolver is used.
+	Resolver Resolver
+
+	// Timeout bounds each lookup. When 0, DefaultTimeout is used.
+	Timeout time.Duration
+
+	// TTL is how long results are cached. When 0, DefaultTTL is used.
+	TTL time.Duration
+}
+
+// Backend decorates a backend.Client resolving hostnames of instances looked up by IP that don't
+// have one. The first name returned for the IP is used, without its trailing dot. When an instance
+// has no local hostname the resolved hostname is used for it too. If the lookup fails, such as
+// when the IP has no name or the lookup times out, the instance is served without a hostname.
+//
+// All other calls are passed through to the decorated client.
+type Backend struct {
+	backend.Client
+
+	resolver Resolver
+	timeout  time.Duration
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	hostname string
+	expires  time.Time
+}
+
+// New creates a new Backend that decorates client.
+func New(client backend.Client, cfg Config) *Backend {
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultTTL
+	}
+
+	return &Backend{
+		Client:   client,
+		resolver: cfg.Resolver,
+		timeout:  cfg.Timeout,
+		ttl:      cfg.TTL,
+		entries:  make(map[string]entry),
+	}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	b.apply(ctx, &instance, ip)
+	return instance, nil
+}
+
+// Subscribe satisfies watch.Client.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	updates, err := b.Client.Subscribe(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(chan ec2.Instance)
+	go func() {
+		defer close(applied)
+
+		for instance := range updates {
+			b.apply(ctx, &instance, ip)
+
+			select {
+			case applied <- instance:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return applied, nil
+}
+
+// apply fills in the hostnames of instance that are empty with the hostname ip resolves to.
+func (b *Backend) apply(ctx context.Context, instance *ec2.Instance, ip string) {
+	metadata := &instance.Metadata
+	if metadata.Hostname == "" {
+		metadata.Hostname = b.resolve(ctx, ip)
+	}
+	if metadata.LocalHostname == "" {
+		metadata.LocalHostname = metadata.Hostname
+	}
+}
+
+// resolve returns the hostname of ip or an empty string if it has none.
+func (b *Backend) resolve(ctx context.Context, ip string) string {
+	now := time.Now()
+	if hostname, ok := b.cached(ip, now); ok {
+		return hostname
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	names, err := b.resolver.LookupAddr(ctx, ip)
+
+	var hostname string
+	switch {
+	case err == nil && len(names) > 0:
+		hostname = strings.TrimSuffix(names[0], ".")
+	case err == nil || isNotFound(err):
+		// Addresses without a name are cached so they aren't looked up on every request.
+	default:
+		// Other failures, such as timeouts, may be transient so they're retried on the next
+		// request.
+		return ""
+	}
+
+	b.store(ip, hostname, now)
+	return hostname
+}
+
+func (b *Backend) cached(ip string, now time.Time) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[ip]
+	if !ok || now.After(e.expires) {
+		return "", false
+	}
+	return e.hostname, true
+}
+
+func (b *Backend) store(ip, hostname string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+	if len(b.entries) >= maxEntries {
+		return
+	}
+
+	b.entries[ip] = entry{hostname: hostname, expires: now.Add(b.ttl)}
+}
+
+// sweep removes expired entries at most once per TTL.
+func (b *Backend) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.ttl {
+		return
+	}
+	b.lastSweep = now
+
+	for ip, e := range b.entries {
+		if now.After(e.expires) {
+			delete(b.entries, ip)
+		}
+	}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,202 @@
+package rdns_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/rdns"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// resolver is a Resolver stub that counts lookups.
+type resolver struct {
+	lookup  func(ctx context.Context, addr string) ([]string, error)
+	lookups atomic.Int64
+}
+
+func (r *resolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.lookups.Add(1)
+	return r.lookup(ctx, addr)
+}
+
+func TestGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Lookup   func(ctx context.Context, addr string) ([]string, error)
+		Instance ec2.Metadata
+		Expect   ec2.Metadata
+	}{
+		{
+			Name: "Resolved",
+			Lookup: func(_ context.Context, addr string) ([]string, error) {
+				if addr != "10.10.10.10" {
+					return nil, errors.New("unexpected address")
+				}
+				return []string{"node-1.example.com.", "alias.example.com."}, nil
+			},
+			Expect: ec2.Metadata{Hostname: "node-1.example.com", LocalHostname: "node-1.example.com"},
+		},
+		{
+			Name: "ExplicitHostname",
+			Lookup: func(context.Context, string) ([]string, error) {
+				return []string{"node-1.example.com."}, nil
+			},
+			Instance: ec2.Metadata{Hostname: "explicit"},
+			Expect:   ec2.Metadata{Hostname: "explicit", LocalHostname: "explicit"},
+		},
+		{
+			Name: "NXDOMAIN",
+			Lookup: func(_ context.Context, addr string) ([]string, error) {
+				return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+			},
+			Expect: ec2.Metadata{},
+		},
+		{
+			Name: "Timeout",
+			Lookup: func(ctx context.Context, _ string) ([]string, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			Expect: ec2.Metadata{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(ec2.Instance{Metadata: tc.Instance}, nil)
+
+			be := New(client, Config{Resolver: &resolver{lookup: tc.Lookup}, Timeout: 10 * time.Millisecond})
+
+			instance, err := be.GetEC2Instance(context.Background(), "10.10.10.10")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.Expect, instance.Metadata); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceCaches(t *testing.T) {
+	cases := []struct {
+		Name            string
+		Lookup          func(ctx context.Context, addr string) ([]string, error)
+		ExpectedLookups int64
+	}{
+		{
+			Name: "Resolved",
+			Lookup: func(context.Context, string) ([]string, error) {
+				return []string{"node-1.example.com."}, nil
+			},
+			ExpectedLookups: 1,
+		},
+		{
+			Name: "NXDOMAIN",
+			Lookup: func(_ context.Context, addr string) ([]string, error) {
+				return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+			},
+			ExpectedLookups: 1,
+		},
+		{
+			// Timeouts may be transient so they're retried.
+			Name: "Timeout",
+			Lookup: func(ctx context.Context, _ string) ([]string, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			ExpectedLookups: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(ec2.Instance{}, nil).
+				Times(2)
+
+			r := &resolver{lookup: tc.Lookup}
+			be := New(client, Config{Resolver: r, Timeout: 10 * time.Millisecond})
+
+			for i := 0; i < 2; i++ {
+				if _, err := be.GetEC2Instance(context.Background(), "10.10.10.10"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if lookups := r.lookups.Load(); lookups != tc.ExpectedLookups {
+				t.Fatalf("Expected lookups: %v; Received: %v", tc.ExpectedLookups, lookups)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceError(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	r := &resolver{lookup: func(context.Context, string) ([]string, error) {
+		return []string{"node-1.example.com."}, nil
+	}}
+
+	_, err := New(client, Config{Resolver: r}).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+
+	// Unknown IPs aren't resolved.
+	if lookups := r.lookups.Load(); lookups != 0 {
+		t.Fatalf("Expected no lookups; Received: %v", lookups)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	updates := make(chan ec2.Instance, 2)
+	updates <- ec2.Instance{}
+	updates <- ec2.Instance{Metadata: ec2.Metadata{Hostname: "explicit"}}
+	close(updates)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		Subscribe(gomock.Any(), "10.10.10.10").
+		Return((<-chan ec2.Instance)(updates), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := &resolver{lookup: func(context.Context, string) ([]string, error) {
+		return []string{"node-1.example.com."}, nil
+	}}
+
+	resolved, err := New(client, Config{Resolver: r}).Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []ec2.Metadata
+	for instance := range resolved {
+		received = append(received, instance.Metadata)
+	}
+
+	expect := []ec2.Metadata{
+		{Hostname: "node-1.example.com", LocalHostname: "node-1.example.com"},
+		{Hostname: "explicit", LocalHostname: "explicit"},
+	}
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Package rediscache contains a backend decorator that caches instance lookups in Redis. Unlike the
+in-memory cache, instances cached by one Hegel replica are served by every replica sharing the
+Redis server so replicas behind a load balancer don't each look up the same instances.
+
+Redis is an optimization rather than a dependency: when it's unavailable lookups fail open to the
+decorated backend.
+*/
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/redis/go-redis/v9"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/backend/ipaddr"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// DefaultTTL is the default duration instances are cached for.
+const DefaultTTL = time.Minute
+
+// DefaultKeyPrefix is the default prefix of the Redis keys instances are cached under.
+const DefaultKeyPrefix = "hegel:instance:ip:"
+
+// Config configures a Backend.
+type Config struct {
+	// TTL is how long found instances are cached. Defaults to DefaultTTL.
+	TTL time.Duration
+
+	// KeyPrefix is prepended to the IP to form the Redis key instances are cached under. Replicas
+	// sharing a cache must use the same prefix. Defaults to DefaultKeyPrefix.
+	KeyPrefix string
+
+	// Logger logs Redis failures.
+	Logger logr.Logger
+}
+
+// NewClient creates a Redis client for url, such as redis://:password@localhost:6379/0.
+func NewClient(url string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return redis.NewClient(opts), nil
+}
+
+// Backend decorates a backend.Client caching EC2 instance lookups by IP in Redis. All other
+// calls are passed through to the decorated client.
+//
+// Cached instances may be stale for up to the configured TTL.
+type Backend struct {
+	backend.Client
+
+	redis     redis.Cmdable
+	ttl       time.Duration
+	keyPrefix string
+	logger    logr.Logger
+}
+
+// New creates a new Backend that decorates client caching instances in rdb.
+func New(client backend.Client, rdb redis.Cmdable, cfg Config) *Backend {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultKeyPrefix
+	}
+
+	return &Backend{
+		Client:    client,
+		redis:     rdb,
+		ttl:       cfg.TTL,
+		keyPrefix: cfg.KeyPrefix,
+		logger:    cfg.Logger,
+	}
+}
+
+// GetEC2Instance satisfies ec2.Client. If Redis can't be read or written the instance is
+// retrieved from the decorated client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	ip = ipaddr.Normalize(ip)
+	key := b.keyPrefix + ip
+
+	raw, err := b.redis.Get(ctx, key).Bytes()
+	switch {
+	case err == nil:
+		var instance ec2.Instance
+		decodeErr := json.Unmarshal(raw, &instance)
+		if decodeErr == nil {
+			return instance, nil
+		}
+		b.logger.Info("Could not decode cached instance, retrieving from backend", "key", key, "error", decodeErr)
+
+	case !errors.Is(err, redis.Nil):
+		b.logger.Info("Could not read instance from Redis, retrieving from backend", "key", key, "error", err)
+	}
+
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	raw, err = json.Marshal(instance)
+	if err != nil {
+		b.logger.Info("Could not encode instance for Redis", "key", key, "error", err)
+		return instance, nil
+	}
+
+	if err := b.redis.Set(ctx, key, raw, b.ttl).Err(); err != nil {
+		b.logger.Info("Could not write instance to Redis", "key", key, "error", err)
+	}
+
+	return instance, nil
+}
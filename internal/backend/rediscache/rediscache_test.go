@@ -0,0 +1,199 @@
+package rediscache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/redis/go-redis/v9"
+	. "github.com/tinkerbell/hegel/internal/backend/rediscache"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	return server, rdb
+}
+
+func TestGetEC2InstanceMissThenHit(t *testing.T) {
+	server, rdb := newRedis(t)
+
+	instance := ec2.Instance{
+		Userdata: "#cloud-config",
+		Metadata: ec2.Metadata{
+			InstanceID: "i-1234",
+			Tags:       []string{"rack=a"},
+			Network: ec2.Network{
+				Interfaces: []ec2.NetworkInterface{{MAC: "00:00:00:00:00:01"}},
+			},
+		},
+	}
+
+	// The decorated client is only called on the first lookup.
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(instance, nil).
+		Times(1)
+
+	be := New(client, rdb, Config{TTL: time.Minute, Logger: logr.Discard()})
+
+	for i := 0; i < 2; i++ {
+		received, err := be.GetEC2Instance(context.Background(), "10.10.10.10")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(instance, received); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+
+	if ttl := server.TTL(DefaultKeyPrefix + "10.10.10.10"); ttl != time.Minute {
+		t.Fatalf("Expected TTL: %v; Received: %v", time.Minute, ttl)
+	}
+}
+
+func TestGetEC2InstanceSharedAcrossReplicas(t *testing.T) {
+	_, rdb := newRedis(t)
+
+	instance := ec2.Instance{Metadata: ec2.Metadata{InstanceID: "i-1234"}}
+
+	first := NewMockClient(gomock.NewController(t))
+	first.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(instance, nil)
+
+	// The second replica is served from Redis so its backend is never called.
+	second := NewMockClient(gomock.NewController(t))
+
+	cfg := Config{Logger: logr.Discard()}
+	if _, err := New(first, rdb, cfg).GetEC2Instance(context.Background(), "10.10.10.10"); err != nil {
+		t.Fatal(err)
+	}
+
+	received, err := New(second, rdb, cfg).GetEC2Instance(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(instance, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestGetEC2InstanceExpires(t *testing.T) {
+	server, rdb := newRedis(t)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, nil).
+		Times(2)
+
+	be := New(client, rdb, Config{TTL: time.Minute, Logger: logr.Discard()})
+
+	if _, err := be.GetEC2Instance(context.Background(), "10.10.10.10"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.FastForward(time.Minute)
+
+	if _, err := be.GetEC2Instance(context.Background(), "10.10.10.10"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetEC2InstanceErrorNotCached(t *testing.T) {
+	server, rdb := newRedis(t)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound).
+		Times(2)
+
+	be := New(client, rdb, Config{Logger: logr.Discard()})
+
+	for i := 0; i < 2; i++ {
+		_, err := be.GetEC2Instance(context.Background(), "10.10.10.10")
+		if !errors.Is(err, ec2.ErrInstanceNotFound) {
+			t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+		}
+	}
+
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Fatalf("Expected no cached keys; Received: %v", keys)
+	}
+}
+
+func TestGetEC2InstanceRedisDown(t *testing.T) {
+	server, rdb := newRedis(t)
+	server.Close()
+
+	instance := ec2.Instance{Metadata: ec2.Metadata{InstanceID: "i-1234"}}
+
+	// Every lookup fails open to the decorated client.
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(instance, nil).
+		Times(2)
+
+	be := New(client, rdb, Config{Logger: logr.Discard()})
+
+	for i := 0; i < 2; i++ {
+		received, err := be.GetEC2Instance(context.Background(), "10.10.10.10")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(instance, received); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+}
+
+func TestGetEC2InstanceCorruptEntry(t *testing.T) {
+	server, rdb := newRedis(t)
+
+	if err := server.Set(DefaultKeyPrefix+"10.10.10.10", "not json"); err != nil {
+		t.Fatal(err)
+	}
+
+	instance := ec2.Instance{Metadata: ec2.Metadata{InstanceID: "i-1234"}}
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(instance, nil)
+
+	received, err := New(client, rdb, Config{Logger: logr.Discard()}).GetEC2Instance(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(instance, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient("redis://localhost:6379/0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewClient("localhost:6379"); err == nil {
+		t.Fatal("Expected error for URL without a scheme")
+	}
+}
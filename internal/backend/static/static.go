@@ -0,0 +1,255 @@
+/*
+Package static contains a backend decorator that fills in metadata missing from instances with
+static values served to every instance. It lets operators serve values common to all instances,
+such as a phone home URL, without modeling them in Hardware.
+
+Static metadata is read from a JSON or YAML file mapping EC2 metadata paths, relative to the API
+version, to values:
+
+	meta-data/x/phone-home: https://provisioner.example.com/phone-home
+	meta-data/instance-type: c3.small.x86
+	vendor-data: "#cloud-config"
+
+Paths identifying an instance, such as meta-data/instance-id and meta-data/local-ipv4, can't be
+static. Any key under meta-data/x/ can be.
+*/
+package static
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"sigs.k8s.io/yaml"
+)
+
+// customPrefix is the path prefix of custom metadata keys.
+const customPrefix = "meta-data/x/"
+
+// fields are the paths that may be static, excluding custom metadata, and the instance field each
+// is served from.
+var fields = []struct {
+	Path  string
+	Field func(i *ec2.Instance) *string
+}{
+	{
+		Path:  "user-data",
+		Field: func(i *ec2.Instance) *string { return &i.Userdata },
+	},
+	{
+		Path:  "vendor-data",
+		Field: func(i *ec2.Instance) *string { return &i.Vendordata },
+	},
+	{
+		Path:  "meta-data/instance-type",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.InstanceType },
+	},
+	{
+		Path:  "meta-data/instance-life-cycle",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.Lifecycle },
+	},
+	{
+		Path:  "meta-data/hostname",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.Hostname },
+	},
+	{
+		Path:  "meta-data/local-hostname",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.LocalHostname },
+	},
+	{
+		Path:  "meta-data/iqn",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.IQN },
+	},
+	{
+		Path:  "meta-data/plan",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.Plan },
+	},
+	{
+		Path:  "meta-data/facility",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.Facility },
+	},
+	{
+		Path:  "meta-data/placement/availability-zone",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.Placement.AvailabilityZone },
+	},
+	{
+		Path:  "meta-data/placement/region",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.Placement.Region },
+	},
+	{
+		Path:  "meta-data/operating-system/slug",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.OperatingSystem.Slug },
+	},
+	{
+		Path:  "meta-data/operating-system/distro",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.OperatingSystem.Distro },
+	},
+	{
+		Path:  "meta-data/operating-system/version",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.OperatingSystem.Version },
+	},
+	{
+		Path:  "meta-data/operating-system/image_tag",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.OperatingSystem.ImageTag },
+	},
+	{
+		Path:  "meta-data/operating-system/license_activation/state",
+		Field: func(i *ec2.Instance) *string { return &i.Metadata.OperatingSystem.LicenseActivation.State },
+	},
+}
+
+// field returns the field of instance served at p. If p isn't a static field it returns nil.
+func field(instance *ec2.Instance, p string) *string {
+	for _, f := range fields {
+		if f.Path == p {
+			return f.Field(instance)
+		}
+	}
+	return nil
+}
+
+// Metadata maps EC2 metadata paths, such as meta-data/x/phone-home, to static values.
+type Metadata map[string]string
+
+// Load reads the static metadata in the JSON or YAML file at path. Paths may have a leading
+// slash.
+func Load(path string) (Metadata, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed map[string]string
+	if err := yaml.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("parse %v: %v", path, err)
+	}
+
+	metadata := make(Metadata, len(parsed))
+	for p, value := range parsed {
+		p = strings.TrimPrefix(p, "/")
+		if !validPath(p) {
+			paths := make([]string, 0, len(fields))
+			for _, f := range fields {
+				paths = append(paths, f.Path)
+			}
+			return nil, fmt.Errorf(
+				"unsupported path %v: expected one of %v or a key under %v",
+				p,
+				strings.Join(paths, ", "),
+				customPrefix,
+			)
+		}
+		metadata[p] = value
+	}
+
+	return metadata, nil
+}
+
+// validPath reports whether p may be static.
+func validPath(p string) bool {
+	if key, ok := strings.CutPrefix(p, customPrefix); ok {
+		return key != "" && !slices.Contains(strings.Split(key, "/"), "")
+	}
+	return field(&ec2.Instance{}, p) != nil
+}
+
+// Backend decorates a backend.Client applying static metadata to instances. All other calls are
+// passed through to the decorated client.
+type Backend struct {
+	backend.Client
+
+	metadata Metadata
+}
+
+// New creates a new Backend that decorates client. Unsupported paths in metadata are ignored; use
+// Load to validate them.
+func New(client backend.Client, metadata Metadata) *Backend {
+	return &Backend{Client: client, metadata: metadata}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	return b.apply(instance), nil
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByMAC(ctx, mac)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	return b.apply(instance), nil
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByID(ctx, id)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	return b.apply(instance), nil
+}
+
+// Subscribe satisfies watch.Client.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	updates, err := b.Client.Subscribe(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(chan ec2.Instance)
+	go func() {
+		defer close(applied)
+
+		for instance := range updates {
+			select {
+			case applied <- b.apply(instance):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return applied, nil
+}
+
+// apply fills in the metadata of instance that is empty. Custom metadata is copied before it's
+// modified as the decorated client may share it between lookups.
+func (b *Backend) apply(instance ec2.Instance) ec2.Instance {
+	copied := false
+	for p, value := range b.metadata {
+		if key, ok := strings.CutPrefix(p, customPrefix); ok {
+			if _, exists := instance.Metadata.Custom[key]; exists {
+				continue
+			}
+
+			if !copied {
+				instance.Metadata.Custom = maps.Clone(instance.Metadata.Custom)
+				if instance.Metadata.Custom == nil {
+					instance.Metadata.Custom = map[string]string{}
+				}
+				copied = true
+			}
+			instance.Metadata.Custom[key] = value
+			continue
+		}
+
+		if v := field(&instance, p); v != nil && *v == "" {
+			*v = value
+		}
+	}
+
+	return instance
+}
@@ -0,0 +1,191 @@
+package static_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/static"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+const statics = `
+/meta-data/x/phone-home: https://provisioner.example.com/phone-home
+meta-data/instance-type: c3.small.x86
+vendor-data: vendordata
+`
+
+func TestGetEC2Instance(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Instance ec2.Instance
+		Expect   ec2.Instance
+	}{
+		{
+			Name: "EmptyFieldsFilled",
+			Instance: ec2.Instance{
+				Metadata: ec2.Metadata{InstanceID: "id"},
+			},
+			Expect: ec2.Instance{
+				Vendordata: "vendordata",
+				Metadata: ec2.Metadata{
+					InstanceID:   "id",
+					InstanceType: "c3.small.x86",
+					Custom:       map[string]string{"phone-home": "https://provisioner.example.com/phone-home"},
+				},
+			},
+		},
+		{
+			Name: "InstanceFieldsKept",
+			Instance: ec2.Instance{
+				Vendordata: "#cloud-config",
+				Metadata: ec2.Metadata{
+					InstanceID:   "id",
+					InstanceType: "m3.large.x86",
+					Custom:       map[string]string{"phone-home": "https://rack.example.com/phone-home"},
+				},
+			},
+			Expect: ec2.Instance{
+				Vendordata: "#cloud-config",
+				Metadata: ec2.Metadata{
+					InstanceID:   "id",
+					InstanceType: "m3.large.x86",
+					Custom:       map[string]string{"phone-home": "https://rack.example.com/phone-home"},
+				},
+			},
+		},
+		{
+			Name: "CustomMetadataMerged",
+			Instance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					Custom: map[string]string{"rack": "r12"},
+				},
+			},
+			Expect: ec2.Instance{
+				Vendordata: "vendordata",
+				Metadata: ec2.Metadata{
+					InstanceType: "c3.small.x86",
+					Custom: map[string]string{
+						"rack":       "r12",
+						"phone-home": "https://provisioner.example.com/phone-home",
+					},
+				},
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "static.yml")
+	if err := os.WriteFile(path, []byte(statics), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// The decorated client may share custom metadata between lookups so it mustn't be
+			// modified.
+			original := tc.Instance.Metadata.Custom
+			originalLen := len(original)
+
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(tc.Instance, nil)
+
+			instance, err := New(client, loaded).GetEC2Instance(context.Background(), "10.10.10.10")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(tc.Expect, instance) {
+				t.Fatal(cmp.Diff(tc.Expect, instance))
+			}
+
+			if len(original) != originalLen {
+				t.Fatalf("Expected decorated client's custom metadata to be unmodified; Received: %v", original)
+			}
+		})
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{}, nil)
+
+	instance, err := New(client, Metadata{"user-data": "#cloud-config"}).
+		GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instance.Userdata != "#cloud-config" {
+		t.Fatalf("Expected static userdata; Received: %q", instance.Userdata)
+	}
+}
+
+func TestGetEC2InstanceNotFound(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	// Static metadata only fills in instances that exist.
+	_, err := New(client, Metadata{"user-data": "#cloud-config"}).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Static string
+		Error  string
+	}{
+		{
+			Name:   "InstanceIdentity",
+			Static: "meta-data/instance-id: id\n",
+			Error:  "unsupported path meta-data/instance-id",
+		},
+		{
+			Name:   "UnknownPath",
+			Static: "meta-data/phone-home: https://provisioner.example.com\n",
+			Error:  "unsupported path meta-data/phone-home",
+		},
+		{
+			Name:   "EmptyCustomKey",
+			Static: "meta-data/x/: value\n",
+			Error:  "unsupported path meta-data/x/",
+		},
+		{
+			Name:   "Malformed",
+			Static: "- user-data: \"#cloud-config\"\n",
+			Error:  "parse",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "static.yml")
+			if err := os.WriteFile(path, []byte(tc.Static), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("Expected error containing: %v; Received: %v", tc.Error, err)
+			}
+		})
+	}
+}
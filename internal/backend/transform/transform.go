@@ -0,0 +1,121 @@
+/*
+Package transform contains a backend decorator that runs a chain of Transformers over instances
+after they're retrieved from the backend and before they're rendered by the frontends. It lets
+operators post-process instances, such as redacting fields or injecting computed values, without
+modifying the backends.
+*/
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// Transformer modifies instances retrieved from the backend.
+type Transformer interface {
+	// Transform modifies instance in place. ip is the address the instance was looked up by; it's
+	// empty when the instance was looked up by another means, such as its MAC address. If
+	// Transform returns an error the instance isn't served.
+	Transform(ctx context.Context, ip string, instance *ec2.Instance) error
+}
+
+// TransformerFunc adapts a function to a Transformer.
+type TransformerFunc func(ctx context.Context, ip string, instance *ec2.Instance) error
+
+// Transform satisfies Transformer.
+func (fn TransformerFunc) Transform(ctx context.Context, ip string, instance *ec2.Instance) error {
+	return fn(ctx, ip, instance)
+}
+
+// RedactUserdata is a Transformer that removes userdata from instances. It's useful when userdata
+// contains secrets that are delivered to instances by other means.
+var RedactUserdata = TransformerFunc(func(_ context.Context, _ string, instance *ec2.Instance) error {
+	instance.Userdata = ""
+	return nil
+})
+
+// Backend decorates a backend.Client running transformers over every instance it returns. All
+// other calls are passed through to the decorated client.
+type Backend struct {
+	backend.Client
+
+	transformers []Transformer
+}
+
+// New creates a new Backend that decorates client. transformers run in order, each receiving the
+// instance as modified by the previous one. The first error stops the chain.
+func New(client backend.Client, transformers ...Transformer) *Backend {
+	return &Backend{Client: client, transformers: transformers}
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	return b.apply(ctx, ip, instance)
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByMAC(ctx, mac)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	return b.apply(ctx, "", instance)
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	instance, err := b.Client.GetEC2InstanceByID(ctx, id)
+	if err != nil {
+		return ec2.Instance{}, err
+	}
+
+	return b.apply(ctx, "", instance)
+}
+
+// Subscribe satisfies watch.Client. The subscription is closed if an instance can't be
+// transformed so subscribers never receive an untransformed instance.
+func (b *Backend) Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error) {
+	updates, err := b.Client.Subscribe(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	transformed := make(chan ec2.Instance)
+	go func() {
+		defer close(transformed)
+
+		for instance := range updates {
+			instance, err := b.apply(ctx, ip, instance)
+			if err != nil {
+				return
+			}
+
+			select {
+			case transformed <- instance:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return transformed, nil
+}
+
+// apply runs the transformers over instance.
+func (b *Backend) apply(ctx context.Context, ip string, instance ec2.Instance) (ec2.Instance, error) {
+	for _, t := range b.transformers {
+		if err := t.Transform(ctx, ip, &instance); err != nil {
+			return ec2.Instance{}, fmt.Errorf("transform instance: %w", err)
+		}
+	}
+	return instance, nil
+}
@@ -0,0 +1,167 @@
+package transform_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/backend/transform"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestGetEC2InstanceChain(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{
+			Userdata: "#cloud-config",
+			Metadata: ec2.Metadata{InstanceID: "i-1234"},
+		}, nil)
+
+	// The second transformer must observe the first transformer's changes.
+	var observed string
+	tagIP := TransformerFunc(func(_ context.Context, ip string, instance *ec2.Instance) error {
+		instance.Metadata.Tags = append(instance.Metadata.Tags, "ip="+ip)
+		return nil
+	})
+	observe := TransformerFunc(func(_ context.Context, _ string, instance *ec2.Instance) error {
+		observed = instance.Userdata
+		return nil
+	})
+
+	instance, err := New(client, RedactUserdata, tagIP, observe).GetEC2Instance(context.Background(), "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := ec2.Instance{
+		Metadata: ec2.Metadata{InstanceID: "i-1234", Tags: []string{"ip=10.10.10.10"}},
+	}
+	if diff := cmp.Diff(expect, instance); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if observed != "" {
+		t.Fatalf("Expected later transformers to observe redacted userdata; Received: %q", observed)
+	}
+}
+
+func TestGetEC2InstanceTransformerError(t *testing.T) {
+	errTransform := errors.New("transform failed")
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{Userdata: "#cloud-config"}, nil)
+
+	var called bool
+	failing := TransformerFunc(func(context.Context, string, *ec2.Instance) error {
+		return errTransform
+	})
+	next := TransformerFunc(func(context.Context, string, *ec2.Instance) error {
+		called = true
+		return nil
+	})
+
+	instance, err := New(client, failing, next).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, errTransform) {
+		t.Fatalf("Expected: %v; Received: %v", errTransform, err)
+	}
+
+	if instance.Userdata != "" {
+		t.Fatalf("Expected no instance; Received: %+v", instance)
+	}
+
+	if called {
+		t.Fatal("Expected the chain to stop at the first error")
+	}
+}
+
+func TestGetEC2InstanceBackendError(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	_, err := New(client, RedactUserdata).GetEC2Instance(context.Background(), "10.10.10.10")
+	if !errors.Is(err, ec2.ErrInstanceNotFound) {
+		t.Fatalf("Expected: %v; Received: %v", ec2.ErrInstanceNotFound, err)
+	}
+}
+
+func TestGetEC2InstanceByMAC(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+		Return(ec2.Instance{Userdata: "#cloud-config"}, nil)
+
+	instance, err := New(client, RedactUserdata).GetEC2InstanceByMAC(context.Background(), "00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instance.Userdata != "" {
+		t.Fatalf("Expected redacted userdata; Received: %q", instance.Userdata)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	updates := make(chan ec2.Instance, 2)
+	updates <- ec2.Instance{Userdata: "#cloud-config"}
+	updates <- ec2.Instance{Userdata: "#cloud-config\nhostname: changed"}
+	close(updates)
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		Subscribe(gomock.Any(), "10.10.10.10").
+		Return((<-chan ec2.Instance)(updates), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transformed, err := New(client, RedactUserdata).Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received int
+	for instance := range transformed {
+		received++
+		if instance.Userdata != "" {
+			t.Fatalf("Expected redacted userdata; Received: %q", instance.Userdata)
+		}
+	}
+
+	if received != 2 {
+		t.Fatalf("Expected 2 instances; Received: %v", received)
+	}
+}
+
+func TestSubscribeTransformerError(t *testing.T) {
+	updates := make(chan ec2.Instance, 1)
+	updates <- ec2.Instance{}
+
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		Subscribe(gomock.Any(), "10.10.10.10").
+		Return((<-chan ec2.Instance)(updates), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failing := TransformerFunc(func(context.Context, string, *ec2.Instance) error {
+		return errors.New("transform failed")
+	})
+
+	transformed, err := New(client, failing).Subscribe(ctx, "10.10.10.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscription is closed without delivering the untransformed instance.
+	if instance, ok := <-transformed; ok {
+		t.Fatalf("Expected closed subscription; Received: %+v", instance)
+	}
+}
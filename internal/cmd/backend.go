@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/version"
+	"k8s.io/client-go/rest"
+)
+
+// BackendOptions encompasses the backend configurability shared by commands that retrieve
+// instance data.
+type BackendOptions struct {
+	Backend              string  `mapstructure:"backend"`
+	KubernetesAPIServer  string  `mapstructure:"kubernetes-apiserver"`
+	KubernetesKubeconfig string  `mapstructure:"kubernetes-kubeconfig"`
+	KubeconfigData       string  `mapstructure:"kubeconfig-data"`
+	KubernetesNamespace  string  `mapstructure:"kubernetes-namespace"`
+	KubernetesQPS        float32 `mapstructure:"kubernetes-qps"`
+	KubernetesBurst      int     `mapstructure:"kubernetes-burst"`
+	BackendMaxRetries    int     `mapstructure:"backend-max-retries"`
+	OnDuplicate          string  `mapstructure:"on-duplicate"`
+	FlatfilePath         string  `mapstructure:"flatfile-path"`
+	HardwareFile         string  `mapstructure:"hardware-file"`
+	MockIPs              string  `mapstructure:"mock-ips"`
+}
+
+// addBackendFlags adds the flags populating BackendOptions to flags.
+func addBackendFlags(flags *pflag.FlagSet) {
+	flags.String(
+		"backend",
+		"kubernetes",
+		"Backend to use for metadata. Options: file, flatfile, kubernetes (aliases: k8s, kube), mock",
+	)
+
+	// Kubernetes backend specific flags.
+	flags.String("kubernetes-kubeconfig", "", "Path to a kubeconfig file")
+	flags.String("kubernetes-apiserver", "", "URL of the Kubernetes API Server")
+	flags.String(
+		"kubernetes-namespace",
+		"",
+		"A comma separated list of Kubernetes namespaces to target; defaults to all namespaces",
+	)
+	flags.Float32("kubernetes-qps", rest.DefaultQPS, "Maximum sustained queries per second to the Kubernetes API Server")
+	flags.Int("kubernetes-burst", rest.DefaultBurst, "Maximum burst of queries to the Kubernetes API Server above --kubernetes-qps")
+
+	flags.Int(
+		"backend-max-retries",
+		3,
+		"How many times Kubernetes lookups failing with transient errors, such as throttling, are retried with exponential backoff",
+	)
+
+	flags.String(
+		"on-duplicate",
+		string(kubernetes.DuplicateError),
+		"How to resolve lookups matching more than one Hardware. Options: error, first (by namespace and name), newest",
+	)
+
+	// Flatfile backend specific flags.
+	flags.String("flatfile-path", "", "Path to the flatfile metadata")
+
+	// File backend specific flags.
+	flags.String("hardware-file", "", "Path to a JSON or YAML file mapping IPs to Hardware specs")
+
+	// Mock backend specific flags.
+	flags.String(
+		"mock-ips",
+		"",
+		"A comma separated list of IPs the mock backend serves generated instances for; defaults to all IPs",
+	)
+}
+
+// bindBackendEnv binds BackendOptions that are only configurable from the environment to vpr.
+// The kubeconfig data is sensitive so it isn't accepted as a flag where it would be visible in
+// the process list.
+func bindBackendEnv(vpr *viper.Viper) error {
+	return vpr.BindEnv("kubeconfig-data")
+}
+
+// normalize canonicalizes the backend name.
+func (o *BackendOptions) normalize() error {
+	name, err := normalizeBackend(o.Backend)
+	if err != nil {
+		return err
+	}
+	o.Backend = name
+	return nil
+}
+
+func (o BackendOptions) validate() error {
+	if _, err := kubernetes.ParseDuplicatePolicy(o.OnDuplicate); err != nil {
+		return fmt.Errorf("invalid --on-duplicate: %v", err)
+	}
+
+	if o.KubernetesQPS <= 0 || o.KubernetesBurst < 1 {
+		return errors.New("--kubernetes-qps and --kubernetes-burst must be positive")
+	}
+
+	if o.BackendMaxRetries < 0 {
+		return errors.New("--backend-max-retries cannot be negative")
+	}
+
+	return nil
+}
+
+// backendAliases maps accepted --backend values to their canonical backend name.
+var backendAliases = map[string]string{
+	"file":       "file",
+	"flatfile":   "flatfile",
+	"kubernetes": "kubernetes",
+	"k8s":        "kubernetes",
+	"kube":       "kubernetes",
+	"mock":       "mock",
+}
+
+// normalizeBackend returns the canonical backend name for the case insensitive name. If name
+// isn't a known backend or alias, it returns an error listing the valid values.
+func normalizeBackend(name string) (string, error) {
+	canonical, ok := backendAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		valid := make([]string, 0, len(backendAliases))
+		for alias := range backendAliases {
+			valid = append(valid, alias)
+		}
+		slices.Sort(valid)
+
+		return "", fmt.Errorf("invalid --backend %q: valid values are %v", name, strings.Join(valid, ", "))
+	}
+
+	return canonical, nil
+}
+
+func (o BackendOptions) toBackendOptions(logger logr.Logger) backend.Options {
+	var backndOpts backend.Options
+	switch o.Backend {
+	case "flatfile":
+		backndOpts = backend.Options{
+			Flatfile: &backend.Flatfile{
+				Path: o.FlatfilePath,
+			},
+		}
+	case "file":
+		backndOpts = backend.Options{
+			File: &backend.File{
+				Path: o.HardwareFile,
+			},
+		}
+	case "mock":
+		backndOpts = backend.Options{
+			Mock: &backend.Mock{
+				IPs: parseList(o.MockIPs),
+			},
+		}
+	case "kubernetes":
+		backndOpts = backend.Options{
+			Kubernetes: &kubernetes.Config{
+				APIServerAddress: o.KubernetesAPIServer,
+				Kubeconfig:       o.KubernetesKubeconfig,
+				KubeconfigData:   o.KubeconfigData,
+				Namespaces:       parseList(o.KubernetesNamespace),
+				QPS:              o.KubernetesQPS,
+				Burst:            o.KubernetesBurst,
+				UserAgent:        userAgent(),
+				MaxRetries:       o.BackendMaxRetries,
+				// The policy is validated when the options are parsed.
+				OnDuplicate: kubernetes.DuplicatePolicy(o.OnDuplicate),
+				Logger:      logger,
+			},
+		}
+	}
+	return backndOpts
+}
+
+// userAgent identifies Hegel's requests to the Kubernetes API Server.
+func userAgent() string {
+	return fmt.Sprintf("hegel/%v (%v/%v)", version.Get().Version, runtime.GOOS, runtime.GOARCH)
+}
+
+// parseList parses a comma separated list ignoring empty and duplicate entries.
+func parseList(list string) []string {
+	var result []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !slices.Contains(result, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,240 @@
+package cmd_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	. "github.com/tinkerbell/hegel/internal/cmd"
+)
+
+func TestKubernetesBackendFromFlags(t *testing.T) {
+	server := newFakeAPIServer(t)
+
+	root, err := NewRootCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = root.ParseFlags([]string{
+		"--backend", "kubernetes",
+		"--kubernetes-kubeconfig", writeKubeconfig(t, server.URL),
+		"--kubernetes-qps", "7",
+		"--kubernetes-burst", "9",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := root.PreRun(root.Command, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	config := newKubernetesBackend(t, root.Opts.BackendOptions).Config().ClientConfig
+
+	if config.Host != server.URL {
+		t.Fatalf("Expected host: %v; Received: %v", server.URL, config.Host)
+	}
+
+	if config.QPS != 7 || config.Burst != 9 {
+		t.Fatalf("Expected QPS 7 and burst 9; Received: QPS %v burst %v", config.QPS, config.Burst)
+	}
+
+	if !strings.HasPrefix(config.UserAgent, "hegel/") {
+		t.Fatalf("Expected hegel user agent; Received: %v", config.UserAgent)
+	}
+
+	if agent := server.UserAgent(); !strings.HasPrefix(agent, "hegel/") {
+		t.Fatalf("Expected API Server requests with hegel user agent; Received: %v", agent)
+	}
+}
+
+func TestKubernetesBackendFromKubeconfigData(t *testing.T) {
+	server := newFakeAPIServer(t)
+	t.Setenv("HEGEL_KUBECONFIG_DATA", base64.StdEncoding.EncodeToString([]byte(kubeconfig(server.URL))))
+
+	root, err := NewRootCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := root.ParseFlags([]string{"--backend", "kubernetes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := root.PreRun(root.Command, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	config := newKubernetesBackend(t, root.Opts.BackendOptions).Config().ClientConfig
+
+	if config.Host != server.URL {
+		t.Fatalf("Expected host: %v; Received: %v", server.URL, config.Host)
+	}
+
+	if agent := server.UserAgent(); !strings.HasPrefix(agent, "hegel/") {
+		t.Fatalf("Expected API Server requests with hegel user agent; Received: %v", agent)
+	}
+}
+
+func TestKubernetesBackendMaxRetries(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Args     []string
+		Expected int
+	}{
+		{Name: "Default", Expected: 3},
+		{Name: "Disabled", Args: []string{"--backend-max-retries", "0"}, Expected: 0},
+		{Name: "Configured", Args: []string{"--backend-max-retries", "5"}, Expected: 5},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			server := newFakeAPIServer(t)
+
+			root, err := NewRootCommand()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			args := append([]string{"--kubernetes-kubeconfig", writeKubeconfig(t, server.URL)}, tc.Args...)
+			if err := root.ParseFlags(args); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := root.PreRun(root.Command, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			retries := newKubernetesBackend(t, root.Opts.BackendOptions).Config().MaxRetries
+			if retries != tc.Expected {
+				t.Fatalf("Expected max retries: %v; Received: %v", tc.Expected, retries)
+			}
+		})
+	}
+}
+
+// newKubernetesBackend creates the backend configured by opts and waits for it to sync. The
+// backend is stopped when t completes.
+func newKubernetesBackend(t *testing.T, opts BackendOptions) *kubernetes.Backend {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	be, err := backend.New(ctx, ToBackendOptions(opts, logr.Discard()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeclient := be.(*kubernetes.Backend)
+	if err := kubeclient.WaitForSync(ctx, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	return kubeclient
+}
+
+// writeKubeconfig writes a kubeconfig for the API Server at server to a temporary file and
+// returns its path.
+func writeKubeconfig(t *testing.T, server string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(kubeconfig(server)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// kubeconfig returns a kubeconfig for the API Server at server.
+func kubeconfig(server string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %v
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+users:
+- name: fake
+  user: {}
+current-context: fake
+`, server)
+}
+
+// fakeAPIServer serves enough of the Kubernetes API for the backend to sync an empty Hardware
+// cache.
+type fakeAPIServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	userAgent string
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	t.Helper()
+
+	// Watches block until the test completes.
+	stop := make(chan struct{})
+
+	s := &fakeAPIServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.userAgent = r.UserAgent()
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/apis/tinkerbell.org/v1alpha1":
+			fmt.Fprint(w, `{"kind":"APIResourceList","apiVersion":"v1","groupVersion":"tinkerbell.org/v1alpha1",`+
+				`"resources":[{"name":"hardware","singularName":"hardware","namespaced":true,"kind":"Hardware",`+
+				`"verbs":["get","list","watch"]}]}`)
+
+		case r.URL.Path == "/apis/tinkerbell.org/v1alpha1/hardware" && r.URL.Query().Get("watch") == "true":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-stop:
+			}
+
+		case r.URL.Path == "/apis/tinkerbell.org/v1alpha1/hardware":
+			fmt.Fprint(w, `{"kind":"HardwareList","apiVersion":"tinkerbell.org/v1alpha1",`+
+				`"metadata":{"resourceVersion":"1"},"items":[]}`)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	t.Cleanup(func() {
+		close(stop)
+		s.Close()
+	})
+
+	return s
+}
+
+// UserAgent returns the User-Agent of the last request received.
+func (s *fakeAPIServer) UserAgent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.userAgent
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// healthCheckTimeout bounds how long the health check waits for a response.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheckOptions encompasses all the configurability of the HealthCheckCommand.
+type HealthCheckOptions struct {
+	Host               string
+	HTTPPort           int
+	RoutePrefix        string
+	TLS                bool
+	InsecureSkipVerify bool
+}
+
+// HealthCheckCommand checks the health of a running Hegel. It's intended for use as a container
+// exec probe so images needn't bundle an HTTP client such as curl.
+type HealthCheckCommand struct {
+	*cobra.Command
+	Opts HealthCheckOptions
+}
+
+// NewHealthCheckCommand creates a new HealthCheckCommand instance.
+func NewHealthCheckCommand() *HealthCheckCommand {
+	c := &HealthCheckCommand{
+		Command: &cobra.Command{
+			Use:          "healthcheck",
+			Short:        "Check the health of a running Hegel",
+			Long:         "Request /healthz from a running Hegel. Exits 0 when healthy, non-zero otherwise.",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+		},
+	}
+
+	c.RunE = c.Run
+	c.Flags().StringVar(&c.Opts.Host, "host", "localhost", "Host Hegel is listening on")
+	c.Flags().IntVar(
+		&c.Opts.HTTPPort,
+		"http-port",
+		50061,
+		"Port Hegel serves /healthz on. When Hegel is run with --admin-port, the admin port",
+	)
+	c.Flags().StringVar(&c.Opts.RoutePrefix, "route-prefix", "", "The --route-prefix Hegel is run with")
+	c.Flags().BoolVar(&c.Opts.TLS, "tls", false, "Request /healthz over HTTPS, as when Hegel is run with --tls-cert")
+	c.Flags().BoolVar(
+		&c.Opts.InsecureSkipVerify,
+		"insecure-skip-verify",
+		false,
+		"Don't verify Hegel's TLS certificate, such as when it's self-signed or issued for another host",
+	)
+
+	return c
+}
+
+// Run executes the health check. It returns an error if Hegel can't be reached or doesn't respond
+// with a 200.
+func (c *HealthCheckCommand) Run(cmd *cobra.Command, _ []string) error {
+	if c.Opts.InsecureSkipVerify && !c.Opts.TLS {
+		return errors.New("--insecure-skip-verify requires --tls")
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), healthCheckTimeout)
+	defer cancel()
+
+	scheme, client := "http", http.DefaultClient
+	if c.Opts.TLS {
+		scheme = "https"
+		client = &http.Client{
+			Transport: &http.Transport{
+				//nolint:gosec // Skipping verification is opted into for self-signed certificates.
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: c.Opts.InsecureSkipVerify},
+			},
+		}
+	}
+
+	url := fmt.Sprintf(
+		"%v://%v%v/healthz",
+		scheme,
+		net.JoinHostPort(c.Opts.Host, strconv.Itoa(c.Opts.HTTPPort)),
+		normalizeRoutePrefix(c.Opts.RoutePrefix),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unhealthy: %v responded with %v", url, resp.Status)
+	}
+
+	return nil
+}
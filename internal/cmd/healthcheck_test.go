@@ -0,0 +1,155 @@
+package cmd_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/cmd"
+)
+
+func TestHealthCheckCommand(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Status  int
+		Healthy bool
+	}{
+		{
+			Name:    "Healthy",
+			Status:  http.StatusOK,
+			Healthy: true,
+		},
+		{
+			Name:   "Unhealthy",
+			Status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/healthz" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tc.Status)
+			}))
+			defer server.Close()
+
+			host, port := splitServerURL(t, server.URL)
+
+			err := executeHealthCheck(t, "--host", host, "--http-port", port)
+			if tc.Healthy && err != nil {
+				t.Fatalf("Expected healthy; Received: %v", err)
+			}
+			if !tc.Healthy && err == nil {
+				t.Fatal("Expected unhealthy; Received nil error")
+			}
+		})
+	}
+}
+
+func TestHealthCheckCommandUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	host, port := splitServerURL(t, server.URL)
+	server.Close()
+
+	if err := executeHealthCheck(t, "--host", host, "--http-port", port); err == nil {
+		t.Fatal("Expected error for unreachable server; Received nil")
+	}
+}
+
+func TestHealthCheckCommandServerOptions(t *testing.T) {
+	cases := []struct {
+		Name string
+		TLS  bool
+		Path string
+		Args []string
+	}{
+		{
+			Name: "RoutePrefix",
+			Path: "/hegel/healthz",
+			Args: []string{"--route-prefix", "/hegel"},
+		},
+		{
+			Name: "TLS",
+			TLS:  true,
+			Path: "/healthz",
+			Args: []string{"--tls", "--insecure-skip-verify"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tc.Path {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			server := httptest.NewUnstartedServer(handler)
+			if tc.TLS {
+				server.StartTLS()
+			} else {
+				server.Start()
+			}
+			defer server.Close()
+
+			host, port := splitServerURL(t, server.URL)
+
+			// The probe fails unless it's configured as the server is.
+			if err := executeHealthCheck(t, "--host", host, "--http-port", port); err == nil {
+				t.Fatal("Expected unhealthy without options; Received nil error")
+			}
+
+			args := append([]string{"--host", host, "--http-port", port}, tc.Args...)
+			if err := executeHealthCheck(t, args...); err != nil {
+				t.Fatalf("Expected healthy; Received: %v", err)
+			}
+		})
+	}
+}
+
+func TestHealthCheckCommandInsecureSkipVerifyWithoutTLS(t *testing.T) {
+	err := executeHealthCheck(t, "--insecure-skip-verify")
+	if err == nil || !strings.Contains(err.Error(), "--insecure-skip-verify requires --tls") {
+		t.Fatalf("Expected error containing: --insecure-skip-verify requires --tls; Received: %v", err)
+	}
+}
+
+func executeHealthCheck(t *testing.T, args ...string) error {
+	t.Helper()
+
+	root, err := NewRootCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root.SetArgs(append([]string{"healthcheck"}, args...))
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+
+	return root.Execute()
+}
+
+func splitServerURL(t *testing.T, raw string) (host, port string) {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, port, err = net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return host, port
+}
@@ -1,29 +1,70 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/equinix-labs/otel-init-go/otelinit"
 	"github.com/gin-gonic/gin"
-	"github.com/go-logr/zerologr"
+	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/tinkerbell/hegel/internal/backend"
-	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/backend/cache"
+	"github.com/tinkerbell/hegel/internal/backend/coalesce"
+	"github.com/tinkerbell/hegel/internal/backend/fqdn"
+	"github.com/tinkerbell/hegel/internal/backend/hostname"
+	"github.com/tinkerbell/hegel/internal/backend/ipcheck"
+	"github.com/tinkerbell/hegel/internal/backend/overlay"
+	"github.com/tinkerbell/hegel/internal/backend/proxy"
+	"github.com/tinkerbell/hegel/internal/backend/rdns"
+	"github.com/tinkerbell/hegel/internal/backend/rediscache"
+	"github.com/tinkerbell/hegel/internal/backend/static"
+	"github.com/tinkerbell/hegel/internal/backend/transform"
+	"github.com/tinkerbell/hegel/internal/frontend/azure"
 	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/gcp"
 	"github.com/tinkerbell/hegel/internal/frontend/hack"
+	"github.com/tinkerbell/hegel/internal/frontend/hegel"
+	"github.com/tinkerbell/hegel/internal/frontend/ignition"
+	"github.com/tinkerbell/hegel/internal/frontend/instances"
+	"github.com/tinkerbell/hegel/internal/frontend/openstack"
+	"github.com/tinkerbell/hegel/internal/frontend/rpc"
+	"github.com/tinkerbell/hegel/internal/frontend/watch"
+	"github.com/tinkerbell/hegel/internal/frontend/whoami"
 	"github.com/tinkerbell/hegel/internal/healthcheck"
 	hegelhttp "github.com/tinkerbell/hegel/internal/http"
+	"github.com/tinkerbell/hegel/internal/http/auth"
+	"github.com/tinkerbell/hegel/internal/http/compress"
+	"github.com/tinkerbell/hegel/internal/http/concurrency"
+	"github.com/tinkerbell/hegel/internal/http/cors"
+	"github.com/tinkerbell/hegel/internal/http/headers"
+	"github.com/tinkerbell/hegel/internal/http/maxbody"
+	"github.com/tinkerbell/hegel/internal/http/ratelimit"
+	"github.com/tinkerbell/hegel/internal/http/requestid"
+	"github.com/tinkerbell/hegel/internal/http/timeout"
+	"github.com/tinkerbell/hegel/internal/http/tracing"
 	hegellogger "github.com/tinkerbell/hegel/internal/logger"
+	"github.com/tinkerbell/hegel/internal/lookup"
 	"github.com/tinkerbell/hegel/internal/metrics"
+	"github.com/tinkerbell/hegel/internal/pprof"
+	"github.com/tinkerbell/hegel/internal/reload"
+	"github.com/tinkerbell/hegel/internal/version"
 	"github.com/tinkerbell/hegel/internal/xff"
 )
 
@@ -44,14 +85,70 @@ const EnvNamePrefix = "HEGEL"
 
 // RootCommandOptions encompasses all the configurability of the RootCommand.
 type RootCommandOptions struct {
-	TrustedProxies       string `mapstructure:"trusted-proxies"`
-	HTTPAddr             string `mapstructure:"http-addr"`
-	Backend              string `mapstructure:"backend"`
-	KubernetesAPIServer  string `mapstructure:"kubernetes-apiserver"`
-	KubernetesKubeconfig string `mapstructure:"kubernetes-kubeconfig"`
-	KubernetesNamespace  string `mapstructure:"kubernetes-namespace"`
-	FlatfilePath         string `mapstructure:"flatfile-path"`
-	Debug                bool   `mapstructure:"debug"`
+	BackendOptions `mapstructure:",squash"`
+
+	TrustedProxies        string        `mapstructure:"trusted-proxies"`
+	TrustedProxiesFile    string        `mapstructure:"trusted-proxies-file"`
+	TrustRealIP           bool          `mapstructure:"trust-real-ip"`
+	HTTPAddr              string        `mapstructure:"http-addr"`
+	UnixSocket            string        `mapstructure:"unix-socket"`
+	EnableH2C             bool          `mapstructure:"enable-h2c"`
+	ProxyProtocol         bool          `mapstructure:"proxy-protocol"`
+	ReusePort             bool          `mapstructure:"reuse-port"`
+	ListenBacklog         int           `mapstructure:"listen-backlog"`
+	AdminPort             int           `mapstructure:"admin-port"`
+	EnablePprof           bool          `mapstructure:"enable-pprof"`
+	EnableDebugLookup     bool          `mapstructure:"enable-debug-lookup"`
+	GRPCPort              int           `mapstructure:"grpc-port"`
+	TLSCert               string        `mapstructure:"tls-cert"`
+	TLSKey                string        `mapstructure:"tls-key"`
+	RoutePrefix           string        `mapstructure:"route-prefix"`
+	RegionPrefixLength    int           `mapstructure:"region-prefix-length"`
+	EC2Versions           string        `mapstructure:"ec2-versions"`
+	Frontends             string        `mapstructure:"frontends"`
+	EnableJSONMetadata    bool          `mapstructure:"enable-json-metadata"`
+	EmptyAs204            bool          `mapstructure:"empty-as-204"`
+	OverlaysFile          string        `mapstructure:"overlays-file"`
+	StaticMetadataFile    string        `mapstructure:"static-metadata-file"`
+	HostnameTemplate      string        `mapstructure:"default-hostname-template"`
+	ResolveHostnameDNS    bool          `mapstructure:"resolve-hostname-dns"`
+	DomainSuffix          string        `mapstructure:"domain-suffix"`
+	RedactUserdata        bool          `mapstructure:"redact-userdata"`
+	StrictMetadata        bool          `mapstructure:"strict-metadata"`
+	ProxyUpstream         string        `mapstructure:"proxy-upstream"`
+	ProxyTimeout          time.Duration `mapstructure:"proxy-timeout"`
+	CacheTTL              time.Duration `mapstructure:"cache-ttl"`
+	NegativeCacheTTL      time.Duration `mapstructure:"negative-cache-ttl"`
+	RedisURL              string        `mapstructure:"redis-url"`
+	RedisTTL              time.Duration `mapstructure:"redis-ttl"`
+	SlowLookupThreshold   time.Duration `mapstructure:"slow-lookup-threshold"`
+	WaitForBackend        bool          `mapstructure:"wait-for-backend"`
+	WaitForBackendTimeout time.Duration `mapstructure:"wait-for-backend-timeout"`
+	SelfTestIP            string        `mapstructure:"selftest-ip"`
+	SelfTestRequired      bool          `mapstructure:"selftest-required"`
+	ShutdownTimeout       time.Duration `mapstructure:"shutdown-timeout"`
+	RequestTimeout        time.Duration `mapstructure:"request-timeout"`
+	ReadTimeout           time.Duration `mapstructure:"read-timeout"`
+	WriteTimeout          time.Duration `mapstructure:"write-timeout"`
+	IdleTimeout           time.Duration `mapstructure:"idle-timeout"`
+	RateLimit             float64       `mapstructure:"rate-limit"`
+	RateBurst             int           `mapstructure:"rate-burst"`
+	MaxConcurrentPerIP    int           `mapstructure:"max-concurrent-per-ip"`
+	MaxBatchSize          int           `mapstructure:"max-batch-size"`
+	GzipMinSize           int           `mapstructure:"gzip-min-size"`
+	MaxRequestBody        int64         `mapstructure:"max-request-body"`
+	CORSAllowOrigin       string        `mapstructure:"cors-allow-origin"`
+	CORSAllowHeaders      string        `mapstructure:"cors-allow-headers"`
+	CORSMaxAge            time.Duration `mapstructure:"cors-max-age"`
+	ResponseHeaders       []string      `mapstructure:"response-header"`
+	AuthToken             string        `mapstructure:"auth-token"`
+	SelfOnly              bool          `mapstructure:"self-only"`
+	MACFallback           bool          `mapstructure:"mac-fallback"`
+	RequireIMDSToken      bool          `mapstructure:"require-imds-token"`
+	AccessLog             bool          `mapstructure:"access-log"`
+	LogLevel              string        `mapstructure:"log-level"`
+	LogFormat             string        `mapstructure:"log-format"`
+	Debug                 bool          `mapstructure:"debug"`
 
 	// Hidden CLI flags.
 	HegelAPI bool `mapstructure:"hegel-api"`
@@ -70,10 +167,14 @@ func NewRootCommand() (*RootCommand, error) {
 		Command: &cobra.Command{
 			Use:          os.Args[0],
 			Long:         longHelp,
+			Version:      version.Get().String(),
 			SilenceUsage: true,
 		},
 	}
 
+	// Cobra handles --version before running the command so the backend isn't initialized.
+	rootCmd.SetVersionTemplate("{{ .Version }}\n")
+
 	rootCmd.PreRunE = rootCmd.PreRun
 	rootCmd.RunE = rootCmd.Run
 	rootCmd.Flags().SortFlags = false // Print flag help in the order they're specified.
@@ -86,83 +187,1051 @@ func NewRootCommand() (*RootCommand, error) {
 		return nil, err
 	}
 
+	// Cobra registers a --version flag when one isn't defined. Define it so the help is consistent
+	// with the other flags; it's added after configureFlags so it isn't bound to an env var.
+	rootCmd.Flags().Bool("version", false, "Print the version information and exit")
+
+	validateCmd, err := NewValidateCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	rootCmd.AddCommand(NewHealthCheckCommand().Command, validateCmd.Command, NewVersionCommand().Command)
+
 	return rootCmd, nil
 }
 
-// PreRun satisfies cobra.Command.PreRunE and unmarshalls. Its responsible for populating c.Opts.
+// PreRun satisfies cobra.Command.PreRunE and unmarshalls. Its responsible for populating and
+// validating c.Opts.
 func (c *RootCommand) PreRun(*cobra.Command, []string) error {
-	return c.vpr.Unmarshal(&c.Opts)
+	if err := c.vpr.Unmarshal(&c.Opts); err != nil {
+		return err
+	}
+
+	if err := c.Opts.BackendOptions.normalize(); err != nil {
+		return err
+	}
+
+	c.Opts.RoutePrefix = normalizeRoutePrefix(c.Opts.RoutePrefix)
+
+	return c.Opts.validate()
+}
+
+func (o RootCommandOptions) validate() error {
+	if o.HTTPAddr == "" && o.UnixSocket == "" {
+		return errors.New("one of --http-addr or --unix-socket must be specified")
+	}
+
+	if o.HTTPAddr == "" && (o.AdminPort != 0 || o.GRPCPort != 0) {
+		return errors.New("--admin-port and --grpc-port require --http-addr")
+	}
+
+	if (o.TLSCert == "") != (o.TLSKey == "") {
+		return errors.New("--tls-cert and --tls-key must be specified together")
+	}
+
+	if o.CacheTTL < 0 || o.NegativeCacheTTL < 0 {
+		return errors.New("--cache-ttl and --negative-cache-ttl cannot be negative")
+	}
+
+	if o.SlowLookupThreshold < 0 {
+		return errors.New("--slow-lookup-threshold cannot be negative")
+	}
+
+	if o.WaitForBackend && o.WaitForBackendTimeout <= 0 {
+		return errors.New("--wait-for-backend-timeout must be positive when --wait-for-backend is specified")
+	}
+
+	if o.SelfTestIP != "" {
+		if _, err := netip.ParseAddr(o.SelfTestIP); err != nil {
+			return fmt.Errorf("invalid --selftest-ip: %v", err)
+		}
+	}
+
+	if o.SelfTestRequired && o.SelfTestIP == "" {
+		return errors.New("--selftest-required requires --selftest-ip")
+	}
+
+	if o.MACFallback && o.SelfOnly {
+		return errors.New("--mac-fallback cannot be used with --self-only")
+	}
+
+	if o.ShutdownTimeout < 0 {
+		return errors.New("--shutdown-timeout cannot be negative")
+	}
+
+	if o.RequestTimeout < 0 {
+		return errors.New("--request-timeout cannot be negative")
+	}
+
+	if o.ReadTimeout < 0 || o.WriteTimeout < 0 || o.IdleTimeout < 0 {
+		return errors.New("--read-timeout, --write-timeout and --idle-timeout cannot be negative")
+	}
+
+	for _, v := range parseList(o.EC2Versions) {
+		if !ec2.ValidVersion(v) {
+			return fmt.Errorf("invalid --ec2-versions: %q is not latest or a date such as 2021-01-03", v)
+		}
+	}
+
+	for _, name := range parseList(o.Frontends) {
+		if !slices.Contains(optionalFrontendNames(), name) {
+			return fmt.Errorf("invalid --frontends: %q is not one of %v", name, strings.Join(optionalFrontendNames(), ", "))
+		}
+	}
+
+	if o.HostnameTemplate != "" {
+		if _, err := hostname.NewTemplate(o.HostnameTemplate); err != nil {
+			return fmt.Errorf("invalid --default-hostname-template: %v", err)
+		}
+	}
+
+	if o.DomainSuffix != "" {
+		if _, err := fqdn.ParseSuffix(o.DomainSuffix); err != nil {
+			return fmt.Errorf("invalid --domain-suffix: %v", err)
+		}
+	}
+
+	if o.ProxyUpstream != "" {
+		if _, err := proxy.NewUpstream(o.ProxyUpstream, o.ProxyTimeout); err != nil {
+			return fmt.Errorf("invalid --proxy-upstream: %v", err)
+		}
+	}
+
+	if o.ProxyTimeout < 0 {
+		return errors.New("--proxy-timeout cannot be negative")
+	}
+
+	if o.RedisURL != "" {
+		if _, err := rediscache.NewClient(o.RedisURL); err != nil {
+			return fmt.Errorf("invalid --redis-url: %v", err)
+		}
+
+		if o.RedisTTL <= 0 {
+			return errors.New("--redis-ttl must be positive when --redis-url is specified")
+		}
+	}
+
+	if o.GzipMinSize < 0 {
+		return errors.New("--gzip-min-size cannot be negative")
+	}
+
+	if o.MaxRequestBody < 0 {
+		return errors.New("--max-request-body cannot be negative")
+	}
+
+	for _, origin := range parseList(o.CORSAllowOrigin) {
+		if !cors.ValidOrigin(origin) {
+			return fmt.Errorf("invalid --cors-allow-origin: %q is not * or an origin such as https://dashboard.example.com", origin)
+		}
+	}
+
+	if o.CORSMaxAge < 0 {
+		return errors.New("--cors-max-age cannot be negative")
+	}
+
+	if _, err := headers.Parse(o.ResponseHeaders); err != nil {
+		return fmt.Errorf("invalid --response-header: %v", err)
+	}
+
+	if o.RateLimit < 0 {
+		return errors.New("--rate-limit cannot be negative")
+	}
+
+	if o.RateLimit > 0 && o.RateBurst < 1 {
+		return errors.New("--rate-burst must be at least 1 when --rate-limit is specified")
+	}
+
+	if o.MaxConcurrentPerIP < 0 {
+		return errors.New("--max-concurrent-per-ip cannot be negative")
+	}
+
+	if o.ListenBacklog < 0 {
+		return errors.New("--listen-backlog cannot be negative")
+	}
+
+	if o.MaxBatchSize < 0 {
+		return errors.New("--max-batch-size cannot be negative")
+	}
+
+	if err := o.BackendOptions.validate(); err != nil {
+		return err
+	}
+
+	if _, err := hegellogger.New(io.Discard, o.LogLevel, o.LogFormat); err != nil {
+		return fmt.Errorf("invalid logging configuration: %v", err)
+	}
+
+	return nil
 }
 
 // Run executes Hegel.
 func (c *RootCommand) Run(cmd *cobra.Command, _ []string) error {
-	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
-	logger := zerologr.New(&zl)
+	logger, err := hegellogger.New(os.Stdout, c.Opts.LogLevel, c.Opts.LogFormat)
+	if err != nil {
+		return err
+	}
 
 	if !c.Opts.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	logger.Info("Root command options", "opts", fmt.Sprintf("%#v", c.Opts))
+	info := version.Get()
+	logger.Info("Starting Hegel", "version", info.Version, "git_commit", info.GitCommit, "build_date", info.BuildDate)
+
+	logger.Info("Root command options", "opts", fmt.Sprintf("%#v", c.Opts.redacted()))
 
 	ctx, otelShutdown := otelinit.InitOpenTelemetry(cmd.Context(), "hegel")
 	defer otelShutdown(ctx)
 
-	be, err := backend.New(ctx, toBackendOptions(c.Opts))
+	be, err := backend.New(ctx, c.Opts.BackendOptions.toBackendOptions(logger))
 	if err != nil {
 		return errors.Errorf("initialize backend: %v", err)
 	}
 
-	xffmw, err := xff.MiddlewareFromUnparsed(c.Opts.TrustedProxies)
+	// The Hardware matching an IP are listed from the backend itself rather than through the
+	// decorators added below, which don't expose them.
+	hardware, _ := be.(lookup.HardwareLister)
+
+	// The self test waits for the backend itself to sync before looking up through the decorators.
+	source := be
+
+	if c.Opts.WaitForBackend {
+		if err := waitForBackend(ctx, logger, be, c.Opts.WaitForBackendTimeout); err != nil {
+			return errors.Errorf("wait for backend: %v", err)
+		}
+	}
+
+	// Configuration sources that can be reloaded, such as the hardware file, are reloaded on
+	// SIGHUP.
+	var reloaders reload.Group
+	if r, ok := be.(reload.Reloader); ok {
+		reloaders.Add("backend", r)
+	}
+
+	// Instances the backend doesn't have are retrieved from the upstream so overlays apply to
+	// them too.
+	if c.Opts.ProxyUpstream != "" {
+		upstream, err := proxy.NewUpstream(c.Opts.ProxyUpstream, c.Opts.ProxyTimeout)
+		if err != nil {
+			return errors.Errorf("initialize proxy upstream: %v", err)
+		}
+		be = proxy.New(be, upstream)
+	}
+
+	if c.Opts.OverlaysFile != "" {
+		overlays, err := overlay.Load(c.Opts.OverlaysFile)
+		if err != nil {
+			return errors.Errorf("load overlays: %v", err)
+		}
+		be = overlay.New(be, overlays)
+	}
+
+	// Hostnames resolved from DNS take precedence over those derived from the template.
+	if c.Opts.ResolveHostnameDNS {
+		be = rdns.New(be, rdns.Config{})
+	}
+
+	if c.Opts.HostnameTemplate != "" {
+		// The template is validated when the options are parsed.
+		template, _ := hostname.NewTemplate(c.Opts.HostnameTemplate)
+		be = hostname.New(be, template)
+	}
+
+	// Derived hostnames are qualified too so every hostname is served as an FQDN.
+	if c.Opts.DomainSuffix != "" {
+		// The suffix is validated when the options are parsed.
+		domain, _ := fqdn.ParseSuffix(c.Opts.DomainSuffix)
+		be = fqdn.New(be, domain)
+	}
+
+	// Static metadata is the same for every instance so it only fills in what more specific
+	// sources didn't.
+	if c.Opts.StaticMetadataFile != "" {
+		metadata, err := static.Load(c.Opts.StaticMetadataFile)
+		if err != nil {
+			return errors.Errorf("load static metadata: %v", err)
+		}
+		be = static.New(be, metadata)
+	}
+
+	// Transformers run last so they observe instances as the frontends will render them.
+	var transformers []transform.Transformer
+	if c.Opts.RedactUserdata {
+		transformers = append(transformers, transform.RedactUserdata)
+	}
+	if len(transformers) > 0 {
+		be = transform.New(be, transformers...)
+	}
+
+	// Instances without IP addresses are checked after transformation so the check applies to what
+	// clients receive. Lookups are only checked on cache misses.
+	var ipcheckOpts []ipcheck.Option
+	if c.Opts.StrictMetadata {
+		ipcheckOpts = append(ipcheckOpts, ipcheck.WithStrict())
+	}
+	be = ipcheck.New(be, logger, ipcheckOpts...)
+
+	// Instances cached in Redis are shared by every replica. Concurrent lookups are coalesced
+	// before reaching Redis.
+	if c.Opts.RedisURL != "" {
+		// The URL is validated when the options are parsed.
+		rdb, _ := rediscache.NewClient(c.Opts.RedisURL)
+		defer rdb.Close()
+
+		be = rediscache.New(be, rdb, rediscache.Config{TTL: c.Opts.RedisTTL, Logger: logger})
+	}
+
+	// Coalesce concurrent lookups for the same instance so boot storms don't fan out to the
+	// backend. The cache, when enabled, sits in front so hits avoid coalescing altogether. Shared
+	// lookups are bounded as the requests waiting on them are.
+	be = coalesce.New(be, c.Opts.RequestTimeout)
+
+	registry := prometheus.NewRegistry()
+
+	if c.Opts.CacheTTL > 0 || c.Opts.NegativeCacheTTL > 0 {
+		be = cache.New(be, cache.Config{
+			TTL:         c.Opts.CacheTTL,
+			NegativeTTL: c.Opts.NegativeCacheTTL,
+			Observer:    metrics.InstrumentCache(registry),
+		})
+	}
+
+	// The self test isn't a frontend lookup so it's run before lookups are counted.
+	if c.Opts.SelfTestIP != "" {
+		err := selfTest(ctx, logger, source, be, c.Opts.SelfTestIP, c.Opts.WaitForBackendTimeout)
+		switch {
+		case err == nil:
+		case c.Opts.SelfTestRequired:
+			return errors.Errorf("self test: %v", err)
+		default:
+			logger.Error(err, "Self test failed; continuing as --selftest-required isn't set", "ip", c.Opts.SelfTestIP)
+		}
+	}
+
+	// Count lookups as the frontends observe them, including those served from the cache.
+	be = metrics.InstrumentBackend(
+		registry,
+		be,
+		metrics.WithSlowLookupThreshold(c.Opts.SlowLookupThreshold, logger),
+	)
+
+	xffmw, err := xffMiddleware(ctx, logger, c.Opts, &reloaders)
 	if err != nil {
 		return err
 	}
 
-	registry := prometheus.NewRegistry()
+	responseHeaders, err := headers.Parse(c.Opts.ResponseHeaders)
+	if err != nil {
+		return err
+	}
 
 	router := gin.New()
 	router.Use(
+		requestid.Middleware(),
+		headers.Middleware(responseHeaders),
 		metrics.InstrumentRequestCount(registry),
 		metrics.InstrumentRequestDuration(registry),
+		metrics.InstrumentRequestsInFlight(registry),
 		gin.Recovery(),
-		hegellogger.Middleware(logger),
 		xffmw,
 	)
 
-	metrics.Configure(router, registry)
-	healthcheck.Configure(router, be)
+	if c.Opts.AccessLog {
+		router.Use(hegellogger.Middleware(logger))
+	}
+
+	// Respond to unknown paths as the AWS EC2 instance metadata service does because some
+	// clients treat its 404 response specially.
+	router.NoRoute(ec2.NotFound)
+
+	// When an admin port is configured, operational endpoints are served from a dedicated
+	// listener so they aren't reachable on the node facing port.
+	adminRouter := router
+	if c.Opts.AdminPort != 0 {
+		adminRouter = gin.New()
+		adminRouter.Use(requestid.Middleware(), headers.Middleware(responseHeaders), gin.Recovery())
+
+		if c.Opts.AccessLog {
+			adminRouter.Use(hegellogger.Middleware(logger))
+		}
+	}
+
+	configureRoutes(router, adminRouter, be, hardware, registry, c.Opts)
+
+	// Metadata requests continue the traces of callers, such as an upstream Hegel proxying to
+	// this one, and backend lookups are traced as part of them.
+	metadataHandler := tracing.Handler(router)
+
+	// Listen for signals to gracefully shutdown. SIGHUP reloads configuration instead.
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	reloaders.Watch(ctx, logger, syscall.SIGHUP)
+
+	serverOpts := []hegelhttp.Option{
+		hegelhttp.WithShutdownTimeout(c.Opts.ShutdownTimeout),
+		hegelhttp.WithReadTimeout(c.Opts.ReadTimeout),
+		hegelhttp.WithWriteTimeout(c.Opts.WriteTimeout),
+		hegelhttp.WithIdleTimeout(c.Opts.IdleTimeout),
+		hegelhttp.WithReloadGroup(&reloaders),
+	}
+	if c.Opts.EnableH2C {
+		serverOpts = append(serverOpts, hegelhttp.WithH2C())
+	}
+
+	// Only the metadata listener is expected behind an L4 load balancer or to receive bursts of
+	// connections from booting instances.
+	var metadataOpts []hegelhttp.Option
+	if c.Opts.ProxyProtocol {
+		metadataOpts = append(metadataOpts, hegelhttp.WithProxyProtocol())
+	}
+	if c.Opts.ReusePort {
+		metadataOpts = append(metadataOpts, hegelhttp.WithReusePort())
+	}
+	if c.Opts.ListenBacklog > 0 {
+		metadataOpts = append(metadataOpts, hegelhttp.WithBacklog(c.Opts.ListenBacklog))
+	}
+	metadataOpts = append(metadataOpts, serverOpts...)
+
+	serveMetadata := func(ctx context.Context) error {
+		if c.Opts.TLSCert != "" {
+			return hegelhttp.ServeTLS(
+				ctx,
+				logger,
+				c.Opts.HTTPAddr,
+				metadataHandler,
+				c.Opts.TLSCert,
+				c.Opts.TLSKey,
+				metadataOpts...,
+			)
+		}
+		return hegelhttp.Serve(ctx, logger, c.Opts.HTTPAddr, metadataHandler, metadataOpts...)
+	}
+
+	var servers []func(context.Context) error
+
+	if c.Opts.HTTPAddr != "" {
+		servers = append(servers, serveMetadata)
+	}
+
+	if c.Opts.UnixSocket != "" {
+		// Socket peers have no IP with which to identify an instance so they're trusted to
+		// supply one with X-Forwarded-For.
+		servers = append(servers, func(ctx context.Context) error {
+			return hegelhttp.ServeUnix(ctx, logger, c.Opts.UnixSocket, xff.TrustAll(metadataHandler), serverOpts...)
+		})
+	}
+
+	if c.Opts.AdminPort != 0 {
+		adminAddr, err := listenAddress(c.Opts.HTTPAddr, c.Opts.AdminPort)
+		if err != nil {
+			return err
+		}
+
+		servers = append(servers, func(ctx context.Context) error {
+			return hegelhttp.Serve(ctx, logger, adminAddr, adminRouter, serverOpts...)
+		})
+	}
+
+	if c.Opts.GRPCPort != 0 {
+		grpcAddr, err := listenAddress(c.Opts.HTTPAddr, c.Opts.GRPCPort)
+		if err != nil {
+			return err
+		}
+
+		servers = append(servers, func(ctx context.Context) error {
+			return rpc.Serve(ctx, logger, grpcAddr, be, c.Opts.ShutdownTimeout)
+		})
+	}
+
+	return serveAll(ctx, servers...)
+}
+
+// syncer is implemented by backends that synchronize their data before they can serve it, such as
+// the Kubernetes backend.
+type syncer interface {
+	WaitForSync(ctx context.Context, timeout time.Duration) error
+}
+
+// waitForBackend blocks until be has synchronized its data or timeout elapses. Backends that don't
+// synchronize their data are ready once created so it returns immediately.
+func waitForBackend(ctx context.Context, logger logr.Logger, be backend.Client, timeout time.Duration) error {
+	s, ok := be.(syncer)
+	if !ok {
+		return nil
+	}
+
+	logger.Info("Waiting for backend to sync", "timeout", timeout)
+	if err := s.WaitForSync(ctx, timeout); err != nil {
+		return err
+	}
+	logger.Info("Backend synced")
+
+	return nil
+}
+
+// selfTest looks up ip with be, once source has synced, to verify instances are resolved end to
+// end. It logs and returns nil when an instance is found. source is the undecorated backend be
+// retrieves instances from.
+func selfTest(
+	ctx context.Context,
+	logger logr.Logger,
+	source, be backend.Client,
+	ip string,
+	timeout time.Duration,
+) error {
+	if err := waitForBackend(ctx, logger, source, timeout); err != nil {
+		return fmt.Errorf("wait for backend: %w", err)
+	}
+
+	instance, err := be.GetEC2Instance(ctx, ip)
+	if err != nil {
+		return fmt.Errorf("lookup %v: %w", ip, err)
+	}
+
+	logger.Info(
+		"Self test passed",
+		"ip", ip,
+		"instanceID", instance.Metadata.InstanceID,
+		"hostname", instance.Metadata.Hostname,
+	)
+
+	return nil
+}
+
+// xffMiddleware creates the X-Forwarded-For middleware trusting the proxies in opts. When a trusted
+// proxies file is configured, it's reloaded when it changes until ctx is cancelled and is added to
+// reloaders.
+func xffMiddleware(
+	ctx context.Context,
+	logger logr.Logger,
+	opts RootCommandOptions,
+	reloaders *reload.Group,
+) (gin.HandlerFunc, error) {
+	var xffOpts []xff.Option
+	if opts.TrustRealIP {
+		xffOpts = append(xffOpts, xff.WithRealIP())
+	}
+
+	inline, err := xff.Parse(opts.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	if xff.TrustsAll(inline) {
+		logger.Info("Trusting X-Forwarded-For from every peer; any client can impersonate any instance")
+	}
+
+	if opts.TrustedProxiesFile == "" {
+		return xff.Middleware(inline, xffOpts...)
+	}
+
+	mw, err := xff.NewFileMiddleware(ctx, logger, inline, opts.TrustedProxiesFile, xffOpts...)
+	if err != nil {
+		return nil, err
+	}
+	reloaders.Add("trusted proxies file", mw)
+
+	return mw.Handle, nil
+}
+
+// configureRoutes configures router with the metadata endpoints and adminRouter with the
+// operational endpoints. All endpoints are registered under opts.RoutePrefix.
+func configureRoutes(
+	router, adminRouter gin.IRouter,
+	be backend.Client,
+	hardware lookup.HardwareLister,
+	registry *prometheus.Registry,
+	opts RootCommandOptions,
+) {
+	routes := router.Group(opts.RoutePrefix)
+	adminRoutes := adminRouter.Group(opts.RoutePrefix)
+
+	// Let browser based tooling on other origins read metadata. Preflight requests are answered by
+	// the middleware but endpoints aren't registered for OPTIONS so a catch all route is needed
+	// for them to match.
+	if origins := parseList(opts.CORSAllowOrigin); len(origins) > 0 {
+		routes = routes.Group("", cors.Middleware(cors.Config{
+			AllowOrigins: origins,
+			AllowHeaders: parseList(opts.CORSAllowHeaders),
+			MaxAge:       opts.CORSMaxAge,
+		}))
+		routes.OPTIONS("/*path", ec2.NotFound)
+	}
+
+	// Authenticate metadata requests. Browsers don't send credentials with preflight requests so
+	// they're answered above.
+	routes = routes.Group("", auth.Middleware(authenticator(opts)))
+
+	metrics.Configure(adminRoutes, registry)
+	healthcheck.Configure(adminRoutes, be)
+	if opts.EnablePprof {
+		pprof.Configure(adminRoutes)
+	}
+	if opts.EnableDebugLookup {
+		lookup.Configure(adminRoutes, be, hardware)
+	}
+	version.Configure(adminRoutes)
+
+	// The options describe the deployment, such as backend addresses, so they're only served on a
+	// dedicated admin port where instances can't read them.
+	if opts.AdminPort != 0 {
+		adminRoutes.GET("/meta-data/_hegel/config", serveRuntimeConfig(opts))
+	}
+
+	// Limit metadata requests only so probes and scrapes of operational endpoints succeed.
+	if opts.RateLimit > 0 {
+		routes = routes.Group("", ratelimit.Middleware(opts.RateLimit, opts.RateBurst))
+	}
+	if opts.MaxConcurrentPerIP > 0 {
+		routes = routes.Group("", concurrency.Middleware(opts.MaxConcurrentPerIP))
+	}
+
+	// Bound request bodies, such as those sent with session token requests, so clients can't
+	// abuse endpoints that read them.
+	if opts.MaxRequestBody > 0 {
+		routes = routes.Group("", maxbody.Middleware(opts.MaxRequestBody))
+	}
+
+	// Bound requests that retrieve data from the backend. The watch frontend streams for the
+	// lifetime of the client connection so it's excluded.
+	var frontends gin.IRouter = routes
+	if opts.RequestTimeout > 0 {
+		frontends = routes.Group("", timeout.Middleware(opts.RequestTimeout))
+	}
+
+	// Compress large responses, such as userdata, for clients that accept it. The compressor is
+	// inside the timeout so it completes the response before the timeout inspects it.
+	if opts.GzipMinSize > 0 {
+		frontends = frontends.Group("", compress.Middleware(opts.GzipMinSize))
+	}
 
 	// TODO(chrisdoherty4) Handle multiple frontends.
-	fe := ec2.New(be)
-	fe.Configure(router)
+	feOpts := []ec2.Option{ec2.WithRegionPrefixLength(opts.RegionPrefixLength)}
+	if opts.SelfOnly {
+		feOpts = append(feOpts, ec2.WithSelfOnly())
+	}
+	if opts.MACFallback {
+		feOpts = append(feOpts, ec2.WithMACFallback())
+	}
+	if opts.RequireIMDSToken {
+		feOpts = append(feOpts, ec2.WithRequireToken())
+	}
+	if versions := parseList(opts.EC2Versions); len(versions) > 0 {
+		feOpts = append(feOpts, ec2.WithVersions(versions...))
+	}
+	if opts.EnableJSONMetadata {
+		feOpts = append(feOpts, ec2.WithContentNegotiation())
+	}
+	if opts.EmptyAs204 {
+		feOpts = append(feOpts, ec2.WithEmptyAsNoContent())
+	}
+	fe := ec2.New(be, feOpts...)
+	fe.Configure(frontends)
+
+	// The experimental Hegel API replaces the hack frontend as both serve /metadata.
+	if opts.HegelAPI {
+		hegel.Configure(frontends, be)
+	} else {
+		hack.Configure(frontends, be)
+	}
+
+	for _, fe := range optionalFrontends {
+		if slices.Contains(parseList(opts.Frontends), fe.Name) {
+			fe.Configure(frontends, be)
+		}
+	}
+
+	// Instances are served by ID to any requester.
+	if !opts.SelfOnly {
+		instances.Configure(frontends, be)
+		if opts.MaxBatchSize > 0 {
+			instances.ConfigureBatch(frontends, be, opts.MaxBatchSize)
+		}
+	}
 
-	hack.Configure(router, be)
+	watch.Configure(routes, be)
+
+	// Report the IP requests are attributed to so operators can check their trusted proxies.
+	whoami.Configure(routes)
+}
+
+// optionalFrontends are the metadata APIs only served when selected with --frontends. They serve
+// instance data, including userdata, on additional paths so deployments opt in to those they need.
+var optionalFrontends = []struct {
+	Name      string
+	Configure func(gin.IRouter, backend.Client)
+}{
+	{Name: "ignition", Configure: func(r gin.IRouter, be backend.Client) { ignition.Configure(r, be) }},
+	{Name: "azure", Configure: func(r gin.IRouter, be backend.Client) { azure.Configure(r, be) }},
+	{Name: "gcp", Configure: func(r gin.IRouter, be backend.Client) { gcp.Configure(r, be) }},
+	{Name: "openstack", Configure: func(r gin.IRouter, be backend.Client) { openstack.Configure(r, be) }},
+}
+
+// optionalFrontendNames returns the names of the optionalFrontends.
+func optionalFrontendNames() []string {
+	names := make([]string, 0, len(optionalFrontends))
+	for _, fe := range optionalFrontends {
+		names = append(names, fe.Name)
+	}
+	return names
+}
+
+// authenticator returns the auth.Authenticator metadata requests are authenticated with. Other
+// schemes, such as mTLS or HMAC signed requests, are added by implementing auth.Authenticator and
+// selecting it here.
+func authenticator(opts RootCommandOptions) auth.Authenticator {
+	if opts.AuthToken != "" {
+		return auth.NewToken(opts.AuthToken)
+	}
+	return auth.None{}
+}
+
+// listenAddress builds the address for an additional listener, such as the admin listener, using
+// the host from httpAddr and port.
+func listenAddress(httpAddr string, port int) (string, error) {
+	host, _, err := net.SplitHostPort(httpAddr)
+	if err != nil {
+		return "", fmt.Errorf("parse http-addr: %v", err)
+	}
 
-	// Listen for signals to gracefully shutdown.
-	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// serveAll runs each blocking serve func until ctx is cancelled or any of them fail. If one fails,
+// all others are shutdown and the first error is returned.
+func serveAll(ctx context.Context, servers ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return hegelhttp.Serve(ctx, logger, c.Opts.HTTPAddr, router)
+	errs := make(chan error, len(servers))
+	for _, serve := range servers {
+		go func(serve func(context.Context) error) {
+			errs <- serve(ctx)
+		}(serve)
+	}
+
+	var err error
+	for range servers {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+		cancel()
+	}
+
+	return err
 }
 
 func (c *RootCommand) configureFlags() error {
 	c.Flags().String(
 		"trusted-proxies",
 		"",
-		"A commma separated list of allowed peer IPs and/or CIDR blocks to replace with X-Forwarded-For",
+		"A commma separated list of allowed peer IPs and/or CIDR blocks to replace with X-Forwarded-For. Use * or all to trust every peer",
+	)
+
+	c.Flags().String(
+		"trusted-proxies-file",
+		"",
+		"Path to a file of trusted proxy IPs and/or CIDR blocks, one per line, merged with --trusted-proxies. Reloaded on change",
+	)
+
+	c.Flags().Bool(
+		"trust-real-ip",
+		false,
+		"Use X-Real-IP from trusted proxies that don't send X-Forwarded-For. X-Forwarded-For takes precedence",
+	)
+
+	c.Flags().String("http-addr", ":50061", "Port to listen on for HTTP requests. When empty, only --unix-socket is served")
+
+	c.Flags().String(
+		"unix-socket",
+		"",
+		"Path of a Unix socket to serve HTTP requests on. Requests identify the instance with X-Forwarded-For",
+	)
+
+	c.Flags().Bool(
+		"enable-h2c",
+		false,
+		"Accept HTTP/2 cleartext (h2c) connections in addition to HTTP/1.1 on listeners not serving TLS",
+	)
+
+	c.Flags().Bool(
+		"proxy-protocol",
+		false,
+		"Read PROXY protocol v1 and v2 headers on --http-addr so clients behind an L4 load balancer are identified by their own IP",
+	)
+
+	c.Flags().Bool(
+		"reuse-port",
+		false,
+		"Listen on --http-addr with SO_REUSEPORT so several Hegel processes can serve the same address. Linux only",
+	)
+
+	c.Flags().Int(
+		"listen-backlog",
+		0,
+		"Length of the queue of connections to --http-addr waiting to be accepted, capped by net.core.somaxconn. When 0, the system maximum is used. Linux only",
+	)
+
+	c.Flags().Int(
+		"admin-port",
+		0,
+		"Port to serve metrics, health and profiling endpoints on. When 0, they're served with HTTP requests",
+	)
+
+	c.Flags().Bool(
+		"enable-pprof",
+		false,
+		"Serve Go runtime profiling endpoints under /debug/pprof with the admin endpoints. Set --admin-port so they aren't served to instances",
+	)
+
+	c.Flags().Bool(
+		"enable-debug-lookup",
+		false,
+		"Serve /debug/lookup?ip=<ip>, reporting the outcome of looking up an IP and its Hardware, with the admin endpoints. Set --admin-port so it isn't served to instances",
+	)
+
+	c.Flags().Int(
+		"grpc-port",
+		0,
+		"Port to serve the gRPC metadata API on. When 0, the gRPC API is disabled",
+	)
+
+	c.Flags().String("tls-cert", "", "Path to a TLS certificate. When specified with --tls-key, HTTPS is served")
+	c.Flags().String("tls-key", "", "Path to a TLS key. When specified with --tls-cert, HTTPS is served")
+
+	addBackendFlags(c.Flags())
+
+	c.Flags().String(
+		"route-prefix",
+		"",
+		"Path prefix to serve all endpoints under, such as /hegel. When empty, endpoints are served from the root",
+	)
+
+	c.Flags().Int(
+		"region-prefix-length",
+		0,
+		"Number of leading availability zone characters used as the placement region. When 0, the region is the availability zone",
+	)
+
+	c.Flags().String(
+		"ec2-versions",
+		"",
+		"Comma separated list of dated EC2 metadata API versions, such as 2021-01-03, to serve in addition to latest and 2009-04-04",
+	)
+
+	c.Flags().String(
+		"frontends",
+		"",
+		fmt.Sprintf(
+			"Comma separated list of additional metadata APIs to serve: %v. When empty, only the EC2 and /metadata APIs are served",
+			strings.Join(optionalFrontendNames(), ", "),
+		),
+	)
+
+	c.Flags().Bool(
+		"enable-json-metadata",
+		false,
+		"Serve EC2 metadata as JSON to clients that prefer it with the Accept header. Other clients are served text",
+	)
+
+	c.Flags().Bool(
+		"empty-as-204",
+		false,
+		"Respond to EC2 metadata that is present but empty with a 204 rather than a 200 with an empty body. Unknown metadata is still a 404",
+	)
+
+	c.Flags().String(
+		"overlays-file",
+		"",
+		"Path to a file of per CIDR defaults for userdata, vendordata, tags and public keys missing from instances",
+	)
+
+	c.Flags().String(
+		"static-metadata-file",
+		"",
+		"Path to a file mapping EC2 metadata paths, such as meta-data/x/phone-home, to values served to instances that don't define them",
+	)
+
+	c.Flags().String(
+		"default-hostname-template",
+		"",
+		"Template used to derive hostnames for instances without one, such as host-{id} or ip-{ip}. When empty, hostnames aren't derived",
+	)
+
+	c.Flags().Bool(
+		"resolve-hostname-dns",
+		false,
+		"Resolve hostnames for instances without one with a reverse DNS lookup of the requesting IP. Lookups are cached and take precedence over --default-hostname-template",
+	)
+
+	c.Flags().String(
+		"domain-suffix",
+		"",
+		"Domain, such as example.com, appended to short hostnames so /meta-data/hostname and /meta-data/local-hostname are served as FQDNs. When empty, hostnames are served as stored",
+	)
+
+	c.Flags().Bool(
+		"redact-userdata",
+		false,
+		"Serve instances without userdata, for example when userdata contains secrets delivered to instances by other means",
+	)
+
+	c.Flags().Bool(
+		"strict-metadata",
+		false,
+		"Fail lookups of instances without IP addresses rather than serving them. Such instances are logged regardless",
+	)
+
+	c.Flags().String(
+		"proxy-upstream",
+		"",
+		"URL of an upstream Hegel to retrieve instances the backend doesn't have from. The upstream must trust X-Forwarded-For from this Hegel",
+	)
+	c.Flags().Duration("proxy-timeout", proxy.DefaultTimeout, "How long requests to --proxy-upstream may take")
+
+	c.Flags().Duration(
+		"shutdown-timeout",
+		hegelhttp.DefaultShutdownTimeout,
+		"How long in-flight requests are given to complete on shutdown before connections are closed",
+	)
+
+	c.Flags().Duration(
+		"request-timeout",
+		0,
+		"How long requests may take to retrieve data from the backend before responding with a 504. When 0, requests aren't bounded",
+	)
+
+	c.Flags().Duration(
+		"read-timeout",
+		hegelhttp.DefaultReadTimeout,
+		"How long clients have to send a request, including its body. When 0, reading isn't bounded",
+	)
+	c.Flags().Duration(
+		"write-timeout",
+		hegelhttp.DefaultWriteTimeout,
+		"How long requests have to be served before the connection is closed. Should exceed --request-timeout. When 0, writing isn't bounded",
+	)
+	c.Flags().Duration(
+		"idle-timeout",
+		hegelhttp.DefaultIdleTimeout,
+		"How long idle keep-alive connections are kept open. When 0, --read-timeout is used",
 	)
 
-	c.Flags().String("http-addr", ":50061", "Port to listen on for HTTP requests")
+	c.Flags().Float64(
+		"rate-limit",
+		0,
+		"Requests per second each client IP may make to metadata endpoints before receiving a 429. When 0, requests aren't limited",
+	)
+	c.Flags().Int("rate-burst", 10, "Requests each client IP may make in a burst above --rate-limit")
+	c.Flags().Int(
+		"max-batch-size",
+		instances.DefaultMaxBatchSize,
+		"Maximum number of IPs in a request to /instances:batch. Larger batches receive a 413. When 0, the endpoint isn't served",
+	)
 
-	c.Flags().String("backend", "kubernetes", "Backend to use for metadata. Options: flatfile, kubernetes")
+	c.Flags().Int(
+		"max-concurrent-per-ip",
+		0,
+		"Requests each client IP may have in flight to metadata endpoints before receiving a 429, including open watches. When 0, requests aren't limited",
+	)
 
-	// Kubernetes backend specific flags.
-	c.Flags().String("kubernetes-kubeconfig", "", "Path to a kubeconfig file")
-	c.Flags().String("kubernetes-apiserver", "", "URL of the Kubernetes API Server")
-	c.Flags().String("kubernetes-namespace", "", "The Kubernetes namespace to target; defaults to the service account")
+	c.Flags().Int(
+		"gzip-min-size",
+		compress.DefaultMinSize,
+		"Minimum size in bytes of metadata responses gzip compressed for clients that accept it. When 0, responses aren't compressed",
+	)
 
-	// Flatfile backend specific flags.
-	c.Flags().String("flatfile-path", "", "Path to the flatfile metadata")
+	c.Flags().Int64(
+		"max-request-body",
+		maxbody.DefaultLimit,
+		"Maximum size in bytes of metadata request bodies. Larger requests receive a 413. When 0, bodies aren't limited",
+	)
+
+	c.Flags().String(
+		"cors-allow-origin",
+		"",
+		"Comma separated list of origins, such as https://dashboard.example.com, permitted to read metadata from browsers. Use * to permit every origin. When empty, CORS is disabled",
+	)
+	c.Flags().String(
+		"cors-allow-headers",
+		"",
+		"Comma separated list of request headers, such as X-Hegel-MAC, browsers may send to origins permitted by --cors-allow-origin",
+	)
+	c.Flags().Duration("cors-max-age", 0, "How long browsers may cache CORS preflight responses. When 0, browsers use their default")
+
+	c.Flags().String(
+		"auth-token",
+		"",
+		"Token metadata requests must carry as a bearer token in the Authorization header. Requests without it receive a 401. When empty, requests aren't authenticated",
+	)
+
+	c.Flags().StringArray(
+		"response-header",
+		nil,
+		"Header, such as 'Cache-Control: no-store', added to every response. Repeat to add several. X-Content-Type-Options: nosniff and X-Frame-Options: DENY are added unless overridden; an empty value, such as 'X-Frame-Options:', removes a header",
+	)
+
+	c.Flags().Duration("cache-ttl", 0, "How long to cache instances found in the backend. When 0, they aren't cached")
+	c.Flags().Duration(
+		"negative-cache-ttl",
+		0,
+		"How long to cache lookups that found no instance in the backend. When 0, they aren't cached",
+	)
+
+	c.Flags().String(
+		"redis-url",
+		"",
+		"URL of a Redis server, such as redis://localhost:6379/0, to cache instances in so they're shared by every replica. When empty, Redis isn't used",
+	)
+	c.Flags().Duration("redis-ttl", rediscache.DefaultTTL, "How long to cache instances in --redis-url")
+
+	c.Flags().Duration(
+		"slow-lookup-threshold",
+		time.Second,
+		"Lookups taking longer are logged and counted as slow. When 0, slow lookups aren't detected",
+	)
+
+	c.Flags().Bool(
+		"wait-for-backend",
+		false,
+		"Wait for the backend to sync before serving, exiting if it doesn't within --wait-for-backend-timeout",
+	)
+	c.Flags().Duration("wait-for-backend-timeout", time.Minute, "How long to wait for the backend to sync")
+
+	c.Flags().String(
+		"selftest-ip",
+		"",
+		"IP of a known instance to look up once the backend has synced, logging whether it resolved, to verify metadata is served end to end",
+	)
+	c.Flags().Bool(
+		"selftest-required",
+		false,
+		"Exit if the --selftest-ip lookup fails instead of logging the failure and serving",
+	)
+
+	c.Flags().Bool(
+		"self-only",
+		false,
+		"Only serve metadata for the requesting IP. Lookups using /instances/:id are disabled",
+	)
+	c.Flags().Bool(
+		"mac-fallback",
+		false,
+		"Retrieve instances using the MAC address in the X-Hegel-MAC header when none is found for the requesting IP. Any client can claim any MAC",
+	)
+
+	c.Flags().Bool(
+		"require-imds-token",
+		false,
+		"Reject EC2 metadata requests without a session token from PUT /latest/api/token, emulating IMDSv2",
+	)
+
+	c.Flags().Bool("access-log", true, "Log method, path, client IP, status code and latency for every request")
+
+	c.Flags().String("log-level", "info", "Minimum level of logs to write. Options: debug, info, warn, error")
+	c.Flags().String("log-format", hegellogger.FormatJSON, "Format to write logs in. Options: json, console")
 
 	c.Flags().Bool("debug", false, "Enable debug logging")
 
@@ -182,27 +1251,73 @@ func (c *RootCommand) configureFlags() error {
 		}
 		err = c.vpr.BindEnv(f.Name)
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	return bindBackendEnv(c.vpr)
 }
 
-func toBackendOptions(opts RootCommandOptions) backend.Options {
-	var backndOpts backend.Options
-	switch opts.Backend {
-	case "flatfile":
-		backndOpts = backend.Options{
-			Flatfile: &backend.Flatfile{
-				Path: opts.FlatfilePath,
-			},
-		}
-	case "kubernetes":
-		backndOpts = backend.Options{
-			Kubernetes: &kubernetes.Config{
-				APIServerAddress: opts.KubernetesAPIServer,
-				Kubeconfig:       opts.KubernetesKubeconfig,
-				Namespace:        opts.KubernetesNamespace,
-			},
-		}
+// redacted returns a copy of o with secrets redacted so it can be logged.
+func (o RootCommandOptions) redacted() RootCommandOptions {
+	if o.KubeconfigData != "" {
+		o.KubeconfigData = "<redacted>"
+	}
+	if o.AuthToken != "" {
+		o.AuthToken = "<redacted>"
+	}
+	o.RedisURL = redactURL(o.RedisURL)
+	o.ProxyUpstream = redactURL(o.ProxyUpstream)
+	return o
+}
+
+// redactURL redacts the password in rawURL's userinfo. Values that aren't URLs are returned as is.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || rawURL == "" {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// RuntimeConfig describes how a running Hegel is configured so operators can verify rollouts.
+type RuntimeConfig struct {
+	// Backend is the normalized name of the backend instances are retrieved from.
+	Backend string `json:"backend"`
+
+	// MetadataAPI is the API served at /metadata: hack or the experimental hegel API.
+	MetadataAPI string `json:"metadataAPI"`
+
+	// Frontends are the additional metadata APIs selected with --frontends.
+	Frontends []string `json:"frontends"`
+
+	// Options are Hegel's options with secrets redacted.
+	Options RootCommandOptions `json:"options"`
+}
+
+// serveRuntimeConfig serves the RuntimeConfig for opts.
+func serveRuntimeConfig(opts RootCommandOptions) gin.HandlerFunc {
+	cfg := RuntimeConfig{
+		Backend:     opts.Backend,
+		MetadataAPI: "hack",
+		Frontends:   parseList(opts.Frontends),
+		Options:     opts.redacted(),
+	}
+	if opts.HegelAPI {
+		cfg.MetadataAPI = "hegel"
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, cfg)
+	}
+}
+
+// normalizeRoutePrefix returns prefix with a leading slash and no trailing slash. An empty or root
+// prefix is normalized to an empty string.
+func normalizeRoutePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
 	}
-	return backndOpts
+	return "/" + prefix
 }
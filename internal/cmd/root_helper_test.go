@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/lookup"
+)
+
+// ConfigureRoutes exposes configureRoutes for testing using a fresh metrics registry. Hardware
+// are listed from be when it implements lookup.HardwareLister.
+func ConfigureRoutes(router, adminRouter gin.IRouter, be backend.Client, opts RootCommandOptions) {
+	hardware, _ := be.(lookup.HardwareLister)
+	configureRoutes(router, adminRouter, be, hardware, prometheus.NewRegistry(), opts)
+}
+
+// SelfTest exposes selfTest for testing.
+var SelfTest = selfTest
+
+// ToBackendOptions exposes BackendOptions.toBackendOptions for testing.
+func ToBackendOptions(o BackendOptions, logger logr.Logger) backend.Options {
+	return o.toBackendOptions(logger)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	hegellogger "github.com/tinkerbell/hegel/internal/logger"
+)
+
+// validateSyncTimeout is how long the ValidateCommand waits for the backend to sync.
+const validateSyncTimeout = time.Minute
+
+// ValidateCommandOptions encompasses all the configurability of the ValidateCommand.
+type ValidateCommandOptions struct {
+	BackendOptions `mapstructure:",squash"`
+
+	IP string `mapstructure:"ip"`
+}
+
+// ValidateCommand resolves an IP using the configured backend and prints the resulting instance
+// without starting the server. It lets operators verify their hardware data before nodes boot.
+type ValidateCommand struct {
+	*cobra.Command
+	vpr  *viper.Viper
+	Opts ValidateCommandOptions
+}
+
+// NewValidateCommand creates a new ValidateCommand instance.
+func NewValidateCommand() (*ValidateCommand, error) {
+	c := &ValidateCommand{
+		Command: &cobra.Command{
+			Use:   "validate",
+			Short: "Print the EC2 instance the backend resolves for an IP",
+			Long: "Resolve --ip using the configured backend and print the resulting EC2 instance as " +
+				"JSON. Backend flags and their environment variables are the same as for the server.",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+		},
+	}
+
+	c.PreRunE = c.PreRun
+	c.RunE = c.Run
+	c.Flags().SortFlags = false
+
+	c.vpr = viper.NewWithOptions(viper.EnvKeyReplacer(strings.NewReplacer("-", "_")))
+	c.vpr.SetEnvPrefix(EnvNamePrefix)
+
+	c.Flags().String("ip", "", "IP address of the instance to resolve")
+	addBackendFlags(c.Flags())
+
+	if err := c.vpr.BindPFlags(c.Flags()); err != nil {
+		return nil, err
+	}
+
+	var err error
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		err = c.vpr.BindEnv(f.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bindBackendEnv(c.vpr); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// PreRun satisfies cobra.Command.PreRunE. Its responsible for populating and validating c.Opts.
+func (c *ValidateCommand) PreRun(*cobra.Command, []string) error {
+	if err := c.vpr.Unmarshal(&c.Opts); err != nil {
+		return err
+	}
+
+	if c.Opts.IP == "" {
+		return errors.New("--ip is required")
+	}
+
+	if net.ParseIP(c.Opts.IP) == nil {
+		return fmt.Errorf("invalid --ip %q", c.Opts.IP)
+	}
+
+	if err := c.Opts.BackendOptions.normalize(); err != nil {
+		return err
+	}
+
+	return c.Opts.BackendOptions.validate()
+}
+
+// Run executes the validation.
+func (c *ValidateCommand) Run(cmd *cobra.Command, _ []string) error {
+	// Backend logs, such as duplicate resolution, are useful context when validating so write
+	// them to stderr keeping stdout for the instance.
+	logger, err := hegellogger.New(cmd.ErrOrStderr(), "info", hegellogger.FormatConsole)
+	if err != nil {
+		return err
+	}
+
+	// Cancelling the context stops any background work started by the backend.
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	be, err := backend.New(ctx, c.Opts.BackendOptions.toBackendOptions(logger))
+	if err != nil {
+		return fmt.Errorf("initialize backend: %v", err)
+	}
+
+	// Backends that sync in the background, such as Kubernetes, can't resolve instances until
+	// they've synced.
+	if err := waitForBackend(ctx, logger, be, validateSyncTimeout); err != nil {
+		return fmt.Errorf("wait for backend: %v", err)
+	}
+
+	instance, err := be.GetEC2Instance(ctx, c.Opts.IP)
+	if err != nil {
+		if errors.Is(err, ec2.ErrInstanceNotFound) {
+			return fmt.Errorf("no instance found for %v", c.Opts.IP)
+		}
+		return fmt.Errorf("resolve %v: %v", c.Opts.IP, err)
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(instance)
+}
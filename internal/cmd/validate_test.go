@@ -0,0 +1,140 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/cmd"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestValidateCommand(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Hardware string
+		IP       string
+		Error    string
+	}{
+		{
+			Name:     "Found",
+			Hardware: "10.10.10.10:\n  metadata:\n    instance:\n      id: instance-id\n      hostname: hostname\n",
+			IP:       "10.10.10.10",
+		},
+		{
+			Name:     "NotFound",
+			Hardware: "10.10.10.10:\n  metadata:\n    instance:\n      id: instance-id\n",
+			IP:       "10.10.10.11",
+			Error:    "no instance found for 10.10.10.11",
+		},
+		{
+			Name: "Duplicate",
+			Hardware: "10.10.10.10:\n  interfaces:\n    - dhcp:\n        mac: \"00:00:00:00:00:01\"\n" +
+				"10.10.10.11:\n  interfaces:\n    - dhcp:\n        mac: \"00:00:00:00:00:01\"\n",
+			IP:    "10.10.10.10",
+			Error: "found on multiple hardware",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hardware.yml")
+			if err := os.WriteFile(path, []byte(tc.Hardware), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, err := executeValidate(t, "--backend", "file", "--hardware-file", path, "--ip", tc.IP)
+
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("Expected error containing: %v; Received: %v", tc.Error, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var instance ec2.Instance
+			if err := json.Unmarshal([]byte(stdout), &instance); err != nil {
+				t.Fatalf("Expected JSON instance; Received: %v", stdout)
+			}
+
+			if instance.Metadata.InstanceID != "instance-id" || instance.Metadata.Hostname != "hostname" {
+				t.Fatalf("Unexpected instance: %+v", instance)
+			}
+		})
+	}
+}
+
+func TestValidateCommandRejectsInvalidOptions(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Args  []string
+		Error string
+	}{
+		{
+			Name:  "MissingIP",
+			Args:  []string{"--backend", "file"},
+			Error: "--ip is required",
+		},
+		{
+			Name:  "InvalidIP",
+			Args:  []string{"--backend", "file", "--ip", "invalid"},
+			Error: `invalid --ip "invalid"`,
+		},
+		{
+			Name:  "UnknownBackend",
+			Args:  []string{"--backend", "bogus", "--ip", "10.10.10.10"},
+			Error: `invalid --backend "bogus"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := executeValidate(t, tc.Args...)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("Expected error containing: %v; Received: %v", tc.Error, err)
+			}
+		})
+	}
+}
+
+func TestValidateCommandKubeconfigData(t *testing.T) {
+	server := newFakeAPIServer(t)
+	t.Setenv("HEGEL_KUBECONFIG_DATA", base64.StdEncoding.EncodeToString([]byte(kubeconfig(server.URL))))
+
+	// The fake API Server has no Hardware so reaching it is evident from the lookup failing to
+	// find an instance rather than failing to load a client config.
+	_, err := executeValidate(t, "--backend", "kubernetes", "--ip", "10.10.10.10")
+	if err == nil || !strings.Contains(err.Error(), "no instance found for 10.10.10.10") {
+		t.Fatalf("Expected error containing: no instance found for 10.10.10.10; Received: %v", err)
+	}
+
+	if agent := server.UserAgent(); !strings.HasPrefix(agent, "hegel/") {
+		t.Fatalf("Expected API Server requests with hegel user agent; Received: %v", agent)
+	}
+}
+
+func executeValidate(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	root, err := NewRootCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	root.SetArgs(append([]string{"validate"}, args...))
+	root.SetOut(&stdout)
+	root.SetErr(io.Discard)
+
+	err = root.Execute()
+	return stdout.String(), err
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tinkerbell/hegel/internal/version"
+)
+
+// VersionCommand prints the version information of the running Hegel build.
+type VersionCommand struct {
+	*cobra.Command
+}
+
+// NewVersionCommand creates a new VersionCommand instance.
+func NewVersionCommand() *VersionCommand {
+	c := &VersionCommand{
+		Command: &cobra.Command{
+			Use:          "version",
+			Short:        "Print the version information",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+		},
+	}
+
+	c.RunE = c.Run
+
+	return c
+}
+
+// Run prints the version information.
+func (c *VersionCommand) Run(cmd *cobra.Command, _ []string) error {
+	_, err := fmt.Fprintln(cmd.OutOrStdout(), version.Get())
+	return err
+}
@@ -0,0 +1,48 @@
+package cmd_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/cmd"
+	"github.com/tinkerbell/hegel/internal/version"
+)
+
+func TestVersion(t *testing.T) {
+	cases := []struct {
+		Name string
+		Args []string
+	}{
+		{Name: "Subcommand", Args: []string{"version"}},
+		{Name: "Flag", Args: []string{"--version"}},
+		{
+			// The backend must not be initialized so invalid backend options don't matter.
+			Name: "FlagWithInvalidOptions",
+			Args: []string{"--version", "--backend", "unknown"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root, err := NewRootCommand()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			root.SetArgs(tc.Args)
+			root.SetOut(&out)
+			root.SetErr(io.Discard)
+
+			if err := root.Execute(); err != nil {
+				t.Fatalf("Expected nil error; Received: %v", err)
+			}
+
+			expect := version.Get().String() + "\n"
+			if out.String() != expect {
+				t.Fatalf("Expected: %q; Received: %q", expect, out.String())
+			}
+		})
+	}
+}
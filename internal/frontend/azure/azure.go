@@ -0,0 +1,181 @@
+/*
+Package azure contains a frontend that serves instance data in the Azure Instance Metadata Service
+(IMDS) format for workloads that expect to run on Azure.
+
+	https://learn.microsoft.com/en-us/azure/virtual-machines/instance-metadata-service
+
+Only the compute and network sections are supported and only the fields that can be derived from
+an ec2.Instance are populated.
+*/
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// MetadataHeader is the request header Azure IMDS requires to be "true" on every request. It
+// protects against server side request forgery as it can't be set by a browser redirect.
+const MetadataHeader = "Metadata"
+
+// OSType is the operating system type reported for all instances.
+const OSType = "Linux"
+
+// Client is a backend for retrieving instance data.
+type Client interface {
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+}
+
+// Configure configures router with a `/metadata/instance` endpoint using client to retrieve
+// instance data. The api-version query parameter is accepted but ignored.
+func Configure(router gin.IRouter, client Client) {
+	router.GET("/metadata/instance", func(ctx *gin.Context) {
+		if !strings.EqualFold(ctx.GetHeader(MetadataHeader), "true") {
+			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("required metadata header not specified"))
+			return
+		}
+
+		ip, err := request.RemoteAddrIP(ctx.Request)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote address"))
+			return
+		}
+
+		instance, err := client.GetEC2Instance(ctx.Request.Context(), ip)
+		if err != nil {
+			clienterror.Abort(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, ToInstance(instance))
+	})
+}
+
+// Instance is the Azure IMDS instance document.
+type Instance struct {
+	Compute Compute `json:"compute"`
+	Network Network `json:"network"`
+}
+
+// Compute is part of Instance.
+type Compute struct {
+	Name       string      `json:"name"`
+	Location   string      `json:"location"`
+	VMID       string      `json:"vmId"`
+	VMSize     string      `json:"vmSize"`
+	OSType     string      `json:"osType"`
+	Tags       string      `json:"tags"`
+	PublicKeys []PublicKey `json:"publicKeys"`
+	UserData   string      `json:"userData"`
+}
+
+// PublicKey is part of Compute.
+type PublicKey struct {
+	KeyData string `json:"keyData"`
+}
+
+// Network is part of Instance.
+type Network struct {
+	Interface []Interface `json:"interface"`
+}
+
+// Interface is part of Network. MACAddress is formatted as upper case hex without separators.
+type Interface struct {
+	IPv4       IPv4   `json:"ipv4"`
+	IPv6       IPv6   `json:"ipv6"`
+	MACAddress string `json:"macAddress"`
+}
+
+// IPv4 is part of Interface.
+type IPv4 struct {
+	IPAddress []IPv4Address `json:"ipAddress"`
+	Subnet    []Subnet      `json:"subnet"`
+}
+
+// IPv4Address is part of IPv4.
+type IPv4Address struct {
+	PrivateIPAddress string `json:"privateIpAddress"`
+	PublicIPAddress  string `json:"publicIpAddress"`
+}
+
+// Subnet is part of IPv4.
+type Subnet struct {
+	Address string `json:"address"`
+	Prefix  string `json:"prefix"`
+}
+
+// IPv6 is part of Interface.
+type IPv6 struct {
+	IPAddress []IPv6Address `json:"ipAddress"`
+}
+
+// IPv6Address is part of IPv6.
+type IPv6Address struct {
+	PrivateIPAddress string `json:"privateIpAddress"`
+}
+
+// ToInstance converts instance to an Azure IMDS instance document. The location is the placement
+// region, or the availability zone if there's no region. The instance public IPv4 is associated
+// with the first network interface.
+func ToInstance(instance ec2.Instance) Instance {
+	compute := Compute{
+		Name:       instance.Metadata.Hostname,
+		Location:   instance.Metadata.Placement.Region,
+		VMID:       instance.Metadata.InstanceID,
+		VMSize:     instance.Metadata.InstanceType,
+		OSType:     OSType,
+		Tags:       strings.Join(instance.Metadata.Tags, ";"),
+		PublicKeys: []PublicKey{},
+		UserData:   base64.StdEncoding.EncodeToString([]byte(instance.Userdata)),
+	}
+
+	if compute.Location == "" {
+		compute.Location = instance.Metadata.Placement.AvailabilityZone
+	}
+
+	for _, key := range instance.Metadata.PublicKeys {
+		compute.PublicKeys = append(compute.PublicKeys, PublicKey{KeyData: key})
+	}
+
+	network := Network{Interface: []Interface{}}
+	for idx, iface := range instance.Metadata.Network.Interfaces {
+		azIface := Interface{
+			IPv4: IPv4{
+				IPAddress: []IPv4Address{},
+				Subnet:    []Subnet{},
+			},
+			IPv6:       IPv6{IPAddress: []IPv6Address{}},
+			MACAddress: strings.ToUpper(strings.ReplaceAll(iface.MAC, ":", "")),
+		}
+
+		for _, ip := range iface.LocalIPv4s {
+			address := IPv4Address{PrivateIPAddress: ip}
+			if idx == 0 && len(azIface.IPv4.IPAddress) == 0 {
+				address.PublicIPAddress = instance.Metadata.PublicIPv4
+			}
+			azIface.IPv4.IPAddress = append(azIface.IPv4.IPAddress, address)
+		}
+
+		if _, subnet, err := net.ParseCIDR(iface.SubnetIPv4CIDRBlock); err == nil {
+			prefix, _ := subnet.Mask.Size()
+			azIface.IPv4.Subnet = append(azIface.IPv4.Subnet, Subnet{
+				Address: subnet.IP.String(),
+				Prefix:  strconv.Itoa(prefix),
+			})
+		}
+
+		network.Interface = append(network.Interface, azIface)
+	}
+
+	return Instance{Compute: compute, Network: network}
+}
@@ -0,0 +1,183 @@
+package azure_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/frontend/azure"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestInstance(t *testing.T) {
+	instance := ec2.Instance{
+		Userdata: "#cloud-config",
+		Metadata: ec2.Metadata{
+			InstanceID:   "instance-id",
+			InstanceType: "c3.small.x86",
+			Hostname:     "hostname",
+			Tags:         []string{"foo", "bar"},
+			PublicKeys:   []string{"ssh-rsa key"},
+			PublicIPv4:   "203.0.113.10",
+			Placement:    ec2.Placement{AvailabilityZone: "sv15", Region: "sv"},
+			Network: ec2.Network{
+				Interfaces: []ec2.NetworkInterface{
+					{
+						MAC:                 "00:00:00:00:00:0a",
+						LocalIPv4s:          []string{"10.10.10.10"},
+						SubnetIPv4CIDRBlock: "10.10.10.0/24",
+					},
+					{
+						MAC:        "00:00:00:00:00:0b",
+						LocalIPv4s: []string{"172.16.5.10"},
+					},
+				},
+			},
+		},
+	}
+
+	expect := Instance{
+		Compute: Compute{
+			Name:       "hostname",
+			Location:   "sv",
+			VMID:       "instance-id",
+			VMSize:     "c3.small.x86",
+			OSType:     OSType,
+			Tags:       "foo;bar",
+			PublicKeys: []PublicKey{{KeyData: "ssh-rsa key"}},
+			UserData:   base64.StdEncoding.EncodeToString([]byte("#cloud-config")),
+		},
+		Network: Network{
+			Interface: []Interface{
+				{
+					IPv4: IPv4{
+						IPAddress: []IPv4Address{
+							{PrivateIPAddress: "10.10.10.10", PublicIPAddress: "203.0.113.10"},
+						},
+						Subnet: []Subnet{{Address: "10.10.10.0", Prefix: "24"}},
+					},
+					IPv6:       IPv6{IPAddress: []IPv6Address{}},
+					MACAddress: "00000000000A",
+				},
+				{
+					IPv4: IPv4{
+						IPAddress: []IPv4Address{{PrivateIPAddress: "172.16.5.10"}},
+						Subnet:    []Subnet{},
+					},
+					IPv6:       IPv6{IPAddress: []IPv6Address{}},
+					MACAddress: "00000000000B",
+				},
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(instance, nil)
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/metadata/instance?api-version=2021-02-01", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+	r.Header.Set(MetadataHeader, "true")
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	var received Instance
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestInstanceLocationFromAvailabilityZone(t *testing.T) {
+	received := ToInstance(ec2.Instance{
+		Metadata: ec2.Metadata{
+			Placement: ec2.Placement{AvailabilityZone: "sv15"},
+		},
+	})
+
+	if received.Compute.Location != "sv15" {
+		t.Fatalf("Expected location: sv15; Received: %v", received.Compute.Location)
+	}
+
+	if len(received.Network.Interface) != 0 {
+		t.Fatalf("Expected no interfaces; Received: %+v", received.Network.Interface)
+	}
+}
+
+func TestInstanceRequiresMetadataHeader(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Value string
+	}{
+		{Name: "Missing"},
+		{Name: "False", Value: "false"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// The backend shouldn't be queried for requests without the header.
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+
+			router := gin.New()
+			Configure(router, client)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/metadata/instance?api-version=2021-02-01", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			if tc.Value != "" {
+				r.Header.Set(MetadataHeader, tc.Value)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status: 400; Received: %v", w.Code)
+			}
+		})
+	}
+}
+
+func TestInstanceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/metadata/instance", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+	r.Header.Set(MetadataHeader, "true")
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status: 404; Received: %v", w.Code)
+	}
+}
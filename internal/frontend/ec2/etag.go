@@ -0,0 +1,33 @@
+package ec2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// etag computes a strong entity tag for data. Identical data always produces the same tag.
+func etag(data string) string {
+	// Hash data in chunks rather than converting it to a []byte, which copies it. Data such as
+	// userdata can be several megabytes so a copy per request would scale memory with concurrency.
+	h := sha256.New()
+	var chunk [32 * 1024]byte
+	for len(data) > 0 {
+		n := copy(chunk[:], data)
+		h.Write(chunk[:n])
+		data = data[n:]
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value ifNoneMatch matches tag. Tags are
+// compared using weak comparison as required for If-None-Match by RFC 9110.
+func etagMatches(ifNoneMatch, tag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
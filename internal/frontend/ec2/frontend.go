@@ -3,8 +3,13 @@ package ec2
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tinkerbell/hegel/internal/frontend/ec2/internal/staticroute"
@@ -16,51 +21,239 @@ import (
 // ErrInstanceNotFound indicates an instance could not be found for the given identifier.
 var ErrInstanceNotFound = errors.New("instance not found")
 
+// NotFoundBody is the body the AWS EC2 instance metadata service responds with when the requested
+// data doesn't exist. Some clients, such as cloud-init, inspect it.
+const NotFoundBody = `<?xml version="1.0" encoding="iso-8859-1"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN"
+	"http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head>
+  <title>404 - Not Found</title>
+ </head>
+ <body>
+  <h1>404 - Not Found</h1>
+ </body>
+</html>
+`
+
+// VersionLatest is the API version that clients not targeting a specific version request.
+const VersionLatest = "latest"
+
+// DefaultVersions are the API versions always served. Clients such as cloud-init request a
+// specific dated version.
+var DefaultVersions = []string{VersionLatest, "2009-04-04"}
+
+// ValidVersion reports whether version is an API version the Frontend can serve: VersionLatest or
+// a date such as 2021-01-03.
+func ValidVersion(version string) bool {
+	if version == VersionLatest {
+		return true
+	}
+
+	_, err := time.Parse(time.DateOnly, version)
+	return err == nil
+}
+
+// MACHeader is the request header used to supply the requesting machine's MAC address. It is
+// consulted only when no instance is found for the requesting IP and the Frontend is configured
+// using WithMACFallback.
+const MACHeader = "X-Hegel-MAC"
+
 // Client is a backend for retrieving EC2 Instance data.
 type Client interface {
 	// GetEC2Instance retrieves an Instance associated with ip. If no Instance can be
 	// found, it should return ErrInstanceNotFound.
 	GetEC2Instance(_ context.Context, ip string) (Instance, error)
+
+	// GetEC2InstanceByMAC retrieves an Instance associated with mac. mac is formatted as a lower
+	// case colon separated address. If no Instance can be found, it should return
+	// ErrInstanceNotFound.
+	GetEC2InstanceByMAC(_ context.Context, mac string) (Instance, error)
 }
 
 // Frontend is an EC2 HTTP API frontend. It is responsible for configuring routers with handlers
 // for the AWS EC2 instance metadata API.
 type Frontend struct {
-	client Client
+	client             Client
+	regionPrefixLength int
+	selfOnly           bool
+	macFallback        bool
+	requireToken       bool
+	versions           []string
+	negotiate          bool
+	emptyAsNoContent   bool
+	tokens             tokenIssuer
+}
+
+// Option configures a Frontend.
+type Option func(*Frontend)
+
+// WithRegionPrefixLength configures the Frontend to derive an instance's placement region from
+// the first n characters of its availability zone when the backend doesn't supply a region. When
+// n is 0 or exceeds the availability zone length, the region is the availability zone.
+func WithRegionPrefixLength(n int) Option {
+	return func(f *Frontend) {
+		f.regionPrefixLength = n
+	}
+}
+
+// WithSelfOnly configures the Frontend to only serve the instance associated with the requesting
+// IP. Hints identifying other instances, such as the MACHeader, are ignored so a client can't
+// retrieve another instance's data.
+func WithSelfOnly() Option {
+	return func(f *Frontend) {
+		f.selfOnly = true
+	}
+}
+
+// WithMACFallback configures the Frontend to retrieve instances using the MAC address in the
+// MACHeader when no instance is found for the requesting IP. Any client can claim any MAC so it
+// should only be used where clients are trusted. It has no effect when the Frontend is self only.
+func WithMACFallback() Option {
+	return func(f *Frontend) {
+		f.macFallback = true
+	}
+}
+
+// WithRequireToken configures the Frontend to reject metadata requests that don't supply a valid
+// session token in the TokenHeader, emulating IMDSv2. When not required, requests may optionally
+// supply a token but invalid tokens are still rejected.
+func WithRequireToken() Option {
+	return func(f *Frontend) {
+		f.requireToken = true
+	}
+}
+
+// WithVersions configures the Frontend to serve the API under versions in addition to the
+// DefaultVersions. Versions should be valid according to ValidVersion.
+func WithVersions(versions ...string) Option {
+	return func(f *Frontend) {
+		f.versions = append(f.versions, versions...)
+	}
+}
+
+// WithContentNegotiation configures the Frontend to serve metadata as JSON to clients preferring
+// it with the Accept header. Leaves are served as a JSON string and directory listings as a JSON
+// array. Other clients, such as cloud-init, are served text as standard.
+func WithContentNegotiation() Option {
+	return func(f *Frontend) {
+		f.negotiate = true
+	}
+}
+
+// WithEmptyAsNoContent configures the Frontend to respond to requests for metadata that is
+// present but empty, such as an instance without a hostname, with a 204 No Content rather than a
+// 200 OK with an empty body. Unknown metadata is still responded to with a 404 Not Found.
+func WithEmptyAsNoContent() Option {
+	return func(f *Frontend) {
+		f.emptyAsNoContent = true
+	}
 }
 
 // New creates a new Frontend.
-func New(client Client) Frontend {
-	return Frontend{
+func New(client Client, opts ...Option) Frontend {
+	f := Frontend{
 		client: client,
+		tokens: newTokenIssuer(),
+	}
+
+	for _, opt := range opts {
+		opt(&f)
 	}
+
+	return f
 }
 
-// Configure configures router with the supported AWS EC2 instance metadata API endpoints.
+// Configure configures router with the supported AWS EC2 instance metadata API endpoints under
+// each of the DefaultVersions and versions configured with WithVersions.
 //
 // TODO(chrisdoherty4) Document unimplemented endpoints.
 func (f Frontend) Configure(router gin.IRouter) {
-	// Setup the 2009-04-04 API path prefix and use a trailing slash route helper to patch
-	// equivalent trailing slash routes.
-	v20090404 := ginutil.TrailingSlashRouteHelper{IRouter: router.Group("/2009-04-04")}
+	// Session tokens are issued from the same path as AWS so IMDSv2 clients find it.
+	router.PUT("/latest/api/token", f.issueToken)
+
+	// Every version serves the same data so requests for a key resolve identically regardless of
+	// the version requested. Unknown versions aren't routed so respond with a 404.
+	seen := map[string]bool{}
+	for _, version := range slices.Concat(DefaultVersions, f.versions) {
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+
+		f.configureVersion(router.Group("/"+version, f.validateToken))
+	}
+}
 
-	dataEndpointBinder := func(router gin.IRouter, endpoint string, filter filterFunc) {
+// configureVersion configures router, grouped under an API version prefix, with the endpoints.
+func (f Frontend) configureVersion(router gin.IRouter) {
+	// Use a trailing slash route helper to patch equivalent trailing slash routes.
+	versioned := ginutil.TrailingSlashRouteHelper{IRouter: router}
+
+	dataEndpointBinder := func(
+		router gin.IRouter,
+		endpoint string,
+		filter filterFunc,
+		exists existsFunc,
+		withETag bool,
+		isJSON bool,
+	) {
 		router.GET(endpoint, func(ctx *gin.Context) {
-			instance, err := f.getInstance(ctx, ctx.Request)
+			instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
 			if err != nil {
-				// If there's an error containing an http status code, use that status code else
-				// assume its an internal server error.
-				var httpErr *httperror.E
-				if errors.As(err, &httpErr) {
-					_ = ctx.AbortWithError(httpErr.StatusCode, err)
-				} else {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+				abortWithError(ctx, err)
+				return
+			}
+
+			if exists != nil && !exists(instance) {
+				abortNotFound(ctx, errors.New("metadata not found"))
+				return
+			}
+
+			data := filter(instance)
+
+			if isJSON {
+				ctx.Data(http.StatusOK, "application/json; charset=utf-8", []byte(data))
+				return
+			}
+
+			format := f.format(ctx)
+			dir := strings.HasSuffix(endpoint, "/")
+
+			if withETag {
+				// Each format is a distinct representation so needs a distinct tag.
+				tag := etag(data)
+				if format == gin.MIMEJSON {
+					tag = strings.TrimSuffix(tag, `"`) + `-json"`
+				}
+
+				ctx.Header("ETag", tag)
+				if etagMatches(ctx.GetHeader("If-None-Match"), tag) {
+					ctx.Status(http.StatusNotModified)
+					return
 				}
+			}
+
+			f.write(ctx, format, data, dir)
+		})
+	}
 
+	// Public key endpoints are parameterized by the key index so can't be modeled as data routes.
+	publicKeyEndpointBinder := func(router gin.IRouter, endpoint string, dir bool, filter func(key string) string) {
+		router.GET(endpoint, func(ctx *gin.Context) {
+			instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+			if err != nil {
+				abortWithError(ctx, err)
 				return
 			}
 
-			ctx.String(http.StatusOK, filter(instance))
+			index, err := strconv.Atoi(ctx.Param("index"))
+			if err != nil || index < 0 || index >= len(instance.Metadata.PublicKeys) {
+				abortNotFound(ctx, errors.New("public key not found"))
+				return
+			}
+
+			f.respond(ctx, filter(instance.Metadata.PublicKeys[index]), dir)
 		})
 	}
 
@@ -71,24 +264,226 @@ func (f Frontend) Configure(router gin.IRouter) {
 	// Configure all dynamic routes. Dynamic routes are anything that requires retrieving a specific
 	// instance and returning data from it.
 	for _, r := range dataRoutes {
-		dataEndpointBinder(v20090404, r.Endpoint, r.Filter)
+		dataEndpointBinder(versioned, r.Endpoint, r.Filter, r.Exists, r.ETag, r.JSON)
 		staticRoutes.FromEndpoint(r.Endpoint)
 	}
 
+	publicKeyEndpointBinder(versioned, "/meta-data/public-keys/:index", true, func(string) string {
+		return "openssh-key"
+	})
+	publicKeyEndpointBinder(versioned, "/meta-data/public-keys/:index/openssh-key", false, func(key string) string {
+		return key
+	})
+
+	// The JSON document is a file rather than a directory so it shouldn't have a trailing slash
+	// alternate.
+	versioned.IRouter.GET("/meta-data.json", func(ctx *gin.Context) {
+		instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		instance.Metadata.Lifecycle = lifecycle(instance)
+		ctx.JSON(http.StatusOK, instance)
+	})
+
+	// Block device mapping endpoints are parameterized by the mapping name so can't be modeled as
+	// data routes.
+	versioned.GET("/meta-data/block-device-mapping/:name", func(ctx *gin.Context) {
+		instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		device, ok := blockDevice(instance, ctx.Param("name"))
+		if !ok {
+			abortNotFound(ctx, errors.New("block device mapping not found"))
+			return
+		}
+
+		f.respond(ctx, device, false)
+	})
+
+	// Instance tag endpoints are parameterized by the tag key so can't be modeled as data routes.
+	// The listing is registered alongside them because modeling it as a data route would make the
+	// tags data route a directory.
+	versioned.GET("/meta-data/tags/instance", func(ctx *gin.Context) {
+		instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		keys, _ := instanceTags(instance)
+		f.respond(ctx, join(keys), true)
+	})
+
+	versioned.GET("/meta-data/tags/instance/:key", func(ctx *gin.Context) {
+		instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		_, values := instanceTags(instance)
+		value, ok := values[ctx.Param("key")]
+		if !ok {
+			abortNotFound(ctx, errors.New("instance tag not found"))
+			return
+		}
+
+		f.respond(ctx, value, false)
+	})
+
+	// Nested custom metadata endpoints are parameterized by the key path so can't be modeled as
+	// data routes. The key path is matched in 2 parts because gin doesn't permit a catch-all
+	// alongside the /meta-data/x/ listing.
+	customMetadataEndpointBinder := func(router gin.IRouter, endpoint string) {
+		router.GET(endpoint, func(ctx *gin.Context) {
+			instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+
+			path := ctx.Param("key") + ctx.Param("path")
+			data, ok := customMetadata(instance, path)
+			if !ok {
+				abortNotFound(ctx, errors.New("custom metadata not found"))
+				return
+			}
+
+			_, key := instance.Metadata.Custom[strings.Trim(path, "/")]
+			f.respond(ctx, data, !key || strings.HasSuffix(path, "/"))
+		})
+	}
+
+	customMetadataEndpointBinder(versioned.IRouter, "/meta-data/x/:key")
+	customMetadataEndpointBinder(versioned.IRouter, "/meta-data/x/:key/*path")
+
+	// Network interface endpoints are parameterized by the interface MAC so can't be modeled as
+	// data routes.
+	networkInterfaceEndpointBinder := func(router gin.IRouter, endpoint string) {
+		router.GET(endpoint, func(ctx *gin.Context) {
+			instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+
+			deviceNumber, iface, ok := networkInterface(instance, ctx.Param("mac"))
+			if !ok {
+				abortNotFound(ctx, errors.New("network interface not found"))
+				return
+			}
+
+			data, ok := networkInterfaceData(deviceNumber, iface, ctx.Param("field"))
+			if !ok {
+				abortNotFound(ctx, errors.New("metadata not found"))
+				return
+			}
+
+			f.respond(ctx, data, ctx.Param("field") == "")
+		})
+	}
+
+	networkInterfaceEndpointBinder(versioned, "/meta-data/network/interfaces/macs/:mac")
+	networkInterfaceEndpointBinder(versioned, "/meta-data/network/interfaces/macs/:mac/:field")
+
 	staticEndpointBinder := func(router gin.IRouter, endpoint string, childEndpoints []string) {
 		router.GET(endpoint, func(ctx *gin.Context) {
-			ctx.String(http.StatusOK, join(childEndpoints))
+			f.respond(ctx, join(childEndpoints), true)
+		})
+	}
+
+	// The meta-data listing is the entry point clients such as cloud-init crawl so it only lists
+	// children populated for the requesting instance. Listing empty children would have clients
+	// follow links that 404.
+	metadataListingBinder := func(router gin.IRouter, endpoint string, childEndpoints []string) {
+		router.GET(endpoint, func(ctx *gin.Context) {
+			instance, err := f.getInstance(ctx.Request.Context(), ctx.Request)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+
+			var children []string
+			for _, child := range childEndpoints {
+				if populated(instance, endpoint+"/"+child) {
+					children = append(children, child)
+				}
+			}
+
+			f.respond(ctx, join(children), true)
 		})
 	}
 
 	for _, r := range staticRoutes.Build() {
-		staticEndpointBinder(v20090404, r.Endpoint, r.Children)
+		if r.Endpoint == "/meta-data" {
+			metadataListingBinder(versioned, r.Endpoint, r.Children)
+			continue
+		}
+
+		staticEndpointBinder(versioned, r.Endpoint, r.Children)
+	}
+}
+
+// issueToken responds with a session token for the requesting IP. The token TTL must be specified
+// in the TokenTTLHeader.
+func (f Frontend) issueToken(ctx *gin.Context) {
+	ttl, err := strconv.Atoi(ctx.GetHeader(TokenTTLHeader))
+	if err != nil || ttl < 1 || time.Duration(ttl)*time.Second > MaxTokenTTL {
+		_ = ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid %v header", TokenTTLHeader))
+		return
+	}
+
+	ip, err := request.RemoteAddrIP(ctx.Request)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote addr"))
+		return
+	}
+
+	ctx.Header(TokenTTLHeader, strconv.Itoa(ttl))
+	ctx.String(http.StatusOK, f.tokens.Issue(ip, time.Duration(ttl)*time.Second))
+}
+
+// validateToken is middleware that aborts requests with an invalid session token, or without a
+// session token when tokens are required, with a 401.
+func (f Frontend) validateToken(ctx *gin.Context) {
+	token := ctx.GetHeader(TokenHeader)
+	if token == "" && !f.requireToken {
+		return
+	}
+
+	ip, err := request.RemoteAddrIP(ctx.Request)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote addr"))
+		return
+	}
+
+	if !f.tokens.Valid(ip, token) {
+		_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("invalid or missing session token"))
 	}
 }
 
 // getInstance is a framework agnostic method for retrieving Instance data based on a remote
-// address.
+// address. If no instance is found for the remote address and the request specifies a MACHeader,
+// the instance is retrieved using the MAC address instead when the MAC fallback is enabled and
+// the Frontend isn't self only.
 func (f Frontend) getInstance(ctx context.Context, r *http.Request) (Instance, error) {
+	instance, err := f.lookupInstance(ctx, r)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	f.derivePlacementRegion(&instance)
+
+	return instance, nil
+}
+
+func (f Frontend) lookupInstance(ctx context.Context, r *http.Request) (Instance, error) {
 	ip, err := request.RemoteAddrIP(r)
 	if err != nil {
 		return Instance{}, httperror.New(http.StatusBadRequest, "invalid remote addr")
@@ -97,12 +492,89 @@ func (f Frontend) getInstance(ctx context.Context, r *http.Request) (Instance, e
 	instance, err := f.client.GetEC2Instance(ctx, ip)
 	if err != nil {
 		if errors.Is(err, ErrInstanceNotFound) {
+			if mac := r.Header.Get(MACHeader); mac != "" && f.macFallback && !f.selfOnly {
+				return f.getInstanceByMAC(ctx, mac)
+			}
+
 			return Instance{}, httperror.New(http.StatusNotFound, "no hardware found for source ip")
 		}
 
 		// TODO(chrisdoherty4) What happens when multiple Instance could be returned? What
 		// is the behavior of GetEC2Instance?
-		return Instance{}, httperror.Wrap(http.StatusInternalServerError, err)
+		return Instance{}, wrapClientError(err)
+	}
+
+	return instance, nil
+}
+
+// derivePlacementRegion populates the placement region of instance from its availability zone
+// if the backend didn't supply one.
+func (f Frontend) derivePlacementRegion(instance *Instance) {
+	placement := &instance.Metadata.Placement
+	if placement.AvailabilityZone == "" || placement.Region != "" {
+		return
+	}
+
+	placement.Region = placement.AvailabilityZone
+	if f.regionPrefixLength > 0 && f.regionPrefixLength < len(placement.AvailabilityZone) {
+		placement.Region = placement.AvailabilityZone[:f.regionPrefixLength]
+	}
+}
+
+// NotFound responds with a 404 and NotFoundBody as the AWS EC2 instance metadata service does. It
+// can be used as a gin.Engine NoRoute handler so unknown paths are indistinguishable from data that
+// doesn't exist.
+func NotFound(ctx *gin.Context) {
+	ctx.Data(http.StatusNotFound, "text/plain; charset=utf-8", []byte(NotFoundBody))
+	ctx.Abort()
+}
+
+// abortNotFound aborts ctx with err and responds using NotFound.
+func abortNotFound(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	NotFound(ctx)
+}
+
+// abortWithError aborts ctx with err. If err contains an HTTP status code it is used, else
+// the status code is assumed to be an internal server error.
+func abortWithError(ctx *gin.Context, err error) {
+	var httpErr *httperror.E
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == http.StatusNotFound {
+			abortNotFound(ctx, err)
+			return
+		}
+
+		_ = ctx.AbortWithError(httpErr.StatusCode, err)
+		return
+	}
+
+	_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+}
+
+// wrapClientError wraps an error returned by the Client with an HTTP status code. Errors caused by
+// the request deadline expiring are a gateway timeout, all others are an internal server error.
+func wrapClientError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return httperror.Wrap(http.StatusGatewayTimeout, err)
+	}
+
+	return httperror.Wrap(http.StatusInternalServerError, err)
+}
+
+func (f Frontend) getInstanceByMAC(ctx context.Context, mac string) (Instance, error) {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return Instance{}, httperror.Newf(http.StatusBadRequest, "invalid %v header", MACHeader)
+	}
+
+	instance, err := f.client.GetEC2InstanceByMAC(ctx, hwAddr.String())
+	if err != nil {
+		if errors.Is(err, ErrInstanceNotFound) {
+			return Instance{}, httperror.New(http.StatusNotFound, "no hardware found for source ip or mac")
+		}
+
+		return Instance{}, wrapClientError(err)
 	}
 
 	return instance, nil
@@ -0,0 +1,10 @@
+package ec2
+
+import "time"
+
+// WithClock configures the Frontend with the clock used to issue and expire session tokens.
+func WithClock(now func() time.Time) Option {
+	return func(f *Frontend) {
+		f.tokens.now = now
+	}
+}
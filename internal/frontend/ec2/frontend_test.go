@@ -1,14 +1,19 @@
 package ec2_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
 	. "github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/xff"
 )
 
 func init() {
@@ -33,6 +38,24 @@ func TestFrontendDynamicEndpoints(t *testing.T) {
 			},
 			Expect: "userdata",
 		},
+		{
+			Name:     "Vendordata",
+			Endpoint: "/2009-04-04/vendor-data",
+			Instance: Instance{
+				Userdata:   "userdata",
+				Vendordata: "vendordata",
+			},
+			Expect: "vendordata",
+		},
+		{
+			// Vendor data is optional so its absence is served as empty rather than a 404.
+			Name:     "VendordataAbsent",
+			Endpoint: "/2009-04-04/vendor-data",
+			Instance: Instance{
+				Userdata: "userdata",
+			},
+			Expect: "",
+		},
 		{
 			Name:     "InstanceID",
 			Endpoint: "/2009-04-04/meta-data/instance-id",
@@ -43,6 +66,32 @@ func TestFrontendDynamicEndpoints(t *testing.T) {
 			},
 			Expect: "instance-id",
 		},
+		{
+			Name:     "InstanceType",
+			Endpoint: "/2009-04-04/meta-data/instance-type",
+			Instance: Instance{
+				Metadata: Metadata{
+					InstanceType: "instance-type",
+				},
+			},
+			Expect: "instance-type",
+		},
+		{
+			Name:     "LifecycleDefault",
+			Endpoint: "/2009-04-04/meta-data/instance-life-cycle",
+			Instance: Instance{},
+			Expect:   "on-demand",
+		},
+		{
+			Name:     "LifecycleSpot",
+			Endpoint: "/2009-04-04/meta-data/instance-life-cycle",
+			Instance: Instance{
+				Metadata: Metadata{
+					Lifecycle: LifecycleSpot,
+				},
+			},
+			Expect: "spot",
+		},
 		{
 			Name:     "Hostname",
 			Endpoint: "/2009-04-04/meta-data/hostname",
@@ -103,16 +152,6 @@ func TestFrontendDynamicEndpoints(t *testing.T) {
 			},
 			Expect: "tag1\ntag2",
 		},
-		{
-			Name:     "PublicKeys",
-			Endpoint: "/2009-04-04/meta-data/public-keys",
-			Instance: Instance{
-				Metadata: Metadata{
-					PublicKeys: []string{"key1", "key2"},
-				},
-			},
-			Expect: "key1\nkey2",
-		},
 		{
 			Name:     "PublicIPv4",
 			Endpoint: "/2009-04-04/meta-data/public-ipv4",
@@ -143,6 +182,48 @@ func TestFrontendDynamicEndpoints(t *testing.T) {
 			},
 			Expect: "local-ipv4",
 		},
+		{
+			Name:     "PublicIPv4s",
+			Endpoint: "/2009-04-04/meta-data/public-ipv4s",
+			Instance: Instance{
+				Metadata: Metadata{
+					PublicIPv4s: []string{"203.0.113.10", "203.0.113.11"},
+					PublicIPv6s: []string{"2001:db8::1"},
+				},
+			},
+			Expect: "203.0.113.10\n203.0.113.11",
+		},
+		{
+			Name:     "PublicIPv6s",
+			Endpoint: "/2009-04-04/meta-data/public-ipv6s",
+			Instance: Instance{
+				Metadata: Metadata{
+					PublicIPv4s: []string{"203.0.113.10", "203.0.113.11"},
+					PublicIPv6s: []string{"2001:db8::1"},
+				},
+			},
+			Expect: "2001:db8::1",
+		},
+		{
+			Name:     "LocalIPv4s",
+			Endpoint: "/2009-04-04/meta-data/local-ipv4s",
+			Instance: Instance{
+				Metadata: Metadata{
+					LocalIPv4s: []string{"10.10.10.11", "10.10.10.12"},
+				},
+			},
+			Expect: "10.10.10.11\n10.10.10.12",
+		},
+		{
+			Name:     "MAC",
+			Endpoint: "/2009-04-04/meta-data/mac",
+			Instance: Instance{
+				Metadata: Metadata{
+					MAC: "00:00:00:00:00:01",
+				},
+			},
+			Expect: "00:00:00:00:00:01",
+		},
 		{
 			Name:     "OperatingSystemSlug",
 			Endpoint: "/2009-04-04/meta-data/operating-system/slug",
@@ -238,23 +319,18 @@ func TestFrontendStaticEndpoints(t *testing.T) {
 			Name:     "Root",
 			Endpoint: "/2009-04-04",
 			Expect: `meta-data/
-user-data`,
+user-data
+vendor-data`,
 		},
 		{
-			Name:     "Metadata",
-			Endpoint: "/2009-04-04/meta-data",
-			Expect: `facility
-hostname
-instance-id
-iqn
-local-hostname
-local-ipv4
-operating-system/
-plan
-public-ipv4
-public-ipv6
-public-keys
-tags`,
+			Name:     "MetadataNetwork",
+			Endpoint: "/2009-04-04/meta-data/network",
+			Expect:   `interfaces/`,
+		},
+		{
+			Name:     "MetadataNetworkInterfaces",
+			Endpoint: "/2009-04-04/meta-data/network/interfaces",
+			Expect:   `macs/`,
 		},
 		{
 			Name:     "MetadataOperatingSystem",
@@ -289,78 +365,1786 @@ version`,
 	}
 }
 
-func validate(t *testing.T, router *gin.Engine, endpoint string, expect string) {
-	t.Helper()
+func TestFrontendMetadataListing(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Instance Instance
+		Expect   string
+	}{
+		{
+			// Instances always have a lifecycle because it defaults to on-demand.
+			Name:     "Minimal",
+			Instance: Instance{},
+			Expect:   `instance-life-cycle`,
+		},
+		{
+			Name: "Partial",
+			Instance: Instance{
+				Metadata: Metadata{
+					InstanceID: "instance-id",
+					Hostname:   "hostname",
+					OperatingSystem: OperatingSystem{
+						LicenseActivation: LicenseActivation{State: "activated"},
+					},
+				},
+			},
+			Expect: `hostname
+instance-id
+instance-life-cycle
+operating-system/`,
+		},
+		{
+			Name: "Full",
+			Instance: Instance{
+				Userdata:   "userdata",
+				Vendordata: "vendordata",
+				Metadata: Metadata{
+					InstanceID:    "instance-id",
+					InstanceType:  "instance-type",
+					Lifecycle:     LifecycleSpot,
+					Hostname:      "hostname",
+					LocalHostname: "local-hostname",
+					IQN:           "iqn",
+					Plan:          "plan",
+					Facility:      "facility",
+					Tags:          []string{"tag"},
+					PublicKeys:    []string{"key"},
+					PublicIPv4:    "10.10.10.10",
+					PublicIPv6:    "2001:db8::1",
+					LocalIPv4:     "10.10.10.11",
+					PublicIPv4s:   []string{"10.10.10.10"},
+					PublicIPv6s:   []string{"2001:db8::1"},
+					LocalIPv4s:    []string{"10.10.10.11"},
+					OperatingSystem: OperatingSystem{
+						Slug: "slug",
+					},
+					Placement: Placement{
+						AvailabilityZone: "zone-a",
+					},
+					BlockDeviceMapping: BlockDeviceMapping{
+						Root: "/dev/sda",
+					},
+					Network: Network{
+						Interfaces: []NetworkInterface{{MAC: "00:00:00:00:00:01"}},
+					},
+				},
+			},
+			Expect: `block-device-mapping/
+facility
+hostname
+instance-id
+instance-life-cycle
+instance-type
+iqn
+local-hostname
+local-ipv4
+local-ipv4s
+network/
+operating-system/
+placement/
+plan
+public-ipv4
+public-ipv4s
+public-ipv6
+public-ipv6s
+public-keys/
+tags`,
+		},
+	}
 
-	w := httptest.NewRecorder()
-	r := httptest.NewRequest("GET", endpoint, nil)
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(tc.Instance, nil)
 
-	// RemoteAddr must be valid for us to perform a lookup successfully. Because we're
-	// mocking the client the address value doesn't matter.
-	r.RemoteAddr = "10.10.10.10:0"
+			router := gin.New()
 
-	router.ServeHTTP(w, r)
+			fe := New(client)
+			fe.Configure(router)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("\nEndpoint=%s\nExpected status: 200; Received status: %d; ", endpoint, w.Code)
+			validate(t, router, "/2009-04-04/meta-data", tc.Expect)
+		})
 	}
+}
 
-	if w.Body.String() != expect {
-		t.Fatalf("\nExpected: %s;\nReceived: %s;\n(Endpoint=%s)", expect, w.Body.String(), endpoint)
+func TestFrontendPlacement(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Options      []Option
+		Placement    Placement
+		Endpoint     string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "Listing",
+			Placement:    Placement{AvailabilityZone: "sv15"},
+			Endpoint:     "/2009-04-04/meta-data/placement",
+			ExpectedCode: http.StatusOK,
+			Expect:       "availability-zone\nregion",
+		},
+		{
+			Name:         "AvailabilityZone",
+			Placement:    Placement{AvailabilityZone: "sv15"},
+			Endpoint:     "/2009-04-04/meta-data/placement/availability-zone",
+			ExpectedCode: http.StatusOK,
+			Expect:       "sv15",
+		},
+		{
+			Name:         "RegionDefaultsToAvailabilityZone",
+			Placement:    Placement{AvailabilityZone: "sv15"},
+			Endpoint:     "/2009-04-04/meta-data/placement/region",
+			ExpectedCode: http.StatusOK,
+			Expect:       "sv15",
+		},
+		{
+			Name:         "RegionFromPrefix",
+			Options:      []Option{WithRegionPrefixLength(2)},
+			Placement:    Placement{AvailabilityZone: "sv15"},
+			Endpoint:     "/2009-04-04/meta-data/placement/region",
+			ExpectedCode: http.StatusOK,
+			Expect:       "sv",
+		},
+		{
+			Name:         "RegionFromBackend",
+			Options:      []Option{WithRegionPrefixLength(2)},
+			Placement:    Placement{AvailabilityZone: "sv15", Region: "silicon-valley"},
+			Endpoint:     "/2009-04-04/meta-data/placement/region",
+			ExpectedCode: http.StatusOK,
+			Expect:       "silicon-valley",
+		},
+		{
+			Name:         "NoPlacementListing",
+			Endpoint:     "/2009-04-04/meta-data/placement",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "NoPlacementAvailabilityZone",
+			Endpoint:     "/2009-04-04/meta-data/placement/availability-zone",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "NoPlacementRegion",
+			Endpoint:     "/2009-04-04/meta-data/placement/region",
+			ExpectedCode: http.StatusNotFound,
+		},
 	}
-}
 
-func Test404OnInstanceNotFound(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	client := NewMockClient(ctrl)
-	client.EXPECT().
-		GetEC2Instance(gomock.Any(), gomock.Any()).
-		Return(Instance{}, ErrInstanceNotFound)
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{Placement: tc.Placement}}, nil)
 
-	router := gin.New()
+			router := gin.New()
 
-	fe := New(client)
-	fe.Configure(router)
+			fe := New(client, tc.Options...)
+			fe.Configure(router)
 
-	w := httptest.NewRecorder()
-	// Ensure we're using an dynamic endpoint else we won't trigger an instance lookup.
-	r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
 
-	// RemoteAddr must be valid for us to perform a lookup successfully. Because we're
-	// mocking the client the address value doesn't matter.
-	r.RemoteAddr = "10.10.10.10:0"
+			router.ServeHTTP(w, r)
 
-	router.ServeHTTP(w, r)
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
 
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("Expected: 404; Received: %d", w.Code)
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
 	}
 }
 
-func Test500OnGenericError(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	client := NewMockClient(ctrl)
-	client.EXPECT().
-		GetEC2Instance(gomock.Any(), gomock.Any()).
-		Return(Instance{}, errors.New("generic error"))
+func TestFrontendBlockDeviceMapping(t *testing.T) {
+	withDevices := BlockDeviceMapping{
+		Root:      "/dev/sda3",
+		Ephemeral: []string{"/dev/sdb"},
+	}
 
-	router := gin.New()
+	cases := []struct {
+		Name               string
+		BlockDeviceMapping BlockDeviceMapping
+		Endpoint           string
+		ExpectedCode       int
+		Expect             string
+	}{
+		{
+			Name:               "Listing",
+			BlockDeviceMapping: withDevices,
+			Endpoint:           "/2009-04-04/meta-data/block-device-mapping",
+			ExpectedCode:       http.StatusOK,
+			Expect:             "ami\nroot\nephemeral0",
+		},
+		{
+			Name:               "AMI",
+			BlockDeviceMapping: withDevices,
+			Endpoint:           "/2009-04-04/meta-data/block-device-mapping/ami",
+			ExpectedCode:       http.StatusOK,
+			Expect:             "/dev/sda3",
+		},
+		{
+			Name:               "Root",
+			BlockDeviceMapping: withDevices,
+			Endpoint:           "/2009-04-04/meta-data/block-device-mapping/root",
+			ExpectedCode:       http.StatusOK,
+			Expect:             "/dev/sda3",
+		},
+		{
+			Name:               "Ephemeral",
+			BlockDeviceMapping: withDevices,
+			Endpoint:           "/2009-04-04/meta-data/block-device-mapping/ephemeral0",
+			ExpectedCode:       http.StatusOK,
+			Expect:             "/dev/sdb",
+		},
+		{
+			Name:               "EphemeralOutOfRange",
+			BlockDeviceMapping: withDevices,
+			Endpoint:           "/2009-04-04/meta-data/block-device-mapping/ephemeral1",
+			ExpectedCode:       http.StatusNotFound,
+		},
+		{
+			Name:               "UnknownMapping",
+			BlockDeviceMapping: withDevices,
+			Endpoint:           "/2009-04-04/meta-data/block-device-mapping/swap",
+			ExpectedCode:       http.StatusNotFound,
+		},
+		{
+			Name:         "NoStorageListing",
+			Endpoint:     "/2009-04-04/meta-data/block-device-mapping/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "",
+		},
+		{
+			Name:         "NoStorageRoot",
+			Endpoint:     "/2009-04-04/meta-data/block-device-mapping/root",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
 
-	fe := New(client)
-	fe.Configure(router)
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{BlockDeviceMapping: tc.BlockDeviceMapping}}, nil)
 
-	w := httptest.NewRecorder()
-	// Ensure we're using an dynamic endpoint else we won't trigger an instance lookup.
-	r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+			router := gin.New()
 
-	// RemoteAddr must be valid for us to perform a lookup successfully. Because we're
-	// mocking the client the address value doesn't matter.
-	r.RemoteAddr = "10.10.10.10:0"
+			fe := New(client)
+			fe.Configure(router)
 
-	router.ServeHTTP(w, r)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
 
-	if w.Code != http.StatusInternalServerError {
-		t.Fatalf("Expected: 500; Received: %d", w.Code)
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendInstanceTags(t *testing.T) {
+	tags := []string{"env=prod", "team=metal", "malformed", "=no-key", "role=db=primary", "env=staging"}
+
+	cases := []struct {
+		Name         string
+		Tags         []string
+		Endpoint     string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "Listing",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance",
+			ExpectedCode: http.StatusOK,
+			Expect:       "env\nteam\nmalformed\nrole",
+		},
+		{
+			Name:         "ListingTrailingSlash",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "env\nteam\nmalformed\nrole",
+		},
+		{
+			Name:         "Value",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance/team",
+			ExpectedCode: http.StatusOK,
+			Expect:       "metal",
+		},
+		{
+			Name:         "RepeatedKeyUsesLastValue",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance/env",
+			ExpectedCode: http.StatusOK,
+			Expect:       "staging",
+		},
+		{
+			Name:         "ValueContainingSeparator",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance/role",
+			ExpectedCode: http.StatusOK,
+			Expect:       "db=primary",
+		},
+		{
+			Name:         "Malformed",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance/malformed",
+			ExpectedCode: http.StatusOK,
+			Expect:       "",
+		},
+		{
+			Name:         "UnknownKey",
+			Tags:         tags,
+			Endpoint:     "/2009-04-04/meta-data/tags/instance/owner",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "NoTagsListing",
+			Endpoint:     "/2009-04-04/meta-data/tags/instance",
+			ExpectedCode: http.StatusOK,
+			Expect:       "",
+		},
+		{
+			// The raw tags are still served from the tags endpoint.
+			Name:         "RawTags",
+			Tags:         []string{"env=prod", "malformed"},
+			Endpoint:     "/2009-04-04/meta-data/tags",
+			ExpectedCode: http.StatusOK,
+			Expect:       "env=prod\nmalformed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{Tags: tc.Tags}}, nil)
+
+			router := gin.New()
+
+			fe := New(client)
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendCustomMetadata(t *testing.T) {
+	custom := map[string]string{
+		"rack":                "r12",
+		"network/vlan":        "100",
+		"network/bond/mode":   "802.3ad",
+		"network/bond/slaves": "eth0,eth1",
+		"empty//segment":      "unaddressable",
+	}
+
+	cases := []struct {
+		Name         string
+		Custom       map[string]string
+		Endpoint     string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "Listing",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x",
+			ExpectedCode: http.StatusOK,
+			Expect:       "network/\nrack",
+		},
+		{
+			Name:         "ListingTrailingSlash",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "network/\nrack",
+		},
+		{
+			Name:         "Value",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/rack",
+			ExpectedCode: http.StatusOK,
+			Expect:       "r12",
+		},
+		{
+			Name:         "NestedListing",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/network",
+			ExpectedCode: http.StatusOK,
+			Expect:       "bond/\nvlan",
+		},
+		{
+			Name:         "NestedListingTrailingSlash",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/network/bond/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "mode\nslaves",
+		},
+		{
+			Name:         "NestedValue",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/network/bond/slaves",
+			ExpectedCode: http.StatusOK,
+			Expect:       "eth0,eth1",
+		},
+		{
+			// Values are files so can't be descended into.
+			Name:         "ValueTrailingSlash",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/rack/",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "UnknownKey",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/network/mtu",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "EmptySegment",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data/x/empty//segment",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "NoCustomMetadataListing",
+			Endpoint:     "/2009-04-04/meta-data/x",
+			ExpectedCode: http.StatusOK,
+			Expect:       "",
+		},
+		{
+			Name:         "MetadataListing",
+			Custom:       custom,
+			Endpoint:     "/2009-04-04/meta-data",
+			ExpectedCode: http.StatusOK,
+			Expect:       "instance-life-cycle\nx/",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{Custom: tc.Custom}}, nil)
+
+			router := gin.New()
+
+			fe := New(client)
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendHardwareCustomMetadata(t *testing.T) {
+	custom := `{"rack":{"name":"r12","position":[3,4]},"owner":"team-a"}`
+
+	cases := []struct {
+		Name                string
+		Instance            Instance
+		Accept              string
+		ExpectedStatus      int
+		ExpectedContentType string
+		ExpectedBody        string
+	}{
+		{
+			Name:                "Nested",
+			Instance:            Instance{Metadata: Metadata{HardwareCustom: []byte(custom)}},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json; charset=utf-8",
+			ExpectedBody:        custom,
+		},
+		{
+			// The document is already JSON so isn't wrapped in a JSON string.
+			Name:                "AcceptJSON",
+			Instance:            Instance{Metadata: Metadata{HardwareCustom: []byte(custom)}},
+			Accept:              "application/json",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json; charset=utf-8",
+			ExpectedBody:        custom,
+		},
+		{
+			Name:           "Absent",
+			ExpectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(tc.Instance, nil)
+
+			router := gin.New()
+			New(client, WithContentNegotiation()).Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/custom", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			if tc.Accept != "" {
+				r.Header.Set("Accept", tc.Accept)
+			}
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedStatus, w.Code)
+			}
+			if tc.ExpectedStatus != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.ExpectedContentType {
+				t.Fatalf("Expected Content-Type: %v; Received: %v", tc.ExpectedContentType, contentType)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected: %q; Received: %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendNetworkInterfaces(t *testing.T) {
+	dualNIC := Network{
+		Interfaces: []NetworkInterface{
+			{
+				MAC:                 "00:00:00:00:00:01",
+				LocalIPv4s:          []string{"10.10.10.10"},
+				SubnetIPv4CIDRBlock: "10.10.10.0/24",
+				Gateway:             "10.10.10.1",
+			},
+			{
+				MAC:        "00:00:00:00:00:0a",
+				LocalIPv4s: []string{"172.16.5.10"},
+			},
+		},
+	}
+
+	cases := []struct {
+		Name         string
+		Network      Network
+		Endpoint     string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "Listing",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs",
+			ExpectedCode: http.StatusOK,
+			Expect:       "00:00:00:00:00:01/\n00:00:00:00:00:0a/",
+		},
+		{
+			Name:         "InterfaceListing",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:01/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "device-number\ngateway\nlocal-ipv4s\nmac\nsubnet-ipv4-cidr-block",
+		},
+		{
+			Name:         "InterfaceListingOmitsEmptyFields",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:0a",
+			ExpectedCode: http.StatusOK,
+			Expect:       "device-number\nlocal-ipv4s\nmac",
+		},
+		{
+			Name:         "DeviceNumber",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:0a/device-number",
+			ExpectedCode: http.StatusOK,
+			Expect:       "1",
+		},
+		{
+			Name:         "LocalIPv4s",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:0a/local-ipv4s",
+			ExpectedCode: http.StatusOK,
+			Expect:       "172.16.5.10",
+		},
+		{
+			Name:         "SubnetIPv4CIDRBlock",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:01/subnet-ipv4-cidr-block",
+			ExpectedCode: http.StatusOK,
+			Expect:       "10.10.10.0/24",
+		},
+		{
+			Name:         "Gateway",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:01/gateway",
+			ExpectedCode: http.StatusOK,
+			Expect:       "10.10.10.1",
+		},
+		{
+			Name:         "UpperCaseMAC",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:0A/mac",
+			ExpectedCode: http.StatusOK,
+			Expect:       "00:00:00:00:00:0a",
+		},
+		{
+			Name:         "EmptyField",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:0a/gateway",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "UnknownInterface",
+			Network:      dualNIC,
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:03/mac",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "NoInterfacesListing",
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "",
+		},
+		{
+			Name:         "NoInterfaces",
+			Endpoint:     "/2009-04-04/meta-data/network/interfaces/macs/00:00:00:00:00:01",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{Network: tc.Network}}, nil)
+
+			router := gin.New()
+
+			fe := New(client)
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendNotFound(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Endpoint     string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "KnownPath",
+			Endpoint:     "/2009-04-04/meta-data/hostname",
+			ExpectedCode: http.StatusOK,
+			Expect:       "hostname",
+		},
+		{
+			Name:         "KnownDirectory",
+			Endpoint:     "/2009-04-04/meta-data/operating-system/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "distro\nimage_tag\nlicense_activation/\nslug\nversion",
+		},
+		{
+			Name:         "UnknownKey",
+			Endpoint:     "/2009-04-04/meta-data/unknown",
+			ExpectedCode: http.StatusNotFound,
+			Expect:       NotFoundBody,
+		},
+		{
+			Name:         "UnknownNestedKey",
+			Endpoint:     "/2009-04-04/meta-data/operating-system/unknown",
+			ExpectedCode: http.StatusNotFound,
+			Expect:       NotFoundBody,
+		},
+		{
+			Name:         "MissingData",
+			Endpoint:     "/2009-04-04/meta-data/placement/region",
+			ExpectedCode: http.StatusNotFound,
+			Expect:       NotFoundBody,
+		},
+		{
+			Name:         "MissingMAC",
+			Endpoint:     "/2009-04-04/meta-data/mac",
+			ExpectedCode: http.StatusNotFound,
+			Expect:       NotFoundBody,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{Hostname: "hostname"}}, nil).
+				AnyTimes()
+
+			router := gin.New()
+			router.NoRoute(NotFound)
+
+			fe := New(client)
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+				t.Fatalf("Expected content type: text/plain; Received: %v", ct)
+			}
+
+			if w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendVersions(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Options      []Option
+		Endpoint     string
+		ExpectedCode int
+	}{
+		{
+			Name:         "Latest",
+			Endpoint:     "/latest/meta-data/hostname",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "20090404",
+			Endpoint:     "/2009-04-04/meta-data/hostname",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "Configured",
+			Options:      []Option{WithVersions("2021-01-03")},
+			Endpoint:     "/2021-01-03/meta-data/hostname",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			// Configuring a default version mustn't register its routes twice.
+			Name:         "ConfiguredDefault",
+			Options:      []Option{WithVersions("2009-04-04", "2021-01-03", "2021-01-03")},
+			Endpoint:     "/2009-04-04/meta-data/hostname",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "Unknown",
+			Endpoint:     "/2021-01-03/meta-data/hostname",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{Hostname: "hostname"}}, nil).
+				AnyTimes()
+
+			router := gin.New()
+			router.NoRoute(NotFound)
+
+			fe := New(client, tc.Options...)
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != "hostname" {
+				t.Fatalf("Expected: hostname; Received: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestValidVersion(t *testing.T) {
+	cases := map[string]bool{
+		"latest":     true,
+		"2009-04-04": true,
+		"2021-01-03": true,
+		"":           false,
+		"Latest":     false,
+		"2021-13-01": false,
+		"20210103":   false,
+		"v1":         false,
+	}
+
+	for version, valid := range cases {
+		t.Run(version, func(t *testing.T) {
+			if ValidVersion(version) != valid {
+				t.Fatalf("Expected valid: %v; Received: %v", valid, !valid)
+			}
+		})
+	}
+}
+
+func TestFrontendJSON(t *testing.T) {
+	instance := Instance{
+		Userdata:   "userdata",
+		Vendordata: "vendordata",
+		Metadata: Metadata{
+			InstanceID:    "instance-id",
+			InstanceType:  "instance-type",
+			Lifecycle:     LifecycleSpot,
+			Hostname:      "hostname",
+			LocalHostname: "local-hostname",
+			IQN:           "iqn",
+			Plan:          "plan",
+			Facility:      "facility",
+			Tags:          []string{"tag"},
+			PublicKeys:    []string{"ssh-rsa key"},
+			PublicIPv4:    "10.10.10.10",
+			PublicIPv6:    "2001:db8::1",
+			LocalIPv4:     "192.168.0.10",
+			OperatingSystem: OperatingSystem{
+				Slug:     "slug",
+				Distro:   "distro",
+				Version:  "version",
+				ImageTag: "image-tag",
+				LicenseActivation: LicenseActivation{
+					State: "state",
+				},
+			},
+			Placement: Placement{
+				AvailabilityZone: "availability-zone",
+				Region:           "region",
+			},
+			BlockDeviceMapping: BlockDeviceMapping{
+				Root:      "/dev/sda3",
+				Ephemeral: []string{"/dev/sdb"},
+			},
+			Network: Network{
+				Interfaces: []NetworkInterface{
+					{
+						MAC:                 "00:00:00:00:00:01",
+						LocalIPv4s:          []string{"10.10.10.10"},
+						SubnetIPv4CIDRBlock: "10.10.10.0/24",
+						Gateway:             "10.10.10.1",
+					},
+				},
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		Return(instance, nil)
+
+	router := gin.New()
+
+	fe := New(client)
+	fe.Configure(router)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/2009-04-04/meta-data.json", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected: 200; Received: %d", w.Code)
+	}
+
+	// Validate field names follow the endpoint naming.
+	var raw struct {
+		Metadata map[string]any `json:"meta-data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{
+		"instance-id",
+		"local-hostname",
+		"public-keys",
+		"operating-system",
+		"placement",
+		"block-device-mapping",
+		"network",
+	} {
+		if _, ok := raw.Metadata[field]; !ok {
+			t.Fatalf("Expected meta-data field: %v; Received: %s", field, w.Body.String())
+		}
+	}
+
+	var received Instance
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(instance, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestFrontendPublicKeys(t *testing.T) {
+	threeKeys := []string{
+		"ssh-rsa AAAAB3NzaC1yc2E key1@host",
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5",
+		"ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTY key3",
+	}
+
+	cases := []struct {
+		Name       string
+		PublicKeys []string
+		Endpoint   string
+		Expect     string
+	}{
+		{
+			Name:     "NoKeysListing",
+			Endpoint: "/2009-04-04/meta-data/public-keys",
+			Expect:   "",
+		},
+		{
+			Name:       "OneKeyListing",
+			PublicKeys: threeKeys[:1],
+			Endpoint:   "/2009-04-04/meta-data/public-keys",
+			Expect:     "0=key1@host",
+		},
+		{
+			Name:       "OneKeyIndex",
+			PublicKeys: threeKeys[:1],
+			Endpoint:   "/2009-04-04/meta-data/public-keys/0",
+			Expect:     "openssh-key",
+		},
+		{
+			Name:       "OneKeyOpenSSHKey",
+			PublicKeys: threeKeys[:1],
+			Endpoint:   "/2009-04-04/meta-data/public-keys/0/openssh-key",
+			Expect:     "ssh-rsa AAAAB3NzaC1yc2E key1@host",
+		},
+		{
+			Name:       "ThreeKeysListing",
+			PublicKeys: threeKeys,
+			Endpoint:   "/2009-04-04/meta-data/public-keys",
+			Expect:     "0=key1@host\n1=key-1\n2=key3",
+		},
+		{
+			Name:       "ThreeKeysOpenSSHKey",
+			PublicKeys: threeKeys,
+			Endpoint:   "/2009-04-04/meta-data/public-keys/2/openssh-key",
+			Expect:     "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTY key3",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{PublicKeys: tc.PublicKeys}}, nil).
+				Times(2)
+
+			router := gin.New()
+
+			fe := New(client)
+			fe.Configure(router)
+
+			// Validate both with and without a trailing slash returns the same result.
+			validate(t, router, tc.Endpoint, tc.Expect)
+			validate(t, router, tc.Endpoint+"/", tc.Expect)
+		})
+	}
+}
+
+func TestFrontendUserdataETag(t *testing.T) {
+	userdata := "#cloud-config\nhostname: foo"
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string) (Instance, error) {
+			return Instance{Userdata: userdata}, nil
+		}).
+		AnyTimes()
+
+	router := gin.New()
+
+	fe := New(client)
+	fe.Configure(router)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/2009-04-04/user-data", nil)
+		r.RemoteAddr = "10.10.10.10:0"
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected: 200; Received: %d", w.Code)
+	}
+	if w.Body.String() != userdata {
+		t.Fatalf("Expected: %q; Received: %q", userdata, w.Body.String())
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag header")
+	}
+
+	// The ETag is stable for identical content.
+	if again := get("").Header().Get("ETag"); again != etag {
+		t.Fatalf("Expected stable ETag: %v; Received: %v", etag, again)
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = get(ifNoneMatch)
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("If-None-Match %v: Expected: 304; Received: %d", ifNoneMatch, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Fatalf("If-None-Match %v: Expected empty body; Received: %q", ifNoneMatch, w.Body.String())
+		}
+	}
+
+	// Changing the userdata changes the ETag so clients with a stale ETag receive the new content.
+	userdata = "#cloud-config\nhostname: bar"
+
+	w = get(etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected: 200; Received: %d", w.Code)
+	}
+	if w.Body.String() != userdata {
+		t.Fatalf("Expected: %q; Received: %q", userdata, w.Body.String())
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Fatalf("Expected ETag to change from: %v", etag)
+	}
+}
+
+func TestFrontendContentNegotiation(t *testing.T) {
+	instance := Instance{
+		Userdata: "#cloud-config\nhostname: foo",
+		Metadata: Metadata{
+			InstanceID: "instance-id",
+			Hostname:   "hostname",
+			PublicKeys: []string{"ssh-rsa key"},
+		},
+	}
+
+	cases := []struct {
+		Name                string
+		Path                string
+		Accept              string
+		Negotiate           bool
+		ExpectedContentType string
+		ExpectedBody        string
+	}{
+		{
+			Name:                "LeafText",
+			Path:                "/2009-04-04/meta-data/hostname",
+			Accept:              "text/plain",
+			Negotiate:           true,
+			ExpectedContentType: "text/plain; charset=utf-8",
+			ExpectedBody:        "hostname",
+		},
+		{
+			Name:                "LeafJSON",
+			Path:                "/2009-04-04/meta-data/hostname",
+			Accept:              "application/json",
+			Negotiate:           true,
+			ExpectedContentType: "application/json; charset=utf-8",
+			ExpectedBody:        `"hostname"`,
+		},
+		{
+			Name:                "MultilineLeafJSON",
+			Path:                "/2009-04-04/user-data",
+			Accept:              "application/json",
+			Negotiate:           true,
+			ExpectedContentType: "application/json; charset=utf-8",
+			ExpectedBody:        `"#cloud-config\nhostname: foo"`,
+		},
+		{
+			Name:                "DirectoryText",
+			Path:                "/2009-04-04/meta-data/public-keys/0",
+			Accept:              "text/plain",
+			Negotiate:           true,
+			ExpectedContentType: "text/plain; charset=utf-8",
+			ExpectedBody:        "openssh-key",
+		},
+		{
+			Name:                "DirectoryJSON",
+			Path:                "/2009-04-04/meta-data/public-keys/0",
+			Accept:              "application/json",
+			Negotiate:           true,
+			ExpectedContentType: "application/json; charset=utf-8",
+			ExpectedBody:        `["openssh-key"]`,
+		},
+		{
+			Name:                "StaticDirectoryJSON",
+			Path:                "/2009-04-04",
+			Accept:              "application/json",
+			Negotiate:           true,
+			ExpectedContentType: "application/json; charset=utf-8",
+			ExpectedBody:        `["meta-data/","user-data","vendor-data"]`,
+		},
+		{
+			// Clients such as cloud-init that accept anything are served text.
+			Name:                "AnyAccepted",
+			Path:                "/2009-04-04/meta-data/hostname",
+			Accept:              "*/*",
+			Negotiate:           true,
+			ExpectedContentType: "text/plain; charset=utf-8",
+			ExpectedBody:        "hostname",
+		},
+		{
+			Name:                "NegotiationDisabled",
+			Path:                "/2009-04-04/meta-data/hostname",
+			Accept:              "application/json",
+			ExpectedContentType: "text/plain; charset=utf-8",
+			ExpectedBody:        "hostname",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(instance, nil).
+				AnyTimes()
+
+			var opts []Option
+			if tc.Negotiate {
+				opts = append(opts, WithContentNegotiation())
+			}
+
+			router := gin.New()
+			New(client, opts...).Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			r.Header.Set("Accept", tc.Accept)
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected: 200; Received: %d", w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.ExpectedContentType {
+				t.Fatalf("Expected Content-Type: %v; Received: %v", tc.ExpectedContentType, contentType)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected: %q; Received: %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendEmptyAsNoContent(t *testing.T) {
+	instance := Instance{
+		Metadata: Metadata{
+			InstanceID: "instance-id",
+			Custom:     map[string]string{"rack": ""},
+		},
+	}
+
+	cases := []struct {
+		Name           string
+		Path           string
+		EmptyAs204     bool
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		{
+			Name:           "Empty",
+			Path:           "/2009-04-04/meta-data/hostname",
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:           "EmptyAs204",
+			Path:           "/2009-04-04/meta-data/hostname",
+			EmptyAs204:     true,
+			ExpectedStatus: http.StatusNoContent,
+		},
+		{
+			Name:           "EmptyCustomKey",
+			Path:           "/2009-04-04/meta-data/x/rack",
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:           "EmptyCustomKeyAs204",
+			Path:           "/2009-04-04/meta-data/x/rack",
+			EmptyAs204:     true,
+			ExpectedStatus: http.StatusNoContent,
+		},
+		{
+			Name:           "Populated",
+			Path:           "/2009-04-04/meta-data/instance-id",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "instance-id",
+		},
+		{
+			Name:           "PopulatedWithEmptyAs204",
+			Path:           "/2009-04-04/meta-data/instance-id",
+			EmptyAs204:     true,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "instance-id",
+		},
+		{
+			Name:           "Unknown",
+			Path:           "/2009-04-04/meta-data/x/row",
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   NotFoundBody,
+		},
+		{
+			Name:           "UnknownWithEmptyAs204",
+			Path:           "/2009-04-04/meta-data/x/row",
+			EmptyAs204:     true,
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   NotFoundBody,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(instance, nil)
+
+			var opts []Option
+			if tc.EmptyAs204 {
+				opts = append(opts, WithEmptyAsNoContent())
+			}
+
+			router := gin.New()
+			New(client, opts...).Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedStatus, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected: %q; Received: %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendContentNegotiationETag(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(Instance{Userdata: "#cloud-config"}, nil).
+		AnyTimes()
+
+	router := gin.New()
+	New(client, WithContentNegotiation()).Configure(router)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/2009-04-04/user-data", nil)
+		r.RemoteAddr = "10.10.10.10:0"
+		r.Header.Set("Accept", accept)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	text, json := get("text/plain"), get("application/json")
+
+	if text.Header().Get("ETag") == json.Header().Get("ETag") {
+		t.Fatalf("Expected distinct ETags for each format; Received: %v", text.Header().Get("ETag"))
+	}
+	if vary := json.Header().Get("Vary"); vary != "Accept" {
+		t.Fatalf("Expected Vary: Accept; Received: %v", vary)
+	}
+}
+
+func TestFrontendLargeUserdata(t *testing.T) {
+	// allocs returns the allocations serving userdata of size bytes averaged over several
+	// requests.
+	allocs := func(size int) float64 {
+		userdata := strings.Repeat("#", size)
+
+		ctrl := gomock.NewController(t)
+		client := NewMockClient(ctrl)
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), gomock.Any()).
+			Return(Instance{Userdata: userdata}, nil).
+			AnyTimes()
+
+		router := gin.New()
+
+		fe := New(client)
+		fe.Configure(router)
+
+		return testing.AllocsPerRun(10, func() {
+			w := &discardResponseWriter{header: http.Header{}}
+
+			// Contexts are created for each request rather than taken from the router's pool, which
+			// the race detector randomly empties, so the allocations are the same for each request.
+			ctx := gin.CreateTestContextOnly(w, router)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/2009-04-04/user-data", nil)
+			ctx.Request.RemoteAddr = "10.10.10.10:0"
+			router.HandleContext(ctx)
+
+			if w.written != size {
+				t.Fatalf("Expected %v bytes; Received: %v", size, w.written)
+			}
+		})
+	}
+
+	// Requests write the backend's userdata without copying it so serving large userdata
+	// allocates no more than serving small userdata. Copies of small values can be made on the
+	// stack so aren't counted.
+	small, large := allocs(16), allocs(8<<20)
+	if large > small {
+		t.Fatalf("Expected no more than %v allocations for large userdata; Received: %v", small, large)
+	}
+}
+
+// discardResponseWriter is an http.ResponseWriter that counts and discards the response body so
+// the response isn't held in memory.
+type discardResponseWriter struct {
+	header  http.Header
+	written int
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) {
+	w.written += len(p)
+	return len(p), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
+func Test404OnPublicKeyNotFound(t *testing.T) {
+	cases := []struct {
+		Name       string
+		PublicKeys []string
+		Endpoint   string
+	}{
+		{
+			Name:     "NoKeys",
+			Endpoint: "/2009-04-04/meta-data/public-keys/0/openssh-key",
+		},
+		{
+			Name:       "IndexOutOfRange",
+			PublicKeys: []string{"key"},
+			Endpoint:   "/2009-04-04/meta-data/public-keys/1/openssh-key",
+		},
+		{
+			Name:       "InvalidIndex",
+			PublicKeys: []string{"key"},
+			Endpoint:   "/2009-04-04/meta-data/public-keys/foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(Instance{Metadata: Metadata{PublicKeys: tc.PublicKeys}}, nil)
+
+			router := gin.New()
+
+			fe := New(client)
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tc.Endpoint, nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("Expected: 404; Received: %d", w.Code)
+			}
+		})
+	}
+}
+
+func validate(t *testing.T, router *gin.Engine, endpoint string, expect string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", endpoint, nil)
+
+	// RemoteAddr must be valid for us to perform a lookup successfully. Because we're
+	// mocking the client the address value doesn't matter.
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("\nEndpoint=%s\nExpected status: 200; Received status: %d; ", endpoint, w.Code)
+	}
+
+	if w.Body.String() != expect {
+		t.Fatalf("\nExpected: %s;\nReceived: %s;\n(Endpoint=%s)", expect, w.Body.String(), endpoint)
+	}
+}
+
+func Test404OnInstanceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		Return(Instance{}, ErrInstanceNotFound)
+
+	router := gin.New()
+
+	fe := New(client)
+	fe.Configure(router)
+
+	w := httptest.NewRecorder()
+	// Ensure we're using an dynamic endpoint else we won't trigger an instance lookup.
+	r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+
+	// RemoteAddr must be valid for us to perform a lookup successfully. Because we're
+	// mocking the client the address value doesn't matter.
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected: 404; Received: %d", w.Code)
+	}
+}
+
+func Test500OnGenericError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		Return(Instance{}, errors.New("generic error"))
+
+	router := gin.New()
+
+	fe := New(client)
+	fe.Configure(router)
+
+	w := httptest.NewRecorder()
+	// Ensure we're using an dynamic endpoint else we won't trigger an instance lookup.
+	r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+
+	// RemoteAddr must be valid for us to perform a lookup successfully. Because we're
+	// mocking the client the address value doesn't matter.
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected: 500; Received: %d", w.Code)
+	}
+}
+
+func TestMACFallback(t *testing.T) {
+	cases := []struct {
+		Name         string
+		MAC          string
+		Configure    func(*MockClient)
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name: "IPFound",
+			MAC:  "00:00:00:00:00:01",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{Metadata: Metadata{Hostname: "by-ip"}}, nil)
+			},
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "by-ip",
+		},
+		{
+			Name: "MACFound",
+			MAC:  "00:00:00:00:00:01",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{}, ErrInstanceNotFound)
+				client.EXPECT().
+					GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+					Return(Instance{Metadata: Metadata{Hostname: "by-mac"}}, nil)
+			},
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "by-mac",
+		},
+		{
+			Name: "MACNormalized",
+			MAC:  "00-00-00-00-00-0A",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{}, ErrInstanceNotFound)
+				client.EXPECT().
+					GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:0a").
+					Return(Instance{Metadata: Metadata{Hostname: "by-mac"}}, nil)
+			},
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "by-mac",
+		},
+		{
+			Name: "MACNotFound",
+			MAC:  "00:00:00:00:00:01",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{}, ErrInstanceNotFound)
+				client.EXPECT().
+					GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").
+					Return(Instance{}, ErrInstanceNotFound)
+			},
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name: "NoMAC",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{}, ErrInstanceNotFound)
+			},
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name: "InvalidMAC",
+			MAC:  "invalid",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{}, ErrInstanceNotFound)
+			},
+			ExpectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			tc.Configure(client)
+
+			router := gin.New()
+
+			fe := New(client, WithMACFallback())
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			if tc.MAC != "" {
+				r.Header.Set(MACHeader, tc.MAC)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected: %s; Received: %s", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMACFallbackDisabledByDefault(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(Instance{}, ErrInstanceNotFound)
+
+	router := gin.New()
+
+	fe := New(client)
+	fe.Configure(router)
+
+	// The MAC identifies another instance and must not be used for the lookup.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+	r.Header.Set(MACHeader, "00:00:00:00:00:01")
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected: %d; Received: %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSelfOnly(t *testing.T) {
+	cases := []struct {
+		Name         string
+		ForwardedFor string
+		MAC          string
+		Configure    func(*MockClient)
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name: "IPFound",
+			MAC:  "00:00:00:00:00:02",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{Metadata: Metadata{Hostname: "self"}}, nil)
+			},
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "self",
+		},
+		{
+			// The MAC identifies another instance and must not be used for the lookup.
+			Name: "MACIgnored",
+			MAC:  "00:00:00:00:00:02",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{}, ErrInstanceNotFound)
+			},
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			// The peer isn't a trusted proxy so the forwarded IP must not be used for the lookup.
+			Name:         "UntrustedForwardedForIgnored",
+			ForwardedFor: "10.10.10.11",
+			Configure: func(client *MockClient) {
+				client.EXPECT().
+					GetEC2Instance(gomock.Any(), "10.10.10.10").
+					Return(Instance{Metadata: Metadata{Hostname: "self"}}, nil)
+			},
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "self",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			tc.Configure(client)
+
+			xffmw, err := xff.MiddlewareFromUnparsed("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := gin.New()
+			router.Use(xffmw)
+
+			fe := New(client, WithSelfOnly())
+			fe.Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/2009-04-04/meta-data/hostname", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			if tc.ForwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tc.ForwardedFor)
+			}
+			if tc.MAC != "" {
+				r.Header.Set(MACHeader, tc.MAC)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected: %s; Received: %s", tc.ExpectedBody, w.Body.String())
+			}
+		})
 	}
 }
 
@@ -1,5 +1,7 @@
 package ec2
 
+import "encoding/json"
+
 // Instance is a struct that contains the hardware data exposed from the EC2 API endpoints. For
 // an explanation of the endpoints refer to the AWS EC2 Instance Metadata documentation.
 //
@@ -7,37 +9,96 @@ package ec2
 //
 // Note not all AWS EC2 Instance Metadata categories are supported as some are not applicable.
 // Deviations from the AWS EC2 Instance Metadata should be documented here.
+//
+// Instance is served as a single JSON document with field names matching the endpoint names.
+//
+// Vendordata is a deviation from AWS EC2 Instance Metadata. It is operator supplied configuration
+// that cloud-init applies in addition to the tenant supplied Userdata.
 type Instance struct {
-	Userdata string
-	Metadata Metadata
+	Userdata   string   `json:"user-data"`
+	Vendordata string   `json:"vendor-data"`
+	Metadata   Metadata `json:"meta-data"`
 }
 
-// Metadata is a part of Instance.
+// Instance lifecycles. Instances without a lifecycle are served as LifecycleOnDemand.
+const (
+	LifecycleOnDemand = "on-demand"
+	LifecycleSpot     = "spot"
+)
+
+// Metadata is a part of Instance. MAC is the primary network interface's MAC address formatted as
+// with NetworkInterface.MAC.
+//
+// HardwareCustom is a deviation from AWS EC2 Instance Metadata. It is a JSON document of custom
+// metadata supplied with the instance's hardware, served as is.
+//
+// PublicIPv4s, PublicIPv6s and LocalIPv4s are a deviation from AWS EC2 Instance Metadata, which
+// only lists an instance's addresses per network interface. They contain every address of the
+// instance; PublicIPv4, PublicIPv6 and LocalIPv4 are the first of each.
 type Metadata struct {
-	InstanceID      string
-	Hostname        string
-	LocalHostname   string
-	IQN             string
-	Plan            string
-	Facility        string
-	Tags            []string
-	PublicKeys      []string
-	PublicIPv4      string
-	PublicIPv6      string
-	LocalIPv4       string
-	OperatingSystem OperatingSystem
+	InstanceID         string             `json:"instance-id"`
+	InstanceType       string             `json:"instance-type"`
+	Lifecycle          string             `json:"instance-life-cycle"`
+	Hostname           string             `json:"hostname"`
+	LocalHostname      string             `json:"local-hostname"`
+	IQN                string             `json:"iqn"`
+	Plan               string             `json:"plan"`
+	Facility           string             `json:"facility"`
+	Tags               []string           `json:"tags"`
+	PublicKeys         []string           `json:"public-keys"`
+	PublicIPv4         string             `json:"public-ipv4"`
+	PublicIPv6         string             `json:"public-ipv6"`
+	LocalIPv4          string             `json:"local-ipv4"`
+	PublicIPv4s        []string           `json:"public-ipv4s,omitempty"`
+	PublicIPv6s        []string           `json:"public-ipv6s,omitempty"`
+	LocalIPv4s         []string           `json:"local-ipv4s,omitempty"`
+	MAC                string             `json:"mac"`
+	OperatingSystem    OperatingSystem    `json:"operating-system"`
+	Placement          Placement          `json:"placement"`
+	BlockDeviceMapping BlockDeviceMapping `json:"block-device-mapping"`
+	Network            Network            `json:"network"`
+	Custom             map[string]string  `json:"x"`
+	HardwareCustom     json.RawMessage    `json:"custom,omitempty"`
 }
 
 // OperatingSystem is part of Metadata.
 type OperatingSystem struct {
-	Slug              string
-	Distro            string
-	Version           string
-	ImageTag          string
-	LicenseActivation LicenseActivation
+	Slug              string            `json:"slug"`
+	Distro            string            `json:"distro"`
+	Version           string            `json:"version"`
+	ImageTag          string            `json:"image_tag"`
+	LicenseActivation LicenseActivation `json:"license_activation"`
 }
 
 // LicenseActivation is part of OperatingSystem.
 type LicenseActivation struct {
-	State string
+	State string `json:"state"`
+}
+
+// Placement is part of Metadata. Instances without an AvailabilityZone have no placement.
+type Placement struct {
+	AvailabilityZone string `json:"availability-zone"`
+	Region           string `json:"region"`
+}
+
+// BlockDeviceMapping is part of Metadata. It contains device names such as /dev/sda.
+type BlockDeviceMapping struct {
+	Root      string   `json:"root"`
+	Ephemeral []string `json:"ephemeral"`
+}
+
+// Network is part of Metadata.
+type Network struct {
+	Interfaces []NetworkInterface `json:"interfaces"`
+}
+
+// NetworkInterface is part of Network. MAC is formatted as a lower case colon separated address.
+// Interfaces are served by MAC and their device number is their index in Network.Interfaces.
+//
+// Gateway is a deviation from AWS EC2 Instance Metadata which has no equivalent.
+type NetworkInterface struct {
+	MAC                 string   `json:"mac"`
+	LocalIPv4s          []string `json:"local-ipv4s"`
+	SubnetIPv4CIDRBlock string   `json:"subnet-ipv4-cidr-block"`
+	Gateway             string   `json:"gateway"`
 }
@@ -12,6 +12,12 @@ func (s unorderedSet) Insert(v string) {
 	s[v] = struct{}{}
 }
 
+// Has returns true if v is in s.
+func (s unorderedSet) Has(v string) bool {
+	_, ok := s[v]
+	return ok
+}
+
 // Range iterates over the elements in s and calls fn for each element.
 func (s unorderedSet) Range(fn func(v string)) {
 	for k := range s {
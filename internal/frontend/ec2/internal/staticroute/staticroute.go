@@ -17,6 +17,9 @@ import (
 //	"/foo/bar" -> baz
 //	"/foo" -> bar/
 //	"" -> foo/
+//
+// Endpoints with a trailing slash are directories that serve their own listing. They are listed
+// as descendable by their parent but no route is built for them even if they have children.
 type Builder map[string]unorderedSet
 
 // NewBuilder returns a new Builder instance.
@@ -53,6 +56,12 @@ func (b Builder) Build() []Route {
 	var routes sortableRoutes
 
 	for parent, children := range b {
+		// Directory endpoints have an empty child, from their trailing slash, and serve their
+		// own listing.
+		if children.Has("") {
+			continue
+		}
+
 		r := Route{Endpoint: parent}
 
 		// Add children to the route prepending a slash for any child that is also a parent.
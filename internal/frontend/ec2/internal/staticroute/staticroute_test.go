@@ -105,6 +105,20 @@ func TestBuilder(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:      "DirectoryEndpoint",
+			Endpoints: []string{"/foo/bar/", "/foo/bar/qux", "/foo/baz"},
+			Routes: []Route{
+				{
+					Endpoint: "",
+					Children: []string{"foo/"},
+				},
+				{
+					Endpoint: "/foo",
+					Children: []string{"bar/", "baz"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -0,0 +1,51 @@
+package ec2
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// format returns the media type metadata is served to ctx as. Metadata is served as text unless
+// content negotiation is enabled and the client prefers JSON.
+func (f Frontend) format(ctx *gin.Context) string {
+	if !f.negotiate {
+		return gin.MIMEPlain
+	}
+
+	// Responses vary with the Accept header so caches mustn't serve one format for the other.
+	ctx.Header("Vary", "Accept")
+	return ctx.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON)
+}
+
+// respond writes data as the response in the format negotiated with ctx. Leaves are served as a
+// JSON string and directory listings, indicated by dir, as a JSON array of their children.
+func (f Frontend) respond(ctx *gin.Context, data string, dir bool) {
+	f.write(ctx, f.format(ctx), data, dir)
+}
+
+// write writes data as the response in format. When configured with WithEmptyAsNoContent, empty
+// data is responded to with a 204 No Content instead.
+func (f Frontend) write(ctx *gin.Context, format, data string, dir bool) {
+	if f.emptyAsNoContent && data == "" {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	if format != gin.MIMEJSON {
+		ctx.String(http.StatusOK, data)
+		return
+	}
+
+	if !dir {
+		ctx.JSON(http.StatusOK, data)
+		return
+	}
+
+	children := []string{}
+	if data != "" {
+		children = strings.Split(data, "\n")
+	}
+	ctx.JSON(http.StatusOK, children)
+}
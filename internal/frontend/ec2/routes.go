@@ -1,20 +1,70 @@
 package ec2
 
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // TODO(chrisdoherty4) Figure out a better way to model routes; this approach is clunky and
 // error prone. Ideally we have a way to define routes and retrieve the children of a route without
 // manually defining everything.
 
 type filterFunc func(i Instance) string
 
+// existsFunc reports whether the data served by an endpoint exists for an instance.
+type existsFunc func(i Instance) bool
+
+// populated reports whether endpoint serves data for i. Directory endpoints, identified by a
+// trailing slash, are populated when any data route beneath them is.
+func populated(i Instance, endpoint string) bool {
+	for _, r := range dataRoutes {
+		if r.Endpoint != endpoint && !(strings.HasSuffix(endpoint, "/") && strings.HasPrefix(r.Endpoint, endpoint)) {
+			continue
+		}
+
+		if r.Exists != nil && !r.Exists(i) {
+			continue
+		}
+
+		if r.Filter(i) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
 var dataRoutes = []struct {
 	Endpoint string
 	Filter   filterFunc
+
+	// Exists is optional. When specified and it returns false, the endpoint responds with a 404.
+	Exists existsFunc
+
+	// ETag enables conditional requests. Responses include an ETag header and requests with a
+	// matching If-None-Match header receive a 304 Not Modified.
+	ETag bool
+
+	// JSON indicates the data is a JSON document. It's served as application/json regardless of
+	// content negotiation.
+	JSON bool
 }{
 	{
 		Endpoint: "/user-data",
 		Filter: func(i Instance) string {
 			return i.Userdata
 		},
+		ETag: true,
+	},
+	{
+		Endpoint: "/vendor-data",
+		Filter: func(i Instance) string {
+			return i.Vendordata
+		},
 	},
 	{
 		Endpoint: "/meta-data/instance-id",
@@ -22,6 +72,16 @@ var dataRoutes = []struct {
 			return i.Metadata.InstanceID
 		},
 	},
+	{
+		Endpoint: "/meta-data/instance-type",
+		Filter: func(i Instance) string {
+			return i.Metadata.InstanceType
+		},
+	},
+	{
+		Endpoint: "/meta-data/instance-life-cycle",
+		Filter:   lifecycle,
+	},
 	{
 		Endpoint: "/meta-data/hostname",
 		Filter: func(i Instance) string {
@@ -58,6 +118,25 @@ var dataRoutes = []struct {
 			return join(i.Metadata.Tags)
 		},
 	},
+	{
+		// Custom metadata is a directory of operator supplied keys. Nested keys are served by the
+		// frontend.
+		Endpoint: "/meta-data/x/",
+		Filter: func(i Instance) string {
+			listing, _ := customMetadata(i, "")
+			return listing
+		},
+	},
+	{
+		Endpoint: "/meta-data/custom",
+		Filter: func(i Instance) string {
+			return string(i.Metadata.HardwareCustom)
+		},
+		Exists: func(i Instance) bool {
+			return len(i.Metadata.HardwareCustom) > 0
+		},
+		JSON: true,
+	},
 	{
 		Endpoint: "/meta-data/public-ipv4",
 		Filter: func(i Instance) string {
@@ -77,11 +156,87 @@ var dataRoutes = []struct {
 		},
 	},
 	{
-		Endpoint: "/meta-data/public-keys",
+		Endpoint: "/meta-data/public-ipv4s",
+		Filter: func(i Instance) string {
+			return join(i.Metadata.PublicIPv4s)
+		},
+	},
+	{
+		Endpoint: "/meta-data/public-ipv6s",
+		Filter: func(i Instance) string {
+			return join(i.Metadata.PublicIPv6s)
+		},
+	},
+	{
+		Endpoint: "/meta-data/local-ipv4s",
+		Filter: func(i Instance) string {
+			return join(i.Metadata.LocalIPv4s)
+		},
+	},
+	{
+		// Instances without network interfaces have no primary MAC.
+		Endpoint: "/meta-data/mac",
+		Filter: func(i Instance) string {
+			return i.Metadata.MAC
+		},
+		Exists: func(i Instance) bool {
+			return i.Metadata.MAC != ""
+		},
+	},
+	{
+		// Public keys are a directory listing each key as "<index>=<name>". The key data is
+		// served from "<index>/openssh-key" by the frontend.
+		Endpoint: "/meta-data/public-keys/",
+		Filter: func(i Instance) string {
+			listing := make([]string, len(i.Metadata.PublicKeys))
+			for idx, key := range i.Metadata.PublicKeys {
+				listing[idx] = fmt.Sprintf("%d=%s", idx, publicKeyName(idx, key))
+			}
+			return join(listing)
+		},
+	},
+	{
+		// Block device mappings are a directory listing each mapping name. The device names are
+		// served from "<name>" by the frontend. Instances without block devices have an empty
+		// listing.
+		Endpoint: "/meta-data/block-device-mapping/",
 		Filter: func(i Instance) string {
-			return join(i.Metadata.PublicKeys)
+			return join(blockDeviceMappingNames(i))
 		},
 	},
+	{
+		// Network interfaces are a directory listing each interface as "<mac>/". The interface
+		// data is served from "<mac>/<field>" by the frontend.
+		Endpoint: "/meta-data/network/interfaces/macs/",
+		Filter: func(i Instance) string {
+			listing := make([]string, len(i.Metadata.Network.Interfaces))
+			for idx, iface := range i.Metadata.Network.Interfaces {
+				listing[idx] = iface.MAC + "/"
+			}
+			return join(listing)
+		},
+	},
+	{
+		Endpoint: "/meta-data/placement/",
+		Filter: func(Instance) string {
+			return join([]string{"availability-zone", "region"})
+		},
+		Exists: hasPlacement,
+	},
+	{
+		Endpoint: "/meta-data/placement/availability-zone",
+		Filter: func(i Instance) string {
+			return i.Metadata.Placement.AvailabilityZone
+		},
+		Exists: hasPlacement,
+	},
+	{
+		Endpoint: "/meta-data/placement/region",
+		Filter: func(i Instance) string {
+			return i.Metadata.Placement.Region
+		},
+		Exists: hasPlacement,
+	},
 	{
 		Endpoint: "/meta-data/operating-system/slug",
 		Filter: func(i Instance) string {
@@ -113,3 +268,207 @@ var dataRoutes = []struct {
 		},
 	},
 }
+
+// publicKeyName returns a name for the OpenSSH formatted key at index. The name is the key comment
+// or, if the key has no comment, a name derived from index.
+func publicKeyName(index int, key string) string {
+	// OpenSSH public keys take the form "<type> <data> [comment]".
+	if fields := strings.Fields(key); len(fields) > 2 {
+		return strings.Join(fields[2:], " ")
+	}
+	return fmt.Sprintf("key-%d", index)
+}
+
+// lifecycle returns the lifecycle of i defaulting to LifecycleOnDemand.
+func lifecycle(i Instance) string {
+	if i.Metadata.Lifecycle == "" {
+		return LifecycleOnDemand
+	}
+	return i.Metadata.Lifecycle
+}
+
+func hasPlacement(i Instance) bool {
+	return i.Metadata.Placement.AvailabilityZone != ""
+}
+
+// instanceTags parses the tags of i into instance tag keys, in the order they first appear, and
+// their values. Tags take the form "key=value"; tags without a "=" are keys with an empty value.
+// When a key is repeated the last value is used.
+func instanceTags(i Instance) ([]string, map[string]string) {
+	var keys []string
+	values := map[string]string{}
+	for _, tag := range i.Metadata.Tags {
+		key, value, _ := strings.Cut(tag, "=")
+		if key == "" {
+			continue
+		}
+
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values
+}
+
+// customMetadata returns the custom metadata of i at path, a key relative to /meta-data/x. When
+// path is a key its value is returned. Otherwise, when path is a directory of keys, a listing of
+// its children is returned with nested directories suffixed by a slash. Keys with empty path
+// segments are ignored. If path is neither it returns false.
+func customMetadata(i Instance, path string) (string, bool) {
+	dir := strings.Trim(path, "/")
+	if dir != "" && hasEmptySegment(dir) {
+		return "", false
+	}
+
+	if dir != "" && !strings.HasSuffix(path, "/") {
+		if value, ok := i.Metadata.Custom[dir]; ok {
+			return value, true
+		}
+	}
+
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	var listing []string
+	seen := map[string]bool{}
+	for key := range i.Metadata.Custom {
+		if !strings.HasPrefix(key, prefix) || hasEmptySegment(key) {
+			continue
+		}
+
+		child, _, nested := strings.Cut(strings.TrimPrefix(key, prefix), "/")
+		if nested {
+			child += "/"
+		}
+
+		if !seen[child] {
+			seen[child] = true
+			listing = append(listing, child)
+		}
+	}
+
+	if len(listing) == 0 {
+		return "", false
+	}
+
+	sort.Strings(listing)
+	return join(listing), true
+}
+
+func hasEmptySegment(path string) bool {
+	return slices.Contains(strings.Split(path, "/"), "")
+}
+
+// blockDeviceMappingNames returns the EC2 block device mapping names for i. The ami and root
+// mappings are the root device and ephemeral mappings are named ephemeral<index>.
+func blockDeviceMappingNames(i Instance) []string {
+	var names []string
+	if i.Metadata.BlockDeviceMapping.Root != "" {
+		names = append(names, "ami", "root")
+	}
+	for idx := range i.Metadata.BlockDeviceMapping.Ephemeral {
+		names = append(names, fmt.Sprintf("ephemeral%d", idx))
+	}
+	return names
+}
+
+// blockDevice returns the device for the block device mapping name. If i has no device for name it
+// returns false.
+func blockDevice(i Instance, name string) (string, bool) {
+	mapping := i.Metadata.BlockDeviceMapping
+
+	switch {
+	case name == "ami" || name == "root":
+		return mapping.Root, mapping.Root != ""
+
+	case strings.HasPrefix(name, "ephemeral"):
+		idx, err := strconv.Atoi(strings.TrimPrefix(name, "ephemeral"))
+		if err != nil || idx < 0 || idx >= len(mapping.Ephemeral) {
+			return "", false
+		}
+		return mapping.Ephemeral[idx], true
+	}
+
+	return "", false
+}
+
+// networkInterfaceFields are the fields served for each network interface. Fields with no value
+// are omitted from the interface listing.
+var networkInterfaceFields = []struct {
+	Name   string
+	Filter func(deviceNumber int, iface NetworkInterface) string
+}{
+	{
+		Name: "device-number",
+		Filter: func(deviceNumber int, _ NetworkInterface) string {
+			return strconv.Itoa(deviceNumber)
+		},
+	},
+	{
+		Name: "gateway",
+		Filter: func(_ int, iface NetworkInterface) string {
+			return iface.Gateway
+		},
+	},
+	{
+		Name: "local-ipv4s",
+		Filter: func(_ int, iface NetworkInterface) string {
+			return join(iface.LocalIPv4s)
+		},
+	},
+	{
+		Name: "mac",
+		Filter: func(_ int, iface NetworkInterface) string {
+			return iface.MAC
+		},
+	},
+	{
+		Name: "subnet-ipv4-cidr-block",
+		Filter: func(_ int, iface NetworkInterface) string {
+			return iface.SubnetIPv4CIDRBlock
+		},
+	},
+}
+
+// networkInterface returns the device number and network interface for mac. If i has no interface
+// for mac it returns false.
+func networkInterface(i Instance, mac string) (int, NetworkInterface, bool) {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return 0, NetworkInterface{}, false
+	}
+
+	for idx, iface := range i.Metadata.Network.Interfaces {
+		if iface.MAC == hwAddr.String() {
+			return idx, iface, true
+		}
+	}
+
+	return 0, NetworkInterface{}, false
+}
+
+// networkInterfaceData returns the listing of fields for the network interface when field is
+// empty, else the value of field. If the interface has no value for field it returns false.
+func networkInterfaceData(deviceNumber int, iface NetworkInterface, field string) (string, bool) {
+	var listing []string
+	for _, f := range networkInterfaceFields {
+		value := f.Filter(deviceNumber, iface)
+		if value == "" {
+			continue
+		}
+
+		if f.Name == field {
+			return value, true
+		}
+		listing = append(listing, f.Name)
+	}
+
+	if field == "" {
+		return join(listing), true
+	}
+
+	return "", false
+}
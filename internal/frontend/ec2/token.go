@@ -0,0 +1,67 @@
+package ec2
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenHeader is the request header used to supply a session token issued by the token endpoint.
+const TokenHeader = "X-aws-ec2-metadata-token"
+
+// TokenTTLHeader is the request header used to specify the TTL, in seconds, of a session token
+// when requesting one from the token endpoint. It's also set on the token endpoint response.
+const TokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+
+// MaxTokenTTL is the maximum TTL of a session token.
+const MaxTokenTTL = 6 * time.Hour
+
+// tokenIssuer issues and validates session tokens. Tokens are bound to the requesting IP and
+// carry their own expiry signed with a key generated per process so they needn't be stored.
+// Tokens are invalidated when the process restarts.
+type tokenIssuer struct {
+	key []byte
+	now func() time.Time
+}
+
+func newTokenIssuer() tokenIssuer {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		// crypto/rand only fails when the OS random source is unavailable, in which case nothing
+		// we could fallback to is safe.
+		panic(err)
+	}
+
+	return tokenIssuer{key: key, now: time.Now}
+}
+
+// Issue creates a token for ip that expires after ttl.
+func (t tokenIssuer) Issue(ip string, ttl time.Duration) string {
+	expiry := strconv.FormatInt(t.now().Add(ttl).UnixMilli(), 10)
+	return expiry + "." + t.sign(ip, expiry)
+}
+
+// Valid reports whether token was issued for ip and hasn't expired.
+func (t tokenIssuer) Valid(ip, token string) bool {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	millis, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !t.now().Before(time.UnixMilli(millis)) {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(t.sign(ip, expiry)))
+}
+
+func (t tokenIssuer) sign(ip, expiry string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(ip + "|" + expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
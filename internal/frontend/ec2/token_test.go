@@ -0,0 +1,168 @@
+package ec2_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+func TestTokenIssue(t *testing.T) {
+	cases := []struct {
+		Name         string
+		TTL          string
+		ExpectedCode int
+	}{
+		{Name: "Valid", TTL: "21600", ExpectedCode: http.StatusOK},
+		{Name: "MissingTTL", ExpectedCode: http.StatusBadRequest},
+		{Name: "ZeroTTL", TTL: "0", ExpectedCode: http.StatusBadRequest},
+		{Name: "ExcessiveTTL", TTL: "21601", ExpectedCode: http.StatusBadRequest},
+		{Name: "MalformedTTL", TTL: "1h", ExpectedCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			router := gin.New()
+			New(NewMockClient(ctrl)).Configure(router)
+
+			w := requestToken(router, "10.10.10.10", tc.TTL)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode != http.StatusOK {
+				return
+			}
+
+			if w.Body.Len() == 0 {
+				t.Fatal("Expected a token; Received an empty body")
+			}
+
+			if ttl := w.Header().Get(TokenTTLHeader); ttl != tc.TTL {
+				t.Fatalf("Expected %v: %v; Received: %v", TokenTTLHeader, tc.TTL, ttl)
+			}
+		})
+	}
+}
+
+func TestTokenValidation(t *testing.T) {
+	cases := []struct {
+		Name         string
+		RequireToken bool
+		// IssueTo is the IP the token is issued to. When empty, no token is sent.
+		IssueTo string
+		// Token overrides the issued token.
+		Token        string
+		Elapsed      time.Duration
+		ExpectedCode int
+	}{
+		{
+			Name:         "OptionalWithoutToken",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "OptionalWithToken",
+			IssueTo:      "10.10.10.10",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "OptionalWithInvalidToken",
+			Token:        "invalid",
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		{
+			Name:         "RequiredWithoutToken",
+			RequireToken: true,
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		{
+			Name:         "RequiredWithToken",
+			RequireToken: true,
+			IssueTo:      "10.10.10.10",
+			Elapsed:      59 * time.Second,
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "RequiredWithExpiredToken",
+			RequireToken: true,
+			IssueTo:      "10.10.10.10",
+			Elapsed:      time.Minute,
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		{
+			Name:         "RequiredWithOtherIPsToken",
+			RequireToken: true,
+			IssueTo:      "10.10.10.11",
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		{
+			Name:         "RequiredWithInvalidToken",
+			RequireToken: true,
+			Token:        "invalid",
+			ExpectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(Instance{Metadata: Metadata{Hostname: "hostname"}}, nil).
+				AnyTimes()
+
+			now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+			opts := []Option{WithClock(func() time.Time { return now })}
+			if tc.RequireToken {
+				opts = append(opts, WithRequireToken())
+			}
+
+			router := gin.New()
+			New(client, opts...).Configure(router)
+
+			token := tc.Token
+			if tc.IssueTo != "" {
+				w := requestToken(router, tc.IssueTo, "60")
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected token issue status: 200; Received: %d", w.Code)
+				}
+				token = w.Body.String()
+			}
+
+			now = now.Add(tc.Elapsed)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/hostname", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			if token != "" {
+				r.Header.Set(TokenHeader, token)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected: %d; Received: %d", tc.ExpectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func requestToken(router *gin.Engine, ip, ttl string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+	r.RemoteAddr = ip + ":0"
+	if ttl != "" {
+		r.Header.Set(TokenTTLHeader, ttl)
+	}
+
+	router.ServeHTTP(w, r)
+
+	return w
+}
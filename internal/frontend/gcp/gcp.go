@@ -0,0 +1,228 @@
+/*
+Package gcp contains a frontend that serves instance data in the Google Compute Engine metadata
+server format for tooling written for GCE.
+
+	https://cloud.google.com/compute/docs/metadata/overview
+
+Only the instance hostname, ID, machine type, zone, tags and attributes are supported. Directories
+are served as a listing of their entries or, when the recursive query parameter is true, as a JSON
+document.
+*/
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// FlavorHeader is the request header the GCE metadata server requires to be FlavorGoogle on every
+// request. It's also set on every response.
+const FlavorHeader = "Metadata-Flavor"
+
+// FlavorGoogle is the required value of FlavorHeader.
+const FlavorGoogle = "Google"
+
+// SSHKeysUser is the user associated with each SSH key. cloud-init authorizes keys for this user
+// for its default user.
+const SSHKeysUser = "cloudinit"
+
+// Client is a backend for retrieving instance data.
+type Client interface {
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+}
+
+// Configure configures router with the `/computeMetadata/v1/` tree using client to retrieve
+// instance data.
+func Configure(router gin.IRouter, client Client) {
+	router.GET("/computeMetadata/v1/*path", func(ctx *gin.Context) {
+		ctx.Header(FlavorHeader, FlavorGoogle)
+
+		if ctx.GetHeader(FlavorHeader) != FlavorGoogle {
+			_ = ctx.AbortWithError(http.StatusForbidden, errors.New("missing Metadata-Flavor:Google header"))
+			return
+		}
+
+		ip, err := request.RemoteAddrIP(ctx.Request)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote address"))
+			return
+		}
+
+		instance, err := client.GetEC2Instance(ctx.Request.Context(), ip)
+		if err != nil {
+			clienterror.Abort(ctx, err)
+			return
+		}
+
+		tree, err := toTree(ToMetadata(instance))
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		node, ok := lookup(tree, ctx.Param("path"))
+		if !ok {
+			_ = ctx.AbortWithError(http.StatusNotFound, errors.New("metadata not found"))
+			return
+		}
+
+		switch v := node.(type) {
+		case map[string]any:
+			if ctx.Query("recursive") == "true" {
+				ctx.JSON(http.StatusOK, v)
+				return
+			}
+			ctx.String(http.StatusOK, listing(v))
+
+		case string:
+			ctx.String(http.StatusOK, v)
+
+		default:
+			// Non-string values, such as tags, are served as JSON.
+			ctx.JSON(http.StatusOK, v)
+		}
+	})
+}
+
+// Metadata is the GCE metadata document served when the root is requested recursively.
+type Metadata struct {
+	Instance Instance `json:"instance"`
+}
+
+// Instance is part of Metadata.
+type Instance struct {
+	Attributes  map[string]string `json:"attributes"`
+	Hostname    string            `json:"hostname"`
+	ID          string            `json:"id"`
+	MachineType string            `json:"machineType"`
+	Tags        []string          `json:"tags"`
+	Zone        string            `json:"zone"`
+}
+
+// ToMetadata converts instance to a GCE metadata document. Public keys are served as the ssh-keys
+// attribute associated with SSHKeysUser and userdata is served as the startup-script attribute.
+func ToMetadata(instance ec2.Instance) Metadata {
+	attributes := map[string]string{}
+
+	if len(instance.Metadata.PublicKeys) > 0 {
+		keys := make([]string, len(instance.Metadata.PublicKeys))
+		for i, key := range instance.Metadata.PublicKeys {
+			keys[i] = SSHKeysUser + ":" + key
+		}
+		attributes["ssh-keys"] = strings.Join(keys, "\n")
+	}
+
+	if instance.Userdata != "" {
+		attributes["startup-script"] = instance.Userdata
+	}
+
+	tags := instance.Metadata.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return Metadata{
+		Instance: Instance{
+			Attributes:  attributes,
+			Hostname:    instance.Metadata.Hostname,
+			ID:          instance.Metadata.InstanceID,
+			MachineType: instance.Metadata.InstanceType,
+			Tags:        tags,
+			Zone:        instance.Metadata.Placement.AvailabilityZone,
+		},
+	}
+}
+
+// toTree converts metadata to a tree of maps keyed by JSON field name so it can be navigated by
+// path.
+func toTree(metadata Metadata) (map[string]any, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// lookup returns the node in tree at path. Path segments are either a JSON field name in its
+// hyphenated form, such as machine-type, or a key such as an attribute name.
+func lookup(tree map[string]any, path string) (any, bool) {
+	var node any = tree
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		dir, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		child, ok := dir[segment]
+		if !ok {
+			child, ok = dir[toCamelCase(segment)]
+		}
+		if !ok {
+			return nil, false
+		}
+
+		node = child
+	}
+
+	return node, true
+}
+
+// listing returns the entries of dir in their hyphenated form. Directories are suffixed with a
+// slash.
+func listing(dir map[string]any) string {
+	entries := make([]string, 0, len(dir))
+	for key, value := range dir {
+		entry := toHyphenated(key)
+		if _, ok := value.(map[string]any); ok {
+			entry += "/"
+		}
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	return strings.Join(entries, "\n")
+}
+
+// toCamelCase converts a hyphenated name, such as machine-type, to camel case.
+func toCamelCase(name string) string {
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// toHyphenated converts a camel case name, such as machineType, to its hyphenated form.
+func toHyphenated(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			b.WriteRune('-')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
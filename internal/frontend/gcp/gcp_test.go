@@ -0,0 +1,237 @@
+package gcp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/gcp"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+var instance = ec2.Instance{
+	Userdata: "#!/bin/sh\necho hello",
+	Metadata: ec2.Metadata{
+		InstanceID:   "instance-id",
+		InstanceType: "c3.small.x86",
+		Hostname:     "hostname",
+		Tags:         []string{"foo", "bar"},
+		PublicKeys:   []string{"ssh-rsa key1", "ssh-ed25519 key2"},
+		Placement:    ec2.Placement{AvailabilityZone: "sv15"},
+	},
+}
+
+func TestRecursive(t *testing.T) {
+	expect := Metadata{
+		Instance: Instance{
+			Attributes: map[string]string{
+				"ssh-keys":       SSHKeysUser + ":ssh-rsa key1\n" + SSHKeysUser + ":ssh-ed25519 key2",
+				"startup-script": "#!/bin/sh\necho hello",
+			},
+			Hostname:    "hostname",
+			ID:          "instance-id",
+			MachineType: "c3.small.x86",
+			Tags:        []string{"foo", "bar"},
+			Zone:        "sv15",
+		},
+	}
+
+	w := serve(t, "/computeMetadata/v1/?recursive=true")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	var received Metadata
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Recursive requests for subdirectories are served the subdirectory document.
+	w = serve(t, "/computeMetadata/v1/instance/attributes/?recursive=true")
+
+	var attributes map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &attributes); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(expect.Instance.Attributes, attributes); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Path         string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "Root",
+			Path:         "/computeMetadata/v1/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "instance/",
+		},
+		{
+			Name:         "Instance",
+			Path:         "/computeMetadata/v1/instance/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "attributes/\nhostname\nid\nmachine-type\ntags\nzone",
+		},
+		{
+			Name:         "Hostname",
+			Path:         "/computeMetadata/v1/instance/hostname",
+			ExpectedCode: http.StatusOK,
+			Expect:       "hostname",
+		},
+		{
+			Name:         "MachineType",
+			Path:         "/computeMetadata/v1/instance/machine-type",
+			ExpectedCode: http.StatusOK,
+			Expect:       "c3.small.x86",
+		},
+		{
+			Name:         "Tags",
+			Path:         "/computeMetadata/v1/instance/tags",
+			ExpectedCode: http.StatusOK,
+			Expect:       `["foo","bar"]`,
+		},
+		{
+			Name:         "Attributes",
+			Path:         "/computeMetadata/v1/instance/attributes/",
+			ExpectedCode: http.StatusOK,
+			Expect:       "ssh-keys\nstartup-script",
+		},
+		{
+			Name:         "SSHKeys",
+			Path:         "/computeMetadata/v1/instance/attributes/ssh-keys",
+			ExpectedCode: http.StatusOK,
+			Expect:       SSHKeysUser + ":ssh-rsa key1\n" + SSHKeysUser + ":ssh-ed25519 key2",
+		},
+		{
+			Name:         "StartupScript",
+			Path:         "/computeMetadata/v1/instance/attributes/startup-script",
+			ExpectedCode: http.StatusOK,
+			Expect:       "#!/bin/sh\necho hello",
+		},
+		{
+			Name:         "UnknownAttribute",
+			Path:         "/computeMetadata/v1/instance/attributes/user-data",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "BeneathLeaf",
+			Path:         "/computeMetadata/v1/instance/hostname/foo",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := serve(t, tc.Path)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if flavor := w.Header().Get(FlavorHeader); flavor != FlavorGoogle {
+				t.Fatalf("Expected %v header: %v; Received: %v", FlavorHeader, FlavorGoogle, flavor)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %s; Received: %s", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequiresFlavorHeader(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Flavor string
+	}{
+		{Name: "Missing"},
+		{Name: "Wrong", Flavor: "Azure"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// The backend shouldn't be queried for requests without the header.
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+
+			router := gin.New()
+			Configure(router, client)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/computeMetadata/v1/instance/hostname", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+			if tc.Flavor != "" {
+				r.Header.Set(FlavorHeader, tc.Flavor)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("Expected status: 403; Received: %v", w.Code)
+			}
+		})
+	}
+}
+
+func TestInstanceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/computeMetadata/v1/instance/hostname", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+	r.Header.Set(FlavorHeader, FlavorGoogle)
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status: 404; Received: %v", w.Code)
+	}
+}
+
+func serve(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), "10.10.10.10").
+		Return(instance, nil)
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = "10.10.10.10:0"
+	r.Header.Set(FlavorHeader, FlavorGoogle)
+
+	router.ServeHTTP(w, r)
+
+	return w
+}
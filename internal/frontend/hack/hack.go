@@ -12,6 +12,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
 	"github.com/tinkerbell/hegel/internal/http/request"
 )
 
@@ -58,9 +59,9 @@ func Configure(router gin.IRouter, client Client) {
 			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote address"))
 		}
 
-		instance, err := client.GetHackInstance(ctx, ip)
+		instance, err := client.GetHackInstance(ctx.Request.Context(), ip)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			clienterror.Abort(ctx, err)
 			return
 		}
 
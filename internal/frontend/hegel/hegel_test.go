@@ -0,0 +1,129 @@
+package hegel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/hegel"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMetadata(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Instance ec2.Instance
+		Golden   string
+	}{
+		{
+			Name: "Full",
+			Instance: ec2.Instance{
+				Userdata:   "#cloud-config",
+				Vendordata: "vendordata",
+				Metadata: ec2.Metadata{
+					InstanceID: "instance-id",
+					Hostname:   "hostname",
+					PublicKeys: []string{"ssh-rsa key1", "ssh-rsa key2"},
+					OperatingSystem: ec2.OperatingSystem{
+						Slug:     "ubuntu_20_04",
+						Distro:   "ubuntu",
+						Version:  "20.04",
+						ImageTag: "image-tag",
+					},
+				},
+			},
+			Golden: "full.json",
+		},
+		{
+			Name:   "Minimal",
+			Golden: "minimal.json",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.10").Return(tc.Instance, nil)
+
+			router := gin.New()
+			Configure(router, client)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status: 200; Received: %v", w.Code)
+			}
+
+			expect, err := os.ReadFile(filepath.Join("testdata", tc.Golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var received bytes.Buffer
+			if err := json.Indent(&received, w.Body.Bytes(), "", "  "); err != nil {
+				t.Fatal(err)
+			}
+			received.WriteString("\n")
+
+			if received.String() != string(expect) {
+				t.Fatalf("Expected:\n%s\nReceived:\n%s", expect, received.String())
+			}
+		})
+	}
+}
+
+func TestMetadataErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Error  error
+		Status int
+	}{
+		{
+			Name:   "NotFound",
+			Error:  ec2.ErrInstanceNotFound,
+			Status: http.StatusNotFound,
+		},
+		{
+			Name:   "BackendError",
+			Error:  errors.New("backend error"),
+			Status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().GetEC2Instance(gomock.Any(), gomock.Any()).Return(ec2.Instance{}, tc.Error)
+
+			router := gin.New()
+			Configure(router, client)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.Status {
+				t.Fatalf("Expected status: %v; Received: %v", tc.Status, w.Code)
+			}
+		})
+	}
+}
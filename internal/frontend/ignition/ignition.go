@@ -0,0 +1,113 @@
+/*
+Package ignition contains a frontend that serves an Ignition config for operating systems, such as
+Flatcar Container Linux and Fedora CoreOS, that consume Ignition rather than EC2 instance metadata.
+*/
+package ignition
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/coreos/ignition/v2/config/v3_3/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// UserdataPath is the path userdata that isn't an Ignition config is written to.
+const UserdataPath = "/etc/hegel/user-data"
+
+// User is the user public keys are authorized for.
+const User = "core"
+
+// userdataMode is the userdata file mode. Userdata commonly contains secrets so it's only
+// readable by root.
+const userdataMode = 0o600
+
+// Client is a backend for retrieving instance data.
+type Client interface {
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+}
+
+// Configure configures router with an `/ignition` endpoint using client to retrieve instance data.
+func Configure(router gin.IRouter, client Client) {
+	router.GET("/ignition", func(ctx *gin.Context) {
+		ip, err := request.RemoteAddrIP(ctx.Request)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote address"))
+			return
+		}
+
+		instance, err := client.GetEC2Instance(ctx.Request.Context(), ip)
+		if err != nil {
+			clienterror.Abort(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, ToConfig(instance))
+	})
+}
+
+// ToConfig converts instance to a minimal Ignition v3 config. Public keys are authorized for the
+// User. Userdata that is an Ignition v3 config is merged into the returned config, other userdata
+// is written to UserdataPath.
+func ToConfig(instance ec2.Instance) types.Config {
+	cfg := types.Config{
+		Ignition: types.Ignition{
+			Version: types.MaxVersion.String(),
+		},
+	}
+
+	if len(instance.Metadata.PublicKeys) > 0 {
+		user := types.PasswdUser{Name: User}
+		for _, key := range instance.Metadata.PublicKeys {
+			user.SSHAuthorizedKeys = append(user.SSHAuthorizedKeys, types.SSHAuthorizedKey(key))
+		}
+		cfg.Passwd.Users = append(cfg.Passwd.Users, user)
+	}
+
+	switch {
+	case instance.Userdata == "":
+	case isIgnitionV3(instance.Userdata):
+		cfg.Ignition.Config.Merge = append(cfg.Ignition.Config.Merge, types.Resource{
+			Source: dataURL(instance.Userdata),
+		})
+	default:
+		mode := userdataMode
+		cfg.Storage.Files = append(cfg.Storage.Files, types.File{
+			Node: types.Node{Path: UserdataPath},
+			FileEmbedded1: types.FileEmbedded1{
+				Contents: types.Resource{Source: dataURL(instance.Userdata)},
+				Mode:     &mode,
+			},
+		})
+	}
+
+	return cfg
+}
+
+// isIgnitionV3 determines if userdata is an Ignition v3 config.
+func isIgnitionV3(userdata string) bool {
+	var cfg struct {
+		Ignition struct {
+			Version string `json:"version"`
+		} `json:"ignition"`
+	}
+
+	if err := json.Unmarshal([]byte(userdata), &cfg); err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(cfg.Ignition.Version, "3.")
+}
+
+// dataURL encodes data as an RFC 2397 data URL.
+func dataURL(data string) *string {
+	url := "data:;base64," + base64.StdEncoding.EncodeToString([]byte(data))
+	return &url
+}
@@ -0,0 +1,159 @@
+package ignition_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v3_3 "github.com/coreos/ignition/v2/config/v3_3"
+	"github.com/coreos/ignition/v2/config/v3_3/types"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/ignition"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestIgnition(t *testing.T) {
+	const ignitionUserdata = `{"ignition":{"version":"3.0.0"}}`
+
+	cases := []struct {
+		Name     string
+		Instance ec2.Instance
+		Validate func(t *testing.T, cfg types.Config)
+	}{
+		{
+			Name: "Empty",
+			Validate: func(t *testing.T, cfg types.Config) {
+				if len(cfg.Passwd.Users) != 0 || len(cfg.Storage.Files) != 0 || len(cfg.Ignition.Config.Merge) != 0 {
+					t.Fatalf("Expected empty config; Received: %+v", cfg)
+				}
+			},
+		},
+		{
+			Name: "PublicKeys",
+			Instance: ec2.Instance{
+				Metadata: ec2.Metadata{
+					PublicKeys: []string{"ssh-rsa foo", "ssh-ed25519 bar"},
+				},
+			},
+			Validate: func(t *testing.T, cfg types.Config) {
+				expect := []types.PasswdUser{
+					{
+						Name:              User,
+						SSHAuthorizedKeys: []types.SSHAuthorizedKey{"ssh-rsa foo", "ssh-ed25519 bar"},
+					},
+				}
+				if diff := cmp.Diff(expect, cfg.Passwd.Users); diff != "" {
+					t.Fatal(diff)
+				}
+			},
+		},
+		{
+			Name: "Userdata",
+			Instance: ec2.Instance{
+				Userdata: "#cloud-config",
+			},
+			Validate: func(t *testing.T, cfg types.Config) {
+				if len(cfg.Storage.Files) != 1 {
+					t.Fatalf("Expected 1 file; Received: %+v", cfg.Storage.Files)
+				}
+
+				file := cfg.Storage.Files[0]
+				if file.Path != UserdataPath {
+					t.Fatalf("Expected path: %v; Received: %v", UserdataPath, file.Path)
+				}
+				expectSource(t, file.Contents.Source, "#cloud-config")
+
+				if len(cfg.Ignition.Config.Merge) != 0 {
+					t.Fatalf("Expected no merged configs; Received: %+v", cfg.Ignition.Config.Merge)
+				}
+			},
+		},
+		{
+			Name: "IgnitionUserdata",
+			Instance: ec2.Instance{
+				Userdata: ignitionUserdata,
+			},
+			Validate: func(t *testing.T, cfg types.Config) {
+				if len(cfg.Ignition.Config.Merge) != 1 {
+					t.Fatalf("Expected 1 merged config; Received: %+v", cfg.Ignition.Config.Merge)
+				}
+				expectSource(t, cfg.Ignition.Config.Merge[0].Source, ignitionUserdata)
+
+				if len(cfg.Storage.Files) != 0 {
+					t.Fatalf("Expected no files; Received: %+v", cfg.Storage.Files)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.10").
+				Return(tc.Instance, nil)
+
+			router := gin.New()
+			Configure(router, client)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/ignition", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status: 200; Received: %v", w.Code)
+			}
+
+			// Parse validates the config against the Ignition spec.
+			cfg, rpt, err := v3_3.Parse(w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("Invalid Ignition config: %v\n%v\n%s", err, rpt, w.Body.String())
+			}
+			if rpt.IsFatal() {
+				t.Fatalf("Invalid Ignition config: %v\n%s", rpt, w.Body.String())
+			}
+
+			tc.Validate(t, cfg)
+		})
+	}
+}
+
+func TestIgnitionInstanceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/ignition", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status: 404; Received: %v", w.Code)
+	}
+}
+
+func expectSource(t *testing.T, source *string, data string) {
+	t.Helper()
+
+	expect := "data:;base64," + base64.StdEncoding.EncodeToString([]byte(data))
+	if source == nil || *source != expect {
+		t.Fatalf("Expected source: %v; Received: %v", expect, source)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Package instances contains a frontend that serves instances by their instance ID for downstream
+tooling that knows an instance's ID but not its IP. The instance is served as the same JSON document
+as the EC2 frontend's meta-data.json endpoint.
+
+Controllers that need several instances at once can POST a JSON array of IPs to
+`/instances:batch`. The response maps each IP to its instance or, when it can't be retrieved, the
+status a lookup of that IP alone would have received.
+
+Instances are served to any requester so the frontend shouldn't be configured when instance data
+must only be served to the instance itself.
+*/
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
+)
+
+// Client is a backend for retrieving instance data by instance ID.
+type Client interface {
+	// GetEC2InstanceByID retrieves the Instance whose instance ID is id. If no Instance can be
+	// found, it should return ec2.ErrInstanceNotFound.
+	GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error)
+}
+
+// IPClient is a backend for retrieving instance data by IP.
+type IPClient interface {
+	// GetEC2Instance retrieves the Instance associated with ip. If no Instance can be found, it
+	// should return ec2.ErrInstanceNotFound.
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+}
+
+// DefaultMaxBatchSize is the default maximum number of IPs in a batch request.
+const DefaultMaxBatchSize = 100
+
+// batchConcurrency bounds the lookups of a single batch request in flight so large batches don't
+// exhaust backend connections.
+const batchConcurrency = 8
+
+// BatchResult is the result of looking up a single IP of a batch request. Exactly one of Instance
+// and Error is set.
+type BatchResult struct {
+	Instance *ec2.Instance `json:"instance,omitempty"`
+
+	// Status is the HTTP status a request for the IP alone would have received.
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Configure configures router with an `/instances/:id` endpoint using client to retrieve instance
+// data.
+func Configure(router gin.IRouter, client Client) {
+	router.GET("/instances/:id", func(ctx *gin.Context) {
+		instance, err := client.GetEC2InstanceByID(ctx.Request.Context(), ctx.Param("id"))
+		if err != nil {
+			clienterror.Abort(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, instance)
+	})
+}
+
+// ConfigureBatch configures router with a POST `/instances:batch` endpoint using client to
+// retrieve instance data for up to maxSize IPs per request. The request body is a JSON array of
+// IPs and the response a JSON object mapping each IP to a BatchResult.
+func ConfigureBatch(router gin.IRouter, client IPClient, maxSize int) {
+	// Gin treats colons as the start of a path parameter so the action is matched as one and
+	// checked by the handler.
+	router.POST("/instances:action", func(ctx *gin.Context) {
+		if ctx.Param("action") != ":batch" {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		var ips []string
+		if err := ctx.ShouldBindJSON(&ips); err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("decode batch: %w", err))
+			return
+		}
+
+		if len(ips) > maxSize {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("batch of %v ips exceeds the maximum of %v", len(ips), maxSize),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, lookupBatch(ctx, client, ips))
+	})
+}
+
+// lookupBatch retrieves the instance for each of ips with at most batchConcurrency lookups in
+// flight. Unexpected errors are added to ctx so they're logged without being exposed to clients.
+func lookupBatch(ctx *gin.Context, client IPClient, ips []string) map[string]BatchResult {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]BatchResult, len(ips))
+		seen    = make(map[string]bool, len(ips))
+		sem     = make(chan struct{}, batchConcurrency)
+	)
+
+	for _, ip := range ips {
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+
+		if _, err := netip.ParseAddr(ip); err != nil {
+			mu.Lock()
+			results[ip] = errorResult(http.StatusBadRequest)
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ip string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result, err := lookup(ctx.Request.Context(), client, ip)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				_ = ctx.Error(err)
+			}
+			results[ip] = result
+		}(ip)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// lookup retrieves the instance for ip. Errors other than ec2.ErrInstanceNotFound are returned
+// alongside the result so they can be logged.
+func lookup(ctx context.Context, client IPClient, ip string) (BatchResult, error) {
+	instance, err := client.GetEC2Instance(ctx, ip)
+	switch {
+	case err == nil:
+		return BatchResult{Instance: &instance, Status: http.StatusOK}, nil
+	case errors.Is(err, ec2.ErrInstanceNotFound):
+		return errorResult(http.StatusNotFound), nil
+	default:
+		return errorResult(clienterror.StatusCode(err)), err
+	}
+}
+
+func errorResult(status int) BatchResult {
+	return BatchResult{Status: status, Error: http.StatusText(status)}
+}
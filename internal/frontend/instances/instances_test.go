@@ -0,0 +1,185 @@
+package instances_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/instances"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestInstances(t *testing.T) {
+	instance := ec2.Instance{
+		Userdata: "userdata",
+		Metadata: ec2.Metadata{
+			InstanceID: "instance-id",
+			Hostname:   "hostname",
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().GetEC2InstanceByID(gomock.Any(), "instance-id").Return(instance, nil)
+
+	w := serve(t, client, "/instances/instance-id")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	var received ec2.Instance
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(instance, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestInstancesErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Error  error
+		Status int
+	}{
+		{
+			Name:   "NotFound",
+			Error:  ec2.ErrInstanceNotFound,
+			Status: http.StatusNotFound,
+		},
+		{
+			Name:   "BackendError",
+			Error:  errors.New("backend error"),
+			Status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().GetEC2InstanceByID(gomock.Any(), "missing").Return(ec2.Instance{}, tc.Error)
+
+			w := serve(t, client, "/instances/missing")
+
+			if w.Code != tc.Status {
+				t.Fatalf("Expected status: %v; Received: %v", tc.Status, w.Code)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	instance := ec2.Instance{
+		Metadata: ec2.Metadata{
+			InstanceID: "instance-id",
+			LocalIPv4:  "10.10.10.10",
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	client := NewMockIPClient(ctrl)
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.10").Return(instance, nil)
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.11").Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.12").Return(ec2.Instance{}, errors.New("backend error"))
+
+	w := serveBatch(t, client, 10, `["10.10.10.10", "10.10.10.11", "10.10.10.12", "not-an-ip", "10.10.10.10"]`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	var received map[string]BatchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]BatchResult{
+		"10.10.10.10": {Instance: &instance, Status: http.StatusOK},
+		"10.10.10.11": {Status: http.StatusNotFound, Error: "Not Found"},
+		"10.10.10.12": {Status: http.StatusInternalServerError, Error: "Internal Server Error"},
+		"not-an-ip":   {Status: http.StatusBadRequest, Error: "Bad Request"},
+	}
+
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Path   string
+		Body   string
+		Status int
+	}{
+		{
+			Name:   "TooLarge",
+			Path:   "/instances:batch",
+			Body:   `["10.10.10.10", "10.10.10.11", "10.10.10.12"]`,
+			Status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			Name:   "Malformed",
+			Path:   "/instances:batch",
+			Body:   `{"ips": ["10.10.10.10"]}`,
+			Status: http.StatusBadRequest,
+		},
+		{
+			Name:   "UnknownAction",
+			Path:   "/instances:delete",
+			Body:   `["10.10.10.10"]`,
+			Status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			router := gin.New()
+			ConfigureBatch(router, NewMockIPClient(gomock.NewController(t)), 2)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.Path, strings.NewReader(tc.Body)))
+
+			if w.Code != tc.Status {
+				t.Fatalf("Expected status: %v; Received: %v", tc.Status, w.Code)
+			}
+		})
+	}
+}
+
+func serveBatch(t *testing.T, client IPClient, maxSize int, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := gin.New()
+	ConfigureBatch(router, client, maxSize)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/instances:batch", strings.NewReader(body)))
+
+	return w
+}
+
+func serve(t *testing.T, client Client, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return w
+}
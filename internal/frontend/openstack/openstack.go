@@ -0,0 +1,143 @@
+/*
+Package openstack contains a frontend that serves instance data in the OpenStack metadata service
+format for images built for OpenStack.
+
+	https://docs.openstack.org/nova/latest/user/metadata.html#openstack-format-metadata
+
+Only meta_data.json and user_data are supported. Every version is served the same data so clients
+requesting a dated version, such as 2012-08-10, are also supported.
+*/
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// Version is the latest OpenStack metadata version served. It's listed alongside "latest".
+const Version = "2012-08-10"
+
+// Client is a backend for retrieving instance data.
+type Client interface {
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+}
+
+// Configure configures router with the `/openstack` tree using client to retrieve instance data.
+func Configure(router gin.IRouter, client Client) {
+	router.GET("/openstack", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, Version+"\nlatest")
+	})
+
+	router.GET("/openstack/:version", func(ctx *gin.Context) {
+		if !isVersion(ctx.Param("version")) {
+			_ = ctx.AbortWithError(http.StatusNotFound, errors.New("unknown version"))
+			return
+		}
+		ctx.String(http.StatusOK, "meta_data.json\nuser_data")
+	})
+
+	router.GET("/openstack/:version/meta_data.json", func(ctx *gin.Context) {
+		instance, ok := getInstance(ctx, client)
+		if !ok {
+			return
+		}
+		ctx.JSON(http.StatusOK, ToMetadata(instance))
+	})
+
+	router.GET("/openstack/:version/user_data", func(ctx *gin.Context) {
+		instance, ok := getInstance(ctx, client)
+		if !ok {
+			return
+		}
+
+		// OpenStack responds with a 404 when an instance has no user data.
+		if instance.Userdata == "" {
+			_ = ctx.AbortWithError(http.StatusNotFound, errors.New("no user data"))
+			return
+		}
+
+		ctx.Data(http.StatusOK, "application/octet-stream", []byte(instance.Userdata))
+	})
+}
+
+func isVersion(version string) bool {
+	return version == "latest" || version == Version
+}
+
+// getInstance retrieves the instance for the requester. If it can't be retrieved, ctx is aborted
+// and getInstance returns false.
+func getInstance(ctx *gin.Context, client Client) (ec2.Instance, bool) {
+	if !isVersion(ctx.Param("version")) {
+		_ = ctx.AbortWithError(http.StatusNotFound, errors.New("unknown version"))
+		return ec2.Instance{}, false
+	}
+
+	ip, err := request.RemoteAddrIP(ctx.Request)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote address"))
+		return ec2.Instance{}, false
+	}
+
+	instance, err := client.GetEC2Instance(ctx.Request.Context(), ip)
+	if err != nil {
+		clienterror.Abort(ctx, err)
+		return ec2.Instance{}, false
+	}
+
+	return instance, true
+}
+
+// Metadata is the OpenStack meta_data.json document.
+type Metadata struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	Hostname         string            `json:"hostname"`
+	AvailabilityZone string            `json:"availability_zone"`
+	LaunchIndex      int               `json:"launch_index"`
+	PublicKeys       map[string]string `json:"public_keys"`
+	Keys             []Key             `json:"keys"`
+	Meta             map[string]string `json:"meta"`
+}
+
+// Key is part of Metadata.
+type Key struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// ToMetadata converts instance to an OpenStack meta_data.json document. Public keys are named by
+// their index, such as key-0. Tags of the form key=value are served as meta items; other tags are
+// served as meta items with an empty value.
+func ToMetadata(instance ec2.Instance) Metadata {
+	md := Metadata{
+		UUID:             instance.Metadata.InstanceID,
+		Name:             instance.Metadata.Hostname,
+		Hostname:         instance.Metadata.Hostname,
+		AvailabilityZone: instance.Metadata.Placement.AvailabilityZone,
+		PublicKeys:       map[string]string{},
+		Keys:             []Key{},
+		Meta:             map[string]string{},
+	}
+
+	for i, key := range instance.Metadata.PublicKeys {
+		name := fmt.Sprintf("key-%d", i)
+		md.PublicKeys[name] = key
+		md.Keys = append(md.Keys, Key{Name: name, Type: "ssh", Data: key})
+	}
+
+	for _, tag := range instance.Metadata.Tags {
+		key, value, _ := strings.Cut(tag, "=")
+		md.Meta[key] = value
+	}
+
+	return md
+}
@@ -0,0 +1,196 @@
+package openstack_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/openstack"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMetadata(t *testing.T) {
+	instance := ec2.Instance{
+		Userdata: "#cloud-config",
+		Metadata: ec2.Metadata{
+			InstanceID: "instance-id",
+			Hostname:   "hostname",
+			Tags:       []string{"role=worker", "gpu"},
+			PublicKeys: []string{"ssh-rsa key1", "ssh-rsa key2"},
+			Placement:  ec2.Placement{AvailabilityZone: "sv15"},
+		},
+	}
+
+	for _, version := range []string{"latest", Version} {
+		t.Run(version, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.10").Return(instance, nil)
+
+			w := serve(t, client, "/openstack/"+version+"/meta_data.json")
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status: 200; Received: %v", w.Code)
+			}
+
+			// Validate the document as a generic structure so field names and types are checked
+			// against what OpenStack clients expect.
+			var received map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+				t.Fatal(err)
+			}
+
+			expect := map[string]any{
+				"uuid":              "instance-id",
+				"name":              "hostname",
+				"hostname":          "hostname",
+				"availability_zone": "sv15",
+				"launch_index":      float64(0),
+				"public_keys": map[string]any{
+					"key-0": "ssh-rsa key1",
+					"key-1": "ssh-rsa key2",
+				},
+				"keys": []any{
+					map[string]any{"name": "key-0", "type": "ssh", "data": "ssh-rsa key1"},
+					map[string]any{"name": "key-1", "type": "ssh", "data": "ssh-rsa key2"},
+				},
+				"meta": map[string]any{
+					"role": "worker",
+					"gpu":  "",
+				},
+			}
+
+			if diff := cmp.Diff(expect, received); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestMetadataMinimal(t *testing.T) {
+	expect := Metadata{
+		PublicKeys: map[string]string{},
+		Keys:       []Key{},
+		Meta:       map[string]string{},
+	}
+
+	if diff := cmp.Diff(expect, ToMetadata(ec2.Instance{})); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestUserdata(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Userdata     string
+		ExpectedCode int
+	}{
+		{
+			Name:         "Userdata",
+			Userdata:     "#cloud-config\nhostname: foo",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "NoUserdata",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), gomock.Any()).
+				Return(ec2.Instance{Userdata: tc.Userdata}, nil)
+
+			w := serve(t, client, "/openstack/latest/user_data")
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Userdata {
+				t.Fatalf("Expected: %q; Received: %q", tc.Userdata, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListings(t *testing.T) {
+	cases := []struct {
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Path:         "/openstack",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: Version + "\nlatest",
+		},
+		{
+			Path:         "/openstack/latest",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "meta_data.json\nuser_data",
+		},
+		{
+			Path:         "/openstack/2000-01-01",
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Path:         "/openstack/2000-01-01/meta_data.json",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+
+			w := serve(t, client, tc.Path)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected: %q; Received: %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetadataInstanceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().GetEC2Instance(gomock.Any(), gomock.Any()).Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	w := serve(t, client, "/openstack/latest/meta_data.json")
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status: 404; Received: %v", w.Code)
+	}
+}
+
+func serve(t *testing.T, client Client, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	return w
+}
@@ -0,0 +1,202 @@
+/*
+Package rpc contains a gRPC frontend that serves instance data from the same backend as the HTTP
+frontends. Unlike the HTTP frontends, instances aren't identified by the caller's address so the
+API is intended for trusted clients such as operators and other Tinkerbell services.
+
+The service is defined in the metadatapb package.
+*/
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/rpc/metadatapb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultShutdownTimeout is the default duration in-flight RPCs are given to complete when
+// shutting down.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// Client is a backend for retrieving instance data.
+type Client interface {
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+	GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error)
+}
+
+// Server implements metadatapb.MetadataServiceServer using a Client.
+type Server struct {
+	metadatapb.UnimplementedMetadataServiceServer
+
+	client Client
+}
+
+// New creates a Server that retrieves instance data using client.
+func New(client Client) *Server {
+	return &Server{client: client}
+}
+
+// Register registers s with registrar.
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	metadatapb.RegisterMetadataServiceServer(registrar, s)
+}
+
+// GetInstance satisfies metadatapb.MetadataServiceServer. Exactly one of the request IP or MAC
+// must be specified.
+func (s *Server) GetInstance(
+	ctx context.Context,
+	req *metadatapb.GetInstanceRequest,
+) (*metadatapb.Instance, error) {
+	var (
+		instance ec2.Instance
+		err      error
+	)
+
+	switch {
+	case req.GetIp() != "" && req.GetMac() != "":
+		return nil, status.Error(codes.InvalidArgument, "only one of ip or mac may be specified")
+
+	case req.GetIp() != "":
+		if net.ParseIP(req.GetIp()) == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid ip: %v", req.GetIp())
+		}
+		instance, err = s.client.GetEC2Instance(ctx, req.GetIp())
+
+	case req.GetMac() != "":
+		mac, perr := net.ParseMAC(req.GetMac())
+		if perr != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid mac: %v", req.GetMac())
+		}
+		instance, err = s.client.GetEC2InstanceByMAC(ctx, mac.String())
+
+	default:
+		return nil, status.Error(codes.InvalidArgument, "one of ip or mac must be specified")
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ec2.ErrInstanceNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, status.Error(codes.DeadlineExceeded, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	return ToInstance(instance), nil
+}
+
+// Serve is a blocking call that serves the MetadataService on address using client. When ctx is
+// cancelled it will attempt to gracefully stop. If in-flight RPCs don't complete within
+// shutdownTimeout, they're forcibly cancelled and an error is returned.
+func Serve(
+	ctx context.Context,
+	logger logr.Logger,
+	address string,
+	client Client,
+	shutdownTimeout time.Duration,
+) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	New(client).Register(server)
+
+	errChan := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("Listening for gRPC on %s", listener.Addr()))
+		if err := server.Serve(listener); err != nil {
+			errChan <- err
+		}
+	}()
+
+	// Wait until we're told to shutdown.
+	select {
+	case <-ctx.Done():
+	case e := <-errChan:
+		return e
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+		logger.Info("Shutdown timeout expired, forcibly closing gRPC connections",
+			"address", address,
+			"timeout", shutdownTimeout,
+		)
+		return errors.New("timed out waiting for graceful gRPC shutdown")
+	}
+}
+
+// ToInstance converts an ec2.Instance to its protobuf representation.
+func ToInstance(instance ec2.Instance) *metadatapb.Instance {
+	md := instance.Metadata
+
+	interfaces := make([]*metadatapb.NetworkInterface, 0, len(md.Network.Interfaces))
+	for _, iface := range md.Network.Interfaces {
+		interfaces = append(interfaces, &metadatapb.NetworkInterface{
+			Mac:                 iface.MAC,
+			LocalIpv4S:          iface.LocalIPv4s,
+			SubnetIpv4CidrBlock: iface.SubnetIPv4CIDRBlock,
+			Gateway:             iface.Gateway,
+		})
+	}
+
+	return &metadatapb.Instance{
+		Userdata:   instance.Userdata,
+		Vendordata: instance.Vendordata,
+		Metadata: &metadatapb.Metadata{
+			InstanceId:    md.InstanceID,
+			InstanceType:  md.InstanceType,
+			Hostname:      md.Hostname,
+			LocalHostname: md.LocalHostname,
+			Iqn:           md.IQN,
+			Plan:          md.Plan,
+			Facility:      md.Facility,
+			Tags:          md.Tags,
+			PublicKeys:    md.PublicKeys,
+			PublicIpv4:    md.PublicIPv4,
+			PublicIpv6:    md.PublicIPv6,
+			LocalIpv4:     md.LocalIPv4,
+			OperatingSystem: &metadatapb.OperatingSystem{
+				Slug:     md.OperatingSystem.Slug,
+				Distro:   md.OperatingSystem.Distro,
+				Version:  md.OperatingSystem.Version,
+				ImageTag: md.OperatingSystem.ImageTag,
+				LicenseActivation: &metadatapb.LicenseActivation{
+					State: md.OperatingSystem.LicenseActivation.State,
+				},
+			},
+			Placement: &metadatapb.Placement{
+				AvailabilityZone: md.Placement.AvailabilityZone,
+				Region:           md.Placement.Region,
+			},
+			BlockDeviceMapping: &metadatapb.BlockDeviceMapping{
+				Root:      md.BlockDeviceMapping.Root,
+				Ephemeral: md.BlockDeviceMapping.Ephemeral,
+			},
+			Network: &metadatapb.Network{
+				Interfaces: interfaces,
+			},
+		},
+	}
+}
@@ -0,0 +1,182 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/rpc"
+	"github.com/tinkerbell/hegel/internal/frontend/rpc/metadatapb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestGetInstance(t *testing.T) {
+	instance := ec2.Instance{
+		Userdata:   "userdata",
+		Vendordata: "vendordata",
+		Metadata: ec2.Metadata{
+			InstanceID: "instance-id",
+			Hostname:   "hostname",
+			PublicKeys: []string{"ssh-rsa key"},
+			LocalIPv4:  "10.10.10.10",
+			Placement: ec2.Placement{
+				AvailabilityZone: "us-east-1a",
+				Region:           "us-east-1",
+			},
+			Network: ec2.Network{
+				Interfaces: []ec2.NetworkInterface{
+					{
+						MAC:                 "00:00:00:00:00:01",
+						LocalIPv4s:          []string{"10.10.10.10"},
+						SubnetIPv4CIDRBlock: "10.10.10.0/24",
+						Gateway:             "10.10.10.1",
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		Name    string
+		Request *metadatapb.GetInstanceRequest
+		Expect  func(*MockClient)
+	}{
+		{
+			Name:    "ByIP",
+			Request: &metadatapb.GetInstanceRequest{Ip: "10.10.10.10"},
+			Expect: func(client *MockClient) {
+				client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.10").Return(instance, nil)
+			},
+		},
+		{
+			Name:    "ByMAC",
+			Request: &metadatapb.GetInstanceRequest{Mac: "00-00-00-00-00-01"},
+			Expect: func(client *MockClient) {
+				client.EXPECT().GetEC2InstanceByMAC(gomock.Any(), "00:00:00:00:00:01").Return(instance, nil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			tc.Expect(client)
+
+			received, err := newClient(t, client).GetInstance(context.Background(), tc.Request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(ToInstance(instance), received, protocmp.Transform()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if received.GetMetadata().GetNetwork().GetInterfaces()[0].GetGateway() != "10.10.10.1" {
+				t.Fatalf("Expected gateway: 10.10.10.1; Received: %v", received)
+			}
+		})
+	}
+}
+
+func TestGetInstanceErrors(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Request *metadatapb.GetInstanceRequest
+		Error   error
+		Code    codes.Code
+	}{
+		{
+			Name:    "NotFound",
+			Request: &metadatapb.GetInstanceRequest{Ip: "10.10.10.10"},
+			Error:   ec2.ErrInstanceNotFound,
+			Code:    codes.NotFound,
+		},
+		{
+			Name:    "DeadlineExceeded",
+			Request: &metadatapb.GetInstanceRequest{Ip: "10.10.10.10"},
+			Error:   context.DeadlineExceeded,
+			Code:    codes.DeadlineExceeded,
+		},
+		{
+			Name:    "BackendError",
+			Request: &metadatapb.GetInstanceRequest{Ip: "10.10.10.10"},
+			Error:   errors.New("backend error"),
+			Code:    codes.Internal,
+		},
+		{
+			Name:    "NoIdentifier",
+			Request: &metadatapb.GetInstanceRequest{},
+			Code:    codes.InvalidArgument,
+		},
+		{
+			Name:    "BothIdentifiers",
+			Request: &metadatapb.GetInstanceRequest{Ip: "10.10.10.10", Mac: "00:00:00:00:00:01"},
+			Code:    codes.InvalidArgument,
+		},
+		{
+			Name:    "InvalidIP",
+			Request: &metadatapb.GetInstanceRequest{Ip: "invalid"},
+			Code:    codes.InvalidArgument,
+		},
+		{
+			Name:    "InvalidMAC",
+			Request: &metadatapb.GetInstanceRequest{Mac: "invalid"},
+			Code:    codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			if tc.Error != nil {
+				client.EXPECT().GetEC2Instance(gomock.Any(), gomock.Any()).Return(ec2.Instance{}, tc.Error)
+			}
+
+			_, err := newClient(t, client).GetInstance(context.Background(), tc.Request)
+			if code := status.Code(err); code != tc.Code {
+				t.Fatalf("Expected code: %v; Received: %v (%v)", tc.Code, code, err)
+			}
+		})
+	}
+}
+
+// newClient serves a Server backed by client over an in-memory connection and returns a gRPC
+// client connected to it.
+func newClient(t *testing.T, client Client) metadatapb.MetadataServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	New(client).Register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return metadatapb.NewMetadataServiceClient(conn)
+}
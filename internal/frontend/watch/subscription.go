@@ -0,0 +1,59 @@
+package watch
+
+import (
+	"sync"
+
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// Subscription delivers instances to a single subscriber. It is intended for use by Client
+// implementations.
+//
+// Publishing never blocks. If the subscriber hasn't received the previously published instance
+// it is replaced so subscribers only ever observe the latest instance.
+type Subscription struct {
+	mu     sync.Mutex
+	closed bool
+	events chan ec2.Instance
+}
+
+// NewSubscription creates a new Subscription instance.
+func NewSubscription() *Subscription {
+	return &Subscription{
+		events: make(chan ec2.Instance, 1),
+	}
+}
+
+// Events returns the channel instances are delivered on.
+func (s *Subscription) Events() <-chan ec2.Instance {
+	return s.events
+}
+
+// Publish delivers instance to the subscriber. Publishing to a closed Subscription is a no-op.
+func (s *Subscription) Publish(instance ec2.Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	// Publish is the only writer and is serialized by s.mu so, once drained, the send cannot
+	// block.
+	select {
+	case <-s.events:
+	default:
+	}
+	s.events <- instance
+}
+
+// Close closes the events channel. It is safe to call Close multiple times.
+func (s *Subscription) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Package watch contains a frontend that streams an instance's metadata to clients as it changes
+using Server-Sent Events. It lets provisioning flows react to changes, such as userdata updates,
+without polling.
+*/
+package watch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/http/clienterror"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// Event is the name of the Server-Sent Event used to deliver instances.
+const Event = "instance"
+
+// Client is a backend for subscribing to instance changes.
+type Client interface {
+	// Subscribe returns a channel that receives the instance associated with ip followed by the
+	// instance each time it changes. The channel is closed when ctx is cancelled or the instance
+	// is removed. If no instance is associated with ip it returns ec2.ErrInstanceNotFound.
+	Subscribe(ctx context.Context, ip string) (<-chan ec2.Instance, error)
+}
+
+// Configure configures router with a `/metadata/events` endpoint using client to stream instance
+// data.
+func Configure(router gin.IRouter, client Client) {
+	router.GET("/metadata/events", func(ctx *gin.Context) {
+		ip, err := request.RemoteAddrIP(ctx.Request)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote address"))
+			return
+		}
+
+		// The request context is cancelled when the client disconnects which releases the
+		// subscription.
+		events, err := client.Subscribe(ctx.Request.Context(), ip)
+		if err != nil {
+			clienterror.Abort(ctx, err)
+			return
+		}
+
+		// The stream outlives the server's write timeout so the deadline is cleared. Writers that
+		// don't support deadlines have none to clear.
+		_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+
+		ctx.Stream(func(io.Writer) bool {
+			select {
+			case <-ctx.Request.Context().Done():
+				return false
+
+			case instance, ok := <-events:
+				if !ok {
+					return false
+				}
+				ctx.SSEvent(Event, instance)
+				return true
+			}
+		})
+	})
+}
@@ -0,0 +1,141 @@
+package watch_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/frontend/watch"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestEvents(t *testing.T) {
+	instances := []ec2.Instance{
+		{Userdata: "before"},
+		{Userdata: "after"},
+	}
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		Subscribe(gomock.Any(), "127.0.0.1").
+		DoAndReturn(func(context.Context, string) (<-chan ec2.Instance, error) {
+			events := make(chan ec2.Instance, len(instances))
+			for _, instance := range instances {
+				events <- instance
+			}
+			close(events)
+			return events, nil
+		})
+
+	router := gin.New()
+	Configure(router, client)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metadata/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status: %v; Received: %v", http.StatusOK, resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected content type: text/event-stream; Received: %v", ct)
+	}
+
+	// The stream ends when the events channel is closed.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expect string
+	for _, instance := range instances {
+		data, err := json.Marshal(instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect += "event:" + Event + "\ndata:" + string(data) + "\n\n"
+	}
+
+	if string(body) != expect {
+		t.Fatalf("Expected:\n%s\nReceived:\n%s", expect, body)
+	}
+}
+
+func TestEventsClientDisconnect(t *testing.T) {
+	cancelled := make(chan struct{})
+
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		Subscribe(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ string) (<-chan ec2.Instance, error) {
+			events := make(chan ec2.Instance, 1)
+			events <- ec2.Instance{Userdata: "userdata"}
+			go func() {
+				<-ctx.Done()
+				close(cancelled)
+			}()
+			return events, nil
+		})
+
+	router := gin.New()
+	Configure(router, client)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metadata/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the first event so we know the stream is established before disconnecting.
+	if _, err := resp.Body.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for subscription context to be cancelled")
+	}
+}
+
+func TestEventsInstanceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().
+		Subscribe(gomock.Any(), gomock.Any()).
+		Return(nil, ec2.ErrInstanceNotFound)
+
+	router := gin.New()
+	Configure(router, client)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/metadata/events", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status: %v; Received: %v", http.StatusNotFound, w.Code)
+	}
+}
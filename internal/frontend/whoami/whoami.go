@@ -0,0 +1,29 @@
+/*
+Package whoami contains an endpoint that reports the IP Hegel attributes to a request. Instances
+are identified by that IP so operators can use it to confirm trusted proxies are configured
+correctly.
+*/
+package whoami
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// Configure configures router with a /whoami endpoint that responds with the requester's IP in
+// plain text. The IP reflects any rewriting performed by X-Forwarded-For middleware so the
+// endpoint should be served after it.
+func Configure(router gin.IRouter) {
+	router.GET("/whoami", func(ctx *gin.Context) {
+		ip, err := request.RemoteAddrIP(ctx.Request)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, errors.New("invalid remote addr"))
+			return
+		}
+
+		ctx.String(http.StatusOK, ip)
+	})
+}
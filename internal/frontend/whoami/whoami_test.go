@@ -0,0 +1,87 @@
+package whoami_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/frontend/whoami"
+	"github.com/tinkerbell/hegel/internal/xff"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestWhoami(t *testing.T) {
+	cases := []struct {
+		Name         string
+		RemoteAddr   string
+		XFFAddr      string
+		ExpectedCode int
+		Expect       string
+	}{
+		{
+			Name:         "TrustedProxy",
+			RemoteAddr:   "192.168.0.1:8080",
+			XFFAddr:      "10.10.10.10",
+			ExpectedCode: http.StatusOK,
+			Expect:       "10.10.10.10",
+		},
+		{
+			Name:         "TrustedProxyWithoutHeader",
+			RemoteAddr:   "192.168.0.1:8080",
+			ExpectedCode: http.StatusOK,
+			Expect:       "192.168.0.1",
+		},
+		{
+			Name:         "UntrustedPeer",
+			RemoteAddr:   "10.10.10.11:8080",
+			XFFAddr:      "10.10.10.10",
+			ExpectedCode: http.StatusOK,
+			Expect:       "10.10.10.11",
+		},
+		{
+			Name:         "IPv6",
+			RemoteAddr:   "[2001:db8::1]:8080",
+			ExpectedCode: http.StatusOK,
+			Expect:       "2001:db8::1",
+		},
+		{
+			Name:         "InvalidRemoteAddr",
+			RemoteAddr:   "invalid",
+			ExpectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			xffmw, err := xff.Middleware([]string{"192.168.0.0/16"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := gin.New()
+			router.Use(xffmw)
+			Configure(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			r.RemoteAddr = tc.RemoteAddr
+			if tc.XFFAddr != "" {
+				r.Header.Set("X-Forwarded-For", tc.XFFAddr)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != tc.Expect {
+				t.Fatalf("Expected: %q; Received: %q", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
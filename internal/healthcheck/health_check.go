@@ -14,6 +14,10 @@ import (
 type Client interface {
 	// IsHealthy returns true if the backend is healthy, else false.
 	IsHealthy(context.Context) bool
+
+	// IsReady returns true if the backend is ready to serve requests, else false. Backends that
+	// cache data should return false until the initial cache population completes.
+	IsReady(context.Context) bool
 }
 
 // NewHandler returns a gin.HandlerFunc that provides a health check endpoint behavior. On each
@@ -43,3 +47,25 @@ func NewHandler(client Client) gin.HandlerFunc {
 		ctx.JSON(status, res)
 	}
 }
+
+// NewReadinessHandler returns a gin.HandlerFunc that provides a readiness check endpoint
+// behavior. On each request it queries client.IsReady and returns a 200 if the backend is ready,
+// else a 503.
+func NewReadinessHandler(client Client) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		isReady := client.IsReady(ctx)
+
+		res := struct {
+			Ready bool `json:"ready"`
+		}{
+			Ready: isReady,
+		}
+
+		status := http.StatusOK
+		if !isReady {
+			status = http.StatusServiceUnavailable
+		}
+
+		ctx.JSON(status, res)
+	}
+}
@@ -56,3 +56,49 @@ func TestHealthCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestReadinessCheck(t *testing.T) {
+	cases := []struct {
+		Name         string
+		ExpectedCode int
+		GetClient    func(*gomock.Controller) Client
+	}{
+		{
+			Name:         "ClientIsReady",
+			ExpectedCode: http.StatusOK,
+			GetClient: func(ctrl *gomock.Controller) Client {
+				client := NewMockClient(ctrl)
+				client.EXPECT().IsReady(gomock.Any()).Return(true)
+				return client
+			},
+		},
+		{
+			Name:         "ClientCacheNotSynced",
+			ExpectedCode: http.StatusServiceUnavailable,
+			GetClient: func(ctrl *gomock.Controller) Client {
+				client := NewMockClient(ctrl)
+				client.EXPECT().IsReady(gomock.Any()).Return(false)
+				return client
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			client := tc.GetClient(ctrl)
+
+			w := ginutil.FakeResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+			ctx := &gin.Context{Writer: w}
+
+			handler := NewReadinessHandler(client)
+
+			handler(ctx)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status code: %d; Received status code: %d", tc.ExpectedCode, w.Code)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package healthcheck
 
 import "github.com/gin-gonic/gin"
 
-// Configure configures router with a /healthz endpoint using a handler created with NewHandler.
+// Configure configures router with a /healthz endpoint using a handler created with NewHandler
+// and a /readyz endpoint using a handler created with NewReadinessHandler.
 func Configure(router gin.IRouter, client Client) {
 	router.GET("/healthz", NewHandler(client))
+	router.GET("/readyz", NewReadinessHandler(client))
 }
@@ -0,0 +1,87 @@
+/*
+Package auth contains a middleware that authenticates requests before they're served.
+
+Schemes are implemented as an Authenticator so operators can plug in the one their environment
+requires, such as mTLS or HMAC signed requests. None, which accepts every request, and Token, which
+requires a bearer token, are provided.
+*/
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrUnauthenticated is returned by Authenticators when a request doesn't carry valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator authenticates requests.
+type Authenticator interface {
+	// Authenticate returns an error if r isn't authenticated. It must not consume r's body.
+	Authenticate(r *http.Request) error
+}
+
+// Challenger is optionally implemented by Authenticators to tell clients the scheme they expect.
+type Challenger interface {
+	// Challenge returns the WWW-Authenticate header value sent with 401 responses.
+	Challenge() string
+}
+
+// Middleware creates a gin middleware that authenticates requests with authenticator. Requests
+// that fail authentication receive a 401 Unauthorized, with a WWW-Authenticate header when
+// authenticator is a Challenger, and aren't passed to the next handler.
+func Middleware(authenticator Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := authenticator.Authenticate(ctx.Request); err != nil {
+			if challenger, ok := authenticator.(Challenger); ok {
+				ctx.Header("WWW-Authenticate", challenger.Challenge())
+			}
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// None is an Authenticator that accepts every request.
+type None struct{}
+
+// Authenticate satisfies Authenticator.
+func (None) Authenticate(*http.Request) error {
+	return nil
+}
+
+// Token is an Authenticator that requires requests to carry a shared token as a bearer token in
+// the Authorization header.
+type Token struct {
+	token []byte
+}
+
+// NewToken creates a Token authenticator accepting requests that carry token.
+func NewToken(token string) *Token {
+	return &Token{token: []byte(token)}
+}
+
+// Authenticate satisfies Authenticator. It returns ErrUnauthenticated if r doesn't carry the token.
+func (t *Token) Authenticate(r *http.Request) error {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ErrUnauthenticated
+	}
+
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), t.token) != 1 {
+		return ErrUnauthenticated
+	}
+
+	return nil
+}
+
+// Challenge satisfies Challenger.
+func (*Token) Challenge() string {
+	return "Bearer"
+}
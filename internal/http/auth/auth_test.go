@@ -0,0 +1,108 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/http/auth"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestToken(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Authorization string
+		Authenticated bool
+	}{
+		{Name: "Valid", Authorization: "Bearer secret", Authenticated: true},
+		{Name: "CaseInsensitiveScheme", Authorization: "bearer secret", Authenticated: true},
+		{Name: "Missing"},
+		{Name: "WrongToken", Authorization: "Bearer wrong"},
+		{Name: "TokenPrefix", Authorization: "Bearer secre"},
+		{Name: "WrongScheme", Authorization: "Basic secret"},
+		{Name: "NoScheme", Authorization: "secret"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.Authorization != "" {
+				r.Header.Set("Authorization", tc.Authorization)
+			}
+
+			err := NewToken("secret").Authenticate(r)
+
+			if tc.Authenticated && err != nil {
+				t.Fatalf("Expected request to be authenticated; Received: %v", err)
+			}
+			if !tc.Authenticated && !errors.Is(err, ErrUnauthenticated) {
+				t.Fatalf("Expected: %v; Received: %v", ErrUnauthenticated, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		Name              string
+		Authenticator     Authenticator
+		Authorization     string
+		ExpectedCode      int
+		ExpectedChallenge string
+	}{
+		{
+			Name:          "None",
+			Authenticator: None{},
+			ExpectedCode:  http.StatusOK,
+		},
+		{
+			Name:          "TokenAccepted",
+			Authenticator: NewToken("secret"),
+			Authorization: "Bearer secret",
+			ExpectedCode:  http.StatusOK,
+		},
+		{
+			Name:              "TokenRejected",
+			Authenticator:     NewToken("secret"),
+			Authorization:     "Bearer wrong",
+			ExpectedCode:      http.StatusUnauthorized,
+			ExpectedChallenge: "Bearer",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Middleware(tc.Authenticator))
+			router.GET("/meta-data", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "hostname")
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/meta-data", nil)
+			if tc.Authorization != "" {
+				r.Header.Set("Authorization", tc.Authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if challenge := w.Header().Get("WWW-Authenticate"); challenge != tc.ExpectedChallenge {
+				t.Fatalf("Expected challenge: %q; Received: %q", tc.ExpectedChallenge, challenge)
+			}
+
+			if tc.ExpectedCode == http.StatusOK && w.Body.String() != "hostname" {
+				t.Fatalf("Expected body: hostname; Received: %v", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Package clienterror responds to requests when instance data can't be retrieved from a client.
+package clienterror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// StatusCode returns the HTTP status code that best represents err returned by a client. Missing
+// instances are not found, errors caused by the request deadline expiring are a gateway timeout
+// and all others are an internal server error.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ec2.ErrInstanceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Abort aborts ctx with err returned by a client using the status code from StatusCode.
+func Abort(ctx *gin.Context, err error) {
+	_ = ctx.AbortWithError(StatusCode(err), err)
+}
@@ -0,0 +1,59 @@
+package clienterror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/http/clienterror"
+)
+
+func TestAbort(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Error  error
+		Expect int
+	}{
+		{
+			Name:   "NotFound",
+			Error:  fmt.Errorf("lookup: %w", ec2.ErrInstanceNotFound),
+			Expect: http.StatusNotFound,
+		},
+		{
+			Name:   "DeadlineExceeded",
+			Error:  fmt.Errorf("lookup: %w", context.DeadlineExceeded),
+			Expect: http.StatusGatewayTimeout,
+		},
+		{
+			Name:   "Other",
+			Error:  errors.New("backend unavailable"),
+			Expect: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			Abort(ctx, tc.Error)
+
+			if w.Code != tc.Expect {
+				t.Fatalf("Expected status: %v; Received: %v", tc.Expect, w.Code)
+			}
+
+			if !ctx.IsAborted() {
+				t.Fatal("Expected context to be aborted")
+			}
+
+			if len(ctx.Errors) != 1 || !errors.Is(ctx.Errors[0], tc.Error) {
+				t.Fatalf("Expected error to be recorded: %v", ctx.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Package compress contains a middleware that gzip compresses responses.
+package compress
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMinSize is the default size, in bytes, a response must reach before it's compressed.
+// Smaller responses gain little from compression.
+const DefaultMinSize = 1024
+
+// Middleware creates a gin middleware that gzip compresses responses of at least minSize bytes
+// when the client accepts gzip. Responses are buffered until they reach minSize so smaller
+// responses, and responses to clients that don't accept gzip, are written unchanged. Every
+// response includes Vary: Accept-Encoding so caches don't serve compressed responses to clients
+// that don't accept them.
+//
+// Responses flushed before reaching minSize, such as event streams, aren't compressed.
+func Middleware(minSize int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(ctx.GetHeader("Accept-Encoding")) {
+			ctx.Next()
+			return
+		}
+
+		w := &writer{ResponseWriter: ctx.Writer, minSize: minSize}
+		ctx.Writer = w
+		defer func() {
+			w.finish()
+			ctx.Writer = w.ResponseWriter
+		}()
+
+		ctx.Next()
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header value accepts gzip.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		// A quality of 0 means the coding isn't acceptable.
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		if quality, err := strconv.ParseFloat(q, 64); err == nil && quality > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writer buffers the response until it reaches minSize and then decides whether to compress it.
+// Headers set by handlers aren't written until the decision is made so Content-Encoding can be
+// added.
+type writer struct {
+	gin.ResponseWriter
+	minSize int
+
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	if len(w.buf)+len(p) < w.minSize {
+		w.buf = append(w.buf, p...)
+		return len(p), nil
+	}
+
+	// Once the response reaches minSize the decision is made and p is written through rather than
+	// copied into the buffer; responses such as userdata can be several megabytes.
+	if err := w.compress(); err != nil {
+		return 0, err
+	}
+	return w.Write(p)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow writes the response uncompressed as its headers must be written before the
+// body size is known.
+func (w *writer) WriteHeaderNow() {
+	if !w.decided {
+		_ = w.passthrough()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *writer) Written() bool {
+	return w.ResponseWriter.Written() || len(w.buf) > 0
+}
+
+// Flush writes the response uncompressed when it hasn't reached minSize, as streams are flushed
+// as they're written, and flushes the compressed data otherwise.
+func (w *writer) Flush() {
+	if !w.decided {
+		_ = w.passthrough()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter so http.ResponseController can access it.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compress writes the buffered response compressed unless the handler already encoded it.
+func (w *writer) compress() error {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return w.passthrough()
+	}
+
+	w.decided = true
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+
+	// The compressed representation differs from the uncompressed one so a strong ETag no longer
+	// identifies it byte for byte.
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// passthrough writes the buffered response uncompressed.
+func (w *writer) passthrough() error {
+	w.decided = true
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// finish writes any buffered response and completes the compressed stream.
+func (w *writer) finish() {
+	if !w.decided {
+		_ = w.passthrough()
+		return
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
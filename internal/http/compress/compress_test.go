@@ -0,0 +1,235 @@
+package compress_test
+
+import (
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/http/compress"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat("#cloud-config\n", 100)
+	small := "#cloud-config\n"
+
+	cases := []struct {
+		Name           string
+		Body           string
+		AcceptEncoding string
+		Compressed     bool
+	}{
+		{
+			Name:           "Large",
+			Body:           large,
+			AcceptEncoding: "gzip",
+			Compressed:     true,
+		},
+		{
+			Name:           "LargeWithMultipleEncodings",
+			Body:           large,
+			AcceptEncoding: "deflate, gzip;q=0.5",
+			Compressed:     true,
+		},
+		{
+			Name:           "LargeWithWildcard",
+			Body:           large,
+			AcceptEncoding: "*",
+			Compressed:     true,
+		},
+		{
+			Name:           "Small",
+			Body:           small,
+			AcceptEncoding: "gzip",
+		},
+		{
+			Name: "GzipNotAccepted",
+			Body: large,
+		},
+		{
+			Name:           "GzipRejected",
+			Body:           large,
+			AcceptEncoding: "gzip;q=0",
+		},
+		{
+			Name:           "OtherEncoding",
+			Body:           large,
+			AcceptEncoding: "br",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Middleware(DefaultMinSize))
+			router.GET("/user-data", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, tc.Body)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/user-data", nil)
+			if tc.AcceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.AcceptEncoding)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status: 200; Received: %v", w.Code)
+			}
+
+			if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Fatalf("Expected Vary: Accept-Encoding; Received: %v", vary)
+			}
+
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+				t.Fatalf("Expected content type: text/plain; Received: %v", ct)
+			}
+
+			body := w.Body.String()
+			if tc.Compressed {
+				if encoding := w.Header().Get("Content-Encoding"); encoding != "gzip" {
+					t.Fatalf("Expected Content-Encoding: gzip; Received: %v", encoding)
+				}
+				body = gunzip(t, w.Body)
+			} else if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+				t.Fatalf("Expected no Content-Encoding; Received: %v", encoding)
+			}
+
+			if body != tc.Body {
+				t.Fatalf("Expected body: %q; Received: %q", tc.Body, body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWeakensETag(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(DefaultMinSize))
+	router.GET("/user-data", func(ctx *gin.Context) {
+		ctx.Header("ETag", `"abc"`)
+		ctx.String(http.StatusOK, strings.Repeat("a", DefaultMinSize))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/user-data", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	router.ServeHTTP(w, r)
+
+	if etag := w.Header().Get("ETag"); etag != `W/"abc"` {
+		t.Fatalf(`Expected ETag: W/"abc"; Received: %v`, etag)
+	}
+}
+
+func TestMiddlewareAlreadyEncoded(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(DefaultMinSize))
+	router.GET("/user-data", func(ctx *gin.Context) {
+		ctx.Header("Content-Encoding", "br")
+		ctx.String(http.StatusOK, strings.Repeat("a", DefaultMinSize))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/user-data", nil)
+	r.Header.Set("Accept-Encoding", "gzip, br")
+
+	router.ServeHTTP(w, r)
+
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "br" {
+		t.Fatalf("Expected Content-Encoding: br; Received: %v", encoding)
+	}
+
+	if w.Body.Len() != DefaultMinSize {
+		t.Fatalf("Expected uncompressed body of %v bytes; Received: %v", DefaultMinSize, w.Body.Len())
+	}
+}
+
+func TestMiddlewareFlushedStream(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(DefaultMinSize))
+	router.GET("/events", func(ctx *gin.Context) {
+		ctx.Header("Content-Type", "text/event-stream")
+		ctx.String(http.StatusOK, "data: event\n\n")
+		ctx.Writer.Flush()
+		ctx.String(http.StatusOK, strings.Repeat("a", DefaultMinSize))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	router.ServeHTTP(w, r)
+
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+		t.Fatalf("Expected no Content-Encoding; Received: %v", encoding)
+	}
+
+	if !strings.HasPrefix(w.Body.String(), "data: event\n\n") {
+		t.Fatalf("Expected uncompressed stream; Received: %q", w.Body.String())
+	}
+}
+
+func TestMiddlewareLargeResponseNotBuffered(t *testing.T) {
+	const chunks = 64
+
+	// Random data doesn't compress so the compressor writes its output as it goes.
+	body := make([]byte, 8<<20)
+	rand.New(rand.NewSource(1)).Read(body)
+	chunk := len(body) / chunks
+
+	w := httptest.NewRecorder()
+
+	// streamed is how much compressed output was written before the handler completed.
+	var streamed int
+
+	router := gin.New()
+	router.Use(Middleware(DefaultMinSize))
+	router.GET("/user-data", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+		for i := 0; i < chunks; i++ {
+			if _, err := ctx.Writer.Write(body[i*chunk : (i+1)*chunk]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		streamed = w.Body.Len()
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/user-data", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, r)
+
+	// Once the response reaches the minimum size it's compressed as it's written rather than
+	// buffered, so most of it reaches the client before the handler completes.
+	if streamed < len(body)/2 {
+		t.Fatalf("Expected at least %v bytes written before the handler completed; Received: %v", len(body)/2, streamed)
+	}
+
+	if received := gunzip(t, w.Body); received != string(body) {
+		t.Fatalf("Expected body of %v bytes; Received: %v", len(body), len(received))
+	}
+}
+
+func gunzip(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,74 @@
+// Package concurrency contains a middleware that limits the requests each client may have in
+// flight.
+package concurrency
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/http/request"
+)
+
+// Middleware creates a gin middleware that limits each client to limit requests in flight.
+// Requests exceeding the limit receive a 429 Too Many Requests. Streaming requests, such as
+// watches, count towards the limit for as long as they're open.
+//
+// Clients are identified by the request remote address IP so the middleware should be installed
+// after X-Forwarded-For middleware.
+func Middleware(limit int) gin.HandlerFunc {
+	return newLimiter(limit).handle
+}
+
+type limiter struct {
+	limit int
+
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+func newLimiter(limit int) *limiter {
+	return &limiter{
+		limit:    limit,
+		inflight: make(map[string]int),
+	}
+}
+
+func (l *limiter) handle(ctx *gin.Context) {
+	ip, err := request.RemoteAddrIP(ctx.Request)
+	if err != nil {
+		ip = ctx.Request.RemoteAddr
+	}
+
+	if !l.acquire(ip) {
+		ctx.AbortWithStatus(http.StatusTooManyRequests)
+		return
+	}
+	defer l.release(ip)
+
+	ctx.Next()
+}
+
+// acquire takes a slot for ip. It reports false if ip has no slots available.
+func (l *limiter) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight[ip] >= l.limit {
+		return false
+	}
+	l.inflight[ip]++
+	return true
+}
+
+// release returns a slot for ip. Clients are forgotten when they have no requests in flight so
+// memory is bounded by the number of clients with requests in flight.
+func (l *limiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight[ip]--
+	if l.inflight[ip] <= 0 {
+		delete(l.inflight, ip)
+	}
+}
@@ -0,0 +1,14 @@
+package concurrency
+
+import "github.com/gin-gonic/gin"
+
+// NewTestMiddleware is the same as Middleware but also returns a func reporting how many clients
+// the middleware is tracking.
+func NewTestMiddleware(limit int) (gin.HandlerFunc, func() int) {
+	l := newLimiter(limit)
+	return l.handle, func() int {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.inflight)
+	}
+}
@@ -0,0 +1,77 @@
+package concurrency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/http/concurrency"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	const limit = 2
+
+	middleware, clients := NewTestMiddleware(limit)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.Use(middleware)
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	router.GET("/held", func(ctx *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	serve := func(ip, path string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = ip + ":0"
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Hold limit requests from one client in flight.
+	var wg sync.WaitGroup
+	codes := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve("10.10.10.10", "/held")
+		}()
+		<-entered
+	}
+
+	if code := serve("10.10.10.10", "/"); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status for request above the limit: 429; Received: %v", code)
+	}
+
+	// Other clients have their own limit.
+	if code := serve("10.10.10.11", "/"); code != http.StatusOK {
+		t.Fatalf("Expected status for other client: 200; Received: %v", code)
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("Expected status for held requests: 200; Received: %v", code)
+		}
+	}
+
+	// Clients without requests in flight shouldn't be retained.
+	if n := clients(); n != 0 {
+		t.Fatalf("Expected no tracked clients once requests complete; Received: %v", n)
+	}
+}
@@ -0,0 +1,109 @@
+// Package cors contains a middleware that lets browser based tooling on other origins read
+// responses.
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AllOrigins permits every origin when used as an allowed origin.
+const AllOrigins = "*"
+
+// allowMethods are the methods endpoints are served with. PUT is used to issue session tokens.
+const allowMethods = "GET, HEAD, PUT"
+
+// Config configures Middleware.
+type Config struct {
+	// AllowOrigins are the origins, such as https://dashboard.example.com, permitted to read
+	// responses. AllOrigins permits every origin.
+	AllowOrigins []string
+
+	// AllowHeaders are the request headers browsers may send in addition to the CORS safelisted
+	// headers.
+	AllowHeaders []string
+
+	// MaxAge is how long browsers may cache preflight responses. When 0, browsers use their
+	// default.
+	MaxAge time.Duration
+}
+
+// ValidOrigin reports whether origin is AllOrigins or an origin such as
+// https://dashboard.example.com, without a path.
+func ValidOrigin(origin string) bool {
+	if origin == AllOrigins {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
+
+// Middleware creates a gin middleware that adds CORS headers to responses for requests from
+// cfg.AllowOrigins. Preflight requests, OPTIONS requests with an Access-Control-Request-Method
+// header, are answered with 204 No Content and not passed to the next handler. Requests from
+// other origins are served without CORS headers so browsers don't expose the response.
+//
+// Endpoints aren't registered for OPTIONS so the middleware should be applied to a group with an
+// OPTIONS route that preflight requests can match.
+func Middleware(cfg Config) gin.HandlerFunc {
+	allowAll := slices.Contains(cfg.AllowOrigins, AllOrigins)
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(ctx *gin.Context) {
+		// Responses differ by origin so caches must not serve one origin's response to another.
+		ctx.Writer.Header().Add("Vary", "Origin")
+
+		origin := ctx.GetHeader("Origin")
+		preflight := ctx.Request.Method == http.MethodOptions &&
+			ctx.GetHeader("Access-Control-Request-Method") != ""
+
+		if origin == "" || !(allowAll || allowed(cfg.AllowOrigins, origin)) {
+			if preflight {
+				ctx.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		if allowAll {
+			ctx.Header("Access-Control-Allow-Origin", AllOrigins)
+		} else {
+			ctx.Header("Access-Control-Allow-Origin", origin)
+		}
+
+		if !preflight {
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			ctx.Header("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			ctx.Header("Access-Control-Max-Age", maxAge)
+		}
+
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// allowed reports whether origin is one of origins. Scheme and host are case insensitive.
+func allowed(origins []string, origin string) bool {
+	return slices.ContainsFunc(origins, func(o string) bool {
+		return strings.EqualFold(o, origin)
+	})
+}
@@ -0,0 +1,165 @@
+package cors_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/http/cors"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		Name         string
+		AllowOrigins []string
+		Method       string
+		Origin       string
+		Preflight    bool
+		ExpectedCode int
+		ExpectedBody string
+		Expected     map[string]string
+	}{
+		{
+			Name:         "Simple",
+			AllowOrigins: []string{"https://dashboard.example.com"},
+			Method:       http.MethodGet,
+			Origin:       "https://dashboard.example.com",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "hostname",
+			Expected: map[string]string{
+				"Access-Control-Allow-Origin":  "https://dashboard.example.com",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			Name:         "SimpleAllOrigins",
+			AllowOrigins: []string{AllOrigins},
+			Method:       http.MethodGet,
+			Origin:       "https://dashboard.example.com",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "hostname",
+			Expected: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+		},
+		{
+			Name:         "SimpleDisallowedOrigin",
+			AllowOrigins: []string{"https://dashboard.example.com"},
+			Method:       http.MethodGet,
+			Origin:       "https://other.example.com",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "hostname",
+			Expected: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			Name:         "NoOrigin",
+			AllowOrigins: []string{AllOrigins},
+			Method:       http.MethodGet,
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "hostname",
+			Expected: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			Name:         "Preflight",
+			AllowOrigins: []string{"https://dashboard.example.com"},
+			Method:       http.MethodOptions,
+			Origin:       "https://dashboard.example.com",
+			Preflight:    true,
+			ExpectedCode: http.StatusNoContent,
+			Expected: map[string]string{
+				"Access-Control-Allow-Origin":  "https://dashboard.example.com",
+				"Access-Control-Allow-Methods": "GET, HEAD, PUT",
+				"Access-Control-Allow-Headers": "X-Hegel-MAC",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			Name:         "PreflightDisallowedOrigin",
+			AllowOrigins: []string{"https://dashboard.example.com"},
+			Method:       http.MethodOptions,
+			Origin:       "https://other.example.com",
+			Preflight:    true,
+			ExpectedCode: http.StatusNoContent,
+			Expected: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			router := gin.New()
+			group := router.Group("", Middleware(Config{
+				AllowOrigins: tc.AllowOrigins,
+				AllowHeaders: []string{"X-Hegel-MAC"},
+				MaxAge:       10 * time.Minute,
+			}))
+			group.GET("/meta-data/hostname", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "hostname")
+			})
+			group.OPTIONS("/*path", func(ctx *gin.Context) {
+				ctx.Status(http.StatusNotFound)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.Method, "/meta-data/hostname", nil)
+			if tc.Origin != "" {
+				r.Header.Set("Origin", tc.Origin)
+			}
+			if tc.Preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if w.Body.String() != tc.ExpectedBody {
+				t.Fatalf("Expected body: %q; Received: %q", tc.ExpectedBody, w.Body.String())
+			}
+
+			for header, expected := range tc.Expected {
+				if received := w.Header().Get(header); received != expected {
+					t.Fatalf("Expected %v: %q; Received: %q", header, expected, received)
+				}
+			}
+
+			if vary := w.Header().Get("Vary"); vary != "Origin" {
+				t.Fatalf("Expected Vary: Origin; Received: %v", vary)
+			}
+		})
+	}
+}
+
+func TestValidOrigin(t *testing.T) {
+	cases := map[string]bool{
+		"*":                                  true,
+		"https://dashboard.example.com":      true,
+		"http://localhost:3000":              true,
+		"dashboard.example.com":              false,
+		"https://dashboard.example.com/path": false,
+		"https://":                           false,
+		"":                                   false,
+	}
+
+	for origin, expect := range cases {
+		t.Run(origin, func(t *testing.T) {
+			if ValidOrigin(origin) != expect {
+				t.Fatalf("Expected ValidOrigin(%q): %v", origin, expect)
+			}
+		})
+	}
+}
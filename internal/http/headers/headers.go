@@ -0,0 +1,58 @@
+// Package headers contains a middleware that adds a static set of headers to responses, such as
+// security headers or Cache-Control.
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http/httpguts"
+)
+
+// defaults are the headers added to responses unless they're overridden. Responses are never
+// meant to be rendered by browsers so they're protected from content sniffing and framing.
+var defaults = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "DENY",
+}
+
+// Parse parses headers in the form `Name: value` into the set of headers added to responses. The
+// set includes the security defaults, which headers may override. A header with an empty value,
+// such as `X-Frame-Options:`, is removed from the set.
+func Parse(headers []string) (http.Header, error) {
+	set := http.Header{}
+	for name, value := range defaults {
+		set.Set(name, value)
+	}
+
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+		if !ok || !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("%q is not a header such as 'Cache-Control: no-store'", header)
+		}
+
+		if value == "" {
+			set.Del(name)
+			continue
+		}
+		set.Set(name, value)
+	}
+
+	return set, nil
+}
+
+// Middleware creates a gin middleware that sets headers on every response. They're set before
+// the next handler runs so handlers may replace them where a response requires it.
+func Middleware(headers http.Header) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		for name, values := range headers {
+			ctx.Writer.Header()[name] = slices.Clone(values)
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,128 @@
+package headers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/http/headers"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Headers  []string
+		Expected http.Header
+	}{
+		{
+			Name: "Defaults",
+			Expected: http.Header{
+				"X-Content-Type-Options": {"nosniff"},
+				"X-Frame-Options":        {"DENY"},
+			},
+		},
+		{
+			Name:    "Added",
+			Headers: []string{"Server: hegel", "cache-control:  no-store, max-age=0 "},
+			Expected: http.Header{
+				"X-Content-Type-Options": {"nosniff"},
+				"X-Frame-Options":        {"DENY"},
+				"Server":                 {"hegel"},
+				"Cache-Control":          {"no-store, max-age=0"},
+			},
+		},
+		{
+			Name:    "OverriddenAndRemoved",
+			Headers: []string{"X-Frame-Options: SAMEORIGIN", "X-Content-Type-Options:"},
+			Expected: http.Header{
+				"X-Frame-Options": {"SAMEORIGIN"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			headers, err := Parse(tc.Headers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.Expected, headers); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, header := range []string{"Server", ": hegel", "Bad Name: value", "Server: line\nbreak"} {
+		t.Run(header, func(t *testing.T) {
+			if _, err := Parse([]string{header}); err == nil {
+				t.Fatalf("Expected error for %q", header)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	headers, err := Parse([]string{"Server: hegel", "Cache-Control: no-store"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(Middleware(headers))
+	router.GET("/meta-data", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "hostname")
+	})
+	router.GET("/watch", func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.String(http.StatusOK, "hostname")
+	})
+	router.NoRoute(func(ctx *gin.Context) {
+		ctx.String(http.StatusNotFound, "not found")
+	})
+
+	cases := []struct {
+		Path     string
+		Expected map[string]string
+	}{
+		{
+			Path: "/meta-data",
+			Expected: map[string]string{
+				"Server":                 "hegel",
+				"Cache-Control":          "no-store",
+				"X-Content-Type-Options": "nosniff",
+				"X-Frame-Options":        "DENY",
+			},
+		},
+		{
+			// Handlers may replace configured headers.
+			Path:     "/watch",
+			Expected: map[string]string{"Server": "hegel", "Cache-Control": "no-cache"},
+		},
+		{
+			Path:     "/missing",
+			Expected: map[string]string{"Server": "hegel", "X-Content-Type-Options": "nosniff"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+
+			for name, expected := range tc.Expected {
+				if received := w.Header().Get(name); received != expected {
+					t.Fatalf("Expected %v: %q; Received: %q", name, expected, received)
+				}
+			}
+		})
+	}
+}
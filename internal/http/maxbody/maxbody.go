@@ -0,0 +1,35 @@
+// Package maxbody contains a middleware that bounds the size of request bodies.
+package maxbody
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLimit is the default maximum request body size in bytes. Hegel's endpoints don't expect
+// bodies so it's only large enough to accommodate clients that send one anyway.
+const DefaultLimit = 64 << 10
+
+// Middleware creates a gin middleware that rejects requests declaring a body larger than limit
+// bytes with a 413 Request Entity Too Large. Bodies of unknown length are limited with
+// http.MaxBytesReader so handlers reading past limit receive an *http.MaxBytesError and should
+// respond with a 413.
+func Middleware(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.ContentLength > limit {
+			_ = ctx.AbortWithError(
+				http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body of %d bytes exceeds limit of %d bytes", ctx.Request.ContentLength, limit),
+			)
+			return
+		}
+
+		if ctx.Request.Body != nil {
+			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		}
+
+		ctx.Next()
+	}
+}
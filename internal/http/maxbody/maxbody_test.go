@@ -0,0 +1,79 @@
+package maxbody_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/http/maxbody"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Body          string
+		ContentLength int64
+		ExpectedCode  int
+	}{
+		{
+			Name:         "NoBody",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:          "WithinLimit",
+			Body:          "0123456789",
+			ContentLength: 10,
+			ExpectedCode:  http.StatusOK,
+		},
+		{
+			Name:          "Oversized",
+			Body:          "01234567890",
+			ContentLength: 11,
+			ExpectedCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			// Bodies of unknown length, such as chunked bodies, are only rejected when read.
+			Name:          "OversizedUnknownLength",
+			Body:          "01234567890",
+			ContentLength: -1,
+			ExpectedCode:  http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Middleware(10))
+			router.PUT("/", func(ctx *gin.Context) {
+				if _, err := io.ReadAll(ctx.Request.Body); err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						ctx.Status(http.StatusRequestEntityTooLarge)
+						return
+					}
+					ctx.Status(http.StatusInternalServerError)
+					return
+				}
+				ctx.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.Body))
+			r.ContentLength = tc.ContentLength
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+		})
+	}
+}
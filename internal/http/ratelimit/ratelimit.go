@@ -0,0 +1,105 @@
+// Package ratelimit contains a middleware that limits the rate of requests from each client.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/http/request"
+	"golang.org/x/time/rate"
+)
+
+// sweepInterval is how often limiters for clients that are no longer making requests are removed.
+const sweepInterval = time.Minute
+
+// Middleware creates a gin middleware that limits each client to limit requests per second with
+// bursts of up to burst requests using a token bucket. Requests exceeding the limit receive a 429
+// Too Many Requests with a Retry-After header.
+//
+// Clients are identified by the request remote address IP so the middleware should be installed
+// after X-Forwarded-For middleware.
+func Middleware(limit float64, burst int) gin.HandlerFunc {
+	l := &limiter{
+		limit:   rate.Limit(limit),
+		burst:   burst,
+		clients: make(map[string]*client),
+	}
+
+	return func(ctx *gin.Context) {
+		ip, err := request.RemoteAddrIP(ctx.Request)
+		if err != nil {
+			ip = ctx.Request.RemoteAddr
+		}
+
+		if delay := l.reserve(ip); delay > 0 {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			ctx.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+type limiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// reserve takes a token for ip. If no token is available it returns how long until one is.
+func (l *limiter) reserve(ip string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return sweepInterval
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		// Rejected requests shouldn't consume tokens else clients retrying early are never served.
+		reservation.CancelAt(now)
+	}
+
+	return delay
+}
+
+// sweep removes clients whose buckets have had time to refill since their last request. They're
+// indistinguishable from new clients.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+	for ip, c := range l.clients {
+		if now.Sub(c.lastSeen) > refill {
+			delete(l.clients, ip)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/tinkerbell/hegel/internal/http/ratelimit"
+	"github.com/tinkerbell/hegel/internal/xff"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	const burst = 3
+
+	router := gin.New()
+	router.Use(Middleware(1, burst))
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	// Drive requests faster than the limit allows. The burst should be served before requests
+	// are rejected.
+	for i := 0; i < burst+2; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.10.10.10:0"
+
+		router.ServeHTTP(w, r)
+
+		if i < burst {
+			if w.Code != http.StatusOK {
+				t.Fatalf("Request %v: Expected status: 200; Received: %v", i, w.Code)
+			}
+			continue
+		}
+
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Request %v: Expected status: 429; Received: %v", i, w.Code)
+		}
+
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		if err != nil || retryAfter < 1 {
+			t.Fatalf("Request %v: Expected Retry-After in seconds; Received: %q", i, w.Header().Get("Retry-After"))
+		}
+	}
+
+	// Other clients have their own bucket.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.10.10.11:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status for other client: 200; Received: %v", w.Code)
+	}
+}
+
+func TestMiddlewareKeysOnForwardedClient(t *testing.T) {
+	xffmw, err := xff.MiddlewareFromUnparsed("192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(xffmw, Middleware(1, 1))
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	// Requests from distinct clients through the same proxy shouldn't share a bucket.
+	requests := []struct {
+		Client string
+		Expect int
+	}{
+		{Client: "10.10.10.10", Expect: http.StatusOK},
+		{Client: "10.10.10.11", Expect: http.StatusOK},
+		{Client: "10.10.10.10", Expect: http.StatusTooManyRequests},
+	}
+
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "192.168.0.1:1234"
+		r.Header.Set("X-Forwarded-For", req.Client)
+
+		router.ServeHTTP(w, r)
+
+		if w.Code != req.Expect {
+			t.Fatalf("Client %v: Expected status: %v; Received: %v", req.Client, req.Expect, w.Code)
+		}
+	}
+}
@@ -1,12 +1,34 @@
 package request
 
 import (
+	"context"
 	"net"
 	"net/http"
 )
 
-// RemoteAddrIP retrieves the remote address IP from r.
+type clientIPKey struct{}
+
+// WithClientIP returns a shallow copy of r whose context carries ip as the resolved client IP.
+// It's used by middleware that resolves the client from proxy headers, such as X-Forwarded-For,
+// so handlers identify the client without depending on r.RemoteAddr.
+func WithClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip))
+}
+
+// ClientIPFromContext retrieves the client IP added to ctx with WithClientIP. It reports false if
+// ctx has no client IP.
+func ClientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok
+}
+
+// RemoteAddrIP retrieves the client IP of r. The IP resolved by middleware with WithClientIP takes
+// precedence over the remote address.
 func RemoteAddrIP(r *http.Request) (string, error) {
+	if ip, ok := ClientIPFromContext(r.Context()); ok {
+		return ip, nil
+	}
+
 	addr, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		return "", err
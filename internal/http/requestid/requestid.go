@@ -0,0 +1,108 @@
+// Package requestid contains a middleware that identifies requests so failures reported by
+// clients can be traced to log entries.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header is the request and response header carrying the request ID.
+const Header = "X-Request-ID"
+
+// maxLength bounds the length of request IDs propagated from clients.
+const maxLength = 128
+
+type contextKey struct{}
+
+// ErrorBody is the response body written for server errors that don't have one.
+type ErrorBody struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id"`
+}
+
+// Middleware creates a gin middleware that identifies each request with the ID supplied in the
+// request Header or, when absent or invalid, a generated ID. The ID is added to the request context
+// and response Header.
+//
+// Server errors that are aborted without a body, such as those from gin.Context.AbortWithError,
+// are responded to with an ErrorBody. The error itself isn't exposed to clients; it's expected to
+// be logged alongside the request ID.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = generate()
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, id))
+		c.Header(Header, id)
+
+		w := &errorWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		c.Next()
+
+		if w.Status() < http.StatusInternalServerError || w.Size() > 0 {
+			return
+		}
+
+		w.WriteHeaderNow()
+		_ = json.NewEncoder(w).Encode(ErrorBody{Error: http.StatusText(w.Status()), RequestID: id})
+	}
+}
+
+// FromContext retrieves the request ID added to ctx by Middleware. If ctx has no request ID it
+// returns an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid reports whether id is acceptable for propagation. IDs are logged and echoed to clients so
+// they're restricted to a bounded length of printable ASCII.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error.
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errorWriter ensures server errors aborted without a body are written with a JSON content type
+// so Middleware can write an ErrorBody after the status has been written.
+type errorWriter struct {
+	gin.ResponseWriter
+}
+
+// WriteHeaderNow satisfies gin.ResponseWriter.
+func (w *errorWriter) WriteHeaderNow() {
+	if !w.Written() && w.Status() >= http.StatusInternalServerError && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Unwrap returns the decorated writer so http.ResponseController can reach the connection.
+func (w *errorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
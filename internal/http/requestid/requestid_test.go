@@ -0,0 +1,209 @@
+package requestid_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr/funcr"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/http/requestid"
+	"github.com/tinkerbell/hegel/internal/logger"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		Name      string
+		RequestID string
+		Propagate bool
+	}{
+		{
+			Name:      "Propagated",
+			RequestID: "client-supplied-id",
+			Propagate: true,
+		},
+		{
+			Name: "Generated",
+		},
+		{
+			Name:      "InvalidCharacters",
+			RequestID: "id with spaces",
+		},
+		{
+			Name:      "TooLong",
+			RequestID: strings.Repeat("a", 129),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var received string
+
+			router := gin.New()
+			router.Use(Middleware())
+			router.GET("/", func(ctx *gin.Context) {
+				received = FromContext(ctx.Request.Context())
+				ctx.String(http.StatusOK, "ok")
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.RequestID != "" {
+				r.Header.Set(Header, tc.RequestID)
+			}
+
+			router.ServeHTTP(w, r)
+
+			id := w.Header().Get(Header)
+			if id == "" {
+				t.Fatal("Expected a request ID header")
+			}
+
+			if tc.Propagate && id != tc.RequestID {
+				t.Fatalf("Expected request ID: %v; Received: %v", tc.RequestID, id)
+			}
+
+			if !tc.Propagate && id == tc.RequestID {
+				t.Fatalf("Expected a generated request ID; Received: %v", id)
+			}
+
+			if received != id {
+				t.Fatalf("Expected context request ID: %v; Received: %v", id, received)
+			}
+
+			if w.Body.String() != "ok" {
+				t.Fatalf("Expected body: ok; Received: %v", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareGeneratesUniqueIDs(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/", func(ctx *gin.Context) {})
+
+	ids := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		ids[w.Header().Get(Header)] = true
+	}
+
+	if len(ids) != 10 {
+		t.Fatalf("Expected 10 unique request IDs; Received: %v", len(ids))
+	}
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	var entries []map[string]any
+	log := funcr.NewJSON(func(obj string) {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(obj), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}, funcr.Options{})
+
+	router := gin.New()
+	router.Use(Middleware(), logger.Middleware(log))
+	ec2.New(failingBackend{}).Configure(router)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/hostname", nil)
+	r.RemoteAddr = "10.10.10.10:0"
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status: %v; Received: %v", http.StatusInternalServerError, w.Code)
+	}
+
+	id := w.Header().Get(Header)
+	if id == "" {
+		t.Fatal("Expected a request ID header")
+	}
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Expected JSON content type; Received: %v", ct)
+	}
+
+	var body ErrorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected JSON body: %v; Received: %v", err, w.Body.String())
+	}
+
+	if body.RequestID != id {
+		t.Fatalf("Expected body request_id: %v; Received: %v", id, body.RequestID)
+	}
+
+	// The backend error is logged rather than exposed to the client.
+	if body.Error != http.StatusText(http.StatusInternalServerError) {
+		t.Fatalf("Expected body error: %v; Received: %v", http.StatusText(http.StatusInternalServerError), body.Error)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry; Received: %v", len(entries))
+	}
+
+	if entries[0]["request_id"] != id {
+		t.Fatalf("Expected logged request_id: %v; Received: %v", id, entries[0]["request_id"])
+	}
+
+	if !strings.Contains(entries[0]["error"].(string), "backend unavailable") {
+		t.Fatalf("Expected logged error to contain the backend error; Received: %v", entries[0]["error"])
+	}
+}
+
+func TestMiddlewareExistingBody(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/error", func(ctx *gin.Context) {
+		ctx.String(http.StatusBadGateway, "upstream failed")
+	})
+	router.NoRoute(ec2.NotFound)
+
+	cases := []struct {
+		Path         string
+		ExpectedCode int
+		Expect       string
+	}{
+		{Path: "/error", ExpectedCode: http.StatusBadGateway, Expect: "upstream failed"},
+		{Path: "/missing", ExpectedCode: http.StatusNotFound, Expect: ec2.NotFoundBody},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if w.Body.String() != tc.Expect {
+				t.Fatalf("Expected body: %v; Received: %v", tc.Expect, w.Body.String())
+			}
+		})
+	}
+}
+
+// failingBackend is an ec2.Client that always errors.
+type failingBackend struct{}
+
+func (failingBackend) GetEC2Instance(context.Context, string) (ec2.Instance, error) {
+	return ec2.Instance{}, errors.New("backend unavailable")
+}
+
+func (failingBackend) GetEC2InstanceByMAC(context.Context, string) (ec2.Instance, error) {
+	return ec2.Instance{}, errors.New("backend unavailable")
+}
@@ -2,19 +2,219 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/pires/go-proxyproto"
+	"github.com/tinkerbell/hegel/internal/reload"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+// DefaultShutdownTimeout is the default duration in-flight requests are given to complete when
+// shutting down.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// Default connection timeouts. They bound how long clients may hold connections so slow or idle
+// clients can't exhaust the server.
+const (
+	DefaultReadTimeout  = 30 * time.Second
+	DefaultWriteTimeout = 60 * time.Second
+	DefaultIdleTimeout  = 120 * time.Second
+)
+
+// Option configures Serve and ServeTLS.
+type Option func(*options)
+
+type options struct {
+	shutdownTimeout time.Duration
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	reloaders       *reload.Group
+	h2c             bool
+	proxyProtocol   bool
+	reusePort       bool
+	backlog         int
+}
+
+// WithShutdownTimeout configures how long in-flight requests are given to complete when ctx is
+// cancelled before their connections are forcibly closed. Defaults to DefaultShutdownTimeout.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithReadTimeout configures how long clients have to send a request, including its body. When 0,
+// reading isn't bounded. Defaults to DefaultReadTimeout.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout configures how long a request has to be served, measured from when its headers
+// were read. When 0, writing isn't bounded. Long lived responses, such as event streams, must
+// clear their write deadline. Defaults to DefaultWriteTimeout.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout configures how long keep-alive connections are kept open waiting for the next
+// request. When 0, the read timeout is used. Defaults to DefaultIdleTimeout.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithReloadGroup configures ServeTLS to add its certificate to reloaders so it's reloaded along
+// with other configuration sources.
+func WithReloadGroup(reloaders *reload.Group) Option {
+	return func(o *options) {
+		o.reloaders = reloaders
+	}
+}
+
+// WithH2C configures Serve and ServeUnix to accept HTTP/2 cleartext (h2c) connections, including
+// those from clients with prior knowledge, in addition to HTTP/1.1 connections. It has no effect on
+// ServeTLS which negotiates HTTP/2 during the TLS handshake.
+func WithH2C() Option {
+	return func(o *options) {
+		o.h2c = true
+	}
+}
+
+// WithProxyProtocol configures Serve and ServeTLS to read PROXY protocol v1 and v2 headers, such
+// as those sent by L4 load balancers, so requests report the client's address rather than the load
+// balancer's. Connections without a header are served with their own address so the listener must
+// only be reachable through the load balancer for client addresses to be trusted.
+func WithProxyProtocol() Option {
+	return func(o *options) {
+		o.proxyProtocol = true
+	}
+}
+
+// WithReusePort configures Serve and ServeTLS to listen with SO_REUSEPORT so several processes
+// can serve the same address, with the kernel balancing connections between them. It's only
+// supported on Linux.
+func WithReusePort() Option {
+	return func(o *options) {
+		o.reusePort = true
+	}
+}
+
+// WithBacklog configures the length of the queue of connections waiting to be accepted by Serve
+// and ServeTLS. Larger backlogs avoid dropping connections when many clients connect at once,
+// such as when a rack boots. The kernel caps the backlog at net.core.somaxconn. When 0, the system
+// maximum is used. It's only supported on Linux.
+func WithBacklog(backlog int) Option {
+	return func(o *options) {
+		o.backlog = backlog
+	}
+}
+
 // Serve is a blocking call that begins serving the provided handler on port. When ctx is cancelled
 // it will attempt to gracefully shutdown. If graceful shutdown fails, it will force shutdown
 // and return an error.
-func Serve(ctx context.Context, logger logr.Logger, address string, handler http.Handler) error {
-	server := http.Server{
+func Serve(ctx context.Context, logger logr.Logger, address string, handler http.Handler, opts ...Option) error {
+	server, conns := newServer(address, handler)
+	return serve(ctx, logger, server, conns, func() error {
+		listener, err := listen(address, opts)
+		if err != nil {
+			return err
+		}
+		return server.Serve(listener)
+	}, opts)
+}
+
+// ServeTLS behaves as Serve but serves HTTPS using the certificate and key files. The certificate
+// is reloaded whenever the files change, or when the group configured with WithReloadGroup is
+// reloaded, so it can be rotated without restarting.
+func ServeTLS(
+	ctx context.Context,
+	logger logr.Logger,
+	address string,
+	handler http.Handler,
+	certFile, keyFile string,
+	opts ...Option,
+) error {
+	reloader, err := newCertificateReloader(ctx, logger, certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.reloaders != nil {
+		o.reloaders.Add("tls certificate", reloader)
+	}
+
+	server, conns := newServer(address, handler)
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	return serve(ctx, logger, server, conns, func() error {
+		listener, err := listen(address, opts)
+		if err != nil {
+			return err
+		}
+
+		// The certificate is provided by the TLSConfig so we needn't specify files.
+		return server.ServeTLS(listener, "", "")
+	}, opts)
+}
+
+// listen listens for TCP connections on address configuring the socket according to opts. When
+// configured with WithProxyProtocol the listener reads PROXY protocol headers from connections.
+func listen(address string, opts []Option) (net.Listener, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var lc net.ListenConfig
+	if o.reusePort {
+		lc.Control = reusePort
+	}
+
+	listener, err := lc.Listen(context.Background(), "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.backlog > 0 {
+		if err := setBacklog(listener, o.backlog); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("set listen backlog: %w", err)
+		}
+	}
+
+	if o.proxyProtocol {
+		listener = &proxyproto.Listener{Listener: listener}
+	}
+
+	return listener, nil
+}
+
+func newServer(address string, handler http.Handler) (*http.Server, *atomic.Int64) {
+	// Track open connections so we can report how many were forcibly closed on shutdown.
+	var conns atomic.Int64
+
+	return &http.Server{
 		Addr:    address,
 		Handler: handler,
 
@@ -22,12 +222,52 @@ func Serve(ctx context.Context, logger logr.Logger, address string, handler http
 		// recommendation. Hegel doesn't really have many headers so 20s should be plenty of time.
 		// https://en.wikipedia.org/wiki/Slowloris_(computer_security)
 		ReadHeaderTimeout: 20 * time.Second,
+
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				conns.Add(1)
+			case http.StateClosed, http.StateHijacked:
+				conns.Add(-1)
+			}
+		},
+	}, &conns
+}
+
+func serve(
+	ctx context.Context,
+	logger logr.Logger,
+	server *http.Server,
+	conns *atomic.Int64,
+	listenAndServe func() error,
+	opts []Option,
+) error {
+	o := options{
+		shutdownTimeout: DefaultShutdownTimeout,
+		readTimeout:     DefaultReadTimeout,
+		writeTimeout:    DefaultWriteTimeout,
+		idleTimeout:     DefaultIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	server.ReadTimeout = o.readTimeout
+	// Headers are read before the body so a shorter read timeout must bound them too.
+	if o.readTimeout > 0 && o.readTimeout < server.ReadHeaderTimeout {
+		server.ReadHeaderTimeout = o.readTimeout
+	}
+	server.WriteTimeout = o.writeTimeout
+	server.IdleTimeout = o.idleTimeout
+
+	if o.h2c && server.TLSConfig == nil {
+		server.Handler = h2c.NewHandler(server.Handler, &http2.Server{IdleTimeout: server.IdleTimeout})
 	}
 
 	errChan := make(chan error, 1)
 	go func() {
-		logger.Info(fmt.Sprintf("Listening on %s", address))
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Info(fmt.Sprintf("Listening on %s", server.Addr))
+		if err := listenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errChan <- err
 		}
 	}()
@@ -39,15 +279,21 @@ func Serve(ctx context.Context, logger logr.Logger, address string, handler http
 		return e
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), o.shutdownTimeout)
 	defer cancel()
 
 	// Attempt a graceful shutdown with timeout.
 	//nolint:contextcheck // We can't derive from the original context as it's already done.
 	if err := server.Shutdown(ctx); err != nil {
+		open := conns.Load()
 		server.Close()
 
 		if errors.Is(err, context.DeadlineExceeded) {
+			logger.Info("Shutdown timeout expired, forcibly closing connections",
+				"address", server.Addr,
+				"timeout", o.shutdownTimeout,
+				"open_connections", open,
+			)
 			return errors.New("timed out waiting for graceful shutdown")
 		}
 
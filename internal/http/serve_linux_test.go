@@ -0,0 +1,83 @@
+//go:build integration
+
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zerologr"
+	"github.com/rs/zerolog"
+	. "github.com/tinkerbell/hegel/internal/http"
+)
+
+// TestServeReusePort validates multiple Serve calls configured WithReusePort can listen on the
+// same port while, without it, the second fails.
+func TestServeReusePort(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Port        int
+		Options     []Option
+		ExpectError bool
+	}{
+		{
+			Name:    "ReusePort",
+			Port:    8084,
+			Options: []Option{WithReusePort(), WithBacklog(1024)},
+		},
+		{
+			Name:        "WithoutReusePort",
+			Port:        8085,
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+			logger := zerologr.New(&zl)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var mux http.ServeMux
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "Hello, world!")
+			})
+
+			address := fmt.Sprintf(":%d", tc.Port)
+			errs := make(chan error, 2)
+			for i := 0; i < 2; i++ {
+				go func() {
+					errs <- Serve(ctx, logger, address, &mux, tc.Options...)
+				}()
+			}
+
+			select {
+			case err := <-errs:
+				if !tc.ExpectError {
+					t.Fatalf("Expected both listeners to serve; Received: %v", err)
+				}
+				return
+			case <-time.After(200 * time.Millisecond):
+				if tc.ExpectError {
+					t.Fatal("Expected the second listener to fail to bind")
+				}
+			}
+
+			resp, err := http.Get(fmt.Sprintf("http://localhost:%d", tc.Port))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status: 200; Received: %v", resp.StatusCode)
+			}
+		})
+	}
+}
@@ -5,17 +5,29 @@ package http_test
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/go-logr/zerologr"
+	"github.com/pires/go-proxyproto"
 	"github.com/rs/zerolog"
 	. "github.com/tinkerbell/hegel/internal/http"
+	"golang.org/x/net/http2"
 )
 
 // TestServe validates the Serve function does in-fact serve a functional HTTP server with the
@@ -55,6 +67,133 @@ func TestServe(t *testing.T) {
 	}
 }
 
+// TestServeH2C validates Serve configured WithH2C serves both HTTP/2 cleartext clients with prior
+// knowledge and HTTP/1.1 clients.
+func TestServeH2C(t *testing.T) {
+	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+	logger := zerologr.New(&zl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mux http.ServeMux
+	mux.HandleFunc("/2009-04-04/meta-data/hostname", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hostname")
+	})
+
+	go Serve(ctx, logger, fmt.Sprintf(":%d", 8082), &mux, WithH2C())
+
+	time.Sleep(50 * time.Millisecond)
+
+	h2cClient := http.Client{
+		Transport: &http2.Transport{
+			// Dial without TLS so the client speaks HTTP/2 with prior knowledge.
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		Name       string
+		Client     *http.Client
+		ProtoMajor int
+	}{
+		{Name: "H2C", Client: &h2cClient, ProtoMajor: 2},
+		{Name: "HTTP1", Client: http.DefaultClient, ProtoMajor: 1},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			resp, err := tc.Client.Get("http://localhost:8082/2009-04-04/meta-data/hostname")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.ProtoMajor != tc.ProtoMajor {
+				t.Fatalf("expected HTTP/%d; received %v", tc.ProtoMajor, resp.Proto)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != "hostname" {
+				t.Fatalf("expected body 'hostname'; received %q", body)
+			}
+		})
+	}
+}
+
+func TestServeProxyProtocol(t *testing.T) {
+	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+	logger := zerologr.New(&zl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mux http.ServeMux
+	mux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		fmt.Fprint(w, host)
+	})
+
+	go Serve(ctx, logger, fmt.Sprintf(":%d", 8083), &mux, WithProxyProtocol())
+
+	time.Sleep(50 * time.Millisecond)
+
+	src := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 49152}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 8083}
+
+	for _, tc := range []struct {
+		Name     string
+		Header   *proxyproto.Header
+		ExpectIP string
+	}{
+		{Name: "V1", Header: proxyproto.HeaderProxyFromAddrs(1, src, dst), ExpectIP: "192.0.2.10"},
+		{Name: "V2", Header: proxyproto.HeaderProxyFromAddrs(2, src, dst), ExpectIP: "192.0.2.10"},
+		{Name: "NoHeader", ExpectIP: "127.0.0.1"},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := http.Client{
+				Transport: &http.Transport{
+					DisableKeepAlives: true,
+					// Send the header as a load balancer would before the request.
+					DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+						var d net.Dialer
+						conn, err := d.DialContext(ctx, network, addr)
+						if err != nil || tc.Header == nil {
+							return conn, err
+						}
+						if _, err := tc.Header.WriteTo(conn); err != nil {
+							conn.Close()
+							return nil, err
+						}
+						return conn, nil
+					},
+				},
+			}
+
+			resp, err := client.Get("http://127.0.0.1:8083/whoami")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tc.ExpectIP {
+				t.Fatalf("expected client IP %v; received %q", tc.ExpectIP, body)
+			}
+		})
+	}
+}
+
 func TestServerFailure(t *testing.T) {
 	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
 	logger := zerologr.New(&zl)
@@ -72,3 +211,262 @@ func TestServerFailure(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+// TestServeShutdownTimeout validates Serve waits for in-flight requests up to the shutdown timeout
+// then forcibly closes their connections.
+func TestServeShutdownTimeout(t *testing.T) {
+	cases := []struct {
+		Name            string
+		Port            int
+		HandlerDuration time.Duration
+		ExpectForced    bool
+	}{
+		{
+			Name:            "RequestCompletes",
+			Port:            8282,
+			HandlerDuration: 100 * time.Millisecond,
+		},
+		{
+			Name:            "TimeoutExpires",
+			Port:            8283,
+			HandlerDuration: 5 * time.Second,
+			ExpectForced:    true,
+		},
+	}
+
+	const shutdownTimeout = 500 * time.Millisecond
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+			logger := zerologr.New(&zl)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			started := make(chan struct{})
+			var mux http.ServeMux
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				time.Sleep(tc.HandlerDuration)
+				fmt.Fprint(w, "done")
+			})
+
+			served := make(chan error)
+			go func() {
+				served <- Serve(ctx, logger, fmt.Sprintf(":%d", tc.Port), &mux, WithShutdownTimeout(shutdownTimeout))
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+
+			requested := make(chan error)
+			go func() {
+				resp, err := http.Get(fmt.Sprintf("http://localhost:%d", tc.Port))
+				if err == nil {
+					resp.Body.Close()
+				}
+				requested <- err
+			}()
+
+			<-started
+			start := time.Now()
+			cancel()
+
+			err := <-served
+			elapsed := time.Since(start)
+			requestErr := <-requested
+
+			if !tc.ExpectForced {
+				if err != nil {
+					t.Fatalf("Expected graceful shutdown; Received: %v", err)
+				}
+				if requestErr != nil {
+					t.Fatalf("Expected in-flight request to complete; Received: %v", requestErr)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Expected shutdown timeout error")
+			}
+			if elapsed < shutdownTimeout || elapsed >= tc.HandlerDuration {
+				t.Fatalf("Expected shutdown to take the shutdown timeout (%v); Received: %v", shutdownTimeout, elapsed)
+			}
+			if requestErr == nil {
+				t.Fatal("Expected in-flight request to fail after its connection was closed")
+			}
+		})
+	}
+}
+
+// TestServeReadTimeout validates connections from clients that don't send a complete request
+// within the read timeout are dropped.
+func TestServeReadTimeout(t *testing.T) {
+	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+	logger := zerologr.New(&zl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mux http.ServeMux
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Hello, world!")
+	})
+
+	const readTimeout = 200 * time.Millisecond
+	go Serve(ctx, logger, fmt.Sprintf(":%d", 8284), &mux, WithReadTimeout(readTimeout))
+
+	time.Sleep(50 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "localhost:8284")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Send an incomplete request as a slowloris client would.
+	if _, err := fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := conn.SetReadDeadline(start.Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := conn.Read(make([]byte, 1))
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected the connection to be closed; Received: %v bytes, %v", n, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Expected the connection to be closed after the read timeout (%v); Took: %v", readTimeout, elapsed)
+	}
+}
+
+// TestServeTLS validates ServeTLS serves HTTPS using the certificate files and reloads the
+// certificate when the files change.
+func TestServeTLS(t *testing.T) {
+	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+	logger := zerologr.New(&zl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	var mux http.ServeMux
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Hello, world!")
+	})
+
+	go ServeTLS(ctx, logger, fmt.Sprintf(":%d", 8443), &mux, certFile, keyFile)
+
+	time.Sleep(50 * time.Millisecond)
+
+	client := http.Client{
+		Transport: &http.Transport{
+			//nolint:gosec // The test certificate is self-signed.
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			// Disable keep alives so each request performs a handshake and observes the current
+			// certificate.
+			DisableKeepAlives: true,
+		},
+	}
+
+	serial := func() int64 {
+		t.Helper()
+
+		resp, err := client.Get("https://localhost:8443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("expected status code 200")
+		}
+
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if s := serial(); s != 1 {
+		t.Fatalf("expected certificate serial 1; received %d", s)
+	}
+
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for serial() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for certificate reload")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestServeTLSMissingCertificate(t *testing.T) {
+	zl := zerolog.New(os.Stdout).With().Timestamp().Caller().Logger()
+	logger := zerologr.New(&zl)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	err := ServeTLS(
+		ctx,
+		logger,
+		fmt.Sprintf(":%d", 8444),
+		&http.ServeMux{},
+		filepath.Join(dir, "tls.crt"),
+		filepath.Join(dir, "tls.key"),
+	)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// writeSelfSignedCert writes a self-signed certificate identified by serial and its key. The
+// key is written before the certificate so a reload observes a matching pair.
+func writeSelfSignedCert(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path, blockType string, data []byte) {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(keyFile, "EC PRIVATE KEY", keyDER)
+	write(certFile, "CERTIFICATE", der)
+}
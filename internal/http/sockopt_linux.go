@@ -0,0 +1,44 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePort sets SO_REUSEPORT on the socket c so multiple listeners can bind the same address. It
+// satisfies net.ListenConfig.Control.
+func reusePort(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setBacklog sets the accept backlog of listener. The net package listens with the system maximum
+// so the socket is listened on again, which Linux permits, to apply backlog. The kernel caps
+// backlog at net.core.somaxconn.
+func setBacklog(listener net.Listener, backlog int) error {
+	sc, ok := listener.(syscall.Conn)
+	if !ok {
+		return errors.New("listener doesn't expose its socket")
+	}
+
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var listenErr error
+	if err := raw.Control(func(fd uintptr) {
+		listenErr = unix.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	return listenErr
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package http
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+var errSockoptUnsupported = errors.New("SO_REUSEPORT and the listen backlog are only configurable on Linux")
+
+func reusePort(_, _ string, _ syscall.RawConn) error {
+	return errSockoptUnsupported
+}
+
+func setBacklog(_ net.Listener, _ int) error {
+	return errSockoptUnsupported
+}
@@ -0,0 +1,31 @@
+// Package timeout contains a middleware that bounds how long requests may take.
+package timeout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware creates a gin middleware that bounds each request's context with timeout. Handlers
+// should pass the request context to blocking calls, such as backend lookups, so they're
+// cancelled when the deadline expires and respond with a 504 Gateway Timeout. If a handler
+// returns after the deadline without writing a response, the middleware responds with a 504.
+//
+// Long lived requests, such as event streams, should not be served with the middleware.
+func Middleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.AbortWithStatus(http.StatusGatewayTimeout)
+		}
+	}
+}
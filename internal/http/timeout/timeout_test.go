@@ -0,0 +1,91 @@
+package timeout_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/http/timeout"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Delay        time.Duration
+		ExpectedCode int
+	}{
+		{
+			Name:         "BackendResponds",
+			ExpectedCode: http.StatusOK,
+		},
+		{
+			Name:         "BackendTimesOut",
+			Delay:        5 * time.Second,
+			ExpectedCode: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			router := gin.New()
+			group := router.Group("", Middleware(50*time.Millisecond))
+			ec2.New(slowBackend{delay: tc.Delay}).Configure(group)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/hostname", nil)
+			r.RemoteAddr = "10.10.10.10:0"
+
+			start := time.Now()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedCode, w.Code)
+			}
+
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("Expected the backend call to be cancelled; Took: %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestMiddlewareNoResponse(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(10 * time.Millisecond))
+	router.GET("/", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status: %v; Received: %v", http.StatusGatewayTimeout, w.Code)
+	}
+}
+
+// slowBackend is an ec2.Client that takes delay to respond unless the context is cancelled.
+type slowBackend struct {
+	delay time.Duration
+}
+
+func (b slowBackend) GetEC2Instance(ctx context.Context, _ string) (ec2.Instance, error) {
+	select {
+	case <-time.After(b.delay):
+		return ec2.Instance{Metadata: ec2.Metadata{Hostname: "hostname"}}, nil
+	case <-ctx.Done():
+		return ec2.Instance{}, ctx.Err()
+	}
+}
+
+func (b slowBackend) GetEC2InstanceByMAC(ctx context.Context, _ string) (ec2.Instance, error) {
+	return b.GetEC2Instance(ctx, "")
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/hegel/internal/reload"
+)
+
+// certificateReloader provides a TLS certificate loaded from a certificate and key file. The
+// certificate is reloaded whenever either file changes.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+	logger   logr.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newCertificateReloader loads the certificate and key and launches a goroutine that reloads them
+// when they change until ctx is cancelled.
+func newCertificateReloader(ctx context.Context, logger logr.Logger, certFile, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile: filepath.Clean(certFile),
+		keyFile:  filepath.Clean(keyFile),
+		logger:   logger,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	err := reload.WatchFiles(ctx, []string{r.certFile, r.keyFile}, r.reload, func(err error) {
+		r.logger.Error(err, "Watching TLS certificate")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate satisfies tls.Config.GetCertificate.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload satisfies reload.Reloader.
+func (r *certificateReloader) Reload() error {
+	return r.load()
+}
+
+// reload reloads the certificate after the files changed logging the outcome.
+func (r *certificateReloader) reload() {
+	// The certificate and key are often updated independently so a mismatched pair is expected
+	// until both have been written. Keep serving the previous certificate.
+	if err := r.load(); err != nil {
+		r.logger.Info("Could not reload TLS certificate, continuing with previous certificate", "error", err)
+		return
+	}
+
+	r.logger.Info("Reloaded TLS certificate")
+}
+
+func (r *certificateReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls key pair: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+
+	return nil
+}
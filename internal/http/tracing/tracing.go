@@ -0,0 +1,29 @@
+// Package tracing contains a handler that continues the traces of clients calling Hegel.
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// operation names the spans created for served requests.
+const operation = "hegel"
+
+// Handler wraps handler so each request is served in a span. When a request carries trace context,
+// such as a traceparent header, the span continues the caller's trace. The span is added to the
+// request context so work done while serving it, such as backend lookups, is traced as its
+// children.
+//
+// Trace context is extracted with the global propagator and spans are created with the global
+// tracer provider unless opts specify otherwise.
+func Handler(handler http.Handler, opts ...otelhttp.Option) http.Handler {
+	opts = append([]otelhttp.Option{otelhttp.WithSpanNameFormatter(spanName)}, opts...)
+	return otelhttp.NewHandler(handler, operation, opts...)
+}
+
+// spanName names spans after the request method. Paths contain instance identifiers so they'd
+// make span names unbounded.
+func spanName(_ string, r *http.Request) string {
+	return "HTTP " + r.Method
+}
@@ -0,0 +1,82 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/tinkerbell/hegel/internal/http/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHandlerContinuesTrace(t *testing.T) {
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// The span in the context handed to the wrapped handler is the parent of any backend spans.
+	var served trace.SpanContext
+	handler := Handler(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			served = trace.SpanContextFromContext(r.Context())
+		}),
+		otelhttp.WithTracerProvider(provider),
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data", nil)
+	r.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span; Received: %v", len(spans))
+	}
+
+	span := spans[0]
+	if span.SpanContext().TraceID().String() != traceID {
+		t.Fatalf("Expected trace ID: %v; Received: %v", traceID, span.SpanContext().TraceID())
+	}
+
+	if span.Parent().SpanID().String() != parentSpanID {
+		t.Fatalf("Expected parent span ID: %v; Received: %v", parentSpanID, span.Parent().SpanID())
+	}
+
+	if span.Name() != "HTTP GET" {
+		t.Fatalf("Expected span name: HTTP GET; Received: %v", span.Name())
+	}
+
+	if served.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("Expected request context span: %v; Received: %v", span.SpanContext().SpanID(), served.SpanID())
+	}
+}
+
+func TestHandlerStartsTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	handler := Handler(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		otelhttp.WithTracerProvider(provider),
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span; Received: %v", len(spans))
+	}
+
+	if spans[0].Parent().IsValid() {
+		t.Fatalf("Expected a root span; Received parent: %v", spans[0].Parent().SpanID())
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/go-logr/logr"
+)
+
+// UnixSocketMode is the file mode applied to sockets created by ServeUnix. Only the owner and group
+// may connect.
+const UnixSocketMode fs.FileMode = 0o660
+
+// ServeUnix behaves as Serve but listens on a Unix domain socket at path. A stale socket left at
+// path, for example by a process that crashed, is removed before listening. The socket is removed
+// on shutdown.
+func ServeUnix(ctx context.Context, logger logr.Logger, path string, handler http.Handler, opts ...Option) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(path, UnixSocketMode); err != nil {
+		listener.Close()
+		return fmt.Errorf("set socket permissions: %w", err)
+	}
+
+	server, conns := newServer(path, handler)
+	return serve(ctx, logger, server, conns, func() error {
+		return server.Serve(listener)
+	}, opts)
+}
+
+// removeStaleSocket removes the socket at path if nothing is listening on it. It fails if path
+// exists but isn't a socket or another process is listening on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%v exists and is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%v is in use by another process", path)
+	}
+
+	return os.Remove(path)
+}
@@ -2,15 +2,60 @@ package logger
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-logr/logr"
+	"github.com/go-logr/zerologr"
+	"github.com/rs/zerolog"
+	"github.com/tinkerbell/hegel/internal/http/request"
+	"github.com/tinkerbell/hegel/internal/http/requestid"
 )
 
+// Supported log formats.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// levels maps supported log level names to zerolog levels. logr has no warn level so at warn only
+// errors are logged.
+var levels = map[string]zerolog.Level{
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+}
+
+// New creates a logger that writes to w. level is one of debug, info, warn or error; debug enables
+// V(1) logs. format is one of FormatJSON or FormatConsole.
+func New(w io.Writer, level, format string) (logr.Logger, error) {
+	lvl, ok := levels[strings.ToLower(level)]
+	if !ok {
+		return logr.Logger{}, fmt.Errorf("unknown log level %q: valid values are debug, info, warn, error", level)
+	}
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+	case FormatConsole:
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339, NoColor: true}
+	default:
+		return logr.Logger{}, fmt.Errorf("unknown log format %q: valid values are json, console", format)
+	}
+
+	zl := zerolog.New(w).Level(lvl).With().Timestamp().Caller().Logger()
+	return zerologr.New(&zl), nil
+}
+
 // Middleware creates a gin middleware that logs requests. It includes client_ip, method,
-// status_code, path and latency.
+// status_code, path and latency. When the request was identified by requestid.Middleware the
+// request_id is included.
+//
+// The client_ip is resolved from the request's remote address after the request has been
+// processed so it reflects any rewriting performed by X-Forwarded-For middleware.
 func Middleware(logger logr.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Process the request recording how long it took.
@@ -31,15 +76,24 @@ func Middleware(logger logr.Logger) gin.HandlerFunc {
 		}
 		path := b.String()
 
+		clientIP, err := request.RemoteAddrIP(c.Request)
+		if err != nil {
+			clientIP = c.Request.RemoteAddr
+		}
+
 		// Build an event with all the values we want to include.
 		event := logger.WithValues(
-			"client_ip", c.ClientIP(),
+			"client_ip", clientIP,
 			"method", c.Request.Method,
 			"status_code", c.Writer.Status(),
 			"path", path,
 			"latency", end.Sub(start),
 		)
 
+		if id := requestid.FromContext(c.Request.Context()); id != "" {
+			event = event.WithValues("request_id", id)
+		}
+
 		// If we received a non-error status code Info else error it.
 		if c.Writer.Status() < 500 {
 			event.Info("")
@@ -0,0 +1,165 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/tinkerbell/hegel/internal/logger"
+	"github.com/tinkerbell/hegel/internal/xff"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Path           string
+		ExpectedStatus int
+	}{
+		{
+			Name:           "OK",
+			Path:           "/ok",
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:           "NotFound",
+			Path:           "/missing",
+			ExpectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var entries []map[string]any
+			logger := funcr.NewJSON(func(obj string) {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(obj), &entry); err != nil {
+					t.Fatal(err)
+				}
+				entries = append(entries, entry)
+			}, funcr.Options{})
+
+			xffmw, err := xff.MiddlewareFromUnparsed("192.168.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := gin.New()
+			router.Use(xffmw, Middleware(logger))
+			router.GET("/ok", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "ok")
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			r.RemoteAddr = "192.168.0.1:1234"
+			r.Header.Set("X-Forwarded-For", "10.10.10.10")
+
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected status: %v; Received: %v", tc.ExpectedStatus, w.Code)
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 log entry; Received: %v", len(entries))
+			}
+			entry := entries[0]
+
+			// JSON numbers are decoded as float64.
+			if status, ok := entry["status_code"].(float64); !ok || int(status) != w.Code {
+				t.Fatalf("Expected status_code: %v; Received: %v", w.Code, entry["status_code"])
+			}
+
+			if entry["client_ip"] != "10.10.10.10" {
+				t.Fatalf("Expected client_ip: 10.10.10.10; Received: %v", entry["client_ip"])
+			}
+
+			if entry["method"] != http.MethodGet {
+				t.Fatalf("Expected method: %v; Received: %v", http.MethodGet, entry["method"])
+			}
+
+			if entry["path"] != tc.Path {
+				t.Fatalf("Expected path: %v; Received: %v", tc.Path, entry["path"])
+			}
+
+			if _, ok := entry["latency"]; !ok {
+				t.Fatal("Expected latency to be logged")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		Level  string
+		Expect []string
+	}{
+		{Level: "debug", Expect: []string{"debug", "info", "error"}},
+		{Level: "info", Expect: []string{"info", "error"}},
+		{Level: "warn", Expect: []string{"error"}},
+		{Level: "error", Expect: []string{"error"}},
+		{Level: "INFO", Expect: []string{"info", "error"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Level, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, tc.Level, FormatJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			logger.V(1).Info("debug")
+			logger.Info("info")
+			logger.Error(errors.New("error"), "error")
+
+			var received []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("Expected JSON log line; Received: %v", line)
+				}
+				received = append(received, entry["message"].(string))
+			}
+
+			if strings.Join(received, ",") != strings.Join(tc.Expect, ",") {
+				t.Fatalf("Expected messages: %v; Received: %v", tc.Expect, received)
+			}
+		})
+	}
+}
+
+func TestNewConsoleFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatConsole)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("hello", "key", "value")
+
+	out := buf.String()
+	if json.Valid([]byte(out)) || !strings.Contains(out, "hello") || !strings.Contains(out, "key=value") {
+		t.Fatalf("Expected console formatted log; Received: %v", out)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", FormatJSON); err == nil {
+		t.Fatal("Expected error for unknown level")
+	}
+
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Fatal("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Package lookup contains an endpoint for debugging why an instance doesn't receive metadata. It
+looks up an IP as the frontends would and reports the outcome along with the Hardware matching the
+IP, when the backend can list them.
+
+Only the outcome and identifiers are reported. Instance data, such as userdata, isn't exposed.
+*/
+package lookup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// Lookup outcomes reported in Result.Outcome.
+const (
+	OutcomeFound        = "found"
+	OutcomeNotFound     = "not_found"
+	OutcomeDuplicate    = "duplicate"
+	OutcomeBackendError = "backend_error"
+)
+
+// Client retrieves instances as the frontends do.
+type Client interface {
+	// GetEC2Instance retrieves the Instance associated with ip. If no Instance can be found, it
+	// should return ec2.ErrInstanceNotFound.
+	GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error)
+}
+
+// HardwareLister is implemented by backends that can list the Hardware matching an IP.
+type HardwareLister interface {
+	// ListHardwareByIP returns the namespaced names of every Hardware associated with ip,
+	// regardless of how duplicates are handled when serving instances.
+	ListHardwareByIP(ctx context.Context, ip string) ([]string, error)
+}
+
+// Result is the outcome of looking up an IP.
+type Result struct {
+	IP         string   `json:"ip"`
+	Outcome    string   `json:"outcome"`
+	InstanceID string   `json:"instance_id,omitempty"`
+	Hardware   []string `json:"hardware,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Configure configures router with a `/debug/lookup?ip=<ip>` endpoint that looks up ip with
+// client and responds with a Result. When hardware is not nil, the Hardware matching ip are
+// included.
+func Configure(router gin.IRouter, client Client, hardware HardwareLister) {
+	router.GET("/debug/lookup", func(ctx *gin.Context) {
+		ip, err := netip.ParseAddr(ctx.Query("ip"))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "ip query parameter must be an ip address"})
+			return
+		}
+
+		result := Result{IP: ip.String()}
+
+		instance, err := client.GetEC2Instance(ctx.Request.Context(), result.IP)
+		result.Outcome = outcome(err)
+		if err != nil && !errors.Is(err, ec2.ErrInstanceNotFound) {
+			result.Error = err.Error()
+		}
+		if err == nil {
+			result.InstanceID = instance.Metadata.InstanceID
+		}
+
+		if hardware != nil {
+			names, err := hardware.ListHardwareByIP(ctx.Request.Context(), result.IP)
+			if err != nil && result.Error == "" {
+				result.Error = err.Error()
+			}
+			result.Hardware = names
+		}
+
+		ctx.JSON(http.StatusOK, result)
+	})
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeFound
+	case errors.Is(err, ec2.ErrInstanceNotFound):
+		return OutcomeNotFound
+	case errors.Is(err, kubernetes.ErrMultipleHardware):
+		return OutcomeDuplicate
+	default:
+		return OutcomeBackendError
+	}
+}
@@ -0,0 +1,89 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/lookup/lookup.go
+
+// Package lookup is a generated GoMock package.
+package lookup
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	ec2 "github.com/tinkerbell/hegel/internal/frontend/ec2"
+)
+
+// MockClient is a mock of Client interface.
+type MockClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockClientMockRecorder
+}
+
+// MockClientMockRecorder is the mock recorder for MockClient.
+type MockClientMockRecorder struct {
+	mock *MockClient
+}
+
+// NewMockClient creates a new mock instance.
+func NewMockClient(ctrl *gomock.Controller) *MockClient {
+	mock := &MockClient{ctrl: ctrl}
+	mock.recorder = &MockClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClient) EXPECT() *MockClientMockRecorder {
+	return m.recorder
+}
+
+// GetEC2Instance mocks base method.
+func (m *MockClient) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEC2Instance", ctx, ip)
+	ret0, _ := ret[0].(ec2.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEC2Instance indicates an expected call of GetEC2Instance.
+func (mr *MockClientMockRecorder) GetEC2Instance(ctx, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEC2Instance", reflect.TypeOf((*MockClient)(nil).GetEC2Instance), ctx, ip)
+}
+
+// MockHardwareLister is a mock of HardwareLister interface.
+type MockHardwareLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockHardwareListerMockRecorder
+}
+
+// MockHardwareListerMockRecorder is the mock recorder for MockHardwareLister.
+type MockHardwareListerMockRecorder struct {
+	mock *MockHardwareLister
+}
+
+// NewMockHardwareLister creates a new mock instance.
+func NewMockHardwareLister(ctrl *gomock.Controller) *MockHardwareLister {
+	mock := &MockHardwareLister{ctrl: ctrl}
+	mock.recorder = &MockHardwareListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHardwareLister) EXPECT() *MockHardwareListerMockRecorder {
+	return m.recorder
+}
+
+// ListHardwareByIP mocks base method.
+func (m *MockHardwareLister) ListHardwareByIP(ctx context.Context, ip string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHardwareByIP", ctx, ip)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHardwareByIP indicates an expected call of ListHardwareByIP.
+func (mr *MockHardwareListerMockRecorder) ListHardwareByIP(ctx, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHardwareByIP", reflect.TypeOf((*MockHardwareLister)(nil).ListHardwareByIP), ctx, ip)
+}
@@ -0,0 +1,131 @@
+package lookup_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/lookup"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestLookup(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Instance ec2.Instance
+		Error    error
+		Hardware []string
+		Expect   Result
+	}{
+		{
+			Name:     "Found",
+			Instance: ec2.Instance{Userdata: "userdata", Metadata: ec2.Metadata{InstanceID: "instance-id"}},
+			Hardware: []string{"default/machine"},
+			Expect: Result{
+				IP:         "10.10.10.10",
+				Outcome:    OutcomeFound,
+				InstanceID: "instance-id",
+				Hardware:   []string{"default/machine"},
+			},
+		},
+		{
+			Name:   "NotFound",
+			Error:  ec2.ErrInstanceNotFound,
+			Expect: Result{IP: "10.10.10.10", Outcome: OutcomeNotFound},
+		},
+		{
+			Name:     "Duplicate",
+			Error:    kubernetes.ErrMultipleHardware,
+			Hardware: []string{"default/machine-a", "default/machine-b"},
+			Expect: Result{
+				IP:       "10.10.10.10",
+				Outcome:  OutcomeDuplicate,
+				Hardware: []string{"default/machine-a", "default/machine-b"},
+				Error:    kubernetes.ErrMultipleHardware.Error(),
+			},
+		},
+		{
+			Name:   "BackendError",
+			Error:  errors.New("backend error"),
+			Expect: Result{IP: "10.10.10.10", Outcome: OutcomeBackendError, Error: "backend error"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.10").Return(tc.Instance, tc.Error)
+			hardware := NewMockHardwareLister(ctrl)
+			hardware.EXPECT().ListHardwareByIP(gomock.Any(), "10.10.10.10").Return(tc.Hardware, nil)
+
+			router := gin.New()
+			Configure(router, client, hardware)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/lookup?ip=10.10.10.10", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status: 200; Received: %v", w.Code)
+			}
+
+			var received Result
+			if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.Expect, received); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestLookupWithoutHardwareLister(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.10").Return(ec2.Instance{}, ec2.ErrInstanceNotFound)
+
+	router := gin.New()
+	Configure(router, client, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/lookup?ip=10.10.10.10", nil))
+
+	var received Result
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := Result{IP: "10.10.10.10", Outcome: OutcomeNotFound}
+	if diff := cmp.Diff(expect, received); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestLookupInvalidIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+
+	router := gin.New()
+	Configure(router, client, nil)
+
+	for _, path := range []string{"/debug/lookup", "/debug/lookup?ip=nonsense"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%v: Expected status: 400; Received: %v", path, w.Code)
+		}
+	}
+}
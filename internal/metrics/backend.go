@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tinkerbell/hegel/internal/backend"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	"github.com/tinkerbell/hegel/internal/frontend/hack"
+)
+
+const resultLabel = "result"
+
+// Lookup results used as the result label value.
+const (
+	lookupFound        = "found"
+	lookupNotFound     = "not_found"
+	lookupDuplicate    = "duplicate"
+	lookupBackendError = "backend_error"
+)
+
+// Backend decorates a backend.Client counting instance lookups by result so expected not found
+// lookups can be distinguished from backend failures. It also tracks the time since the backend
+// last answered a lookup so a wedged backend can be alerted on. All other calls are passed through
+// to the decorated client.
+type Backend struct {
+	backend.Client
+
+	lookups     *prometheus.CounterVec
+	slowLookups prometheus.Counter
+
+	slowThreshold time.Duration
+	logger        logr.Logger
+
+	now func() time.Time
+
+	// lastSuccess is the Unix time in nanoseconds of the last lookup that wasn't a backend error.
+	lastSuccess atomic.Int64
+}
+
+// BackendOption configures a Backend.
+type BackendOption func(*Backend)
+
+// WithSlowLookupThreshold configures the Backend to count lookups taking longer than threshold
+// as slow and log them to logger.
+func WithSlowLookupThreshold(threshold time.Duration, logger logr.Logger) BackendOption {
+	return func(b *Backend) {
+		b.slowThreshold = threshold
+		b.logger = logger
+	}
+}
+
+// InstrumentBackend adds a CounterVec to registrar and returns a Backend that decorates client
+// incrementing the count with every lookup.
+//
+// A gauge of the seconds since the last successful lookup is added too. Lookups are successful
+// unless they're a backend error; an instance not being found is an answer from the backend. Until
+// a lookup succeeds the gauge counts from when the Backend was created.
+func InstrumentBackend(registrar prometheus.Registerer, client backend.Client, opts ...BackendOption) *Backend {
+	m := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "backend_lookups_total",
+			Help: "Count of backend instance lookups by result",
+		},
+		[]string{resultLabel},
+	)
+
+	// Initialize every result so alerts on rates don't wait for the first occurrence.
+	for _, result := range []string{lookupFound, lookupNotFound, lookupDuplicate, lookupBackendError} {
+		m.WithLabelValues(result)
+	}
+
+	slow := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "backend_slow_lookups_total",
+		Help: "Count of backend instance lookups exceeding the slow lookup threshold",
+	})
+
+	b := &Backend{Client: client, lookups: m, slowLookups: slow, now: time.Now}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.lastSuccess.Store(b.now().UnixNano())
+
+	sinceSuccess := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "backend_seconds_since_last_successful_lookup",
+			Help: "Seconds since a backend instance lookup last succeeded",
+		},
+		func() float64 {
+			return b.now().Sub(time.Unix(0, b.lastSuccess.Load())).Seconds()
+		},
+	)
+
+	registrar.MustRegister(m, slow, sinceSuccess)
+
+	return b
+}
+
+// GetEC2Instance satisfies ec2.Client.
+func (b *Backend) GetEC2Instance(ctx context.Context, ip string) (ec2.Instance, error) {
+	start := time.Now()
+	instance, err := b.Client.GetEC2Instance(ctx, ip)
+	b.observe(start, "ip", ip, err)
+	return instance, err
+}
+
+// GetEC2InstanceByMAC satisfies ec2.Client.
+func (b *Backend) GetEC2InstanceByMAC(ctx context.Context, mac string) (ec2.Instance, error) {
+	start := time.Now()
+	instance, err := b.Client.GetEC2InstanceByMAC(ctx, mac)
+	b.observe(start, "mac", mac, err)
+	return instance, err
+}
+
+// GetEC2InstanceByID satisfies instances.Client.
+func (b *Backend) GetEC2InstanceByID(ctx context.Context, id string) (ec2.Instance, error) {
+	start := time.Now()
+	instance, err := b.Client.GetEC2InstanceByID(ctx, id)
+	b.observe(start, "id", id, err)
+	return instance, err
+}
+
+// GetHackInstance satisfies hack.Client.
+func (b *Backend) GetHackInstance(ctx context.Context, ip string) (hack.Instance, error) {
+	start := time.Now()
+	instance, err := b.Client.GetHackInstance(ctx, ip)
+	b.observe(start, "ip", ip, err)
+	return instance, err
+}
+
+// observe records the result of the lookup of value by key started at start.
+func (b *Backend) observe(start time.Time, key, value string, err error) {
+	result := lookupResult(err)
+	b.lookups.WithLabelValues(result).Inc()
+
+	if result != lookupBackendError {
+		b.lastSuccess.Store(b.now().UnixNano())
+	}
+
+	if b.slowThreshold <= 0 {
+		return
+	}
+
+	if duration := time.Since(start); duration > b.slowThreshold {
+		b.slowLookups.Inc()
+		b.logger.Info("Slow backend lookup", key, value, "duration", duration, "threshold", b.slowThreshold)
+	}
+}
+
+// lookupResult classifies the error returned by a lookup.
+func lookupResult(err error) string {
+	switch {
+	case err == nil:
+		return lookupFound
+	case errors.Is(err, ec2.ErrInstanceNotFound):
+		return lookupNotFound
+	case errors.Is(err, kubernetes.ErrMultipleHardware):
+		return lookupDuplicate
+	default:
+		return lookupBackendError
+	}
+}
@@ -0,0 +1,11 @@
+package metrics
+
+import "time"
+
+// WithClock configures the Backend with the clock used to track the time since the last
+// successful lookup.
+func WithClock(now func() time.Time) BackendOption {
+	return func(b *Backend) {
+		b.now = now
+	}
+}
@@ -0,0 +1,200 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr/funcr"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tinkerbell/hegel/internal/backend/kubernetes"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/metrics"
+)
+
+func TestInstrumentBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+
+	lookups := map[string]struct {
+		Instance ec2.Instance
+		Error    error
+	}{
+		"10.10.10.1": {Instance: ec2.Instance{Metadata: ec2.Metadata{InstanceID: "instance-id"}}},
+		"10.10.10.2": {Error: ec2.ErrInstanceNotFound},
+		"10.10.10.3": {Error: fmt.Errorf("%w for 10.10.10.3: default/a, default/b", kubernetes.ErrMultipleHardware)},
+		"10.10.10.4": {Error: errors.New("connection refused")},
+	}
+	for ip, lookup := range lookups {
+		client.EXPECT().
+			GetEC2Instance(gomock.Any(), ip).
+			Return(lookup.Instance, lookup.Error)
+	}
+
+	registry := prometheus.NewRegistry()
+
+	router := gin.New()
+	ec2.New(InstrumentBackend(registry, client)).Configure(router)
+	Configure(router, registry)
+
+	for ip := range lookups {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/instance-id", nil)
+		r.RemoteAddr = ip + ":0"
+		router.ServeHTTP(w, r)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	expect := []string{
+		`backend_lookups_total{result="found"} 1`,
+		`backend_lookups_total{result="not_found"} 1`,
+		`backend_lookups_total{result="duplicate"} 1`,
+		`backend_lookups_total{result="backend_error"} 1`,
+	}
+
+	body := w.Body.String()
+	for _, line := range expect {
+		if !strings.Contains(body, line) {
+			t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+		}
+	}
+}
+
+func TestInstrumentBackendInitializesResults(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	router := gin.New()
+	InstrumentBackend(registry, NewMockClient(gomock.NewController(t)))
+	Configure(router, registry)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Every result is exported before the first lookup so rate based alerts have a baseline.
+	body := w.Body.String()
+	for _, result := range []string{"found", "not_found", "duplicate", "backend_error"} {
+		line := fmt.Sprintf(`backend_lookups_total{result="%v"} 0`, result)
+		if !strings.Contains(body, line) {
+			t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+		}
+	}
+}
+
+func TestInstrumentBackendSlowLookups(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Threshold    time.Duration
+		ExpectedSlow int
+	}{
+		{Name: "Enabled", Threshold: 10 * time.Millisecond, ExpectedSlow: 1},
+		{Name: "Disabled", ExpectedSlow: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := NewMockClient(gomock.NewController(t))
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.1").
+				Return(ec2.Instance{}, nil)
+			client.EXPECT().
+				GetEC2Instance(gomock.Any(), "10.10.10.2").
+				DoAndReturn(func(context.Context, string) (ec2.Instance, error) {
+					time.Sleep(20 * time.Millisecond)
+					return ec2.Instance{}, nil
+				})
+
+			var logs []string
+			logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+
+			registry := prometheus.NewRegistry()
+
+			router := gin.New()
+			ec2.New(InstrumentBackend(registry, client, WithSlowLookupThreshold(tc.Threshold, logger))).Configure(router)
+			Configure(router, registry)
+
+			for _, ip := range []string{"10.10.10.1", "10.10.10.2"} {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/instance-id", nil)
+				r.RemoteAddr = ip + ":0"
+				router.ServeHTTP(w, r)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+			line := fmt.Sprintf("backend_slow_lookups_total %v", tc.ExpectedSlow)
+			if body := w.Body.String(); !strings.Contains(body, line) {
+				t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+			}
+
+			if len(logs) != tc.ExpectedSlow {
+				t.Fatalf("Expected %v slow lookup logs; Received: %v", tc.ExpectedSlow, logs)
+			}
+			if tc.ExpectedSlow > 0 && !strings.Contains(logs[0], `"ip"="10.10.10.2"`) {
+				t.Fatalf("Expected the slow lookup IP to be logged; Received: %v", logs[0])
+			}
+		})
+	}
+}
+
+func TestInstrumentBackendSinceLastSuccess(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	gomock.InOrder(
+		client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.1").Return(ec2.Instance{}, nil),
+		client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.2").Return(ec2.Instance{}, errors.New("connection refused")),
+		client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.3").Return(ec2.Instance{}, ec2.ErrInstanceNotFound),
+	)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	registry := prometheus.NewRegistry()
+	be := InstrumentBackend(registry, client, WithClock(clock))
+
+	router := gin.New()
+	Configure(router, registry)
+
+	expectGauge := func(t *testing.T, seconds int) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		line := fmt.Sprintf("backend_seconds_since_last_successful_lookup %v\n", seconds)
+		if body := w.Body.String(); !strings.Contains(body, line) {
+			t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+		}
+	}
+
+	// Before any lookup the gauge counts from when the backend was instrumented.
+	expectGauge(t, 0)
+	now = now.Add(30 * time.Second)
+	expectGauge(t, 30)
+
+	if _, err := be.GetEC2Instance(context.Background(), "10.10.10.1"); err != nil {
+		t.Fatal(err)
+	}
+	expectGauge(t, 0)
+
+	// Backend errors don't reset the gauge so it grows during an outage.
+	now = now.Add(10 * time.Second)
+	_, _ = be.GetEC2Instance(context.Background(), "10.10.10.2")
+	expectGauge(t, 10)
+
+	// Not found is an answer from the backend so it's successful.
+	now = now.Add(5 * time.Second)
+	_, _ = be.GetEC2Instance(context.Background(), "10.10.10.3")
+	expectGauge(t, 0)
+}
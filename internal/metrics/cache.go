@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tinkerbell/hegel/internal/backend/cache"
+)
+
+const (
+	kindLabel   = "kind"
+	reasonLabel = "reason"
+)
+
+// Cache instruments the in-memory instance cache. It satisfies cache.Observer.
+type Cache struct {
+	entries   *prometheus.GaugeVec
+	hits      *prometheus.CounterVec
+	misses    *prometheus.CounterVec
+	evictions *prometheus.CounterVec
+}
+
+// InstrumentCache adds cache metrics to registrar and returns a Cache that updates them. The Cache
+// should be supplied as the cache.Config Observer.
+func InstrumentCache(registrar prometheus.Registerer) *Cache {
+	c := &Cache{
+		entries: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "cache_entries",
+				Help: "Number of entries in the instance cache by lookup kind",
+			},
+			[]string{kindLabel},
+		),
+		hits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cache_hits_total",
+				Help: "Count of lookups served from the instance cache by lookup kind",
+			},
+			[]string{kindLabel},
+		),
+		misses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cache_misses_total",
+				Help: "Count of lookups not served from the instance cache by lookup kind",
+			},
+			[]string{kindLabel},
+		),
+		evictions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cache_evictions_total",
+				Help: "Count of entries evicted from the instance cache by lookup kind and reason",
+			},
+			[]string{kindLabel, reasonLabel},
+		),
+	}
+
+	// Initialize every kind so ratios, such as the hit ratio, have a baseline.
+	for _, kind := range []string{cache.LookupIP, cache.LookupMAC} {
+		c.entries.WithLabelValues(kind)
+		c.hits.WithLabelValues(kind)
+		c.misses.WithLabelValues(kind)
+		for _, reason := range []string{cache.EvictedCapacity, cache.EvictedExpired} {
+			c.evictions.WithLabelValues(kind, reason)
+		}
+	}
+
+	registrar.MustRegister(c.entries, c.hits, c.misses, c.evictions)
+
+	return c
+}
+
+// Hit satisfies cache.Observer.
+func (c *Cache) Hit(kind string) {
+	c.hits.WithLabelValues(kind).Inc()
+}
+
+// Miss satisfies cache.Observer.
+func (c *Cache) Miss(kind string) {
+	c.misses.WithLabelValues(kind).Inc()
+}
+
+// Evicted satisfies cache.Observer.
+func (c *Cache) Evicted(kind, reason string) {
+	c.evictions.WithLabelValues(kind, reason).Inc()
+}
+
+// Entries satisfies cache.Observer.
+func (c *Cache) Entries(kind string, n int) {
+	c.entries.WithLabelValues(kind).Set(float64(n))
+}
@@ -0,0 +1,73 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tinkerbell/hegel/internal/backend/cache"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/metrics"
+)
+
+func TestInstrumentCache(t *testing.T) {
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.1").Return(ec2.Instance{}, nil)
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.2").Return(ec2.Instance{}, ec2.ErrInstanceNotFound).Times(2)
+	client.EXPECT().GetEC2Instance(gomock.Any(), "10.10.10.3").Return(ec2.Instance{}, nil)
+
+	registry := prometheus.NewRegistry()
+
+	// Found instances outlive the test while not found results expire almost immediately.
+	be := cache.New(client, cache.Config{
+		TTL:         time.Hour,
+		NegativeTTL: time.Millisecond,
+		Size:        2,
+		Observer:    InstrumentCache(registry),
+	})
+
+	lookup := func(ip string) {
+		_, _ = be.GetEC2Instance(context.Background(), ip)
+	}
+
+	lookup("10.10.10.1") // Miss.
+	lookup("10.10.10.1") // Hit.
+	lookup("10.10.10.2") // Miss.
+
+	time.Sleep(10 * time.Millisecond)
+
+	lookup("10.10.10.2") // Miss evicting the expired entry.
+	lookup("10.10.10.3") // Miss evicting 10.10.10.1 for capacity.
+
+	router := gin.New()
+	Configure(router, registry)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	expect := []string{
+		`cache_entries{kind="ip"} 2`,
+		`cache_entries{kind="mac"} 0`,
+		`cache_hits_total{kind="ip"} 1`,
+		`cache_misses_total{kind="ip"} 4`,
+		`cache_evictions_total{kind="ip",reason="capacity"} 1`,
+		`cache_evictions_total{kind="ip",reason="expired"} 1`,
+		`cache_evictions_total{kind="mac",reason="capacity"} 0`,
+	}
+
+	body := w.Body.String()
+	for _, line := range expect {
+		if !strings.Contains(body, line) {
+			t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+		}
+	}
+}
@@ -2,11 +2,11 @@ package metrics
 
 import (
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 const (
@@ -17,13 +17,16 @@ const (
 
 // InstrumentRequestCount adds a CounterVec to registrar and returns a handler that increments
 // the count with every request.
+//
+// Requests are labeled with the route template, such as /meta-data/public-keys/:index, rather than
+// the raw path to bound cardinality. Requests that don't match a route have an empty route label.
 func InstrumentRequestCount(registrar prometheus.Registerer) gin.HandlerFunc {
-	m := promauto.NewCounterVec(
+	m := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_server_requests_total",
 			Help: "Count of HTTP requests",
 		},
-		[]string{methodLabel, statusCodeLabel},
+		[]string{routeLabel, methodLabel, statusCodeLabel},
 	)
 
 	registrar.MustRegister(m)
@@ -31,14 +34,58 @@ func InstrumentRequestCount(registrar prometheus.Registerer) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		ctx.Next()
 		m.WithLabelValues(
+			ctx.FullPath(),
 			ctx.Request.Method,
 			strconv.Itoa(ctx.Writer.Status()),
 		).Inc()
 	}
 }
 
-// InstrumentReuqestDuration adds a HistogramVec to registrar and returns a handler that records
-// request durations with every request.
+// InstrumentRequestsInFlight adds Gauges to registrar and returns a handler that tracks the number
+// of requests being served and the maximum number observed being served concurrently since
+// startup.
+func InstrumentRequestsInFlight(registrar prometheus.Registerer) gin.HandlerFunc {
+	inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_server_requests_in_flight",
+		Help: "Number of HTTP requests being served",
+	})
+	maxInFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_server_requests_in_flight_max",
+		Help: "Maximum number of HTTP requests observed being served concurrently",
+	})
+
+	registrar.MustRegister(inFlight, maxInFlight)
+
+	// Track the counts under a lock so the maximum can't be observed out of order.
+	var (
+		mu      sync.Mutex
+		current int
+		peak    int
+	)
+
+	return func(ctx *gin.Context) {
+		mu.Lock()
+		current++
+		inFlight.Set(float64(current))
+		if current > peak {
+			peak = current
+			maxInFlight.Set(float64(peak))
+		}
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			current--
+			inFlight.Set(float64(current))
+			mu.Unlock()
+		}()
+
+		ctx.Next()
+	}
+}
+
+// InstrumentRequestDuration adds a HistogramVec to registrar and returns a handler that records
+// request durations with every request. Requests are labeled the same as InstrumentRequestCount.
 func InstrumentRequestDuration(registrar prometheus.Registerer) gin.HandlerFunc {
 	m := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
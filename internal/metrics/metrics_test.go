@@ -0,0 +1,136 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tinkerbell/hegel/internal/frontend/ec2"
+	. "github.com/tinkerbell/hegel/internal/metrics"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestInstrumentRequests(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	router := gin.New()
+	router.Use(
+		InstrumentRequestCount(registry),
+		InstrumentRequestDuration(registry),
+	)
+	router.GET("/meta-data/public-keys/:index", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "openssh-key")
+	})
+	Configure(router, registry)
+
+	for _, path := range []string{"/meta-data/public-keys/0", "/meta-data/public-keys/1", "/missing"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	// Requests are labeled with the route template so both public key requests share a series.
+	expect := []string{
+		`http_server_requests_total{method="GET",route="/meta-data/public-keys/:index",status_code="200"} 2`,
+		`http_server_requests_total{method="GET",route="",status_code="404"} 1`,
+		`http_server_request_duration_seconds_count{method="GET",route="/meta-data/public-keys/:index",status_code="200"} 2`,
+		`http_server_request_duration_seconds_count{method="GET",route="",status_code="404"} 1`,
+	}
+
+	body := w.Body.String()
+	for _, line := range expect {
+		if !strings.Contains(body, line) {
+			t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+		}
+	}
+
+	if strings.Contains(body, "/meta-data/public-keys/0") {
+		t.Fatalf("Expected raw paths to be excluded from labels\nReceived:\n%v", body)
+	}
+}
+
+func TestInstrumentRequestsInFlight(t *testing.T) {
+	const requests = 3
+
+	// The backend blocks lookups until released so requests are held in flight.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	client := NewMockClient(gomock.NewController(t))
+	client.EXPECT().
+		GetEC2Instance(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, string) (ec2.Instance, error) {
+			started <- struct{}{}
+			<-release
+			return ec2.Instance{}, nil
+		}).
+		Times(requests)
+
+	registry := prometheus.NewRegistry()
+
+	router := gin.New()
+	router.Use(InstrumentRequestsInFlight(registry))
+	ec2.New(client).Configure(router)
+
+	// Scrape from a separate router so the scrape isn't itself counted as in flight.
+	admin := gin.New()
+	Configure(admin, registry)
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/2009-04-04/meta-data/instance-id", nil))
+		}()
+	}
+
+	for i := 0; i < requests; i++ {
+		<-started
+	}
+
+	expectScrape(t, admin,
+		"http_server_requests_in_flight 3",
+		"http_server_requests_in_flight_max 3",
+	)
+
+	close(release)
+	wg.Wait()
+
+	// The maximum is retained after requests complete.
+	expectScrape(t, admin,
+		"http_server_requests_in_flight 0",
+		"http_server_requests_in_flight_max 3",
+	)
+}
+
+func expectScrape(t *testing.T, router *gin.Engine, expect ...string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, line := range expect {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("Expected scrape to contain: %v\nReceived:\n%v", line, body)
+		}
+	}
+}
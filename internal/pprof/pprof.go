@@ -0,0 +1,22 @@
+// Package pprof exposes the Go runtime profiling endpoints provided by net/http/pprof.
+package pprof
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configure configures router with the /debug/pprof endpoints.
+func Configure(router gin.IRouter) {
+	debug := router.Group("/debug/pprof")
+	debug.GET("/", gin.WrapF(pprof.Index))
+	debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/profile", gin.WrapF(pprof.Profile))
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/trace", gin.WrapF(pprof.Trace))
+
+	// Named profiles, such as heap and goroutine, are served by the index handler.
+	debug.GET("/:profile", gin.WrapF(pprof.Index))
+}
@@ -0,0 +1,86 @@
+/*
+Package reload reloads configuration sources, such as the trusted proxies file, when Hegel
+receives a signal. Sources already reload when their files change; a signal lets operators force a
+reload, for example when files are on a filesystem that doesn't deliver change notifications.
+*/
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// Reloader is a configuration source that can be reloaded.
+type Reloader interface {
+	// Reload reloads the source. If the source can't be reloaded it should continue to serve the
+	// previously loaded configuration.
+	Reload() error
+}
+
+// Group is a set of named Reloaders that are reloaded together. The zero value is ready to use.
+type Group struct {
+	mu        sync.Mutex
+	names     []string
+	reloaders []Reloader
+}
+
+// Add adds r to g. name identifies r in logs and errors.
+func (g *Group) Add(name string, r Reloader) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.names = append(g.names, name)
+	g.reloaders = append(g.reloaders, r)
+}
+
+// Reload reloads every Reloader in g. A failure to reload one Reloader doesn't prevent the others
+// from being reloaded; all failures are returned.
+func (g *Group) Reload() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for i, r := range g.reloaders {
+		if err := r.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", g.names[i], err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Watch launches a goroutine that reloads g each time the process receives one of signals until
+// ctx is cancelled. The signals are no longer delivered to any other handler, such as the default
+// handler that terminates the process, until ctx is cancelled.
+func (g *Group) Watch(ctx context.Context, logger logr.Logger, signals ...os.Signal) {
+	// Register before returning so signals sent as soon as Watch returns are observed.
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case sig := <-ch:
+				logger.Info("Reloading configuration", "signal", sig.String())
+
+				if err := g.Reload(); err != nil {
+					logger.Info("Could not reload configuration, continuing with previous configuration", "error", err)
+					continue
+				}
+
+				logger.Info("Reloaded configuration")
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package reload_test
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/tinkerbell/hegel/internal/reload"
+)
+
+// reloader counts reloads and fails them with err.
+type reloader struct {
+	reloads chan struct{}
+	err     error
+}
+
+func newReloader(err error) *reloader {
+	return &reloader{reloads: make(chan struct{}, 10), err: err}
+}
+
+func (r *reloader) Reload() error {
+	r.reloads <- struct{}{}
+	return r.err
+}
+
+func TestGroupReload(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	ok := newReloader(nil)
+	failed := newReloader(errFailed)
+
+	var g Group
+	g.Add("failed", failed)
+	g.Add("ok", ok)
+
+	err := g.Reload()
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Expected: %v; Received: %v", errFailed, err)
+	}
+
+	// A failed reloader mustn't prevent the others from reloading.
+	for name, r := range map[string]*reloader{"failed": failed, "ok": ok} {
+		if len(r.reloads) != 1 {
+			t.Fatalf("Expected %v to reload once; Received: %v", name, len(r.reloads))
+		}
+	}
+}
+
+func TestGroupWatch(t *testing.T) {
+	r := newReloader(nil)
+
+	var g Group
+	g.Add("reloader", r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g.Watch(ctx, logr.Discard(), syscall.SIGHUP)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-r.reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected SIGHUP to reload the group")
+	}
+}
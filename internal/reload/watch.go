@@ -0,0 +1,133 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchFiles launches a goroutine that calls onChange when any of the files at paths changes
+// until ctx is cancelled. Errors watching the files are passed to onError.
+//
+// The parent directories are watched rather than the files so files that are atomically replaced
+// are observed. Kubernetes ConfigMap and Secret volumes are updated by swapping a ..data symlink
+// the files resolve through so events name the symlink rather than the files. Any event in a
+// watched directory therefore checks whether the files resolve to a different target or were
+// modified.
+func WatchFiles(ctx context.Context, paths []string, onChange func(), onError func(error)) error {
+	w := &fileWatcher{
+		files:    make(map[string]fileState, len(paths)),
+		onChange: onChange,
+		onError:  onError,
+	}
+
+	var dirs []string
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		w.files[path] = statFile(path)
+
+		if dir := filepath.Dir(path); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %v", err)
+	}
+
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("watch %v: %v", dir, err)
+		}
+	}
+
+	go w.watch(ctx, watcher)
+
+	return nil
+}
+
+// fileState identifies a version of a file.
+type fileState struct {
+	target  string
+	size    int64
+	modTime time.Time
+}
+
+// statFile returns the state of the file at path resolving symlinks. If the file can't be
+// resolved the zero state is returned.
+func statFile(path string) fileState {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{target: target, size: info.Size(), modTime: info.ModTime()}
+}
+
+type fileWatcher struct {
+	// files maps watched paths to their last observed state.
+	files    map[string]fileState
+	onChange func()
+	onError  func(error)
+}
+
+// changed reports whether event indicates a watched file changed and records the state of the
+// files.
+func (w *fileWatcher) changed(event fsnotify.Event) bool {
+	var changed bool
+	for path, previous := range w.files {
+		current := statFile(path)
+
+		// Files that are missing, such as mid way through being replaced, are reported once
+		// they reappear.
+		if current == (fileState{}) {
+			continue
+		}
+
+		named := filepath.Clean(event.Name) == path && event.Has(fsnotify.Write|fsnotify.Create)
+		if named || current != previous {
+			changed = true
+		}
+		w.files[path] = current
+	}
+	return changed
+}
+
+func (w *fileWatcher) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if w.changed(event) {
+				w.onChange()
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.onError(err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package reload_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/tinkerbell/hegel/internal/reload"
+)
+
+func TestWatchFiles(t *testing.T) {
+	cases := []struct {
+		Name string
+		// Update changes the files in dir after watching dir/file starts.
+		Update func(t *testing.T, dir string)
+		// Changed is whether the update should be observed as a change.
+		Changed bool
+	}{
+		{
+			Name: "Write",
+			Update: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "file"), "after")
+			},
+			Changed: true,
+		},
+		{
+			Name: "AtomicReplace",
+			Update: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "file.tmp"), "after")
+				rename(t, filepath.Join(dir, "file.tmp"), filepath.Join(dir, "file"))
+			},
+			Changed: true,
+		},
+		{
+			Name: "UnrelatedFile",
+			Update: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "unrelated"), "after")
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "file"), "before")
+
+			changes := watchFiles(t, filepath.Join(dir, "file"))
+
+			tc.Update(t, dir)
+
+			if tc.Changed {
+				expectChange(t, changes)
+			} else {
+				expectNoChange(t, changes)
+			}
+		})
+	}
+}
+
+// TestWatchFilesKubernetesVolume simulates how kubelet updates ConfigMap and Secret volumes.
+// Files are symlinks through a ..data symlink to a timestamped directory. Updates write a new
+// timestamped directory and atomically swap ..data to it so no event names the file.
+func TestWatchFilesKubernetesVolume(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "..2024_01_01_00_00_00.1", "tls.crt"), "before")
+	symlink(t, "..2024_01_01_00_00_00.1", filepath.Join(dir, "..data"))
+	symlink(t, filepath.Join("..data", "tls.crt"), filepath.Join(dir, "tls.crt"))
+
+	changes := watchFiles(t, filepath.Join(dir, "tls.crt"))
+
+	writeFile(t, filepath.Join(dir, "..2024_01_01_00_01_00.2", "tls.crt"), "after")
+	symlink(t, "..2024_01_01_00_01_00.2", filepath.Join(dir, "..data_tmp"))
+	rename(t, filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))
+
+	expectChange(t, changes)
+
+	if err := os.RemoveAll(filepath.Join(dir, "..2024_01_01_00_00_00.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing the previous version doesn't change the file.
+	expectNoChange(t, changes)
+}
+
+// watchFiles watches paths until t completes returning a channel that receives a value for each
+// change.
+func watchFiles(t *testing.T, paths ...string) <-chan struct{} {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := make(chan struct{}, 10)
+	err := WatchFiles(ctx, paths, func() { changes <- struct{}{} }, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return changes
+}
+
+func expectChange(t *testing.T, changes <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for change")
+	}
+}
+
+func expectNoChange(t *testing.T, changes <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-changes:
+		t.Fatal("Received unexpected change")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, path string) {
+	t.Helper()
+
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rename(t *testing.T, from, to string) {
+	t.Helper()
+
+	if err := os.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package version reports the version information of the running Hegel build.
+package version
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tinkerbell/hegel/internal/build"
+)
+
+// Injected at build time with -ldflags "-X github.com/tinkerbell/hegel/internal/version.<var>=".
+var (
+	version   = "dev"
+	gitCommit string
+	buildDate string
+)
+
+// Info describes a Hegel build.
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Get returns the Info for the running build. If the git commit wasn't injected at build time it's
+// sourced from the VCS information embedded by the Go toolchain.
+func Get() Info {
+	commit := gitCommit
+	if commit == "" {
+		commit = build.GetGitRevision()
+	}
+
+	return Info{
+		Version:   version,
+		GitCommit: commit,
+		BuildDate: buildDate,
+	}
+}
+
+// String formats i for printing on the command line. Fields without a value are reported as
+// unknown.
+func (i Info) String() string {
+	orUnknown := func(v string) string {
+		if v == "" {
+			return "unknown"
+		}
+		return v
+	}
+
+	return fmt.Sprintf(
+		"Version: %v\nGit commit: %v\nBuild date: %v",
+		orUnknown(i.Version),
+		orUnknown(i.GitCommit),
+		orUnknown(i.BuildDate),
+	)
+}
+
+// Configure configures router with a /versionz endpoint that serves the Info for the running build.
+func Configure(router gin.IRouter) {
+	router.GET("/versionz", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, Get())
+	})
+}
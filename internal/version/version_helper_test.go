@@ -0,0 +1,10 @@
+package version
+
+// SetBuildInfo overrides the build time injected variables until the returned func is called.
+func SetBuildInfo(v, commit, date string) (restore func()) {
+	prevVersion, prevCommit, prevDate := version, gitCommit, buildDate
+	version, gitCommit, buildDate = v, commit, date
+	return func() {
+		version, gitCommit, buildDate = prevVersion, prevCommit, prevDate
+	}
+}
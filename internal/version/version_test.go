@@ -0,0 +1,80 @@
+package version_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/version"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestVersionz(t *testing.T) {
+	restore := SetBuildInfo("v0.12.0", "0123456789abcdef", "2024-06-01T00:00:00Z")
+	defer restore()
+
+	router := gin.New()
+	Configure(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/versionz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status: 200; Received: %v", w.Code)
+	}
+
+	var received Info
+	if err := json.Unmarshal(w.Body.Bytes(), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := Info{
+		Version:   "v0.12.0",
+		GitCommit: "0123456789abcdef",
+		BuildDate: "2024-06-01T00:00:00Z",
+	}
+	if !cmp.Equal(expect, received) {
+		t.Fatal(cmp.Diff(expect, received))
+	}
+
+	if received != Get() {
+		t.Fatalf("Expected endpoint to serve Get(): %v; Received: %v", Get(), received)
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Info   Info
+		Expect string
+	}{
+		{
+			Name: "Complete",
+			Info: Info{
+				Version:   "v0.12.0",
+				GitCommit: "0123456789abcdef",
+				BuildDate: "2024-06-01T00:00:00Z",
+			},
+			Expect: "Version: v0.12.0\nGit commit: 0123456789abcdef\nBuild date: 2024-06-01T00:00:00Z",
+		},
+		{
+			Name:   "Missing",
+			Info:   Info{Version: "dev"},
+			Expect: "Version: dev\nGit commit: unknown\nBuild date: unknown",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if received := tc.Info.String(); received != tc.Expect {
+				t.Fatalf("Expected: %q; Received: %q", tc.Expect, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package xff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	"github.com/tinkerbell/hegel/internal/reload"
+)
+
+// ReadFile reads trusted proxies from the file at path. Entries are separated by new lines or
+// commas and lines beginning with # are comments. Valid entries are formatted as with Parse.
+// Invalid entries are skipped and returned in invalid.
+func ReadFile(path string) (proxies, invalid []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, entry := range strings.Split(line, ",") {
+			parsed, err := Parse(entry)
+			if err != nil {
+				invalid = append(invalid, strings.TrimSpace(entry))
+				continue
+			}
+			proxies = append(proxies, parsed...)
+		}
+	}
+
+	return proxies, invalid, nil
+}
+
+// FileMiddleware is an X-Forwarded-For middleware that trusts both a fixed set of proxies and the
+// proxies read from a file. The file is re-read whenever it changes.
+type FileMiddleware struct {
+	path    string
+	inline  []string
+	opts    []Option
+	logger  logr.Logger
+	handler atomic.Pointer[gin.HandlerFunc]
+}
+
+// NewFileMiddleware creates a FileMiddleware trusting inline, formatted as with Parse, and the
+// proxies in the file at path. It launches a goroutine that reloads the file when it changes until
+// ctx is cancelled. Invalid entries in the file are logged and skipped. If the file can't be
+// reloaded the previous proxies continue to be trusted. opts are passed to Middleware.
+func NewFileMiddleware(
+	ctx context.Context,
+	logger logr.Logger,
+	inline []string,
+	path string,
+	opts ...Option,
+) (*FileMiddleware, error) {
+	m := &FileMiddleware{
+		path:   filepath.Clean(path),
+		inline: inline,
+		opts:   opts,
+		logger: logger,
+	}
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	err := reload.WatchFiles(ctx, []string{m.path}, m.reload, func(err error) {
+		m.logger.Error(err, "Watching trusted proxies file")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Handle is the gin.HandlerFunc for m.
+func (m *FileMiddleware) Handle(ctx *gin.Context) {
+	(*m.handler.Load())(ctx)
+}
+
+// Reload re-reads the trusted proxies file. If it can't be read the previous proxies continue to be
+// trusted. It satisfies reload.Reloader.
+func (m *FileMiddleware) Reload() error {
+	return m.load()
+}
+
+// reload reloads the trusted proxies after the file changed logging the outcome.
+func (m *FileMiddleware) reload() {
+	if err := m.load(); err != nil {
+		m.logger.Info("Could not reload trusted proxies, continuing with previous proxies", "error", err)
+		return
+	}
+
+	m.logger.Info("Reloaded trusted proxies", "file", m.path)
+}
+
+func (m *FileMiddleware) load() error {
+	proxies, invalid, err := ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("read trusted proxies file: %v", err)
+	}
+
+	if len(invalid) > 0 {
+		m.logger.Info("Skipping invalid trusted proxies", "file", m.path, "entries", invalid)
+	}
+
+	if TrustsAll(proxies) {
+		m.logger.Info(
+			"Trusting X-Forwarded-For from every peer; any client can impersonate any instance",
+			"file", m.path,
+		)
+	}
+
+	handler, err := Middleware(append(append([]string{}, m.inline...), proxies...), m.opts...)
+	if err != nil {
+		return err
+	}
+	m.handler.Store(&handler)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package xff_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/go-cmp/cmp"
+	. "github.com/tinkerbell/hegel/internal/xff"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestReadFile(t *testing.T) {
+	path := writeFile(t, filepath.Join(t.TempDir(), "proxies"), strings.Join([]string{
+		"# Load balancers",
+		"192.168.0.1",
+		"10.0.0.0/8, 2001:db8::1",
+		"",
+		"invalid",
+		"192.168.0.0/33",
+	}, "\n"))
+
+	proxies, invalid, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"192.168.0.1/32", "10.0.0.0/8", "2001:db8::1/128"}, proxies); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if diff := cmp.Diff([]string{"invalid", "192.168.0.0/33"}, invalid); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestReadFileMissing(t *testing.T) {
+	if _, _, err := ReadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("Expected error, received nil")
+	}
+}
+
+func TestFileMiddleware(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		logged []string
+	)
+	logger := funcr.New(func(_, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, args)
+	}, funcr.Options{})
+
+	path := writeFile(t, filepath.Join(t.TempDir(), "proxies"), "192.168.0.0/16\ninvalid")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mw, err := NewFileMiddleware(ctx, logger, []string{"172.16.0.1/32"}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(mw.Handle)
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.Request.RemoteAddr)
+	})
+
+	// The invalid entry is skipped so the remaining entries are trusted.
+	expectRemoteAddr(t, router, "192.168.0.1:0", "10.10.10.10:0")
+	expectRemoteAddr(t, router, "172.16.0.1:0", "10.10.10.10:0")
+	expectRemoteAddr(t, router, "10.0.0.1:0", "10.0.0.1:0")
+
+	mu.Lock()
+	if len(logged) != 1 || !strings.Contains(logged[0], "invalid") {
+		t.Fatalf("Expected the invalid entry to be logged; Received: %v", logged)
+	}
+	mu.Unlock()
+
+	writeFile(t, path, "10.0.0.0/8")
+
+	// Wait for the reload to be observed.
+	deadline := time.Now().Add(5 * time.Second)
+	for serve(router, "10.0.0.1:0") != "10.10.10.10:0" {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for trusted proxies to reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Proxies removed from the file are no longer trusted but inline proxies are.
+	expectRemoteAddr(t, router, "192.168.0.1:0", "192.168.0.1:0")
+	expectRemoteAddr(t, router, "172.16.0.1:0", "10.10.10.10:0")
+}
+
+func TestFileMiddlewareMissingFile(t *testing.T) {
+	_, err := NewFileMiddleware(context.Background(), funcr.New(nil, funcr.Options{}), nil, filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatal("Expected error, received nil")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func expectRemoteAddr(t *testing.T, router *gin.Engine, remoteAddr, expect string) {
+	t.Helper()
+
+	if received := serve(router, remoteAddr); received != expect {
+		t.Fatalf("Expected remote addr: %v; Received: %v", expect, received)
+	}
+}
+
+// serve makes a request from remoteAddr forwarded for 10.10.10.10 and returns the remote addr
+// observed by the handler.
+func serve(router *gin.Engine, remoteAddr string) string {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	r.Header.Set("X-Forwarded-For", "10.10.10.10")
+
+	router.ServeHTTP(w, r)
+
+	return w.Body.String()
+}
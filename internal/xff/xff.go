@@ -4,15 +4,28 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/packethost/xff"
 	"github.com/pkg/errors"
+	"github.com/tinkerbell/hegel/internal/http/request"
 )
 
+// Wildcards accepted by Parse in place of a CIDR or IP to trust every peer.
+const (
+	WildcardAll  = "all"
+	WildcardStar = "*"
+)
+
+// allSubnets contains every IPv4 and IPv6 address.
+var allSubnets = []string{"0.0.0.0/0", "::/0"}
+
 // Parse parses a string of comma separated trusted proxies. A trusted proxy can be a CIDR or an IP.
-// IPs are converetd to CIDR notation with /32 or /128 for IPv4 and IPv6 respectively.
+// IPs are converetd to CIDR notation with /32 or /128 for IPv4 and IPv6 respectively. The
+// WildcardAll and WildcardStar wildcards are converted to CIDRs containing every address.
 //
 // Parse formats proxies appropriate for use with Middleware.
 func Parse(trustedProxies string) ([]string, error) {
@@ -24,6 +37,11 @@ func Parse(trustedProxies string) ([]string, error) {
 			continue
 		}
 
+		if cidr == WildcardStar || strings.EqualFold(cidr, WildcardAll) {
+			result = append(result, allSubnets...)
+			continue
+		}
+
 		_, _, err := net.ParseCIDR(cidr)
 		if err == nil {
 			result = append(result, cidr)
@@ -48,16 +66,61 @@ func Parse(trustedProxies string) ([]string, error) {
 	return result, nil
 }
 
+// TrustsAll reports whether proxies, formatted as with Parse, trust every IPv4 or every IPv6 peer.
+// Any client of a middleware trusting every peer can impersonate any instance so it's only safe
+// when every peer is a trusted proxy.
+func TrustsAll(proxies []string) bool {
+	for _, proxy := range proxies {
+		if slices.Contains(allSubnets, proxy) {
+			return true
+		}
+	}
+	return false
+}
+
+// Option configures Middleware.
+type Option func(*options)
+
+type options struct {
+	realIP bool
+}
+
+// WithRealIP configures Middleware to use the X-Real-IP header address when a trusted proxy
+// doesn't supply an X-Forwarded-For header. When both headers are present X-Forwarded-For takes
+// precedence.
+func WithRealIP() Option {
+	return func(o *options) {
+		o.realIP = true
+	}
+}
+
 // Middleware creates an X-Forward-For middlware in the form of an http.Handler. The middleware
 // will replace the http.Request.RemoteAddr with the X-Forward-For header address if the
 // http.Request.RemoteAddr is in allowedSubnets. It then calls handler with the newly configured
 // http.Request.
 //
+// The client is the rightmost X-Forwarded-For address that isn't a trusted proxy so clients can't
+// spoof their address by prepending to the header. The resolved client IP is added to the request
+// context, retrievable with request.ClientIPFromContext, and is used by request.RemoteAddrIP.
+//
+// Overrides are only honored from trusted proxies. The X-Forwarded-For and X-Real-IP headers are
+// removed from requests sent by any other peer so handlers can't mistake them for the requester's
+// address. Requests from trusted proxies whose override headers contain a malformed address are
+// rejected with 400 Bad Request rather than being attributed to the proxy.
+//
 // allowedSubnets is a slice of CIDR blocks. Individual IPs should be formatted with /32 or /128
 // for IPv4 and IPv6 respectively.
-func Middleware(proxies []string) (gin.HandlerFunc, error) {
+func Middleware(proxies []string, opts ...Option) (gin.HandlerFunc, error) {
 	if len(proxies) == 0 {
-		return func(_ *gin.Context) {}, nil
+		return func(ctx *gin.Context) {
+			stripOverrides(ctx.Request.Header)
+			ctx.Request = withClientIP(ctx.Request)
+		}, nil
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
 	}
 
 	xffmw, err := xff.New(xff.Options{AllowedSubnets: proxies})
@@ -65,30 +128,157 @@ func Middleware(proxies []string) (gin.HandlerFunc, error) {
 		return nil, errors.Errorf("create forward for handler: %v", err)
 	}
 
+	subnets, err := parseSubnets(proxies)
+	if err != nil {
+		return nil, errors.Errorf("create forward for handler: %v", err)
+	}
+
 	// The upstream xff package doesn't support Gin so we need to leverage what it does provide
 	// to create a Gin compatible middleware. The ServeHTTP satisfies a different framework
 	// but is the clearest way to call the xffmw while honoring expected Gin behavior.
 	//
 	// When we separate from packethost packages we can tidy this up with our own implementation.
 	return func(ctx *gin.Context) {
+		if !trusted(ctx.Request.RemoteAddr, subnets) {
+			stripOverrides(ctx.Request.Header)
+			ctx.Request = withClientIP(ctx.Request)
+			ctx.Next()
+			return
+		}
+
+		if err := validateOverrides(ctx.Request.Header, o.realIP); err != nil {
+			_ = ctx.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		if o.realIP && ctx.Request.Header.Get("X-Forwarded-For") == "" {
+			replaceWithRealIP(ctx.Request)
+		}
+
 		xffmw.ServeHTTP(
 			ctx.Writer,
 			ctx.Request,
-			http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
 				// Given we're a Gin middleware we need to call the next handler in the chain.
+				ctx.Request = withClientIP(r)
 				ctx.Next()
 			}),
 		)
 	}, nil
 }
 
+// trusted reports whether the host of remoteAddr is in subnets.
+func trusted(remoteAddr string, subnets []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+
+	peer := net.ParseIP(host)
+	if peer == nil {
+		return false
+	}
+
+	for _, subnet := range subnets {
+		if subnet.Contains(peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// withClientIP adds the host of r.RemoteAddr, once any overrides have been applied, to the context
+// of r as the resolved client IP. If r.RemoteAddr has no host r is returned unmodified.
+func withClientIP(r *http.Request) *http.Request {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r
+	}
+	return request.WithClientIP(r, host)
+}
+
+// stripOverrides removes the headers a peer could use to override its address.
+func stripOverrides(header http.Header) {
+	header.Del("X-Forwarded-For")
+	header.Del("X-Real-IP")
+}
+
+// validateOverrides returns an error if the X-Forwarded-For header, or the X-Real-IP header when
+// realIP is set, contains anything other than IP addresses.
+func validateOverrides(header http.Header, realIP bool) error {
+	if forwardedFor := header.Get("X-Forwarded-For"); forwardedFor != "" {
+		for _, addr := range strings.Split(forwardedFor, ",") {
+			if _, err := netip.ParseAddr(strings.TrimSpace(addr)); err != nil {
+				return fmt.Errorf("invalid X-Forwarded-For header: %v", err)
+			}
+		}
+	}
+
+	if realIP {
+		if addr := header.Get("X-Real-IP"); addr != "" {
+			if _, err := netip.ParseAddr(strings.TrimSpace(addr)); err != nil {
+				return fmt.Errorf("invalid X-Real-IP header: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// replaceWithRealIP replaces r.RemoteAddr with the X-Real-IP header address. r must be from a
+// trusted proxy and its X-Real-IP header must have been validated with validateOverrides.
+func replaceWithRealIP(r *http.Request) {
+	realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+	if err != nil {
+		return
+	}
+
+	_, port, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return
+	}
+
+	r.RemoteAddr = net.JoinHostPort(realIP.String(), port)
+}
+
+func parseSubnets(proxies []string) ([]*net.IPNet, error) {
+	subnets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		_, subnet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
 // MiddlewareFromUnparsed is a helpe that calls Parse then Middleware. proxies must conform to the
 // Parse constraints.
-func MiddlewareFromUnparsed(proxies string) (gin.HandlerFunc, error) {
+func MiddlewareFromUnparsed(proxies string, opts ...Option) (gin.HandlerFunc, error) {
 	parsed, err := Parse(proxies)
 	if err != nil {
 		return nil, err
 	}
 
-	return Middleware(parsed)
+	return Middleware(parsed, opts...)
+}
+
+// TrustAll wraps handler replacing the http.Request.RemoteAddr with the X-Forwarded-For header
+// address for every request. It should only be used for listeners where every peer is trusted,
+// such as Unix domain sockets whose peers have no IP with which to identify an instance. Requests
+// with a malformed X-Forwarded-For header are rejected with 400 Bad Request.
+func TrustAll(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := validateOverrides(r.Header, false); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		header := r.Header.Get("X-Forwarded-For")
+		if ip := xff.Parse(header, func(string) bool { return true }); ip != "" {
+			r.RemoteAddr = net.JoinHostPort(ip, "0")
+		}
+		handler.ServeHTTP(w, withClientIP(r))
+	})
 }
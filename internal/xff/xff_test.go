@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/go-cmp/cmp"
 	"github.com/tinkerbell/hegel/internal/ginutil"
+	"github.com/tinkerbell/hegel/internal/http/request"
 	. "github.com/tinkerbell/hegel/internal/xff"
 )
 
@@ -98,6 +99,21 @@ func TestParse(t *testing.T) {
 			Proxies: "256.256.256.256",
 			Err:     true,
 		},
+		{
+			Name:    "Star wildcard",
+			Proxies: "*",
+			Parsed:  []string{"0.0.0.0/0", "::/0"},
+		},
+		{
+			Name:    "All wildcard",
+			Proxies: "ALL",
+			Parsed:  []string{"0.0.0.0/0", "::/0"},
+		},
+		{
+			Name:    "Wildcard with IP",
+			Proxies: "192.178.1.1, all",
+			Parsed:  []string{"192.178.1.1/32", "0.0.0.0/0", "::/0"},
+		},
 		{
 			Name:    "Invalid CIDR",
 			Proxies: "128.128.128.0/256",
@@ -200,6 +216,333 @@ func TestMiddleware(t *testing.T) {
 	}
 }
 
+func TestMiddlewareMultiHop(t *testing.T) {
+	proxies := []string{"192.168.0.0/16", "172.16.0.1/32", "172.16.0.2/32"}
+
+	cases := []struct {
+		Name       string
+		RemoteAddr string
+		XFF        string
+		ExpectedIP string
+	}{
+		{
+			Name:       "OriginBeforeTrustedProxies",
+			RemoteAddr: "192.168.0.1:0",
+			XFF:        "10.10.10.10, 172.16.0.1, 172.16.0.2, 192.168.0.2",
+			ExpectedIP: "10.10.10.10",
+		},
+		{
+			// Clients can prepend anything to the header so only addresses appended by trusted
+			// proxies are believed.
+			Name:       "SpoofedPrefix",
+			RemoteAddr: "192.168.0.1:0",
+			XFF:        "10.0.0.1, 10.10.10.10, 172.16.0.1, 192.168.0.2",
+			ExpectedIP: "10.10.10.10",
+		},
+		{
+			Name:       "EveryAddressTrusted",
+			RemoteAddr: "192.168.0.1:0",
+			XFF:        "172.16.0.1, 192.168.0.2",
+			ExpectedIP: "172.16.0.1",
+		},
+		{
+			Name:       "UntrustedPeer",
+			RemoteAddr: "10.0.0.1:0",
+			XFF:        "10.10.10.10, 172.16.0.1",
+			ExpectedIP: "10.0.0.1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mw, err := Middleware(proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var fromContext, resolved string
+			router := gin.New()
+			router.Use(mw)
+			router.GET("/", func(ctx *gin.Context) {
+				fromContext, _ = request.ClientIPFromContext(ctx.Request.Context())
+				resolved, _ = request.RemoteAddrIP(ctx.Request)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.RemoteAddr
+			req.Header.Set("X-Forwarded-For", tc.XFF)
+
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if fromContext != tc.ExpectedIP {
+				t.Fatalf("Expected context client IP: %v; Received: %v", tc.ExpectedIP, fromContext)
+			}
+			if resolved != tc.ExpectedIP {
+				t.Fatalf("Expected resolved client IP: %v; Received: %v", tc.ExpectedIP, resolved)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWildcard(t *testing.T) {
+	proxies, err := Parse(WildcardStar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mw, err := Middleware(proxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, remoteAddr := range []string{"192.168.0.1:0", "203.0.113.7:0", "[2001:db8::1]:0"} {
+		t.Run(remoteAddr, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", "10.10.10.10")
+
+			w := ginutil.FakeResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+			mw(&gin.Context{Request: req, Writer: w})
+
+			if req.RemoteAddr != "10.10.10.10:0" {
+				t.Fatalf("unexpected remote addr: got %s, want 10.10.10.10:0", req.RemoteAddr)
+			}
+		})
+	}
+}
+
+func TestTrustsAll(t *testing.T) {
+	cases := []struct {
+		Proxies string
+		Expect  bool
+	}{
+		{Proxies: "", Expect: false},
+		{Proxies: "192.168.0.0/16,2001:db8::/32", Expect: false},
+		{Proxies: "*", Expect: true},
+		{Proxies: "192.168.0.1,all", Expect: true},
+		{Proxies: "0.0.0.0/0", Expect: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Proxies, func(t *testing.T) {
+			proxies, err := Parse(tc.Proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if TrustsAll(proxies) != tc.Expect {
+				t.Fatalf("Expected TrustsAll: %v; Received: %v", tc.Expect, !tc.Expect)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRealIP(t *testing.T) {
+	cases := []struct {
+		Name               string
+		RealIP             bool
+		RemoteAddr         string
+		XFFAddr            string
+		RealIPAddr         string
+		ExpectedRemoteAddr string
+		ExpectedStatus     int
+	}{
+		{
+			Name:               "Trusted proxy with only X-Real-IP",
+			RealIP:             true,
+			RemoteAddr:         "192.168.0.1:0",
+			RealIPAddr:         "10.10.10.10",
+			ExpectedRemoteAddr: "10.10.10.10:0",
+		},
+		{
+			Name:               "Trusted proxy with only XFF",
+			RealIP:             true,
+			RemoteAddr:         "192.168.0.1:0",
+			XFFAddr:            "10.10.10.10",
+			ExpectedRemoteAddr: "10.10.10.10:0",
+		},
+		{
+			Name:               "Trusted proxy with both prefers XFF",
+			RealIP:             true,
+			RemoteAddr:         "192.168.0.1:0",
+			XFFAddr:            "10.10.10.10",
+			RealIPAddr:         "10.10.10.11",
+			ExpectedRemoteAddr: "10.10.10.10:0",
+		},
+		{
+			Name:               "Untrusted peer with X-Real-IP",
+			RealIP:             true,
+			RemoteAddr:         "192.178.0.1:0",
+			RealIPAddr:         "10.10.10.10",
+			ExpectedRemoteAddr: "192.178.0.1:0",
+		},
+		{
+			Name:               "Invalid X-Real-IP",
+			RealIP:             true,
+			RemoteAddr:         "192.168.0.1:0",
+			RealIPAddr:         "invalid",
+			ExpectedRemoteAddr: "192.168.0.1:0",
+			ExpectedStatus:     http.StatusBadRequest,
+		},
+		{
+			Name:               "X-Real-IP disabled",
+			RemoteAddr:         "192.168.0.1:0",
+			RealIPAddr:         "10.10.10.10",
+			ExpectedRemoteAddr: "192.168.0.1:0",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.RemoteAddr
+			if tc.XFFAddr != "" {
+				req.Header.Set("X-Forwarded-For", tc.XFFAddr)
+			}
+			if tc.RealIPAddr != "" {
+				req.Header.Set("X-Real-IP", tc.RealIPAddr)
+			}
+
+			w := ginutil.FakeResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+
+			ctx := &gin.Context{
+				Request: req,
+				Writer:  w,
+			}
+
+			var opts []Option
+			if tc.RealIP {
+				opts = append(opts, WithRealIP())
+			}
+
+			mw, err := Middleware([]string{"192.168.0.0/16"}, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mw(ctx)
+
+			expectedStatus := tc.ExpectedStatus
+			if expectedStatus == 0 {
+				expectedStatus = http.StatusOK
+			}
+			if w.Code != expectedStatus {
+				t.Fatalf("unexpected status code: got %d, want %d", w.Code, expectedStatus)
+			}
+
+			if req.RemoteAddr != tc.ExpectedRemoteAddr {
+				t.Fatalf(
+					"unexpected remote addr: got %s, want %s",
+					req.RemoteAddr,
+					tc.ExpectedRemoteAddr,
+				)
+			}
+		})
+	}
+}
+
+func TestMiddlewareOverrides(t *testing.T) {
+	cases := []struct {
+		Name               string
+		RemoteAddr         string
+		XFFAddr            string
+		RealIPAddr         string
+		ExpectedRemoteAddr string
+		ExpectedStatus     int
+		ExpectStripped     bool
+	}{
+		{
+			Name:               "Valid IPv4 override",
+			RemoteAddr:         "192.168.0.1:0",
+			XFFAddr:            "10.10.10.10",
+			ExpectedRemoteAddr: "10.10.10.10:0",
+			ExpectedStatus:     http.StatusOK,
+		},
+		{
+			Name:               "Valid IPv6 override",
+			RemoteAddr:         "192.168.0.1:0",
+			XFFAddr:            "2001:db8::1",
+			ExpectedRemoteAddr: "[2001:db8::1]:0",
+			ExpectedStatus:     http.StatusOK,
+		},
+		{
+			Name:               "Valid IPv6 X-Real-IP override",
+			RemoteAddr:         "[2001:db8::ffff]:0",
+			RealIPAddr:         "2001:db8::1",
+			ExpectedRemoteAddr: "[2001:db8::1]:0",
+			ExpectedStatus:     http.StatusOK,
+		},
+		{
+			Name:               "Malformed override",
+			RemoteAddr:         "192.168.0.1:0",
+			XFFAddr:            "10.10.10.256",
+			ExpectedRemoteAddr: "192.168.0.1:0",
+			ExpectedStatus:     http.StatusBadRequest,
+		},
+		{
+			Name:               "Malformed override in proxy chain",
+			RemoteAddr:         "192.168.0.1:0",
+			XFFAddr:            "10.10.10.10, invalid",
+			ExpectedRemoteAddr: "192.168.0.1:0",
+			ExpectedStatus:     http.StatusBadRequest,
+		},
+		{
+			Name:               "Override from untrusted peer",
+			RemoteAddr:         "192.178.0.1:0",
+			XFFAddr:            "10.10.10.10",
+			RealIPAddr:         "10.10.10.11",
+			ExpectedRemoteAddr: "192.178.0.1:0",
+			ExpectedStatus:     http.StatusOK,
+			ExpectStripped:     true,
+		},
+		{
+			Name:               "Malformed override from untrusted peer",
+			RemoteAddr:         "192.178.0.1:0",
+			XFFAddr:            "invalid",
+			ExpectedRemoteAddr: "192.178.0.1:0",
+			ExpectedStatus:     http.StatusOK,
+			ExpectStripped:     true,
+		},
+	}
+
+	mw, err := Middleware([]string{"192.168.0.0/16", "2001:db8::ffff/128"}, WithRealIP())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.RemoteAddr
+			if tc.XFFAddr != "" {
+				req.Header.Set("X-Forwarded-For", tc.XFFAddr)
+			}
+			if tc.RealIPAddr != "" {
+				req.Header.Set("X-Real-IP", tc.RealIPAddr)
+			}
+
+			w := ginutil.FakeResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+			mw(&gin.Context{Request: req, Writer: w})
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("unexpected status code: got %d, want %d", w.Code, tc.ExpectedStatus)
+			}
+
+			if req.RemoteAddr != tc.ExpectedRemoteAddr {
+				t.Fatalf("unexpected remote addr: got %s, want %s", req.RemoteAddr, tc.ExpectedRemoteAddr)
+			}
+
+			if tc.ExpectStripped {
+				for _, header := range []string{"X-Forwarded-For", "X-Real-IP"} {
+					if value := req.Header.Get(header); value != "" {
+						t.Fatalf("Expected %v to be removed; Received: %v", header, value)
+					}
+				}
+			}
+		})
+	}
+}
+
 func TestMiddlewareInvalidSubnets(t *testing.T) {
 	cases := []string{
 		"dsadsa",
@@ -216,3 +559,67 @@ func TestMiddlewareInvalidSubnets(t *testing.T) {
 		})
 	}
 }
+
+func TestTrustAll(t *testing.T) {
+	cases := []struct {
+		Name               string
+		RemoteAddr         string
+		XFFAddr            string
+		ExpectedRemoteAddr string
+		ExpectedStatus     int
+	}{
+		{
+			Name:               "XFF from Unix socket",
+			RemoteAddr:         "@",
+			XFFAddr:            "10.10.10.10",
+			ExpectedRemoteAddr: "10.10.10.10:0",
+		},
+		{
+			Name:               "XFF with multiple proxies",
+			RemoteAddr:         "@",
+			XFFAddr:            "10.10.10.10, 192.168.0.1",
+			ExpectedRemoteAddr: "10.10.10.10:0",
+		},
+		{
+			Name:               "No XFF",
+			RemoteAddr:         "@",
+			ExpectedRemoteAddr: "@",
+		},
+		{
+			Name:           "Invalid XFF",
+			RemoteAddr:     "@",
+			XFFAddr:        "invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.RemoteAddr
+			if tc.XFFAddr != "" {
+				req.Header.Set("X-Forwarded-For", tc.XFFAddr)
+			}
+
+			var received string
+			handler := TrustAll(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				received = r.RemoteAddr
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			expectedStatus := tc.ExpectedStatus
+			if expectedStatus == 0 {
+				expectedStatus = http.StatusOK
+			}
+			if w.Code != expectedStatus {
+				t.Fatalf("unexpected status code: got %d, want %d", w.Code, expectedStatus)
+			}
+
+			if received != tc.ExpectedRemoteAddr {
+				t.Fatalf("unexpected remote addr: got %s, want %s", received, tc.ExpectedRemoteAddr)
+			}
+		})
+	}
+}